@@ -7,6 +7,8 @@ require (
 	github.com/gin-gonic/gin v1.12.0
 	github.com/golang-jwt/jwt/v5 v5.3.1
 	go.mongodb.org/mongo-driver v1.17.9
+	golang.org/x/image v0.25.0
+	golang.org/x/oauth2 v0.36.0
 )
 
 require (
@@ -17,7 +17,9 @@ import (
 	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	webpush "github.com/case-framework/case-backend/pkg/messaging/web-push"
 )
 
 // Environment variables
@@ -34,8 +36,10 @@ const (
 	ENV_MESSAGING_DB_USERNAME        = "MESSAGING_DB_USERNAME"
 	ENV_MESSAGING_DB_PASSWORD        = "MESSAGING_DB_PASSWORD"
 
-	ENV_SMTP_BRIDGE_API_KEY = "SMTP_BRIDGE_API_KEY"
-	ENV_STUDY_GLOBAL_SECRET = "STUDY_GLOBAL_SECRET"
+	ENV_SMTP_BRIDGE_API_KEY        = "SMTP_BRIDGE_API_KEY"
+	ENV_SMS_GATEWAY_API_KEY        = "SMS_GATEWAY_API_KEY"
+	ENV_STUDY_GLOBAL_SECRET        = "STUDY_GLOBAL_SECRET"
+	ENV_WEB_PUSH_VAPID_PRIVATE_KEY = "WEB_PUSH_VAPID_PRIVATE_KEY"
 )
 
 type config struct {
@@ -54,6 +58,9 @@ type config struct {
 
 	MessagingConfigs messagingTypes.MessagingConfigs `json:"messaging_configs" yaml:"messaging_configs"`
 
+	// Web Push delivery for templates with the push channel, disabled if not configured
+	WebPushConfig *webpush.Config `json:"web_push" yaml:"web_push"`
+
 	RunTasks struct {
 		ProcessOutgoingEmails     bool `json:"process_outgoing_emails" yaml:"process_outgoing_emails"`
 		ScheduleHandler           bool `json:"schedule_handler" yaml:"schedule_handler"`
@@ -80,6 +87,7 @@ var (
 	globalInfosDBService     *globalinfosDB.GlobalInfosDBService
 	messagingDBService       *messagingDB.MessagingDBService
 	studyDBService           *studyDB.StudyDBService
+	webPushClient            *webpush.Client
 )
 
 func init() {
@@ -115,6 +123,7 @@ func init() {
 
 	// init message sending
 	initMessageSendingConfig()
+	initWebPush()
 
 	// init study service
 	if shouldInitStudyService() {
@@ -165,9 +174,20 @@ func secretsOverride() {
 		conf.MessagingConfigs.SmtpBridgeConfig.APIKey = apiKey
 	}
 
+	if smsGatewayAPIKey := os.Getenv(ENV_SMS_GATEWAY_API_KEY); smsGatewayAPIKey != "" {
+		if conf.MessagingConfigs.SMSConfig == nil {
+			conf.MessagingConfigs.SMSConfig = &messagingTypes.SMSGatewayConfig{}
+		}
+		conf.MessagingConfigs.SMSConfig.APIKey = smsGatewayAPIKey
+	}
+
 	if globalSecret := os.Getenv(ENV_STUDY_GLOBAL_SECRET); globalSecret != "" {
 		conf.StudyConfigs.GlobalSecret = globalSecret
 	}
+
+	if vapidPrivateKey := os.Getenv(ENV_WEB_PUSH_VAPID_PRIVATE_KEY); vapidPrivateKey != "" && conf.WebPushConfig != nil {
+		conf.WebPushConfig.VAPID.PrivateKey = vapidPrivateKey
+	}
 }
 
 func initDBs() {
@@ -203,6 +223,25 @@ func initMessageSendingConfig() {
 		conf.MessagingConfigs.GlobalEmailTemplateConstants,
 		messagingDBService,
 	)
+
+	// study messages for participants without email address
+	sms.Init(
+		conf.MessagingConfigs.SMSConfig,
+		messagingDBService,
+	)
+}
+
+func initWebPush() {
+	if conf.WebPushConfig == nil {
+		slog.Info("Web Push not configured, push channel is disabled")
+		return
+	}
+	var err error
+	webPushClient, err = webpush.NewClient(*conf.WebPushConfig)
+	if err != nil {
+		slog.Error("Error initializing Web Push client", slog.String("error", err.Error()))
+		panic(err)
+	}
 }
 
 func initStudyService() {
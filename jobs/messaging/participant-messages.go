@@ -2,17 +2,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"maps"
 	"sync"
 	"time"
 
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/inbox"
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 	studyservice "github.com/case-framework/case-backend/pkg/study"
+	"github.com/case-framework/case-backend/pkg/study/calendar"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func handleParticipantMessages(wg *sync.WaitGroup) {
@@ -95,13 +101,6 @@ func handleParticipantMessages(wg *sync.WaitGroup) {
 							"language":     user.Account.PreferredLanguage,
 						}
 
-						loginToken, err := getTemploginToken(instanceID, user, study.Key)
-						if err != nil {
-							slog.Error("Error getting login token", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
-						} else {
-							payload["loginToken"] = loginToken
-						}
-
 						// include participant flags into payload:
 						for k, v := range p.Flags {
 							payload["flags."+k] = v
@@ -112,34 +111,100 @@ func handleParticipantMessages(wg *sync.WaitGroup) {
 							payload["linkingCodes."+k] = v
 						}
 
-						subject, content, err := emailsending.GenerateEmailContent(template, user.Account.PreferredLanguage, payload)
-						if err != nil {
-							counters.IncreaseCounter(false)
-							slog.Error("Error generating email content", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type), slog.String("error", err.Error()))
-							continue
+						channels := messagingTypes.ResolveDeliveryChannels(message.Channels, template.Channels)
+						delivered := false
+						inboxMessageID := ""
+						if channels.Inbox {
+							// inbox messages are stored, so they must not contain a login token
+							inboxMessage, err := inbox.GenerateInboxMessage(template, user.ID.Hex(), currentProfile.ID.Hex(), user.Account.PreferredLanguage, maps.Clone(payload), message.SurveyKey)
+							if err == nil {
+								inboxMessage, err = messagingDBService.AddInboxMessage(instanceID, inboxMessage)
+							}
+							if err != nil {
+								counters.IncreaseCounter(false)
+								slog.Error("Failed to deliver inbox message", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type), slog.String("error", err.Error()))
+							} else {
+								counters.IncreaseCounter(true)
+								delivered = true
+								inboxMessageID = inboxMessage.ID.Hex()
+							}
 						}
 
-						to := []string{
-							user.Account.AccountID,
+						if channels.Push {
+							err := sendPushNotification(instanceID, user.ID.Hex(), template, user.Account.PreferredLanguage, inboxMessageID)
+							if errors.Is(err, errNoPushSubscription) {
+								slog.Debug("No push subscription for participant", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type))
+								// push only messages cannot reach the participant, retrying would not change that
+								delivered = delivered || (!channels.Email && !channels.Inbox)
+							} else if err != nil {
+								counters.IncreaseCounter(false)
+								slog.Error("Failed to deliver push notification", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type), slog.String("error", err.Error()))
+							} else {
+								counters.IncreaseCounter(true)
+								delivered = true
+							}
 						}
 
-						outgoingEmail := messagingTypes.OutgoingEmail{
-							MessageType:     message.Type,
-							HeaderOverrides: template.HeaderOverrides,
-							To:              to,
-							UserID:          user.ID.Hex(),
-							Subject:         subject,
-							Content:         content,
+						email, emailErr := user.GetMessagingEmail()
+						phone, phoneErr := user.GetMessagingPhone()
+						if channels.Email && emailErr != nil && phoneErr == nil {
+							// participants without email address receive the SMS template of the study
+							loginToken, err := getTemploginToken(instanceID, user, study.Key)
+							if err != nil {
+								slog.Error("Error getting login token", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+							} else {
+								payload["loginToken"] = loginToken
+							}
+
+							err = sms.SendStudySMS(instanceID, phone, user.ID.Hex(), study.Key, message.Type, user.Account.PreferredLanguage, payload)
+							if errors.Is(err, mongo.ErrNoDocuments) {
+								slog.Debug("No SMS template for participant without email address", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type))
+								delivered = delivered || (!channels.Push && !channels.Inbox)
+							} else if err != nil {
+								counters.IncreaseCounter(false)
+								slog.Error("Failed to send SMS", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type), slog.String("error", err.Error()))
+							} else {
+								counters.IncreaseCounter(true)
+								delivered = true
+							}
+						} else if channels.Email && emailErr != nil {
+							slog.Debug("No email address for participant", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type))
+							// email only messages cannot reach the participant, retrying would not change that
+							delivered = delivered || (!channels.Push && !channels.Inbox)
+						} else if channels.Email {
+							loginToken, err := getTemploginToken(instanceID, user, study.Key)
+							if err != nil {
+								slog.Error("Error getting login token", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
+							} else {
+								payload["loginToken"] = loginToken
+							}
+
+							attachments := []messagingTypes.EmailAttachment{}
+							if template.AttachCalendar {
+								cal := calendar.Calendar{
+									Name:   calendar.LocalisedText(study.Props.Name, user.Account.PreferredLanguage),
+									Events: calendar.ParticipantEvents(study, p, currentProfile.ID.Hex(), "", user.Account.PreferredLanguage, calendar.SurveyPropsFromDB(studyDBService, instanceID, study.Key)),
+								}
+								attachments = append(attachments, messagingTypes.EmailAttachment{
+									Filename:    calendar.ATTACHMENT_FILENAME,
+									ContentType: calendar.CONTENT_TYPE,
+									Content:     cal.Encode(),
+								})
+							}
+
+							if err := addParticipantMessageToOutgoingEmails(instanceID, template, user.Account.PreferredLanguage, payload, user.ID.Hex(), email, attachments); err != nil {
+								counters.IncreaseCounter(false)
+								slog.Error("Failed to prepare outgoing email", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("messageType", message.Type), slog.String("error", err.Error()))
+							} else {
+								counters.IncreaseCounter(true)
+								delivered = true
+							}
 						}
 
-						_, err = messagingDBService.AddToOutgoingEmails(instanceID, outgoingEmail)
-						if err != nil {
-							slog.Error("Failed to save outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
-							counters.IncreaseCounter(false)
-							continue
+						// a message counts as sent once it reached the participant on one channel, retrying would duplicate it on the others
+						if delivered {
+							sentMessages = append(sentMessages, message.ID)
 						}
-						counters.IncreaseCounter(true)
-						sentMessages = append(sentMessages, message.ID.Hex())
 					}
 
 					// delete messages from participant
@@ -167,28 +232,43 @@ func handleParticipantMessages(wg *sync.WaitGroup) {
 	slog.Info("Finished handling participant messages")
 }
 
-func getRelevantMessages(p studyTypes.Participant) []studyTypes.StudyMessage {
-	messages := []studyTypes.StudyMessage{}
+func getRelevantMessages(p studyTypes.Participant) []studyTypes.ParticipantMessage {
+	messages := []studyTypes.ParticipantMessage{}
 
 	for _, message := range p.Messages {
 		if message.ScheduledFor > time.Now().Unix() {
 			continue
 		}
-		_id, err := primitive.ObjectIDFromHex(message.ID)
-		if err != nil {
+		if _, err := primitive.ObjectIDFromHex(message.ID); err != nil {
 			slog.Error("Error parsing message id", slog.String("messageID", message.ID), slog.String("error", err.Error()))
 			continue
 		}
-		messages = append(messages, studyTypes.StudyMessage{
-			ID:      _id,
-			Type:    message.Type,
-			Payload: p.Flags,
-		})
+		messages = append(messages, message)
 	}
 
 	return messages
 }
 
+func addParticipantMessageToOutgoingEmails(instanceID string, template messagingTypes.EmailTemplate, lang string, payload map[string]string, userID string, to string, attachments []messagingTypes.EmailAttachment) error {
+	subject, content, err := emailsending.GenerateEmailContent(template, lang, payload)
+	if err != nil {
+		return err
+	}
+
+	outgoingEmail := messagingTypes.OutgoingEmail{
+		MessageType:     template.MessageType,
+		HeaderOverrides: template.HeaderOverrides,
+		To:              []string{to},
+		UserID:          userID,
+		Subject:         subject,
+		Content:         content,
+		Attachments:     attachments,
+	}
+
+	_, err = messagingDBService.AddToOutgoingEmails(instanceID, outgoingEmail)
+	return err
+}
+
 func getProfileID(instanceID string, study studyTypes.Study, p studyTypes.Participant) (string, error) {
 	confidentialPID, err := studyservice.ComputeConfidentialIDForParticipant(study, p.ParticipantID)
 	if err != nil {
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	emailtemplates "github.com/case-framework/case-backend/pkg/messaging/email-templates"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	webpush "github.com/case-framework/case-backend/pkg/messaging/web-push"
+)
+
+var errNoPushSubscription = errors.New("no push subscription for user")
+
+// sendPushNotification notifies all devices of the user the message template is configured for.
+// The payload only carries the localised subject, the full content is in the inbox or email.
+// Subscriptions the push service reports as gone are removed.
+func sendPushNotification(instanceID string, userID string, template messagingTypes.EmailTemplate, lang string, inboxMessageID string) error {
+	if webPushClient == nil {
+		return errors.New("web push not configured")
+	}
+
+	subs, err := messagingDBService.GetPushSubscriptionsForUser(instanceID, userID)
+	if err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return errNoPushSubscription
+	}
+
+	translation := emailtemplates.GetTemplateTranslation(template, lang)
+	payload, err := json.Marshal(messagingTypes.PushNotificationPayload{
+		Title:          translation.Subject,
+		MessageType:    template.MessageType,
+		StudyKey:       template.StudyKey,
+		Language:       translation.Lang,
+		InboxMessageID: inboxMessageID,
+	})
+	if err != nil {
+		return err
+	}
+
+	delivered := 0
+	for _, sub := range subs {
+		err := webPushClient.Send(context.Background(), webpush.Subscription{
+			Endpoint: sub.Endpoint,
+			P256dh:   sub.Keys.P256dh,
+			Auth:     sub.Keys.Auth,
+		}, payload, webpush.Options{
+			Topic: template.MessageType,
+		})
+		if errors.Is(err, webpush.ErrSubscriptionGone) {
+			slog.Info("Removing expired push subscription", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("subscriptionID", sub.ID.Hex()))
+			if err := messagingDBService.DeletePushSubscriptionByID(instanceID, sub.ID); err != nil {
+				slog.Error("Failed to remove push subscription", slog.String("instanceID", instanceID), slog.String("subscriptionID", sub.ID.Hex()), slog.String("error", err.Error()))
+			}
+			continue
+		}
+		if err != nil {
+			slog.Error("Failed to send push notification", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("subscriptionID", sub.ID.Hex()), slog.String("error", err.Error()))
+			continue
+		}
+		delivered++
+		if err := messagingDBService.UpdatePushSubscriptionLastUsed(instanceID, sub.ID); err != nil {
+			slog.Error("Failed to update push subscription", slog.String("instanceID", instanceID), slog.String("subscriptionID", sub.ID.Hex()), slog.String("error", err.Error()))
+		}
+	}
+
+	if delivered == 0 {
+		return errors.New("push notification could not be delivered to any device")
+	}
+	return nil
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/inbox"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 	studyservice "github.com/case-framework/case-backend/pkg/study"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
@@ -105,30 +107,11 @@ func generateScheduledEmailsForAllUsers(instanceID string, message messagingType
 				return nil
 			}
 
-			if !hasAccountType(&user, "email") {
+			if !hasAccountType(&user, umTypes.ACCOUNT_TYPE_EMAIL, umTypes.ACCOUNT_TYPE_OIDC) {
 				return nil
 			}
 
-			outgoingEmail, err := prepOutgoingFromScheduledEmail(
-				instanceID,
-				message,
-				user,
-			)
-			if err != nil {
-				slog.Error("Failed to prepare outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
-				counters.IncreaseCounter(false)
-				return err
-			}
-
-			_, err = messagingDBService.AddToOutgoingEmails(instanceID, *outgoingEmail)
-			if err != nil {
-				slog.Error("Failed to save outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
-				counters.IncreaseCounter(false)
-				return err
-			}
-
-			counters.IncreaseCounter(true)
-			return nil
+			return deliverScheduledMessage(instanceID, message, user, &counters)
 		},
 	)
 	counters.Stop()
@@ -158,7 +141,7 @@ func generateScheduledEmailsForStudyParticipants(instanceID string, message mess
 				return nil
 			}
 
-			if !hasAccountType(&user, "email") {
+			if !hasAccountType(&user, umTypes.ACCOUNT_TYPE_EMAIL, umTypes.ACCOUNT_TYPE_OIDC) {
 				return nil
 			}
 
@@ -171,26 +154,7 @@ func generateScheduledEmailsForStudyParticipants(instanceID string, message mess
 				return err
 			}
 
-			outgoingEmail, err := prepOutgoingFromScheduledEmail(
-				instanceID,
-				message,
-				user,
-			)
-			if err != nil {
-				slog.Error("Failed to prepare outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
-				counters.IncreaseCounter(false)
-				return err
-			}
-
-			_, err = messagingDBService.AddToOutgoingEmails(instanceID, *outgoingEmail)
-			if err != nil {
-				slog.Error("Failed to save outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
-				counters.IncreaseCounter(false)
-				return err
-			}
-
-			counters.IncreaseCounter(true)
-			return nil
+			return deliverScheduledMessage(instanceID, message, user, &counters)
 		},
 	)
 	counters.Stop()
@@ -201,6 +165,70 @@ func generateScheduledEmailsForStudyParticipants(instanceID string, message mess
 	slog.Info("Generated messages for scheduled email", slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.Int("generatedMessages", counters.Success), slog.Int("failedMessages", counters.Failed), slog.String("label", message.Label))
 }
 
+// deliverScheduledMessage sends the message to the user on the channels configured in the template
+func deliverScheduledMessage(instanceID string, message messagingTypes.ScheduledEmail, user umTypes.User, counters *MessageCounter) error {
+	channels := messagingTypes.ResolveDeliveryChannels(nil, message.Template.Channels)
+
+	inboxMessageID := ""
+	if channels.Inbox {
+		payload := map[string]string{
+			"language": user.Account.PreferredLanguage,
+			"studyKey": message.StudyKey,
+		}
+		inboxMessage, err := inbox.GenerateInboxMessage(message.Template, user.ID.Hex(), "", user.Account.PreferredLanguage, payload, "")
+		if err == nil {
+			inboxMessage, err = messagingDBService.AddInboxMessage(instanceID, inboxMessage)
+		}
+		if err != nil {
+			slog.Error("Failed to deliver inbox message", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
+			counters.IncreaseCounter(false)
+		} else {
+			counters.IncreaseCounter(true)
+			inboxMessageID = inboxMessage.ID.Hex()
+		}
+	}
+
+	if channels.Push {
+		err := sendPushNotification(instanceID, user.ID.Hex(), message.Template, user.Account.PreferredLanguage, inboxMessageID)
+		if err != nil && !errors.Is(err, errNoPushSubscription) {
+			slog.Error("Failed to deliver push notification", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
+			counters.IncreaseCounter(false)
+		} else if err == nil {
+			counters.IncreaseCounter(true)
+		}
+	}
+
+	if !channels.Email {
+		return nil
+	}
+
+	if _, err := user.GetMessagingEmail(); err != nil {
+		slog.Debug("No email address for participant", slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
+		return nil
+	}
+
+	outgoingEmail, err := prepOutgoingFromScheduledEmail(
+		instanceID,
+		message,
+		user,
+	)
+	if err != nil {
+		slog.Error("Failed to prepare outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
+		counters.IncreaseCounter(false)
+		return err
+	}
+
+	_, err = messagingDBService.AddToOutgoingEmails(instanceID, *outgoingEmail)
+	if err != nil {
+		slog.Error("Failed to save outgoing email", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("messageID", message.ID.Hex()), slog.String("userID", user.ID.Hex()))
+		counters.IncreaseCounter(false)
+		return err
+	}
+
+	counters.IncreaseCounter(true)
+	return nil
+}
+
 func isSubscribed(user *umTypes.User, messageType string) bool {
 	switch messageType {
 	case messagingTypes.EMAIL_TYPE_WEEKLY:
@@ -211,8 +239,8 @@ func isSubscribed(user *umTypes.User, messageType string) bool {
 	return true
 }
 
-func hasAccountType(user *umTypes.User, accountType string) bool {
-	return user.Account.Type == accountType
+func hasAccountType(user *umTypes.User, accountTypes ...string) bool {
+	return slices.Contains(accountTypes, user.Account.Type)
 }
 
 func prepOutgoingFromScheduledEmail(
@@ -225,8 +253,8 @@ func prepOutgoingFromScheduledEmail(
 		HeaderOverrides: message.Template.HeaderOverrides,
 	}
 
-	if user.Account.Type == "email" {
-		outgoingEmail.To = []string{user.Account.AccountID}
+	if email, err := user.GetMessagingEmail(); err == nil {
+		outgoingEmail.To = []string{email}
 	}
 
 	payload := map[string]string{}
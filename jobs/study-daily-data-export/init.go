@@ -30,6 +30,8 @@ type ResponseExportTask struct {
 	Separator       string   `json:"separator" yaml:"separator"`
 	ShortKeys       bool     `json:"short_keys" yaml:"short_keys"`
 	CreateEmptyFile bool     `json:"create_empty_file" yaml:"create_empty_file"`
+	NotShownMarker  string   `json:"not_shown_marker" yaml:"not_shown_marker"` // value for questions hidden by their conditions
+	IncludeScores   bool     `json:"include_scores" yaml:"include_scores"`
 }
 
 type ConfidentialResponsesExportTask struct {
@@ -36,7 +36,7 @@ func runResponseExportsForTask(rExpTask ResponseExportTask) {
 	}
 
 	for _, surveyKey := range rExpTask.SurveyKeys {
-		parser, trackAccount, err := initResponseParser(rExpTask.InstanceID, rExpTask.StudyKey, surveyKey, rExpTask.ShortKeys, rExpTask.Separator)
+		parser, trackAccount, err := initResponseParser(rExpTask.InstanceID, rExpTask.StudyKey, surveyKey, rExpTask.ShortKeys, rExpTask.Separator, rExpTask.NotShownMarker, rExpTask.IncludeScores)
 		if err != nil {
 			continue
 		}
@@ -62,7 +62,7 @@ func runResponseExportsForTask(rExpTask ResponseExportTask) {
 	}
 }
 
-func initResponseParser(instanceID string, studyKey string, surveyKey string, shortKeys bool, separator string) (*surveyresponses.ResponseParser, bool, error) {
+func initResponseParser(instanceID string, studyKey string, surveyKey string, shortKeys bool, separator string, notShownMarker string, includeScores bool) (*surveyresponses.ResponseParser, bool, error) {
 	study, err := studyDBService.GetStudy(instanceID, studyKey)
 	if err != nil {
 		slog.Error("failed to get study", slog.String("error", err.Error()))
@@ -101,6 +101,18 @@ func initResponseParser(instanceID string, studyKey string, surveyKey string, sh
 	if trackAccount {
 		parser.EnableAccountTracking()
 	}
+	if notShownMarker != "" {
+		parser.EnableNotShownMarker(notShownMarker)
+	}
+	if includeScores {
+		parser.EnableScoreColumns(func(responseID string) map[string]float64 {
+			record, err := studyDBService.GetSurveyScoreRecordByResponseID(instanceID, studyKey, responseID)
+			if err != nil {
+				return nil
+			}
+			return record.Map()
+		})
+	}
 	return parser, trackAccount, nil
 }
 
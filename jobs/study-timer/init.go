@@ -3,6 +3,7 @@ package main
 import (
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/case-framework/case-backend/pkg/db"
 	"github.com/case-framework/case-backend/pkg/study"
@@ -24,6 +25,10 @@ const (
 	ENV_STUDY_GLOBAL_SECRET = "STUDY_GLOBAL_SECRET"
 )
 
+const (
+	defaultAbandonedUploadsMaxAge = 24 * time.Hour
+)
+
 type config struct {
 	// Logging configs
 	Logging utils.LoggerConfig `json:"logging" yaml:"logging"`
@@ -45,6 +50,10 @@ type config struct {
 	CleanUpConfig struct {
 		FilestorePath            string `json:"filestore_path" yaml:"filestore_path"`
 		CleanOrphanedTaskResults bool   `json:"clean_orphaned_task_results" yaml:"clean_orphaned_task_results"`
+
+		CleanAbandonedUploads    bool          `json:"clean_abandoned_uploads" yaml:"clean_abandoned_uploads"`
+		ParticipantFilestorePath string        `json:"participant_filestore_path" yaml:"participant_filestore_path"`
+		AbandonedUploadsMaxAge   time.Duration `json:"abandoned_uploads_max_age" yaml:"abandoned_uploads_max_age"`
 	} `json:"clean_up_config" yaml:"clean_up_config"`
 }
 
@@ -82,6 +91,10 @@ func init() {
 	// Override secrets from environment variables
 	secretsOverride()
 
+	if conf.CleanUpConfig.AbandonedUploadsMaxAge <= 0 {
+		conf.CleanUpConfig.AbandonedUploadsMaxAge = defaultAbandonedUploadsMaxAge
+	}
+
 	// init db
 	initDBs()
 
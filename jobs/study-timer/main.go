@@ -8,6 +8,7 @@ import (
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	studyUtils "github.com/case-framework/case-backend/pkg/study/utils"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func main() {
@@ -24,7 +25,19 @@ func main() {
 
 		for _, study := range studies {
 			updateStudyStats(instanceID, study)
+			applyScheduledStudyVariableChanges(instanceID, study.Key)
+			resetDueStudyCounters(instanceID, study.Key)
 			studyservice.OnStudyTimer(instanceID, &study)
+
+			if conf.CleanUpConfig.CleanAbandonedUploads {
+				studyUtils.CleanUpAbandonedUploads(
+					instanceID,
+					study.Key,
+					studyDBService,
+					conf.CleanUpConfig.ParticipantFilestorePath,
+					conf.CleanUpConfig.AbandonedUploadsMaxAge,
+				)
+			}
 		}
 
 		if conf.CleanUpConfig.CleanOrphanedTaskResults {
@@ -70,3 +83,43 @@ func updateStudyStats(instanceID string, study studyTypes.Study) {
 		slog.Error("Failed to update study stats", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
 	}
 }
+
+func applyScheduledStudyVariableChanges(instanceID string, studyKey string) {
+	now := time.Now().UTC()
+	for {
+		change, err := studyDBService.ClaimNextDueStudyVariableScheduledChange(instanceID, studyKey, now)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				slog.Error("Failed to get due study variable changes", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+			}
+			return
+		}
+
+		_, err = studyDBService.UpdateStudyVariableValue(instanceID, studyKey, change.Key, change.Value, studyTypes.StudyVariableChangeOrigin{
+			Source:    studyTypes.STUDY_VARIABLE_CHANGE_SOURCE_SCHEDULE,
+			ChangedBy: change.ID.Hex(),
+		})
+		if err != nil {
+			slog.Error("Failed to apply scheduled study variable change", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("variableKey", change.Key))
+			if err := studyDBService.MarkStudyVariableScheduledChangeFailed(instanceID, change.ID, err.Error()); err != nil {
+				slog.Error("Failed to mark scheduled study variable change as failed", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+			}
+			continue
+		}
+		if err := studyDBService.MarkStudyVariableScheduledChangeApplied(instanceID, change.ID, time.Now().UTC()); err != nil {
+			slog.Error("Failed to mark scheduled study variable change as applied", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+		}
+		slog.Info("Applied scheduled study variable change", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("variableKey", change.Key))
+	}
+}
+
+func resetDueStudyCounters(instanceID string, studyKey string) {
+	count, err := studyDBService.ResetDueStudyCounters(instanceID, studyKey, time.Now().UTC())
+	if err != nil {
+		slog.Error("Failed to reset study counters", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+		return
+	}
+	if count > 0 {
+		slog.Info("Reset study counters", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.Int64("count", count))
+	}
+}
@@ -16,6 +16,7 @@ import (
 	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 	"gopkg.in/yaml.v2"
 )
@@ -34,6 +35,7 @@ const (
 	ENV_MESSAGING_DB_USERNAME        = "MESSAGING_DB_USERNAME"
 	ENV_MESSAGING_DB_PASSWORD        = "MESSAGING_DB_PASSWORD"
 	ENV_SMTP_BRIDGE_API_KEY          = "SMTP_BRIDGE_API_KEY"
+	ENV_SMS_GATEWAY_API_KEY          = "SMS_GATEWAY_API_KEY"
 	ENV_STUDY_GLOBAL_SECRET          = "STUDY_GLOBAL_SECRET"
 )
 
@@ -51,6 +53,9 @@ type config struct {
 
 	InstanceIDs []string `json:"instance_ids" yaml:"instance_ids"`
 
+	// filestore path of the participant API, used to remove data export archives of deleted users
+	ParticipantFilestorePath string `json:"participant_filestore_path" yaml:"participant_filestore_path"`
+
 	// user management configs
 	UserManagementConfig struct {
 		DeleteUnverifiedUsersAfter                 time.Duration `json:"delete_unverified_users_after" yaml:"delete_unverified_users_after"`
@@ -184,6 +189,13 @@ func secretsOverride() {
 		conf.MessagingConfigs.SmtpBridgeConfig.APIKey = apiKey
 	}
 
+	if smsGatewayAPIKey := os.Getenv(ENV_SMS_GATEWAY_API_KEY); smsGatewayAPIKey != "" {
+		if conf.MessagingConfigs.SMSConfig == nil {
+			conf.MessagingConfigs.SMSConfig = &messagingTypes.SMSGatewayConfig{}
+		}
+		conf.MessagingConfigs.SMSConfig.APIKey = smsGatewayAPIKey
+	}
+
 	if globalSecret := os.Getenv(ENV_STUDY_GLOBAL_SECRET); globalSecret != "" {
 		conf.StudyConfigs.GlobalSecret = globalSecret
 	}
@@ -240,10 +252,16 @@ func initMessageSendingConfig() {
 		conf.MessagingConfigs.GlobalEmailTemplateConstants,
 		messagingDBService,
 	)
+
+	// SMS are sent directly, for accounts without email address
+	sms.Init(
+		conf.MessagingConfigs.SMSConfig,
+		messagingDBService,
+	)
 }
 
 func initUserManagement() {
-	usermanagement.Init(participantUserDBService, globalInfosDBService)
+	usermanagement.Init(participantUserDBService, globalInfosDBService, messagingDBService)
 }
 
 func initStudyService() {
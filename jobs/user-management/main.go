@@ -8,6 +8,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 	studyService "github.com/case-framework/case-backend/pkg/study"
 	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
@@ -67,6 +68,7 @@ func cleanUpUnverifiedUsers() {
 						for _, profile := range profiles {
 							studyService.OnProfileDeleted(instanceID, profile, nil)
 						}
+						studyService.DeleteDataExportsForUser(instanceID, user.ID.Hex(), conf.ParticipantFilestorePath)
 						return nil
 					},
 					func(email string) error {
@@ -88,6 +90,21 @@ func cleanUpUnverifiedUsers() {
 						}
 						return nil
 					},
+					func(phone string) error {
+						err := sms.SendSMS(
+							instanceID,
+							phone,
+							user.ID.Hex(),
+							sms.SMS_MESSAGE_TYPE_ACCOUNT_DELETED,
+							user.Account.PreferredLanguage,
+							map[string]string{},
+						)
+						if err != nil {
+							slog.Error("failed to send account deleted SMS", slog.String("error", err.Error()))
+							return err
+						}
+						return nil
+					},
 				)
 				if err != nil {
 					slog.Error("failed to delete user", slog.String("error", err.Error()))
@@ -113,6 +130,7 @@ func sendReminderToConfirmAccounts() {
 		createdBefore := time.Now().Add(-conf.UserManagementConfig.SendReminderToConfirmAccountAfter).Unix()
 		filter := bson.M{}
 		filter["$and"] = bson.A{
+			bson.M{"account.type": umTypes.ACCOUNT_TYPE_EMAIL},
 			bson.M{"account.accountConfirmedAt": bson.M{"$lt": 1}},
 			bson.M{"timestamps.reminderToConfirmSentAt": bson.M{"$lt": 1}},
 			bson.M{"timestamps.createdAt": bson.M{"$lt": createdBefore}},
@@ -219,46 +237,76 @@ func notifyInactiveUsersAndMarkForDeletion() {
 			nil,
 			false,
 			func(user umTypes.User, args ...interface{}) error {
-				// Generate token
-				tempTokenInfos := umTypes.TempToken{
-					UserID:     user.ID.Hex(),
-					InstanceID: instanceID,
-					Purpose:    umTypes.TOKEN_PURPOSE_INACTIVE_USER_NOTIFICATION,
-					Info: map[string]string{
-						"type":  umTypes.ACCOUNT_TYPE_EMAIL,
-						"email": user.Account.AccountID,
-					},
-					Expiration: umUtils.GetExpirationTime(conf.UserManagementConfig.MarkForDeletionAfterInactivityNotification),
-				}
-				tempToken, err := globalInfosDBService.AddTempToken(tempTokenInfos)
-				if err != nil {
-					slog.Error("failed to create verification token", slog.String("error", err.Error()))
-					return err
-				}
-
-				// Call message sending
-				err = emailsending.QueueEmailByTemplate(
-					instanceID,
-					[]string{
-						user.Account.AccountID,
-					},
-					user.ID.Hex(),
-					emailTypes.EMAIL_TYPE_ACCOUNT_INACTIVITY,
-					"",
-					user.Account.PreferredLanguage,
-					map[string]string{
-						"token": tempToken,
-					},
-					true,
-				)
-				if err != nil {
-					slog.Error("failed to queue inactivity notice email", slog.String("error", err.Error()))
-					return err
+				email, emailErr := user.GetMessagingEmail()
+				phone, phoneErr := user.GetMessagingPhone()
+				if emailErr != nil && phoneErr != nil {
+					// cannot be notified, e.g. accounts created through an identity provider without a verified email address
+					slog.Info("no email address or phone number to send inactivity notice, marking for deletion without notice", slog.String("instanceID", instanceID), slog.String("userID", user.ID.Hex()))
+				} else {
+					// Generate token
+					tempTokenInfos := umTypes.TempToken{
+						UserID:     user.ID.Hex(),
+						InstanceID: instanceID,
+						Purpose:    umTypes.TOKEN_PURPOSE_INACTIVE_USER_NOTIFICATION,
+						Info: map[string]string{
+							"type":  umTypes.ACCOUNT_TYPE_EMAIL,
+							"email": email,
+						},
+						Expiration: umUtils.GetExpirationTime(conf.UserManagementConfig.MarkForDeletionAfterInactivityNotification),
+					}
+					if emailErr != nil {
+						tempTokenInfos.Info = map[string]string{
+							"type":  umTypes.ACCOUNT_TYPE_PHONE,
+							"phone": phone,
+						}
+					}
+					tempToken, err := globalInfosDBService.AddTempToken(tempTokenInfos)
+					if err != nil {
+						slog.Error("failed to create verification token", slog.String("error", err.Error()))
+						return err
+					}
+
+					// Call message sending
+					if emailErr == nil {
+						err = emailsending.QueueEmailByTemplate(
+							instanceID,
+							[]string{
+								email,
+							},
+							user.ID.Hex(),
+							emailTypes.EMAIL_TYPE_ACCOUNT_INACTIVITY,
+							"",
+							user.Account.PreferredLanguage,
+							map[string]string{
+								"token": tempToken,
+							},
+							true,
+						)
+						if err != nil {
+							slog.Error("failed to queue inactivity notice email", slog.String("error", err.Error()))
+							return err
+						}
+					} else {
+						err = sms.SendSMS(
+							instanceID,
+							phone,
+							user.ID.Hex(),
+							sms.SMS_MESSAGE_TYPE_ACCOUNT_INACTIVITY,
+							user.Account.PreferredLanguage,
+							map[string]string{
+								"token": tempToken,
+							},
+						)
+						if err != nil {
+							slog.Error("failed to send inactivity notice SMS", slog.String("error", err.Error()))
+							return err
+						}
+					}
 				}
 
 				// Update user record
 				update := bson.M{"$set": bson.M{"timestamps.markedForDeletion": time.Now().Add(conf.UserManagementConfig.MarkForDeletionAfterInactivityNotification).Unix()}}
-				err = participantUserDBService.UpdateUser(instanceID, user.ID.Hex(), update)
+				err := participantUserDBService.UpdateUser(instanceID, user.ID.Hex(), update)
 				if err != nil {
 					slog.Error("failed to update user record", slog.String("error", err.Error()))
 					return err
@@ -304,6 +352,7 @@ func cleanUpUsersMarkedForDeletion() {
 						for _, profile := range profiles {
 							studyService.OnProfileDeleted(instanceID, profile, nil)
 						}
+						studyService.DeleteDataExportsForUser(instanceID, user.ID.Hex(), conf.ParticipantFilestorePath)
 						return nil
 					},
 					func(email string) error {
@@ -325,6 +374,21 @@ func cleanUpUsersMarkedForDeletion() {
 						}
 						return nil
 					},
+					func(phone string) error {
+						err := sms.SendSMS(
+							instanceID,
+							phone,
+							user.ID.Hex(),
+							sms.SMS_MESSAGE_TYPE_ACCOUNT_DELETED_AFTER_INACTIVITY,
+							user.Account.PreferredLanguage,
+							map[string]string{},
+						)
+						if err != nil {
+							slog.Error("failed to send account deleted SMS", slog.String("error", err.Error()))
+							return err
+						}
+						return nil
+					},
 				)
 				if err != nil {
 					slog.Error("failed to delete user", slog.String("error", err.Error()))
@@ -82,6 +82,24 @@ func ParseEscapedJSONQueryFromContext(c *gin.Context, key string) (bson.M, error
 	return jsonMap, nil
 }
 
+const (
+	ARM_FILTER_FIELD_PARTICIPANTS = "arm.key"
+	ARM_FILTER_FIELD_RESPONSES    = "context.arm"
+)
+
+// ApplyArmFilterFromCtx restricts the filter to the study arm given in the "arm" query parameter
+func ApplyArmFilterFromCtx(c *gin.Context, filter bson.M, field string) bson.M {
+	arm := c.DefaultQuery("arm", "")
+	if arm == "" {
+		return filter
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+	filter[field] = arm
+	return filter
+}
+
 type ResponseExportQuery struct {
 	SurveyKey         string
 	UseShortKeys      bool
@@ -90,6 +108,8 @@ type ResponseExportQuery struct {
 	IncludeMeta       *surveyresponses.IncludeMeta
 	PaginationInfos   *PagenatedQuery
 	ExtraCtxCols      *[]string
+	NotShownMarker    string // value for questions hidden by their conditions, empty to export them like skipped questions
+	IncludeScores     bool
 }
 
 func ParseResponseExportQueryFromCtx(c *gin.Context) (*ResponseExportQuery, error) {
@@ -105,6 +125,7 @@ func ParseResponseExportQueryFromCtx(c *gin.Context) (*ResponseExportQuery, erro
 		}
 	}
 	paginatedQuery.Filter["key"] = surveyKey
+	paginatedQuery.Filter = ApplyArmFilterFromCtx(c, paginatedQuery.Filter, ARM_FILTER_FIELD_RESPONSES)
 	useShortKeys, err := strconv.ParseBool(c.DefaultQuery("shortKeys", "false"))
 	if err != nil {
 		return nil, err
@@ -112,6 +133,11 @@ func ParseResponseExportQueryFromCtx(c *gin.Context) (*ResponseExportQuery, erro
 
 	questionOptionSep := c.DefaultQuery("questionOptionSep", "-")
 
+	includeScores, err := strconv.ParseBool(c.DefaultQuery("includeScores", "false"))
+	if err != nil {
+		return nil, err
+	}
+
 	format := c.DefaultQuery("format", "wide")
 	q := &ResponseExportQuery{
 		SurveyKey:         surveyKey,
@@ -119,6 +145,8 @@ func ParseResponseExportQueryFromCtx(c *gin.Context) (*ResponseExportQuery, erro
 		QuestionOptionSep: questionOptionSep,
 		Format:            format,
 		PaginationInfos:   paginatedQuery,
+		NotShownMarker:    c.DefaultQuery("notShownMarker", ""),
+		IncludeScores:     includeScores,
 	}
 
 	extraCtxColsQuery := c.DefaultQuery("extraContextColumns", "")
@@ -128,6 +128,18 @@ func (dbService *GlobalInfosDBService) DeleteTempToken(token string) error {
 	return nil
 }
 
+// ConsumeTempToken removes the token and returns it, so that it can be used only once
+func (dbService *GlobalInfosDBService) ConsumeTempToken(token string) (userTypes.TempToken, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"token": token}
+
+	t := userTypes.TempToken{}
+	err := dbService.collectionTemptokens().FindOneAndDelete(ctx, filter).Decode(&t)
+	return t, err
+}
+
 func (dbService *GlobalInfosDBService) UpdateTempTokenExpirationTime(token string, newExpiration time.Time) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
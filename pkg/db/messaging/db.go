@@ -13,12 +13,14 @@ import (
 
 // collection names
 const (
-	COLLECTION_NAME_EMAIL_TEMPLATES = "email-templates"
-	COLLECTION_NAME_SMS_TEMPLATES   = "sms-templates"
-	COLLECTION_NAME_EMAIL_SCHEDULES = "auto-messages"
-	COLLECTION_NAME_OUTGOING_EMAILS = "outgoing-emails"
-	COLLECTION_NAME_SENT_EMAILS     = "sent-emails"
-	COLLECTION_NAME_SENT_SMS        = "sent-sms"
+	COLLECTION_NAME_EMAIL_TEMPLATES    = "email-templates"
+	COLLECTION_NAME_SMS_TEMPLATES      = "sms-templates"
+	COLLECTION_NAME_EMAIL_SCHEDULES    = "auto-messages"
+	COLLECTION_NAME_OUTGOING_EMAILS    = "outgoing-emails"
+	COLLECTION_NAME_SENT_EMAILS        = "sent-emails"
+	COLLECTION_NAME_SENT_SMS           = "sent-sms"
+	COLLECTION_NAME_INBOX_MESSAGES     = "inbox-messages"
+	COLLECTION_NAME_PUSH_SUBSCRIPTIONS = "push-subscriptions"
 )
 
 type MessagingDBService struct {
@@ -91,6 +93,14 @@ func (dbService *MessagingDBService) collectionSentSMS(instanceID string) *mongo
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_SENT_SMS)
 }
 
+func (dbService *MessagingDBService) collectionInboxMessages(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_INBOX_MESSAGES)
+}
+
+func (dbService *MessagingDBService) collectionPushSubscriptions(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_PUSH_SUBSCRIPTIONS)
+}
+
 func (dbService *MessagingDBService) getContext() (ctx context.Context, cancel context.CancelFunc) {
 	return context.WithTimeout(context.Background(), time.Duration(dbService.timeout)*time.Second)
 }
@@ -105,6 +115,8 @@ func (dbService *MessagingDBService) CreateDefaultIndexes() {
 		// outgoing emails collection has no default indexes at the moment
 		dbService.CreateDefaultIndexesForSentEmailsCollection(instanceID)
 		dbService.CreateDefaultIndexesForSentSMSCollection(instanceID)
+		dbService.CreateDefaultIndexesForInboxMessagesCollection(instanceID)
+		dbService.CreateDefaultIndexesForPushSubscriptionsCollection(instanceID)
 		slog.Info("Default indexes created for messaging DB", slog.String("instanceID", instanceID), slog.String("duration", time.Since(start).String()))
 	}
 }
@@ -119,6 +131,8 @@ func (dbService *MessagingDBService) DropIndexes(dropAll bool) {
 		dbService.DropIndexForOutgoingEmailsCollection(instanceID, dropAll)
 		dbService.DropIndexForSentEmailsCollection(instanceID, dropAll)
 		dbService.DropIndexForSentSMSCollection(instanceID, dropAll)
+		dbService.DropIndexForInboxMessagesCollection(instanceID, dropAll)
+		dbService.DropIndexForPushSubscriptionsCollection(instanceID, dropAll)
 		slog.Info("Indexes dropped for messaging DB", slog.String("instanceID", instanceID), slog.String("duration", time.Since(start).String()))
 	}
 }
@@ -151,6 +165,12 @@ func (dbService *MessagingDBService) GetIndexes() (map[string]map[string][]bson.
 		if collectionIndexes[COLLECTION_NAME_SENT_SMS], err = db.ListCollectionIndexes(ctx, dbService.collectionSentSMS(instanceID)); err != nil {
 			return nil, err
 		}
+		if collectionIndexes[COLLECTION_NAME_INBOX_MESSAGES], err = db.ListCollectionIndexes(ctx, dbService.collectionInboxMessages(instanceID)); err != nil {
+			return nil, err
+		}
+		if collectionIndexes[COLLECTION_NAME_PUSH_SUBSCRIPTIONS], err = db.ListCollectionIndexes(ctx, dbService.collectionPushSubscriptions(instanceID)); err != nil {
+			return nil, err
+		}
 
 		results[instanceID] = collectionIndexes
 	}
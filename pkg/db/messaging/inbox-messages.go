@@ -0,0 +1,234 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var indexesForInboxMessagesCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "userID", Value: 1},
+			{Key: "createdAt", Value: -1},
+		},
+		Options: options.Index().SetName("userID_1_createdAt_-1"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "userID", Value: 1},
+			{Key: "readAt", Value: 1},
+		},
+		Options: options.Index().SetName("userID_1_readAt_1"),
+	},
+}
+
+func (dbService *MessagingDBService) DropIndexForInboxMessagesCollection(instanceID string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if dropAll {
+		_, err := dbService.collectionInboxMessages(instanceID).Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for inbox messages", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		}
+	} else {
+		for _, index := range indexesForInboxMessagesCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for inbox messages collection", slog.String("index", fmt.Sprintf("%+v", index)))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := dbService.collectionInboxMessages(instanceID).Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for inbox messages", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *MessagingDBService) CreateDefaultIndexesForInboxMessagesCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionInboxMessages(instanceID).Indexes().CreateMany(ctx, indexesForInboxMessagesCollection)
+	if err != nil {
+		slog.Error("Error creating index for inbox messages", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+	}
+}
+
+func (dbService *MessagingDBService) AddInboxMessage(instanceID string, message types.InboxMessage) (types.InboxMessage, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if message.UserID == "" {
+		return message, errors.New("userID is required")
+	}
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+	message.ID = primitive.NilObjectID
+
+	res, err := dbService.collectionInboxMessages(instanceID).InsertOne(ctx, message)
+	if err != nil {
+		return message, err
+	}
+	message.ID = res.InsertedID.(primitive.ObjectID)
+	return message, nil
+}
+
+// GetInboxMessagesForUser returns the messages of the user (newest first) and the total number of matching messages
+func (dbService *MessagingDBService) GetInboxMessagesForUser(instanceID string, userID string, unreadOnly bool, page int64, limit int64) ([]types.InboxMessage, int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"userID": userID}
+	if unreadOnly {
+		filter["readAt"] = bson.M{"$exists": false}
+	}
+
+	totalCount, err := dbService.collectionInboxMessages(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+
+	messages := []types.InboxMessage{}
+	cursor, err := dbService.collectionInboxMessages(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = cursor.All(ctx, &messages); err != nil {
+		return nil, 0, err
+	}
+	return messages, totalCount, nil
+}
+
+func (dbService *MessagingDBService) CountUnreadInboxMessagesForUser(instanceID string, userID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"userID": userID,
+		"readAt": bson.M{"$exists": false},
+	}
+	return dbService.collectionInboxMessages(instanceID).CountDocuments(ctx, filter)
+}
+
+// MarkInboxMessageAsRead sets the read time if the message is still unread, returns mongo.ErrNoDocuments if the message does not exist for the user
+func (dbService *MessagingDBService) MarkInboxMessageAsRead(instanceID string, userID string, messageID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": _id, "userID": userID}
+	res, err := dbService.collectionInboxMessages(instanceID).UpdateOne(ctx,
+		bson.M{"_id": _id, "userID": userID, "readAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"readAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+
+	// already read messages are fine, only report missing ones
+	count, err := dbService.collectionInboxMessages(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *MessagingDBService) MarkAllInboxMessagesAsRead(instanceID string, userID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionInboxMessages(instanceID).UpdateMany(ctx,
+		bson.M{"userID": userID, "readAt": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"readAt": time.Now()}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (dbService *MessagingDBService) DeleteInboxMessage(instanceID string, userID string, messageID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return err
+	}
+
+	res, err := dbService.collectionInboxMessages(instanceID).DeleteOne(ctx, bson.M{"_id": _id, "userID": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *MessagingDBService) DeleteInboxMessagesForUser(instanceID string, userID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionInboxMessages(instanceID).DeleteMany(ctx, bson.M{"userID": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// WatchInboxMessages calls onInsert for every new inbox message (without body) using a change stream (needs a replica set).
+// Blocks until the context is cancelled or the stream fails.
+func (dbService *MessagingDBService) WatchInboxMessages(ctx context.Context, instanceID string, onInsert func(types.InboxMessage)) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": "insert"}}},
+		{{Key: "$project", Value: bson.M{"fullDocument.body": 0}}},
+	}
+
+	stream, err := dbService.collectionInboxMessages(instanceID).Watch(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var event struct {
+			FullDocument types.InboxMessage `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+		onInsert(event.FullDocument)
+	}
+	return stream.Err()
+}
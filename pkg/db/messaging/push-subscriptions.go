@@ -0,0 +1,151 @@
+package messaging
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/messaging/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var indexesForPushSubscriptionsCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "endpoint", Value: 1},
+		},
+		Options: options.Index().SetName("endpoint_1").SetUnique(true),
+	},
+	{
+		Keys: bson.D{
+			{Key: "userID", Value: 1},
+		},
+		Options: options.Index().SetName("userID_1"),
+	},
+}
+
+func (dbService *MessagingDBService) DropIndexForPushSubscriptionsCollection(instanceID string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if dropAll {
+		_, err := dbService.collectionPushSubscriptions(instanceID).Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for push subscriptions", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		}
+	} else {
+		for _, index := range indexesForPushSubscriptionsCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for push subscriptions collection", slog.String("index", fmt.Sprintf("%+v", index)))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := dbService.collectionPushSubscriptions(instanceID).Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for push subscriptions", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *MessagingDBService) CreateDefaultIndexesForPushSubscriptionsCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionPushSubscriptions(instanceID).Indexes().CreateMany(ctx, indexesForPushSubscriptionsCollection)
+	if err != nil {
+		slog.Error("Error creating index for push subscriptions", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+	}
+}
+
+// SavePushSubscription stores the subscription for the user. An endpoint belongs to one browser, so registering it again
+// (e.g. after logging in with another account on the same device) replaces the previous entry.
+func (dbService *MessagingDBService) SavePushSubscription(instanceID string, sub types.PushSubscription) (types.PushSubscription, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if sub.CreatedAt.IsZero() {
+		sub.CreatedAt = time.Now()
+	}
+
+	filter := bson.M{"endpoint": sub.Endpoint}
+	update := bson.M{
+		"$set": bson.M{
+			"userID":    sub.UserID,
+			"keys":      sub.Keys,
+			"userAgent": sub.UserAgent,
+			"createdAt": sub.CreatedAt,
+		},
+		"$unset": bson.M{"lastUsedAt": ""},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved types.PushSubscription
+	err := dbService.collectionPushSubscriptions(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved)
+	return saved, err
+}
+
+func (dbService *MessagingDBService) GetPushSubscriptionsForUser(instanceID string, userID string) ([]types.PushSubscription, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	subs := []types.PushSubscription{}
+	cursor, err := dbService.collectionPushSubscriptions(instanceID).Find(ctx, bson.M{"userID": userID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &subs); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (dbService *MessagingDBService) UpdatePushSubscriptionLastUsed(instanceID string, id primitive.ObjectID) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionPushSubscriptions(instanceID).UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastUsedAt": time.Now()}})
+	return err
+}
+
+func (dbService *MessagingDBService) DeletePushSubscription(instanceID string, userID string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	res, err := dbService.collectionPushSubscriptions(instanceID).DeleteOne(ctx, bson.M{"_id": _id, "userID": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// DeletePushSubscriptionByID is used when the push service reports the subscription as gone
+func (dbService *MessagingDBService) DeletePushSubscriptionByID(instanceID string, id primitive.ObjectID) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionPushSubscriptions(instanceID).DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (dbService *MessagingDBService) DeletePushSubscriptionsForUser(instanceID string, userID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionPushSubscriptions(instanceID).DeleteMany(ctx, bson.M{"userID": userID})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
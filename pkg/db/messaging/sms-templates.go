@@ -16,8 +16,9 @@ var indexesForSMSTemplatesCollection = []mongo.IndexModel{
 	{
 		Keys: bson.D{
 			{Key: "messageType", Value: 1},
+			{Key: "studyKey", Value: 1},
 		},
-		Options: options.Index().SetUnique(true).SetName("messageType_1"),
+		Options: options.Index().SetUnique(true).SetName("messageType_1_studyKey_1"),
 	},
 }
 
@@ -86,7 +87,7 @@ func (messagingDBService *MessagingDBService) GetSMSTemplateByType(instanceID st
 	ctx, cancel := messagingDBService.getContext()
 	defer cancel()
 
-	filter := bson.M{"messageType": messageType}
+	filter := bson.M{"messageType": messageType, "studyKey": bson.M{"$exists": false}}
 
 	var smsTemplate messagingTypes.SMSTemplate
 	err := messagingDBService.collectionSMSTemplates(instanceID).FindOne(ctx, filter).Decode(&smsTemplate)
@@ -95,3 +96,48 @@ func (messagingDBService *MessagingDBService) GetSMSTemplateByType(instanceID st
 	}
 	return &smsTemplate, nil
 }
+
+// GetStudySMSTemplateByMessageType returns the SMS variant of a study message, used for participants without email address
+func (messagingDBService *MessagingDBService) GetStudySMSTemplateByMessageType(instanceID string, studyKey string, messageType string) (*messagingTypes.SMSTemplate, error) {
+	ctx, cancel := messagingDBService.getContext()
+	defer cancel()
+
+	filter := bson.M{"messageType": messageType, "studyKey": studyKey}
+
+	var smsTemplate messagingTypes.SMSTemplate
+	err := messagingDBService.collectionSMSTemplates(instanceID).FindOne(ctx, filter).Decode(&smsTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return &smsTemplate, nil
+}
+
+// find all SMS templates by study key
+func (messagingDBService *MessagingDBService) GetStudySMSTemplates(instanceID string, studyKey string) ([]messagingTypes.SMSTemplate, error) {
+	ctx, cancel := messagingDBService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+
+	var smsTemplates []messagingTypes.SMSTemplate
+	cursor, err := messagingDBService.collectionSMSTemplates(instanceID).Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &smsTemplates); err != nil {
+		return nil, err
+	}
+	return smsTemplates, nil
+}
+
+func (messagingDBService *MessagingDBService) DeleteSMSTemplate(instanceID string, messageType string, studyKey string) error {
+	ctx, cancel := messagingDBService.getContext()
+	defer cancel()
+
+	filter := bson.M{"messageType": messageType, "studyKey": studyKey}
+	if studyKey == "" {
+		filter["studyKey"] = bson.M{"$exists": false}
+	}
+	_, err := messagingDBService.collectionSMSTemplates(instanceID).DeleteOne(ctx, filter)
+	return err
+}
@@ -18,6 +18,8 @@ const (
 	COLLECTION_NAME_RENEW_TOKENS                = "renewTokens"
 	COLLECTION_NAME_OTPS                        = "otps"
 	COLLECTION_NAME_FAILED_OTP_ATTEMPTS         = "failedOtpAttempts"
+	COLLECTION_NAME_LOGIN_LINK_AUDIT            = "loginLinkAudit"
+	COLLECTION_NAME_WEBAUTHN_CREDENTIALS        = "webauthnCredentials"
 )
 
 type ParticipantUserDBService struct {
@@ -89,6 +91,14 @@ func (dbService *ParticipantUserDBService) collectionFailedOtpAttempts(instanceI
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_FAILED_OTP_ATTEMPTS)
 }
 
+func (dbService *ParticipantUserDBService) collectionLoginLinkAudit(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_LOGIN_LINK_AUDIT)
+}
+
+func (dbService *ParticipantUserDBService) collectionWebAuthnCredentials(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_WEBAUTHN_CREDENTIALS)
+}
+
 func (dbService *ParticipantUserDBService) CreateDefaultIndexes() {
 	for _, instanceID := range dbService.InstanceIDs {
 		start := time.Now()
@@ -98,6 +108,8 @@ func (dbService *ParticipantUserDBService) CreateDefaultIndexes() {
 		dbService.CreateDefaultIndexesForRenewTokensCollection(instanceID)
 		dbService.CreateDefaultIndexesForOTPsCollection(instanceID)
 		dbService.CreateDefaultIndexesForFailedOtpAttemptsCollection(instanceID)
+		dbService.CreateDefaultIndexesForLoginLinkAuditCollection(instanceID)
+		dbService.CreateDefaultIndexesForWebAuthnCredentialsCollection(instanceID)
 		slog.Info("Default indexes created for participant user DB", slog.String("instanceID", instanceID), slog.String("duration", time.Since(start).String()))
 	}
 }
@@ -111,6 +123,8 @@ func (dbService *ParticipantUserDBService) DropIndexes(dropAll bool) {
 		dbService.DropIndexForRenewTokensCollection(instanceID, dropAll)
 		dbService.DropIndexForOTPsCollection(instanceID, dropAll)
 		dbService.DropIndexForFailedOtpAttemptsCollection(instanceID, dropAll)
+		dbService.DropIndexForLoginLinkAuditCollection(instanceID, dropAll)
+		dbService.DropIndexForWebAuthnCredentialsCollection(instanceID, dropAll)
 		slog.Info("Indexes dropped for participant user DB", slog.String("instanceID", instanceID), slog.String("duration", time.Since(start).String()))
 	}
 }
@@ -140,6 +154,12 @@ func (dbService *ParticipantUserDBService) GetIndexes() (map[string]map[string][
 		if collectionIndexes[COLLECTION_NAME_FAILED_OTP_ATTEMPTS], err = db.ListCollectionIndexes(ctx, dbService.collectionFailedOtpAttempts(instanceID)); err != nil {
 			return nil, err
 		}
+		if collectionIndexes[COLLECTION_NAME_LOGIN_LINK_AUDIT], err = db.ListCollectionIndexes(ctx, dbService.collectionLoginLinkAudit(instanceID)); err != nil {
+			return nil, err
+		}
+		if collectionIndexes[COLLECTION_NAME_WEBAUTHN_CREDENTIALS], err = db.ListCollectionIndexes(ctx, dbService.collectionWebAuthnCredentials(instanceID)); err != nil {
+			return nil, err
+		}
 
 		results[instanceID] = collectionIndexes
 	}
@@ -0,0 +1,136 @@
+package participantuser
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	LOGIN_LINK_AUDIT_RETENTION = 60 * 60 * 24 * 365 // seconds
+
+	LOGIN_LINK_EVENT_REQUESTED    = "requested"
+	LOGIN_LINK_EVENT_RATE_LIMITED = "rate-limited"
+	LOGIN_LINK_EVENT_USED         = "used"
+	LOGIN_LINK_EVENT_REJECTED     = "rejected"
+)
+
+// LoginLinkAuditRecord documents a request or use of a sign-in link, also for unknown accounts
+type LoginLinkAuditRecord struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+	Event     string             `json:"event" bson:"event"`
+	AccountID string             `json:"accountId,omitempty" bson:"accountID,omitempty"`
+	UserID    string             `json:"userId,omitempty" bson:"userID,omitempty"`
+	Reason    string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	IPAddress string             `json:"ipAddress,omitempty" bson:"ipAddress,omitempty"`
+	UserAgent string             `json:"userAgent,omitempty" bson:"userAgent,omitempty"`
+}
+
+var indexesForLoginLinkAuditCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "accountID", Value: 1},
+			{Key: "event", Value: 1},
+			{Key: "timestamp", Value: -1},
+		},
+		Options: options.Index().SetName("accountID_1_event_1_timestamp_-1"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "userID", Value: 1},
+			{Key: "timestamp", Value: -1},
+		},
+		Options: options.Index().SetName("userID_1_timestamp_-1"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "timestamp", Value: 1},
+		},
+		Options: options.Index().SetExpireAfterSeconds(LOGIN_LINK_AUDIT_RETENTION).SetName("timestamp_1"),
+	},
+}
+
+func (dbService *ParticipantUserDBService) DropIndexForLoginLinkAuditCollection(instanceID string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if dropAll {
+		_, err := dbService.collectionLoginLinkAudit(instanceID).Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for LoginLinkAudit", slog.String("error", err.Error()))
+		}
+	} else {
+		for _, index := range indexesForLoginLinkAuditCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for LoginLinkAudit collection", slog.String("index", fmt.Sprintf("%+v", index)))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := dbService.collectionLoginLinkAudit(instanceID).Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for LoginLinkAudit", slog.String("error", err.Error()), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *ParticipantUserDBService) CreateDefaultIndexesForLoginLinkAuditCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionLoginLinkAudit(instanceID).Indexes().CreateMany(ctx, indexesForLoginLinkAuditCollection)
+	if err != nil {
+		slog.Error("Error creating index for LoginLinkAudit", slog.String("error", err.Error()))
+	}
+}
+
+func (dbService *ParticipantUserDBService) AddLoginLinkAuditRecord(instanceID string, record LoginLinkAuditRecord) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	record.ID = primitive.NilObjectID
+	if record.Timestamp.IsZero() {
+		record.Timestamp = time.Now()
+	}
+	_, err := dbService.collectionLoginLinkAudit(instanceID).InsertOne(ctx, record)
+	return err
+}
+
+// CountLoginLinkRequests counts the sign-in links requested for the account ID within the last intervalSeconds
+func (dbService *ParticipantUserDBService) CountLoginLinkRequests(instanceID string, accountID string, intervalSeconds int64) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"accountID": accountID,
+		"event":     LOGIN_LINK_EVENT_REQUESTED,
+		"timestamp": bson.M{
+			"$gt": time.Now().Add(-time.Duration(intervalSeconds) * time.Second),
+		},
+	}
+	return dbService.collectionLoginLinkAudit(instanceID).CountDocuments(ctx, filter)
+}
+
+// GetLoginLinkAuditRecordsForUser returns the most recent records of the user, newest first
+func (dbService *ParticipantUserDBService) GetLoginLinkAuditRecordsForUser(instanceID string, userID string, limit int64) ([]LoginLinkAuditRecord, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(limit)
+
+	records := []LoginLinkAuditRecord{}
+	cursor, err := dbService.collectionLoginLinkAudit(instanceID).Find(ctx, bson.M{"userID": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
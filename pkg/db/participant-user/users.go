@@ -48,6 +48,15 @@ var indexesForParticipantUsersCollection = []mongo.IndexModel{
 		},
 		Options: options.Index().SetName("contactPreferences.receiveWeeklyMessageDayOfWeek_1"),
 	},
+	{
+		Keys: bson.D{
+			{Key: "account.externalIdentities.provider", Value: 1},
+			{Key: "account.externalIdentities.subject", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{"account.externalIdentities.subject": bson.M{"$exists": true}}).
+			SetName("uniq_account.externalIdentities.provider_1_subject_1"),
+	},
 }
 
 func (dbService *ParticipantUserDBService) DropIndexForParticipantUsersCollection(instanceID string, dropAll bool) {
@@ -151,6 +160,16 @@ func (dbService *ParticipantUserDBService) GetUserByAccountID(instanceID, accoun
 	return user, err
 }
 
+func (dbService *ParticipantUserDBService) GetUserByExternalIdentity(instanceID, provider, subject string) (umTypes.User, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var user umTypes.User
+	filter := bson.M{"account.externalIdentities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+	err := dbService.collectionParticipantUsers(instanceID).FindOne(ctx, filter).Decode(&user)
+	return user, err
+}
+
 func (dbService *ParticipantUserDBService) GetUserByProfileID(instanceID, profileID string) (umTypes.User, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
@@ -299,3 +318,79 @@ func (dbService *ParticipantUserDBService) FindAndExecuteOnUsers(
 	}
 	return nil
 }
+
+// UpdateTOTPLastUsedStep stores the time step of a used TOTP code, returns false if the same or a later step was already used
+func (dbService *ParticipantUserDBService) UpdateTOTPLastUsedStep(instanceID string, userID string, step int64) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": _id, "account.totp.lastUsedStep": bson.M{"$lt": step}}
+	update := bson.M{"$set": bson.M{"account.totp.lastUsedStep": step}}
+	res, err := dbService.collectionParticipantUsers(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RemoveTOTPRecoveryCode removes the hash of a used recovery code, returns false if it was not present (anymore)
+func (dbService *ParticipantUserDBService) RemoveTOTPRecoveryCode(instanceID string, userID string, codeHash string) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": _id, "account.totp.recoveryCodes": codeHash}
+	update := bson.M{"$pull": bson.M{"account.totp.recoveryCodes": codeHash}}
+	res, err := dbService.collectionParticipantUsers(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// AddExternalIdentity links an identity of a provider to the account, returns false if the account has already an identity of this provider
+func (dbService *ParticipantUserDBService) AddExternalIdentity(instanceID string, userID string, identity umTypes.ExternalIdentity) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": _id, "account.externalIdentities.provider": bson.M{"$ne": identity.Provider}}
+	update := bson.M{"$push": bson.M{"account.externalIdentities": identity}}
+	res, err := dbService.collectionParticipantUsers(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RemoveExternalIdentity unlinks the identity of the provider, returns false if none was linked
+func (dbService *ParticipantUserDBService) RemoveExternalIdentity(instanceID string, userID string, provider string) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": _id, "account.externalIdentities.provider": provider}
+	update := bson.M{"$pull": bson.M{"account.externalIdentities": bson.M{"provider": provider}}}
+	res, err := dbService.collectionParticipantUsers(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
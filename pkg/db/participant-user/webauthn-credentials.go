@@ -0,0 +1,163 @@
+package participantuser
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var indexesForWebAuthnCredentialsCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "credentialID", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetName("credentialID_1"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "userID", Value: 1},
+		},
+		Options: options.Index().SetName("userID_1"),
+	},
+}
+
+func (dbService *ParticipantUserDBService) DropIndexForWebAuthnCredentialsCollection(instanceID string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if dropAll {
+		_, err := dbService.collectionWebAuthnCredentials(instanceID).Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for WebAuthnCredentials", slog.String("error", err.Error()))
+		}
+	} else {
+		for _, index := range indexesForWebAuthnCredentialsCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for WebAuthnCredentials collection", slog.String("index", fmt.Sprintf("%+v", index)))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := dbService.collectionWebAuthnCredentials(instanceID).Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for WebAuthnCredentials", slog.String("error", err.Error()), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *ParticipantUserDBService) CreateDefaultIndexesForWebAuthnCredentialsCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionWebAuthnCredentials(instanceID).Indexes().CreateMany(ctx, indexesForWebAuthnCredentialsCollection)
+	if err != nil {
+		slog.Error("Error creating index for WebAuthnCredentials", slog.String("error", err.Error()))
+	}
+}
+
+func (dbService *ParticipantUserDBService) AddWebAuthnCredential(instanceID string, credential userTypes.WebAuthnCredential) (string, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	credential.ID = primitive.NilObjectID
+	if credential.CreatedAt.IsZero() {
+		credential.CreatedAt = time.Now()
+	}
+	res, err := dbService.collectionWebAuthnCredentials(instanceID).InsertOne(ctx, credential)
+	if err != nil {
+		return "", err
+	}
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+func (dbService *ParticipantUserDBService) GetWebAuthnCredentialsForUser(instanceID string, userID string) ([]userTypes.WebAuthnCredential, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	credentials := []userTypes.WebAuthnCredential{}
+	cursor, err := dbService.collectionWebAuthnCredentials(instanceID).Find(ctx, bson.M{"userID": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &credentials); err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (dbService *ParticipantUserDBService) CountWebAuthnCredentialsForUser(instanceID string, userID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	return dbService.collectionWebAuthnCredentials(instanceID).CountDocuments(ctx, bson.M{"userID": userID})
+}
+
+func (dbService *ParticipantUserDBService) GetWebAuthnCredentialByCredentialID(instanceID string, credentialID string) (userTypes.WebAuthnCredential, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var credential userTypes.WebAuthnCredential
+	err := dbService.collectionWebAuthnCredentials(instanceID).FindOne(ctx, bson.M{"credentialID": credentialID}).Decode(&credential)
+	return credential, err
+}
+
+// UpdateWebAuthnCredentialUsage stores the new sign counter, but only if no other login has used the previous value meanwhile
+func (dbService *ParticipantUserDBService) UpdateWebAuthnCredentialUsage(instanceID string, credentialID string, oldSignCount uint32, newSignCount uint32, backedUp bool) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"credentialID": credentialID,
+		"signCount":    oldSignCount,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"signCount":  newSignCount,
+			"backedUp":   backedUp,
+			"lastUsedAt": time.Now(),
+		},
+	}
+	res, err := dbService.collectionWebAuthnCredentials(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return errors.New("credential not found or sign counter changed")
+	}
+	return nil
+}
+
+func (dbService *ParticipantUserDBService) DeleteWebAuthnCredential(instanceID string, userID string, credentialID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"userID":       userID,
+		"credentialID": credentialID,
+	}
+	res, err := dbService.collectionWebAuthnCredentials(instanceID).DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *ParticipantUserDBService) DeleteWebAuthnCredentialsForUser(instanceID string, userID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionWebAuthnCredentials(instanceID).DeleteMany(ctx, bson.M{"userID": userID})
+	return err
+}
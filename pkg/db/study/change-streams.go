@@ -0,0 +1,93 @@
+package study
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// ParticipantChange is a change of a participant state or report, as seen by WatchParticipantChanges
+type ParticipantChange struct {
+	StudyKey      string
+	Collection    string // COLLECTION_NAME_SUFFIX_PARTICIPANTS or COLLECTION_NAME_SUFFIX_REPORTS
+	ParticipantID string
+
+	// participant state changes
+	AssignedSurveys []studyTypes.AssignedSurvey
+
+	// report changes
+	ReportID  string
+	ReportKey string
+}
+
+// WatchParticipantChanges follows participant states and reports of all studies with a change stream and calls onChange
+// for every insert, update or replace. Blocks until the context is cancelled or the stream fails.
+// Change streams need a replica set (or sharded cluster), every caller gets all changes, so this works with multiple API instances.
+func (dbService *StudyDBService) WatchParticipantChanges(ctx context.Context, instanceID string, onChange func(ParticipantChange)) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"operationType": bson.M{"$in": bson.A{"insert", "update", "replace"}},
+			"ns.coll":       bson.M{"$regex": "_(" + COLLECTION_NAME_SUFFIX_PARTICIPANTS + "|" + COLLECTION_NAME_SUFFIX_REPORTS + ")$"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"ns.coll":                      1,
+			"fullDocument._id":             1,
+			"fullDocument.participantID":   1,
+			"fullDocument.assignedSurveys": 1,
+			"fullDocument.key":             1,
+		}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+
+	stream, err := dbService.DBClient.Database(dbService.getDBName(instanceID)).Watch(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var event struct {
+			NS struct {
+				Coll string `bson:"coll"`
+			} `bson:"ns"`
+			FullDocument *struct {
+				ID              any                         `bson:"_id"`
+				ParticipantID   string                      `bson:"participantID"`
+				AssignedSurveys []studyTypes.AssignedSurvey `bson:"assignedSurveys"`
+				Key             string                      `bson:"key"`
+			} `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+		// document deleted before the lookup
+		if event.FullDocument == nil {
+			continue
+		}
+
+		change := ParticipantChange{
+			ParticipantID: event.FullDocument.ParticipantID,
+		}
+		if studyKey, ok := strings.CutSuffix(event.NS.Coll, "_"+COLLECTION_NAME_SUFFIX_PARTICIPANTS); ok {
+			change.StudyKey = studyKey
+			change.Collection = COLLECTION_NAME_SUFFIX_PARTICIPANTS
+			change.AssignedSurveys = event.FullDocument.AssignedSurveys
+		} else if studyKey, ok := strings.CutSuffix(event.NS.Coll, "_"+COLLECTION_NAME_SUFFIX_REPORTS); ok {
+			change.StudyKey = studyKey
+			change.Collection = COLLECTION_NAME_SUFFIX_REPORTS
+			change.ReportKey = event.FullDocument.Key
+			if id, ok := event.FullDocument.ID.(interface{ Hex() string }); ok {
+				change.ReportID = id.Hex()
+			}
+		} else {
+			continue
+		}
+		onChange(change)
+	}
+	return stream.Err()
+}
@@ -0,0 +1,293 @@
+package study
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+var indexesForConsentDocumentsCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "consentKey", Value: 1},
+			{Key: "version", Value: -1},
+		},
+		Options: options.Index().SetName("consentKey_1_version_-1").SetUnique(true),
+	},
+}
+
+var indexesForConsentRecordsCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "participantID", Value: 1},
+			{Key: "consentKey", Value: 1},
+			{Key: "acceptedAt", Value: -1},
+		},
+		Options: options.Index().SetName("participantID_1_consentKey_1_acceptedAt_-1"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "consentKey", Value: 1},
+			{Key: "version", Value: 1},
+		},
+		Options: options.Index().SetName("consentKey_1_version_1"),
+	},
+}
+
+func (dbService *StudyDBService) DropIndexForConsentDocumentsCollection(instanceID string, studyKey string, dropAll bool) {
+	dbService.dropIndexesForConsentCollection(dbService.collectionConsentDocuments(instanceID, studyKey), indexesForConsentDocumentsCollection, dropAll, instanceID, studyKey)
+}
+
+func (dbService *StudyDBService) DropIndexForConsentRecordsCollection(instanceID string, studyKey string, dropAll bool) {
+	dbService.dropIndexesForConsentCollection(dbService.collectionConsentRecords(instanceID, studyKey), indexesForConsentRecordsCollection, dropAll, instanceID, studyKey)
+}
+
+func (dbService *StudyDBService) dropIndexesForConsentCollection(collection *mongo.Collection, indexes []mongo.IndexModel, dropAll bool, instanceID string, studyKey string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for consent collection", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("collection", collection.Name()))
+		}
+	} else {
+		for _, index := range indexes {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for consent collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for consent collection", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForConsentDocumentsCollection(instanceID string, studyKey string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionConsentDocuments(instanceID, studyKey).Indexes().CreateMany(ctx, indexesForConsentDocumentsCollection)
+	if err != nil {
+		slog.Error("Error creating index for consent documents", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForConsentRecordsCollection(instanceID string, studyKey string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionConsentRecords(instanceID, studyKey).Indexes().CreateMany(ctx, indexesForConsentRecordsCollection)
+	if err != nil {
+		slog.Error("Error creating index for consent records", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+	}
+}
+
+// PublishConsentDocument stores the document as the next version of its consent key
+func (dbService *StudyDBService) PublishConsentDocument(instanceID string, studyKey string, doc studytypes.ConsentDocument, publishedBy string) (studytypes.ConsentDocument, error) {
+	var previous *studytypes.ConsentDocument
+	latest, err := dbService.GetLatestConsentDocument(instanceID, studyKey, doc.ConsentKey)
+	if err == nil {
+		previous = &latest
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		return doc, err
+	}
+
+	if err := doc.PrepareForPublish(previous, publishedBy); err != nil {
+		return doc, err
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	// unique index on consentKey and version rejects concurrent publishes of the same version
+	res, err := dbService.collectionConsentDocuments(instanceID, studyKey).InsertOne(ctx, doc)
+	if err != nil {
+		return doc, err
+	}
+	doc.ID = res.InsertedID.(primitive.ObjectID)
+	return doc, nil
+}
+
+func (dbService *StudyDBService) GetLatestConsentDocument(instanceID string, studyKey string, consentKey string) (doc studytypes.ConsentDocument, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"consentKey": consentKey}
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+	err = dbService.collectionConsentDocuments(instanceID, studyKey).FindOne(ctx, filter, opts).Decode(&doc)
+	return doc, err
+}
+
+func (dbService *StudyDBService) GetConsentDocument(instanceID string, studyKey string, consentKey string, version int) (doc studytypes.ConsentDocument, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"consentKey": consentKey, "version": version}
+	err = dbService.collectionConsentDocuments(instanceID, studyKey).FindOne(ctx, filter).Decode(&doc)
+	return doc, err
+}
+
+// GetConsentDocumentVersions returns all published versions of a consent, newest first
+func (dbService *StudyDBService) GetConsentDocumentVersions(instanceID string, studyKey string, consentKey string) (docs []studytypes.ConsentDocument, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"consentKey": consentKey}
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := dbService.collectionConsentDocuments(instanceID, studyKey).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs = []studytypes.ConsentDocument{}
+	err = cursor.All(ctx, &docs)
+	return docs, err
+}
+
+// GetLatestConsentDocuments returns the current version of each consent of the study
+func (dbService *StudyDBService) GetLatestConsentDocuments(instanceID string, studyKey string) (docs []studytypes.ConsentDocument, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$sort", Value: bson.D{{Key: "consentKey", Value: 1}, {Key: "version", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$consentKey"},
+			{Key: "doc", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$doc"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "consentKey", Value: 1}}}},
+	}
+
+	cursor, err := dbService.collectionConsentDocuments(instanceID, studyKey).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs = []studytypes.ConsentDocument{}
+	err = cursor.All(ctx, &docs)
+	return docs, err
+}
+
+func (dbService *StudyDBService) AddConsentRecord(instanceID string, studyKey string, record studytypes.ConsentRecord) (studytypes.ConsentRecord, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	record.ID = primitive.NilObjectID
+	res, err := dbService.collectionConsentRecords(instanceID, studyKey).InsertOne(ctx, record)
+	if err != nil {
+		return record, err
+	}
+	record.ID = res.InsertedID.(primitive.ObjectID)
+	return record, nil
+}
+
+// GetLatestConsentRecord returns the most recent acceptance of the participant for the consent, withdrawn or not
+func (dbService *StudyDBService) GetLatestConsentRecord(instanceID string, studyKey string, participantID string, consentKey string) (record studytypes.ConsentRecord, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": participantID, "consentKey": consentKey}
+	opts := options.FindOne().SetSort(bson.D{{Key: "acceptedAt", Value: -1}, {Key: "_id", Value: -1}})
+	err = dbService.collectionConsentRecords(instanceID, studyKey).FindOne(ctx, filter, opts).Decode(&record)
+	return record, err
+}
+
+// GetConsentRecordsForParticipant returns the consent history of the participant, newest first
+func (dbService *StudyDBService) GetConsentRecordsForParticipant(instanceID string, studyKey string, participantID string) (records []studytypes.ConsentRecord, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": participantID}
+	opts := options.Find().SetSort(bson.D{{Key: "acceptedAt", Value: -1}, {Key: "_id", Value: -1}})
+	cursor, err := dbService.collectionConsentRecords(instanceID, studyKey).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records = []studytypes.ConsentRecord{}
+	err = cursor.All(ctx, &records)
+	return records, err
+}
+
+func (dbService *StudyDBService) GetConsentRecords(instanceID string, studyKey string, filter bson.M, page int64, limit int64) (records []studytypes.ConsentRecord, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionConsentRecords(instanceID, studyKey)
+	totalCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return records, nil, err
+	}
+
+	paginationInfo = prepPaginationInfos(
+		totalCount,
+		page,
+		limit,
+	)
+
+	skip := (paginationInfo.CurrentPage - 1) * paginationInfo.PageSize
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "acceptedAt", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetSkip(skip)
+	opts.SetLimit(paginationInfo.PageSize)
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return records, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records = []studytypes.ConsentRecord{}
+	err = cursor.All(ctx, &records)
+	return records, paginationInfo, err
+}
+
+// WithdrawConsentRecord marks an accepted record as withdrawn, returns mongo.ErrNoDocuments if the record is not accepted
+func (dbService *StudyDBService) WithdrawConsentRecord(instanceID string, studyKey string, recordID primitive.ObjectID, withdrawnAt time.Time) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"_id": recordID, "status": studytypes.CONSENT_RECORD_STATUS_ACCEPTED}
+	update := bson.M{"$set": bson.M{
+		"status":      studytypes.CONSENT_RECORD_STATUS_WITHDRAWN,
+		"withdrawnAt": withdrawnAt,
+	}}
+	res, err := dbService.collectionConsentRecords(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount < 1 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateParticipantIDonConsentRecords(instanceID string, studyKey string, oldID string, newID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": oldID}
+	update := bson.M{"$set": bson.M{"participantID": newID}}
+	res, err := dbService.collectionConsentRecords(instanceID, studyKey).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
@@ -23,10 +23,20 @@ const (
 	COLLECTION_NAME_SUFFIX_REPORTS                = "reports"
 	COLLECTION_NAME_SUFFIX_FILES                  = "participantFiles"
 	COLLECTION_NAME_SUFFIX_RESEARCHER_MESSAGES    = "researcherMessages"
+	COLLECTION_NAME_SUFFIX_RESPONSE_DRAFTS        = "surveyResponseDrafts"
+	COLLECTION_NAME_SUFFIX_SURVEY_SCORES          = "surveyScores"
+	COLLECTION_NAME_SUFFIX_CONSENT_DOCUMENTS      = "consentDocuments"
+	COLLECTION_NAME_SUFFIX_CONSENT_RECORDS        = "consentRecords"
+	COLLECTION_NAME_SUFFIX_SUBMISSION_CLAIMS      = "submissionClaims"
 	COLLECTION_NAME_TASK_QUEUE                    = "taskQueue"
 	COLLECTION_NAME_STUDY_CODE_LISTS              = "studyCodeLists"
 	COLLECTION_NAME_STUDY_COUNTERS                = "studyCounters"
+	COLLECTION_NAME_STUDY_COUNTER_LOG             = "studyCounterLog"
+	COLLECTION_NAME_STUDY_QUOTA_COUNTS            = "studyQuotaCounts"
+	COLLECTION_NAME_STUDY_QUOTA_ENTRIES           = "studyQuotaEntries"
 	COLLECTION_NAME_STUDY_VARIABLES               = "studyVariables"
+	COLLECTION_NAME_STUDY_VARIABLE_HISTORY        = "studyVariableHistory"
+	COLLECTION_NAME_STUDY_VARIABLE_SCHEDULES      = "studyVariableSchedules"
 )
 
 type StudyDBService struct {
@@ -123,6 +133,26 @@ func (dbService *StudyDBService) collectionResearcherMessages(instanceID string,
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_RESEARCHER_MESSAGES))
 }
 
+func (dbService *StudyDBService) collectionSurveyResponseDrafts(instanceID string, studyKey string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_RESPONSE_DRAFTS))
+}
+
+func (dbService *StudyDBService) collectionSubmissionClaims(instanceID string, studyKey string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_SUBMISSION_CLAIMS))
+}
+
+func (dbService *StudyDBService) collectionSurveyScores(instanceID string, studyKey string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_SURVEY_SCORES))
+}
+
+func (dbService *StudyDBService) collectionConsentDocuments(instanceID string, studyKey string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_CONSENT_DOCUMENTS))
+}
+
+func (dbService *StudyDBService) collectionConsentRecords(instanceID string, studyKey string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_CONSENT_RECORDS))
+}
+
 func (dbService *StudyDBService) collectionStudyCodeLists(instanceID string) *mongo.Collection {
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_STUDY_CODE_LISTS)
 }
@@ -131,10 +161,30 @@ func (dbService *StudyDBService) collectionStudyCounters(instanceID string) *mon
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_STUDY_COUNTERS)
 }
 
+func (dbService *StudyDBService) collectionStudyCounterLog(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_STUDY_COUNTER_LOG)
+}
+
+func (dbService *StudyDBService) collectionStudyQuotaCounts(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_STUDY_QUOTA_COUNTS)
+}
+
+func (dbService *StudyDBService) collectionStudyQuotaEntries(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_STUDY_QUOTA_ENTRIES)
+}
+
 func (dbService *StudyDBService) collectionStudyVariables(instanceID string) *mongo.Collection {
 	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_STUDY_VARIABLES)
 }
 
+func (dbService *StudyDBService) collectionStudyVariableHistory(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_STUDY_VARIABLE_HISTORY)
+}
+
+func (dbService *StudyDBService) collectionStudyVariableSchedules(instanceID string) *mongo.Collection {
+	return dbService.DBClient.Database(dbService.getDBName(instanceID)).Collection(COLLECTION_NAME_STUDY_VARIABLE_SCHEDULES)
+}
+
 func (dbService *StudyDBService) getContext() (ctx context.Context, cancel context.CancelFunc) {
 	return context.WithTimeout(context.Background(), time.Duration(dbService.timeout)*time.Second)
 }
@@ -147,10 +197,15 @@ func (dbService *StudyDBService) dropIndexes(all bool) {
 		dbService.DropIndexForConfidentialIDMapCollection(instanceID, all)
 		dbService.DropIndexForStudyCodeListsCollection(instanceID, all)
 		dbService.DropIndexForStudyCountersCollection(instanceID, all)
+		dbService.DropIndexForStudyCounterLogCollection(instanceID, all)
+		dbService.DropIndexForStudyQuotaCountsCollection(instanceID, all)
+		dbService.DropIndexForStudyQuotaEntriesCollection(instanceID, all)
 		dbService.DropIndexForStudyInfosCollection(instanceID, all)
 		dbService.DropIndexForStudyRulesCollection(instanceID, all)
 		dbService.DropIndexForTaskQueueCollection(instanceID, all)
 		dbService.DropIndexForStudyVariablesCollection(instanceID, all)
+		dbService.DropIndexForStudyVariableHistoryCollection(instanceID, all)
+		dbService.DropIndexForStudyVariableSchedulesCollection(instanceID, all)
 		// researcher messages has no default indexes at the moment
 
 		//fetch studyKeys from studyInfos
@@ -168,6 +223,11 @@ func (dbService *StudyDBService) dropIndexes(all bool) {
 			dbService.DropIndexForReportsCollection(instanceID, studyKey, all)
 			dbService.DropIndexForParticipantsCollection(instanceID, studyKey, all)
 			dbService.DropIndexForParticipantFilesCollection(instanceID, studyKey, all)
+			dbService.DropIndexForSurveyResponseDraftsCollection(instanceID, studyKey, all)
+			dbService.DropIndexForSurveyScoresCollection(instanceID, studyKey, all)
+			dbService.DropIndexForConsentDocumentsCollection(instanceID, studyKey, all)
+			dbService.DropIndexForConsentRecordsCollection(instanceID, studyKey, all)
+			dbService.DropIndexForSubmissionClaimsCollection(instanceID, studyKey, all)
 		}
 
 		slog.Info("Indexes dropped for study DB", slog.String("instanceID", instanceID), slog.String("duration", time.Since(start).String()))
@@ -200,10 +260,15 @@ func (dbService *StudyDBService) CreateDefaultIndexes() {
 		dbService.CreateDefaultIndexesForConfidentialIDMapCollection(instanceID)
 		dbService.CreateDefaultIndexesForStudyCodeListsCollection(instanceID)
 		dbService.CreateDefaultIndexesForStudyCountersCollection(instanceID)
+		dbService.CreateDefaultIndexesForStudyCounterLogCollection(instanceID)
+		dbService.CreateDefaultIndexesForStudyQuotaCountsCollection(instanceID)
+		dbService.CreateDefaultIndexesForStudyQuotaEntriesCollection(instanceID)
 		dbService.CreateDefaultIndexesForStudyInfosCollection(instanceID)
 		dbService.CreateDefaultIndexesForStudyRulesCollection(instanceID)
 		dbService.CreateDefaultIndexesForTaskQueueCollection(instanceID)
 		dbService.CreateDefaultIndexesForStudyVariablesCollection(instanceID)
+		dbService.CreateDefaultIndexesForStudyVariableHistoryCollection(instanceID)
+		dbService.CreateDefaultIndexesForStudyVariableSchedulesCollection(instanceID)
 		// researcher messages has no default indexes at the moment
 
 		for _, study := range studies {
@@ -215,6 +280,11 @@ func (dbService *StudyDBService) CreateDefaultIndexes() {
 			dbService.CreateDefaultIndexesForReportsCollection(instanceID, studyKey)
 			dbService.CreateDefaultIndexesForParticipantsCollection(instanceID, studyKey)
 			dbService.CreateDefaultIndexesForParticipantFilesCollection(instanceID, studyKey)
+			dbService.CreateDefaultIndexesForSurveyResponseDraftsCollection(instanceID, studyKey)
+			dbService.CreateDefaultIndexesForSurveyScoresCollection(instanceID, studyKey)
+			dbService.CreateDefaultIndexesForConsentDocumentsCollection(instanceID, studyKey)
+			dbService.CreateDefaultIndexesForConsentRecordsCollection(instanceID, studyKey)
+			dbService.CreateDefaultIndexesForSubmissionClaimsCollection(instanceID, studyKey)
 		}
 		slog.Info("Default indexes created for study DB", slog.String("instanceID", instanceID), slog.String("duration", time.Since(start).String()))
 	}
@@ -239,6 +309,15 @@ func (dbService *StudyDBService) GetIndexes() (map[string]map[string][]bson.M, e
 		if collectionIndexes[COLLECTION_NAME_STUDY_COUNTERS], err = db.ListCollectionIndexes(ctx, dbService.collectionStudyCounters(instanceID)); err != nil {
 			return nil, err
 		}
+		if collectionIndexes[COLLECTION_NAME_STUDY_COUNTER_LOG], err = db.ListCollectionIndexes(ctx, dbService.collectionStudyCounterLog(instanceID)); err != nil {
+			return nil, err
+		}
+		if collectionIndexes[COLLECTION_NAME_STUDY_QUOTA_COUNTS], err = db.ListCollectionIndexes(ctx, dbService.collectionStudyQuotaCounts(instanceID)); err != nil {
+			return nil, err
+		}
+		if collectionIndexes[COLLECTION_NAME_STUDY_QUOTA_ENTRIES], err = db.ListCollectionIndexes(ctx, dbService.collectionStudyQuotaEntries(instanceID)); err != nil {
+			return nil, err
+		}
 		if collectionIndexes[COLLECTION_NAME_STUDY_INFOS], err = db.ListCollectionIndexes(ctx, dbService.collectionStudyInfos(instanceID)); err != nil {
 			return nil, err
 		}
@@ -251,6 +330,12 @@ func (dbService *StudyDBService) GetIndexes() (map[string]map[string][]bson.M, e
 		if collectionIndexes[COLLECTION_NAME_STUDY_VARIABLES], err = db.ListCollectionIndexes(ctx, dbService.collectionStudyVariables(instanceID)); err != nil {
 			return nil, err
 		}
+		if collectionIndexes[COLLECTION_NAME_STUDY_VARIABLE_HISTORY], err = db.ListCollectionIndexes(ctx, dbService.collectionStudyVariableHistory(instanceID)); err != nil {
+			return nil, err
+		}
+		if collectionIndexes[COLLECTION_NAME_STUDY_VARIABLE_SCHEDULES], err = db.ListCollectionIndexes(ctx, dbService.collectionStudyVariableSchedules(instanceID)); err != nil {
+			return nil, err
+		}
 
 		studies, err := dbService.GetStudies(instanceID, "", true)
 		if err != nil {
@@ -283,6 +368,26 @@ func (dbService *StudyDBService) GetIndexes() (map[string]map[string][]bson.M, e
 			if collectionIndexes[collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_FILES)], err = db.ListCollectionIndexes(ctx, dbService.collectionFiles(instanceID, studyKey)); err != nil {
 				return nil, err
 			}
+
+			if collectionIndexes[collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_RESPONSE_DRAFTS)], err = db.ListCollectionIndexes(ctx, dbService.collectionSurveyResponseDrafts(instanceID, studyKey)); err != nil {
+				return nil, err
+			}
+
+			if collectionIndexes[collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_SURVEY_SCORES)], err = db.ListCollectionIndexes(ctx, dbService.collectionSurveyScores(instanceID, studyKey)); err != nil {
+				return nil, err
+			}
+
+			if collectionIndexes[collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_CONSENT_DOCUMENTS)], err = db.ListCollectionIndexes(ctx, dbService.collectionConsentDocuments(instanceID, studyKey)); err != nil {
+				return nil, err
+			}
+
+			if collectionIndexes[collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_CONSENT_RECORDS)], err = db.ListCollectionIndexes(ctx, dbService.collectionConsentRecords(instanceID, studyKey)); err != nil {
+				return nil, err
+			}
+
+			if collectionIndexes[collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_SUBMISSION_CLAIMS)], err = db.ListCollectionIndexes(ctx, dbService.collectionSubmissionClaims(instanceID, studyKey)); err != nil {
+				return nil, err
+			}
 		}
 
 		results[instanceID] = collectionIndexes
@@ -194,3 +194,110 @@ func (dbService *StudyDBService) UpdateParticipantFileInfoPathAndStatus(instance
 	}
 	return nil
 }
+
+// update file info path and status together with the result of the malware scan
+func (dbService *StudyDBService) UpdateParticipantFileInfoScanResult(instanceID string, studyKey string, fileInfoID string, path string, status string, scanResult studytypes.FileScanResult) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(fileInfoID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id": _id,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"path":       path,
+			"status":     status,
+			"scanResult": scanResult,
+			"updatedAt":  time.Now(),
+		},
+	}
+	_, err = dbService.collectionFiles(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	return err
+}
+
+// update preview path and size after processing the stored file
+func (dbService *StudyDBService) UpdateParticipantFileInfoPreviewAndSize(instanceID string, studyKey string, fileInfoID string, previewPath string, size int64) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(fileInfoID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id": _id,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"previewPath": previewPath,
+			"size":        size,
+			"updatedAt":   time.Now(),
+		},
+	}
+	_, err = dbService.collectionFiles(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	return err
+}
+
+// update the offset of a resumable upload, only succeeds if the stored offset still matches the expected one
+func (dbService *StudyDBService) UpdateParticipantFileUploadOffset(instanceID string, studyKey string, fileInfoID string, expectedOffset int64, newOffset int64) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(fileInfoID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id":    _id,
+		"status": studytypes.FILE_STATUS_UPLOADING,
+	}
+	if expectedOffset == 0 {
+		filter["uploadOffset"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["uploadOffset"] = expectedOffset
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"uploadOffset": newOffset,
+			"updatedAt":    time.Now(),
+		},
+	}
+	res, err := dbService.collectionFiles(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// get file infos that are still uploading and were not updated since the given time
+func (dbService *StudyDBService) GetStaleUploadingFileInfos(instanceID string, studyKey string, notUpdatedSince time.Time) (fileInfos []studytypes.FileInfo, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"status": studytypes.FILE_STATUS_UPLOADING,
+		"$or": bson.A{
+			bson.M{"updatedAt": bson.M{"$lt": notUpdatedSince}},
+			bson.M{"updatedAt": bson.M{"$exists": false}, "createdAt": bson.M{"$lt": notUpdatedSince}},
+		},
+	}
+
+	cursor, err := dbService.collectionFiles(instanceID, studyKey).Find(ctx, filter)
+	if err != nil {
+		return fileInfos, err
+	}
+	defer cursor.Close(ctx)
+
+	err = cursor.All(ctx, &fileInfos)
+	return fileInfos, err
+}
@@ -136,6 +136,38 @@ func (dbService *StudyDBService) UpdateParticipantIfNotModified(instanceID strin
 	return updatedParticipant, nil
 }
 
+// UpdateParticipantArm saves the arm assignment and arm history of the participant, if not modified since it was fetched
+func (dbService *StudyDBService) UpdateParticipantArm(instanceID string, studyKey string, pState studyTypes.Participant) (studyTypes.Participant, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"participantID": pState.ParticipantID,
+		"modifiedAt":    pState.ModifiedAt,
+	}
+
+	set := bson.M{
+		"armHistory": pState.ArmHistory,
+		"modifiedAt": time.Now().Unix(),
+	}
+	update := bson.M{"$set": set}
+	if pState.Arm != nil {
+		set["arm"] = pState.Arm
+	} else {
+		update["$unset"] = bson.M{"arm": ""}
+	}
+
+	var updatedParticipant studyTypes.Participant
+	err := dbService.collectionParticipants(instanceID, studyKey).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updatedParticipant)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return pState, errors.New("participant not found or has been modified since last fetch")
+		}
+		return pState, err
+	}
+	return updatedParticipant, nil
+}
+
 // get participant by id
 func (dbService *StudyDBService) GetParticipantByID(instanceID string, studyKey string, participantID string) (participant studyTypes.Participant, err error) {
 	ctx, cancel := dbService.getContext()
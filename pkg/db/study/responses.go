@@ -48,6 +48,15 @@ var indexesForResponsesCollection = []mongo.IndexModel{
 		},
 		Options: options.Index().SetName("key_1"),
 	},
+	{
+		Keys: bson.D{
+			{Key: "participantID", Value: 1},
+			{Key: "submissionID", Value: 1},
+		},
+		Options: options.Index().SetName("participantID_1_submissionID_1").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"submissionID": bson.M{"$type": "string"}}),
+	},
 }
 
 func (dbService *StudyDBService) DropIndexForResponsesCollection(instanceID string, studyKey string, dropAll bool) {
@@ -93,8 +102,25 @@ func (dbService *StudyDBService) AddSurveyResponse(instanceID string, studyKey s
 		response.ArrivedAt = time.Now().Unix()
 	}
 	res, err := dbService.collectionResponses(instanceID, studyKey).InsertOne(ctx, response)
+	if err != nil {
+		return "", err
+	}
 	id := res.InsertedID.(primitive.ObjectID)
-	return id.Hex(), err
+	return id.Hex(), nil
+}
+
+// get the response stored for a client-generated submission ID, returns mongo.ErrNoDocuments if there is none
+func (dbService *StudyDBService) GetResponseBySubmissionID(instanceID string, studyKey string, participantID string, submissionID string) (response studyTypes.SurveyResponse, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"participantID": participantID,
+		"submissionID":  submissionID,
+	}
+
+	err = dbService.collectionResponses(instanceID, studyKey).FindOne(ctx, filter).Decode(&response)
+	return response, err
 }
 
 // get response by id
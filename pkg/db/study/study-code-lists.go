@@ -1,6 +1,8 @@
 package study
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -59,21 +61,89 @@ func (dbService *StudyDBService) CreateDefaultIndexesForStudyCodeListsCollection
 	}
 }
 
-func (dbService *StudyDBService) AddStudyCodeListEntry(instanceID string, studyKey string, listKey string, code string) error {
+// status filter value for listing codes regardless of their status
+const STUDY_CODE_LIST_STATUS_FILTER_ALL = "all"
+
+// availableStudyCodesFilter matches codes that can be drawn or claimed: not yet claimed, not expired,
+// and either not reserved or with an elapsed reservation
+func availableStudyCodesFilter(studyKey string, listKey string, now time.Time) bson.M {
+	return bson.M{
+		"studyKey": studyKey,
+		"listKey":  listKey,
+		"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"status": bson.M{"$in": bson.A{nil, studytypes.STUDY_CODE_STATUS_AVAILABLE}}},
+				bson.M{"status": studytypes.STUDY_CODE_STATUS_RESERVED, "reservedUntil": bson.M{"$lt": now}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"expiresAt": nil},
+				bson.M{"expiresAt": bson.M{"$gt": now}},
+			}},
+		},
+	}
+}
+
+// AddStudyCodeListEntry inserts a single available code into the list.
+// Codes are unique per list and stay in the list after being drawn, reserved or claimed, so a used code
+// cannot be added again and the insert fails with a duplicate key error.
+func (dbService *StudyDBService) AddStudyCodeListEntry(instanceID string, studyKey string, listKey string, code string, expiresAt *time.Time) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
 	entry := studytypes.StudyCodeListEntry{
-		StudyKey: studyKey,
-		ListKey:  listKey,
-		Code:     code,
-		AddedAt:  time.Now(),
+		StudyKey:  studyKey,
+		ListKey:   listKey,
+		Code:      code,
+		AddedAt:   time.Now(),
+		Status:    studytypes.STUDY_CODE_STATUS_AVAILABLE,
+		ExpiresAt: expiresAt,
 	}
 
 	_, err := dbService.collectionStudyCodeLists(instanceID).InsertOne(ctx, entry)
 	return err
 }
 
+// AddStudyCodeListEntries inserts multiple codes at once and returns the number of inserted codes and
+// the codes that were skipped because they already exist in the list. This includes codes that were already
+// drawn, reserved or claimed, as used codes are kept in the list and are not made available again.
+func (dbService *StudyDBService) AddStudyCodeListEntries(instanceID string, studyKey string, listKey string, entries []studytypes.StudyCodeListEntry) (inserted int64, alreadyExisting []string, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	alreadyExisting = []string{}
+	if len(entries) == 0 {
+		return 0, alreadyExisting, nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(entries))
+	for i := range entries {
+		entries[i].StudyKey = studyKey
+		entries[i].ListKey = listKey
+		entries[i].AddedAt = now
+		entries[i].Status = studytypes.STUDY_CODE_STATUS_AVAILABLE
+		docs[i] = entries[i]
+	}
+
+	res, err := dbService.collectionStudyCodeLists(instanceID).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if res != nil {
+		inserted = int64(len(res.InsertedIDs))
+	}
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) {
+			return inserted, alreadyExisting, err
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return inserted, alreadyExisting, err
+			}
+			alreadyExisting = append(alreadyExisting, entries[writeErr.Index].Code)
+		}
+		inserted = int64(len(entries) - len(alreadyExisting))
+	}
+	return inserted, alreadyExisting, nil
+}
 func (dbService *StudyDBService) GetUniqueStudyCodeListKeysForStudy(instanceID string, studyKey string) ([]string, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
@@ -106,16 +176,14 @@ func (dbService *StudyDBService) GetStudyCodeListEntries(
 	instanceID string,
 	studyKey string,
 	listKey string,
+	status string,
 	page int64,
 	limit int64,
 ) ([]studytypes.StudyCodeListEntry, *PaginationInfos, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{
-		"studyKey": studyKey,
-		"listKey":  listKey,
-	}
+	filter := studyCodeListStatusFilter(studyKey, listKey, status)
 
 	totalCount, err := dbService.collectionStudyCodeLists(instanceID).CountDocuments(ctx, filter)
 	if err != nil {
@@ -148,28 +216,44 @@ func (dbService *StudyDBService) GetStudyCodeListEntries(
 	return entries, paginationInfo, err
 }
 
+// studyCodeListStatusFilter builds the filter for listing codes with a given status.
+// An empty status lists the available codes like before code statuses existed, "all" lists every code.
+func studyCodeListStatusFilter(studyKey string, listKey string, status string) bson.M {
+	switch status {
+	case STUDY_CODE_LIST_STATUS_FILTER_ALL:
+		return bson.M{
+			"studyKey": studyKey,
+			"listKey":  listKey,
+		}
+	case "", studytypes.STUDY_CODE_STATUS_AVAILABLE:
+		return availableStudyCodesFilter(studyKey, listKey, time.Now())
+	default:
+		return bson.M{
+			"studyKey": studyKey,
+			"listKey":  listKey,
+			"status":   status,
+		}
+	}
+}
+
+// count codes that are still available (not claimed, reserved or expired)
 func (dbService *StudyDBService) CountStudyCodeListEntries(instanceID string, studyKey string, listKey string) (int64, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{
-		"studyKey": studyKey,
-		"listKey":  listKey,
-	}
+	filter := availableStudyCodesFilter(studyKey, listKey, time.Now())
 
 	count, err := dbService.collectionStudyCodeLists(instanceID).CountDocuments(ctx, filter)
 	return count, err
 }
 
+// check if the code is in the list and still available (not claimed, reserved or expired)
 func (dbService *StudyDBService) StudyCodeListEntryExists(instanceID string, studyKey string, listKey string, code string) (bool, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{
-		"studyKey": studyKey,
-		"listKey":  listKey,
-		"code":     code,
-	}
+	filter := availableStudyCodesFilter(studyKey, listKey, time.Now())
+	filter["code"] = code
 
 	count, err := dbService.collectionStudyCodeLists(instanceID).CountDocuments(ctx, filter)
 	return count > 0, err
@@ -210,17 +294,46 @@ func (dbService *StudyDBService) DeleteStudyCodeListsForStudy(instanceID string,
 	return err
 }
 
-func (dbService *StudyDBService) DrawStudyCode(instanceID string, studyKey string, listKey string) (string, error) {
+// DrawStudyCode marks the next available code of the list as claimed and returns it.
+// Returns an empty string if no code is available.
+func (dbService *StudyDBService) DrawStudyCode(instanceID string, studyKey string, listKey string, claim studytypes.StudyCodeClaim) (string, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	filter := bson.M{
-		"studyKey": studyKey,
-		"listKey":  listKey,
+	now := time.Now()
+	filter := availableStudyCodesFilter(studyKey, listKey, now)
+	update := claimStudyCodeUpdate(claim, now)
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	var result studytypes.StudyCodeListEntry
+	err := dbService.collectionStudyCodeLists(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&result)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		return "", err
 	}
+	return result.Code, nil
+}
+
+// ReserveStudyCode marks the next available code of the list as reserved for the participant from reservedAt until reservedUntil.
+// Availability is checked against reservedAt, so both ends of the reservation use the same clock.
+// Returns an empty string if no code is available.
+func (dbService *StudyDBService) ReserveStudyCode(instanceID string, studyKey string, listKey string, participantID string, reservedAt time.Time, reservedUntil time.Time) (string, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := availableStudyCodesFilter(studyKey, listKey, reservedAt)
+	update := bson.M{"$set": bson.M{
+		"status":        studytypes.STUDY_CODE_STATUS_RESERVED,
+		"reservedAt":    reservedAt,
+		"reservedUntil": reservedUntil,
+		"reservedBy":    participantID,
+	}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "_id", Value: 1}})
 
 	var result studytypes.StudyCodeListEntry
-	err := dbService.collectionStudyCodeLists(instanceID).FindOneAndDelete(ctx, filter).Decode(&result)
+	err := dbService.collectionStudyCodeLists(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&result)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return "", nil
@@ -229,3 +342,112 @@ func (dbService *StudyDBService) DrawStudyCode(instanceID string, studyKey strin
 	}
 	return result.Code, nil
 }
+
+// ClaimStudyCode marks a specific available code as claimed. Returns mongo.ErrNoDocuments if the code is not available.
+func (dbService *StudyDBService) ClaimStudyCode(instanceID string, studyKey string, listKey string, code string, claim studytypes.StudyCodeClaim) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now()
+	filter := availableStudyCodesFilter(studyKey, listKey, now)
+	filter["code"] = code
+
+	res, err := dbService.collectionStudyCodeLists(instanceID).UpdateOne(ctx, filter, claimStudyCodeUpdate(claim, now))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ConfirmStudyCodeReservation turns a reservation of the participant into a claim
+func (dbService *StudyDBService) ConfirmStudyCodeReservation(instanceID string, studyKey string, listKey string, code string, claim studytypes.StudyCodeClaim) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":   studyKey,
+		"listKey":    listKey,
+		"code":       code,
+		"status":     studytypes.STUDY_CODE_STATUS_RESERVED,
+		"reservedBy": claim.ParticipantID,
+	}
+
+	res, err := dbService.collectionStudyCodeLists(instanceID).UpdateOne(ctx, filter, claimStudyCodeUpdate(claim, time.Now()))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ReleaseStudyCodeReservation makes a code reserved by the participant available again
+func (dbService *StudyDBService) ReleaseStudyCodeReservation(instanceID string, studyKey string, listKey string, code string, participantID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":   studyKey,
+		"listKey":    listKey,
+		"code":       code,
+		"status":     studytypes.STUDY_CODE_STATUS_RESERVED,
+		"reservedBy": participantID,
+	}
+	update := bson.M{
+		"$set":   bson.M{"status": studytypes.STUDY_CODE_STATUS_AVAILABLE},
+		"$unset": bson.M{"reservedAt": "", "reservedUntil": "", "reservedBy": ""},
+	}
+
+	_, err := dbService.collectionStudyCodeLists(instanceID).UpdateOne(ctx, filter, update)
+	return err
+}
+
+func claimStudyCodeUpdate(claim studytypes.StudyCodeClaim, now time.Time) bson.M {
+	return bson.M{
+		"$set": bson.M{
+			"status":         studytypes.STUDY_CODE_STATUS_CLAIMED,
+			"claimedAt":      now,
+			"claimedBy":      claim.ParticipantID,
+			"claimedByEvent": claim.Event,
+		},
+		"$unset": bson.M{"reservedUntil": ""},
+	}
+}
+
+// iterate over all codes of a list in insertion order
+func (dbService *StudyDBService) FindAndExecuteOnStudyCodeListEntries(
+	ctx context.Context,
+	instanceID string,
+	studyKey string,
+	listKey string,
+	fn func(entry studytypes.StudyCodeListEntry) error,
+) error {
+	filter := bson.M{
+		"studyKey": studyKey,
+		"listKey":  listKey,
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := dbService.collectionStudyCodeLists(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry studytypes.StudyCodeListEntry
+		if err = cursor.Decode(&entry); err != nil {
+			slog.Error("Error while decoding study code list entry", slog.String("error", err.Error()))
+			continue
+		}
+
+		if err = fn(entry); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
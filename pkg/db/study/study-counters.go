@@ -3,16 +3,40 @@ package study
 import (
 	"fmt"
 	"log/slog"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
 type StudyCounter struct {
 	StudyKey string `json:"studyKey" bson:"studyKey"`
 	Scope    string `json:"scope" bson:"scope"`
 	Value    int64  `json:"value" bson:"value"`
+	// start of the reset period the current value belongs to (only for counters with a reset schedule)
+	PeriodStart *time.Time                     `json:"periodStart,omitempty" bson:"periodStart,omitempty"`
+	Config      *studytypes.StudyCounterConfig `json:"config,omitempty" bson:"config,omitempty"`
+}
+
+var indexesForStudyCounterLogCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "studyKey", Value: 1},
+			{Key: "scope", Value: 1},
+			{Key: "incrementedAt", Value: -1},
+		},
+		Options: options.Index().SetName("studyKey_1_scope_1_incrementedAt_-1"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "studyKey", Value: 1},
+			{Key: "origin.participantID", Value: 1},
+		},
+		Options: options.Index().SetName("studyKey_1_origin.participantID_1"),
+	},
 }
 
 var indexesForStudyCountersCollection = []mongo.IndexModel{
@@ -60,13 +84,52 @@ func (dbService *StudyDBService) CreateDefaultIndexesForStudyCountersCollection(
 	}
 }
 
-// Get current counter value (without incrementing)
-func (dbService *StudyDBService) GetCurrentStudyCounterValue(instanceID string, studyKey string, scope string) (int64, error) {
+func (dbService *StudyDBService) DropIndexForStudyCounterLogCollection(instanceID string, dropAll bool) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	counter := StudyCounter{}
-	err := dbService.collectionStudyCounters(instanceID).FindOne(ctx, bson.M{"studyKey": studyKey, "scope": scope}).Decode(&counter)
+	collection := dbService.collectionStudyCounterLog(instanceID)
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for studyCounterLog", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		}
+	} else {
+		for _, index := range indexesForStudyCounterLogCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for studyCounterLog collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for studyCounterLog", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForStudyCounterLogCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionStudyCounterLog(instanceID).Indexes().CreateMany(ctx, indexesForStudyCounterLogCollection)
+	if err != nil {
+		slog.Error("Error creating index for studyCounterLog", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+	}
+}
+
+func (dbService *StudyDBService) getStudyCounter(instanceID string, studyKey string, scope string) (counter StudyCounter, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	err = dbService.collectionStudyCounters(instanceID).FindOne(ctx, bson.M{"studyKey": studyKey, "scope": scope}).Decode(&counter)
+	return counter, err
+}
+
+// Get current counter value (without incrementing)
+func (dbService *StudyDBService) GetCurrentStudyCounterValue(instanceID string, studyKey string, scope string) (int64, error) {
+	counter, err := dbService.getStudyCounter(instanceID, studyKey, scope)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return 0, nil
@@ -74,9 +137,46 @@ func (dbService *StudyDBService) GetCurrentStudyCounterValue(instanceID string,
 		return 0, err
 	}
 
+	// value belongs to a period that is over, but no increment or timer run has reset it yet
+	if counter.Config != nil {
+		if ps := counter.Config.PeriodStart(time.Now()); ps != nil && counter.PeriodStart != nil && counter.PeriodStart.Before(*ps) {
+			return 0, nil
+		}
+	}
+
 	return counter.Value, nil
 }
 
+// Get config of a counter, nil if the counter has no config
+func (dbService *StudyDBService) GetStudyCounterConfig(instanceID string, studyKey string, scope string) (*studytypes.StudyCounterConfig, error) {
+	counter, err := dbService.getStudyCounter(instanceID, studyKey, scope)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return counter.Config, nil
+}
+
+// Save config of a counter (creates the counter if it does not exist yet)
+func (dbService *StudyDBService) SaveStudyCounterConfig(instanceID string, studyKey string, scope string, config studytypes.StudyCounterConfig) (StudyCounter, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	counter := StudyCounter{}
+	err := dbService.collectionStudyCounters(instanceID).FindOneAndUpdate(
+		ctx,
+		bson.M{"studyKey": studyKey, "scope": scope},
+		bson.M{
+			"$set":         bson.M{"config": config},
+			"$setOnInsert": bson.M{"studyKey": studyKey, "scope": scope, "value": 0},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	return counter, err
+}
+
 // Get all counter values for a study
 func (dbService *StudyDBService) GetAllStudyCounterValues(instanceID string, studyKey string) ([]StudyCounter, error) {
 	ctx, cancel := dbService.getContext()
@@ -95,26 +195,217 @@ func (dbService *StudyDBService) GetAllStudyCounterValues(instanceID string, stu
 	return counters, nil
 }
 
-// Increment counter value (atomical find and update)
-func (dbService *StudyDBService) IncrementAndGetStudyCounterValue(instanceID string, studyKey string, scope string) (int64, error) {
+// Increment counter value respecting the counter's bounds and reset schedule.
+// The increment is applied with a single atomic update, so concurrent increments never hand out the same value twice.
+// Every increment is recorded in the counter log together with the origin.
+func (dbService *StudyDBService) IncrementAndGetStudyCounterValue(instanceID string, studyKey string, scope string, origin studytypes.StudyCounterIncrementOrigin) (int64, error) {
+	now := time.Now().UTC()
+	previous, err := dbService.incrementStudyCounter(instanceID, studyKey, scope, now)
+	if err != nil && mongo.IsDuplicateKeyError(err) {
+		// concurrent insert of a new counter, the counter exists now
+		previous, err = dbService.incrementStudyCounter(instanceID, studyKey, scope, now)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// the update applies the same rules as NextValue, so the result can be derived from the previous state
+	config := studytypes.StudyCounterConfig{}
+	if previous.Config != nil {
+		config = *previous.Config
+	}
+	next, reset, wrapped, err := config.NextValue(previous.Value, previous.PeriodStart, now)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := dbService.addStudyCounterLogEntry(instanceID, studytypes.StudyCounterLogEntry{
+		StudyKey:      studyKey,
+		Scope:         scope,
+		Value:         next,
+		Reset:         reset,
+		Wrapped:       wrapped,
+		IncrementedAt: now,
+		Origin:        origin,
+	}); err != nil {
+		slog.Error("Error saving study counter log entry", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("scope", scope))
+	}
+	return next, nil
+}
+
+// incrementStudyCounter increments the counter with an update pipeline (creating it if needed) and returns the counter
+// as it was before the update, or an empty counter if it did not exist yet.
+// On overflow without wrapping the value is left unchanged.
+func (dbService *StudyDBService) incrementStudyCounter(instanceID string, studyKey string, scope string, now time.Time) (previous StudyCounter, err error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	counter := StudyCounter{}
-	err := dbService.collectionStudyCounters(instanceID).FindOneAndUpdate(
+	dailyStart := studytypes.StudyCounterConfig{ResetSchedule: studytypes.STUDY_COUNTER_RESET_DAILY}.PeriodStart(now)
+	monthlyStart := studytypes.StudyCounterConfig{ResetSchedule: studytypes.STUDY_COUNTER_RESET_MONTHLY}.PeriodStart(now)
+
+	pipeline := mongo.Pipeline{
+		// start of the current period, null if the counter has no reset schedule
+		{{Key: "$set", Value: bson.M{"_currentPeriodStart": bson.M{"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{"case": bson.M{"$eq": bson.A{"$config.resetSchedule", studytypes.STUDY_COUNTER_RESET_DAILY}}, "then": dailyStart},
+				bson.M{"case": bson.M{"$eq": bson.A{"$config.resetSchedule", studytypes.STUDY_COUNTER_RESET_MONTHLY}}, "then": monthlyStart},
+			},
+			"default": nil,
+		}}}}},
+		// only counters with a period start from an earlier period are reset
+		{{Key: "$set", Value: bson.M{"_reset": bson.M{"$and": bson.A{
+			bson.M{"$ne": bson.A{"$_currentPeriodStart", nil}},
+			bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{"$periodStart", nil}}, nil}},
+			bson.M{"$lt": bson.A{"$periodStart", "$_currentPeriodStart"}},
+		}}}}},
+		{{Key: "$set", Value: bson.M{"_next": bson.M{"$add": bson.A{
+			bson.M{"$cond": bson.A{"$_reset", 0, bson.M{"$ifNull": bson.A{"$value", 0}}}},
+			1,
+		}}}}},
+		{{Key: "$set", Value: bson.M{"_overflow": bson.M{"$and": bson.A{
+			bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$config.maxValue", 0}}, 0}},
+			bson.M{"$gt": bson.A{"$_next", "$config.maxValue"}},
+		}}}}},
+		{{Key: "$set", Value: bson.M{
+			"value": bson.M{"$cond": bson.A{
+				"$_overflow",
+				bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$config.onOverflow", studytypes.STUDY_COUNTER_OVERFLOW_WRAP}}, 1, "$value"}},
+				"$_next",
+			}},
+			"periodStart": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{"$_currentPeriodStart", nil}},
+				"$periodStart",
+				bson.M{"$cond": bson.A{"$_reset", "$_currentPeriodStart", bson.M{"$ifNull": bson.A{"$periodStart", "$_currentPeriodStart"}}}},
+			}},
+		}}},
+		{{Key: "$unset", Value: bson.A{"_currentPeriodStart", "_reset", "_next", "_overflow"}}},
+	}
+
+	err = dbService.collectionStudyCounters(instanceID).FindOneAndUpdate(
 		ctx,
 		bson.M{"studyKey": studyKey, "scope": scope},
-		bson.M{
-			"$inc":         bson.M{"value": 1},
-			"$setOnInsert": bson.M{"studyKey": studyKey, "scope": scope},
-		},
-		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
-	).Decode(&counter)
+		pipeline,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return StudyCounter{}, nil
+	}
+	return previous, err
+}
+
+func (dbService *StudyDBService) addStudyCounterLogEntry(instanceID string, entry studytypes.StudyCounterLogEntry) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionStudyCounterLog(instanceID).InsertOne(ctx, entry)
+	return err
+}
+
+// get the increment log of a counter (newest first) with pagination, optionally for a single participant
+func (dbService *StudyDBService) GetStudyCounterLog(instanceID string, studyKey string, scope string, participantID string, page int64, limit int64) (entries []studytypes.StudyCounterLogEntry, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "scope": scope}
+	if participantID != "" {
+		filter["origin.participantID"] = participantID
+	}
+
+	totalCount, err := dbService.collectionStudyCounterLog(instanceID).CountDocuments(ctx, filter)
 	if err != nil {
-		return 0, err
+		return entries, nil, err
 	}
 
-	return counter.Value, nil
+	paginationInfo = prepPaginationInfos(
+		totalCount,
+		page,
+		limit,
+	)
+
+	skip := (paginationInfo.CurrentPage - 1) * paginationInfo.PageSize
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "incrementedAt", Value: -1}, {Key: "_id", Value: -1}})
+	opts.SetSkip(skip)
+	opts.SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionStudyCounterLog(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return entries, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries = []studytypes.StudyCounterLogEntry{}
+	err = cursor.All(ctx, &entries)
+	return entries, paginationInfo, err
+}
+
+// Reset counters of a study whose reset period is over, returns the number of reset counters.
+// Every reset is recorded in the counter log. Counters without a period start are assigned to the current period
+// without being reset, like on their first increment.
+func (dbService *StudyDBService) ResetDueStudyCounters(instanceID string, studyKey string, now time.Time) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	var count int64
+	for _, schedule := range []string{studytypes.STUDY_COUNTER_RESET_DAILY, studytypes.STUDY_COUNTER_RESET_MONTHLY} {
+		periodStart := studytypes.StudyCounterConfig{ResetSchedule: schedule}.PeriodStart(now)
+
+		_, err := dbService.collectionStudyCounters(instanceID).UpdateMany(ctx, bson.M{
+			"studyKey":             studyKey,
+			"config.resetSchedule": schedule,
+			"periodStart":          nil,
+		}, bson.M{
+			"$set": bson.M{"periodStart": periodStart},
+		})
+		if err != nil {
+			return count, err
+		}
+
+		filter := bson.M{
+			"studyKey":             studyKey,
+			"config.resetSchedule": schedule,
+			"periodStart":          bson.M{"$lt": periodStart},
+		}
+		cursor, err := dbService.collectionStudyCounters(instanceID).Find(ctx, filter)
+		if err != nil {
+			return count, err
+		}
+		var dueCounters []StudyCounter
+		if err := cursor.All(ctx, &dueCounters); err != nil {
+			return count, err
+		}
+
+		for _, counter := range dueCounters {
+			// the period is checked again, so a counter reset by a concurrent increment is left alone
+			res, err := dbService.collectionStudyCounters(instanceID).UpdateOne(ctx, bson.M{
+				"studyKey":    studyKey,
+				"scope":       counter.Scope,
+				"periodStart": bson.M{"$lt": periodStart},
+			}, bson.M{
+				"$set": bson.M{"value": 0, "periodStart": periodStart},
+			})
+			if err != nil {
+				return count, err
+			}
+			if res.ModifiedCount == 0 {
+				continue
+			}
+			count++
+
+			if err := dbService.addStudyCounterLogEntry(instanceID, studytypes.StudyCounterLogEntry{
+				StudyKey:      studyKey,
+				Scope:         counter.Scope,
+				Value:         0,
+				Reset:         true,
+				IncrementedAt: now,
+				Origin:        studytypes.StudyCounterIncrementOrigin{Event: studytypes.STUDY_COUNTER_LOG_EVENT_SCHEDULED_RESET},
+			}); err != nil {
+				slog.Error("Error saving study counter log entry", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("scope", counter.Scope))
+			}
+		}
+	}
+	return count, nil
 }
 
 // Save counter value (upsert: update if exists, insert if not)
@@ -142,20 +433,35 @@ func (dbService *StudyDBService) SaveStudyCounterValue(instanceID string, studyK
 	return counter.Value, nil
 }
 
-// Remove study counter value (reset to 0)
+// Remove study counter value (reset to 0), counters with a config keep their config
 func (dbService *StudyDBService) RemoveStudyCounterValue(instanceID string, studyKey string, scope string) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
-	_, err := dbService.collectionStudyCounters(instanceID).DeleteOne(ctx, bson.M{"studyKey": studyKey, "scope": scope})
+	res, err := dbService.collectionStudyCounters(instanceID).DeleteOne(ctx, bson.M{"studyKey": studyKey, "scope": scope, "config": bson.M{"$exists": false}})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount > 0 {
+		return nil
+	}
+
+	_, err = dbService.collectionStudyCounters(instanceID).UpdateOne(ctx,
+		bson.M{"studyKey": studyKey, "scope": scope},
+		bson.M{"$set": bson.M{"value": 0}},
+	)
 	return err
 }
 
-// Remove all study counters for a study
+// Remove all study counters and their logs for a study
 func (dbService *StudyDBService) RemoveAllStudyCounters(instanceID string, studyKey string) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
 	_, err := dbService.collectionStudyCounters(instanceID).DeleteMany(ctx, bson.M{"studyKey": studyKey})
+	if err != nil {
+		return err
+	}
+	_, err = dbService.collectionStudyCounterLog(instanceID).DeleteMany(ctx, bson.M{"studyKey": studyKey})
 	return err
 }
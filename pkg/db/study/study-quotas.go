@@ -0,0 +1,462 @@
+package study
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+type studyQuotaCount struct {
+	StudyKey string `bson:"studyKey"`
+	QuotaKey string `bson:"quotaKey"`
+	Count    int64  `bson:"count"`
+}
+
+var indexesForStudyQuotaCountsCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "studyKey", Value: 1},
+			{Key: "quotaKey", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetName("studyKey_1_quotaKey_1"),
+	},
+}
+
+var indexesForStudyQuotaEntriesCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "studyKey", Value: 1},
+			{Key: "quotaKey", Value: 1},
+			{Key: "participantID", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetName("studyKey_1_quotaKey_1_participantID_1"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "studyKey", Value: 1},
+			{Key: "quotaKey", Value: 1},
+			{Key: "status", Value: 1},
+			{Key: "createdAt", Value: 1},
+		},
+		Options: options.Index().SetName("studyKey_1_quotaKey_1_status_1_createdAt_1"),
+	},
+}
+
+func (dbService *StudyDBService) DropIndexForStudyQuotaCountsCollection(instanceID string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionStudyQuotaCounts(instanceID)
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for studyQuotaCounts", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		}
+	} else {
+		for _, index := range indexesForStudyQuotaCountsCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for studyQuotaCounts collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for studyQuotaCounts", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForStudyQuotaCountsCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionStudyQuotaCounts(instanceID).Indexes().CreateMany(ctx, indexesForStudyQuotaCountsCollection)
+	if err != nil {
+		slog.Error("Error creating index for studyQuotaCounts", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+	}
+}
+
+func (dbService *StudyDBService) DropIndexForStudyQuotaEntriesCollection(instanceID string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionStudyQuotaEntries(instanceID)
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for studyQuotaEntries", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		}
+	} else {
+		for _, index := range indexesForStudyQuotaEntriesCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for studyQuotaEntries collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for studyQuotaEntries", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForStudyQuotaEntriesCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionStudyQuotaEntries(instanceID).Indexes().CreateMany(ctx, indexesForStudyQuotaEntriesCollection)
+	if err != nil {
+		slog.Error("Error creating index for studyQuotaEntries", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+	}
+}
+
+// get the quota entry of a participant, returns mongo.ErrNoDocuments if the participant is not counted or waitlisted
+func (dbService *StudyDBService) GetStudyQuotaEntry(instanceID string, studyKey string, quotaKey string, participantID string) (entry studytypes.StudyQuotaEntry, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "quotaKey": quotaKey, "participantID": participantID}
+	err = dbService.collectionStudyQuotaEntries(instanceID).FindOne(ctx, filter).Decode(&entry)
+	return entry, err
+}
+
+// ClaimStudyQuotaSlot counts the participant towards the quota if the quota is not full yet.
+// The count is incremented atomically with the limit as condition, so concurrent events cannot exceed the limit.
+// Returns false if the quota is full, and ErrStudyQuotaSlotAlreadyHeld if a concurrent event counted the participant first.
+func (dbService *StudyDBService) ClaimStudyQuotaSlot(instanceID string, studyKey string, quotaKey string, participantID string, limit int64) (bool, error) {
+	if limit <= 0 {
+		return false, nil
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	// a full quota does not match the filter, so the upsert tries to insert a second document and fails on the unique index
+	_, err := dbService.collectionStudyQuotaCounts(instanceID).UpdateOne(ctx,
+		bson.M{"studyKey": studyKey, "quotaKey": quotaKey, "count": bson.M{"$lt": limit}},
+		bson.M{"$inc": bson.M{"count": 1}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	now := time.Now().UTC()
+	_, err = dbService.collectionStudyQuotaEntries(instanceID).UpdateOne(ctx,
+		bson.M{
+			"studyKey":      studyKey,
+			"quotaKey":      quotaKey,
+			"participantID": participantID,
+			"status":        bson.M{"$ne": studytypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED},
+		},
+		bson.M{
+			"$set":         bson.M{"status": studytypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED, "updatedAt": now},
+			"$setOnInsert": bson.M{"createdAt": now},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		// undo the increment: on duplicate key the participant was already counted by a concurrent event
+		if _, decErr := dbService.collectionStudyQuotaCounts(instanceID).UpdateOne(ctx,
+			bson.M{"studyKey": studyKey, "quotaKey": quotaKey},
+			bson.M{"$inc": bson.M{"count": -1}},
+		); decErr != nil {
+			slog.Error("Error reverting study quota count", slog.String("error", decErr.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("quotaKey", quotaKey))
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return false, studytypes.ErrStudyQuotaSlotAlreadyHeld
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseStudyQuotaSlot removes the participant from the quota and frees the slot
+func (dbService *StudyDBService) ReleaseStudyQuotaSlot(instanceID string, studyKey string, quotaKey string, participantID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionStudyQuotaEntries(instanceID).DeleteOne(ctx, bson.M{
+		"studyKey":      studyKey,
+		"quotaKey":      quotaKey,
+		"participantID": participantID,
+		"status":        studytypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED,
+	})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+
+	_, err = dbService.collectionStudyQuotaCounts(instanceID).UpdateOne(ctx,
+		bson.M{"studyKey": studyKey, "quotaKey": quotaKey},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	return err
+}
+
+// ReleaseStudyQuotaEntriesForParticipant removes the participant from all quotas of the study, frees counted slots
+// and removes waitlist entries. Returns the number of removed entries.
+func (dbService *StudyDBService) ReleaseStudyQuotaEntriesForParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	entries, err := dbService.getStudyQuotaEntriesForParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for _, entry := range entries {
+		if err := dbService.removeStudyQuotaEntry(instanceID, entry); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// TransferStudyQuotaEntries moves the quota entries of a participant to another participant, e.g. when participants are merged.
+// If the target already has an entry for a quota, the better one is kept (counted before waitlisted) and the other one is
+// removed, so the merged participant never occupies two slots of the same quota. Returns the number of moved entries.
+func (dbService *StudyDBService) TransferStudyQuotaEntries(instanceID string, studyKey string, fromParticipantID string, toParticipantID string) (int64, error) {
+	entries, err := dbService.getStudyQuotaEntriesForParticipant(instanceID, studyKey, fromParticipantID)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for _, entry := range entries {
+		target, err := dbService.GetStudyQuotaEntry(instanceID, studyKey, entry.QuotaKey, toParticipantID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return count, err
+		}
+		if err == nil {
+			if target.Status == studytypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED || entry.Status != studytypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED {
+				if err := dbService.removeStudyQuotaEntry(instanceID, entry); err != nil {
+					return count, err
+				}
+				continue
+			}
+			// the counted entry replaces the target's waitlist entry
+			if err := dbService.removeStudyQuotaEntry(instanceID, target); err != nil {
+				return count, err
+			}
+		}
+
+		moved, err := dbService.updateStudyQuotaEntryParticipantID(instanceID, entry, toParticipantID)
+		if err != nil {
+			return count, err
+		}
+		if moved {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (dbService *StudyDBService) getStudyQuotaEntriesForParticipant(instanceID string, studyKey string, participantID string) (entries []studytypes.StudyQuotaEntry, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	cursor, err := dbService.collectionStudyQuotaEntries(instanceID).Find(ctx, bson.M{"studyKey": studyKey, "participantID": participantID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries = []studytypes.StudyQuotaEntry{}
+	err = cursor.All(ctx, &entries)
+	return entries, err
+}
+
+// removeStudyQuotaEntry deletes the entry and frees its slot if it was counted
+func (dbService *StudyDBService) removeStudyQuotaEntry(instanceID string, entry studytypes.StudyQuotaEntry) error {
+	if entry.Status == studytypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED {
+		err := dbService.ReleaseStudyQuotaSlot(instanceID, entry.StudyKey, entry.QuotaKey, entry.ParticipantID)
+		if err == mongo.ErrNoDocuments {
+			// already released concurrently
+			return nil
+		}
+		return err
+	}
+
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionStudyQuotaEntries(instanceID).DeleteOne(ctx, bson.M{
+		"studyKey":      entry.StudyKey,
+		"quotaKey":      entry.QuotaKey,
+		"participantID": entry.ParticipantID,
+		"status":        entry.Status,
+	})
+	return err
+}
+
+// updateStudyQuotaEntryParticipantID assigns the entry to another participant, if the target got an entry for the
+// same quota in the meantime, the entry is removed instead
+func (dbService *StudyDBService) updateStudyQuotaEntryParticipantID(instanceID string, entry studytypes.StudyQuotaEntry, participantID string) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	res, err := dbService.collectionStudyQuotaEntries(instanceID).UpdateOne(ctx,
+		bson.M{"studyKey": entry.StudyKey, "quotaKey": entry.QuotaKey, "participantID": entry.ParticipantID},
+		bson.M{"$set": bson.M{"participantID": participantID, "updatedAt": time.Now().UTC()}},
+	)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, dbService.removeStudyQuotaEntry(instanceID, entry)
+		}
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// add participant to the waitlist of a quota, keeps the original position if already waitlisted
+func (dbService *StudyDBService) AddParticipantToStudyQuotaWaitlist(instanceID string, studyKey string, quotaKey string, participantID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now().UTC()
+	_, err := dbService.collectionStudyQuotaEntries(instanceID).UpdateOne(ctx,
+		bson.M{"studyKey": studyKey, "quotaKey": quotaKey, "participantID": participantID},
+		bson.M{"$setOnInsert": bson.M{
+			"status":    studytypes.STUDY_QUOTA_ENTRY_STATUS_WAITLISTED,
+			"createdAt": now,
+			"updatedAt": now,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (dbService *StudyDBService) getStudyQuotaCount(instanceID string, studyKey string, quotaKey string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	count := studyQuotaCount{}
+	err := dbService.collectionStudyQuotaCounts(instanceID).FindOne(ctx, bson.M{"studyKey": studyKey, "quotaKey": quotaKey}).Decode(&count)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return count.Count, nil
+}
+
+// get number of free slots of a quota defined on the study
+func (dbService *StudyDBService) GetStudyQuotaRemainingCapacity(instanceID string, studyKey string, quotaKey string) (int64, error) {
+	study, err := dbService.GetStudy(instanceID, studyKey)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, q := range study.Quotas {
+		if q.Key != quotaKey {
+			continue
+		}
+		count, err := dbService.getStudyQuotaCount(instanceID, studyKey, quotaKey)
+		if err != nil {
+			return 0, err
+		}
+		return max(q.Limit-count, 0), nil
+	}
+	return 0, errors.New("quota not found")
+}
+
+// get fill levels of all quotas defined on the study
+func (dbService *StudyDBService) GetStudyQuotaFillLevels(instanceID string, study studytypes.Study) ([]studytypes.StudyQuotaFillLevel, error) {
+	fillLevels := []studytypes.StudyQuotaFillLevel{}
+	for _, q := range study.Quotas {
+		count, err := dbService.getStudyQuotaCount(instanceID, study.Key, q.Key)
+		if err != nil {
+			return nil, err
+		}
+		waitlisted, err := dbService.countStudyQuotaEntries(instanceID, study.Key, q.Key, studytypes.STUDY_QUOTA_ENTRY_STATUS_WAITLISTED)
+		if err != nil {
+			return nil, err
+		}
+		remaining := max(q.Limit-count, 0)
+		fillLevels = append(fillLevels, studytypes.StudyQuotaFillLevel{
+			Key:        q.Key,
+			Label:      q.Label,
+			Limit:      q.Limit,
+			OnFull:     q.OnFull,
+			Count:      count,
+			Remaining:  remaining,
+			Waitlisted: waitlisted,
+			IsFull:     remaining == 0,
+		})
+	}
+	return fillLevels, nil
+}
+
+func (dbService *StudyDBService) countStudyQuotaEntries(instanceID string, studyKey string, quotaKey string, status string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	return dbService.collectionStudyQuotaEntries(instanceID).CountDocuments(ctx, bson.M{"studyKey": studyKey, "quotaKey": quotaKey, "status": status})
+}
+
+// get entries of a quota (oldest first, i.e. waitlist order) with pagination, optionally filtered by status
+func (dbService *StudyDBService) GetStudyQuotaEntries(instanceID string, studyKey string, quotaKey string, status string, page int64, limit int64) (entries []studytypes.StudyQuotaEntry, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "quotaKey": quotaKey}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	totalCount, err := dbService.collectionStudyQuotaEntries(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return entries, nil, err
+	}
+
+	paginationInfo = prepPaginationInfos(
+		totalCount,
+		page,
+		limit,
+	)
+
+	skip := (paginationInfo.CurrentPage - 1) * paginationInfo.PageSize
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	opts.SetSkip(skip)
+	opts.SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionStudyQuotaEntries(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return entries, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries = []studytypes.StudyQuotaEntry{}
+	err = cursor.All(ctx, &entries)
+	return entries, paginationInfo, err
+}
+
+// remove quota counts and entries of a study
+func (dbService *StudyDBService) DeleteStudyQuotaDataForStudy(instanceID string, studyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	if _, err := dbService.collectionStudyQuotaCounts(instanceID).DeleteMany(ctx, filter); err != nil {
+		return err
+	}
+	_, err := dbService.collectionStudyQuotaEntries(instanceID).DeleteMany(ctx, filter)
+	return err
+}
@@ -0,0 +1,317 @@
+package study
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+var indexesForStudyVariableHistoryCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "studyKey", Value: 1},
+			{Key: "key", Value: 1},
+			{Key: "changedAt", Value: -1},
+		},
+		Options: options.Index().SetName("studyKey_1_key_1_changedAt_-1"),
+	},
+}
+
+var indexesForStudyVariableSchedulesCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "studyKey", Value: 1},
+			{Key: "key", Value: 1},
+			{Key: "scheduledFor", Value: 1},
+		},
+		Options: options.Index().SetName("studyKey_1_key_1_scheduledFor_1"),
+	},
+	{
+		Keys: bson.D{
+			{Key: "studyKey", Value: 1},
+			{Key: "status", Value: 1},
+			{Key: "scheduledFor", Value: 1},
+		},
+		Options: options.Index().SetName("studyKey_1_status_1_scheduledFor_1"),
+	},
+}
+
+func (dbService *StudyDBService) DropIndexForStudyVariableHistoryCollection(instanceID string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionStudyVariableHistory(instanceID)
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for studyVariableHistory", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		}
+	} else {
+		for _, index := range indexesForStudyVariableHistoryCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for studyVariableHistory collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for studyVariableHistory", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForStudyVariableHistoryCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionStudyVariableHistory(instanceID).Indexes().CreateMany(ctx, indexesForStudyVariableHistoryCollection)
+	if err != nil {
+		slog.Error("Error creating index for studyVariableHistory", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+	}
+}
+
+func (dbService *StudyDBService) DropIndexForStudyVariableSchedulesCollection(instanceID string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionStudyVariableSchedules(instanceID)
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for studyVariableSchedules", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+		}
+	} else {
+		for _, index := range indexesForStudyVariableSchedulesCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for studyVariableSchedules collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for studyVariableSchedules", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForStudyVariableSchedulesCollection(instanceID string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionStudyVariableSchedules(instanceID).Indexes().CreateMany(ctx, indexesForStudyVariableSchedulesCollection)
+	if err != nil {
+		slog.Error("Error creating index for studyVariableSchedules", slog.String("error", err.Error()), slog.String("instanceID", instanceID))
+	}
+}
+
+func (dbService *StudyDBService) addStudyVariableHistoryEntry(instanceID string, entry studytypes.StudyVariableHistoryEntry) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_, err := dbService.collectionStudyVariableHistory(instanceID).InsertOne(ctx, entry)
+	return err
+}
+
+// get the value change history of a study variable (newest first) with pagination
+func (dbService *StudyDBService) GetStudyVariableHistory(instanceID string, studyKey string, key string, page int64, limit int64) (entries []studytypes.StudyVariableHistoryEntry, paginationInfo *PaginationInfos, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "key": key}
+
+	totalCount, err := dbService.collectionStudyVariableHistory(instanceID).CountDocuments(ctx, filter)
+	if err != nil {
+		return entries, nil, err
+	}
+
+	paginationInfo = prepPaginationInfos(
+		totalCount,
+		page,
+		limit,
+	)
+
+	skip := (paginationInfo.CurrentPage - 1) * paginationInfo.PageSize
+
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "changedAt", Value: -1}})
+	opts.SetSkip(skip)
+	opts.SetLimit(paginationInfo.PageSize)
+
+	cursor, err := dbService.collectionStudyVariableHistory(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return entries, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries = []studytypes.StudyVariableHistoryEntry{}
+	err = cursor.All(ctx, &entries)
+	return entries, paginationInfo, err
+}
+
+// create a new pending value change for a study variable
+func (dbService *StudyDBService) CreateStudyVariableScheduledChange(instanceID string, change studytypes.StudyVariableScheduledChange) (string, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	if change.CreatedAt.IsZero() {
+		change.CreatedAt = time.Now().UTC()
+	}
+	change.Status = studytypes.STUDY_VARIABLE_SCHEDULE_STATUS_PENDING
+
+	res, err := dbService.collectionStudyVariableSchedules(instanceID).InsertOne(ctx, change)
+	if err != nil {
+		return "", err
+	}
+	id := res.InsertedID.(primitive.ObjectID)
+	return id.Hex(), nil
+}
+
+// get scheduled value changes for a study variable, optionally only the pending ones
+func (dbService *StudyDBService) GetStudyVariableScheduledChanges(instanceID string, studyKey string, key string, onlyPending bool) ([]studytypes.StudyVariableScheduledChange, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey, "key": key}
+	if onlyPending {
+		filter["status"] = studytypes.STUDY_VARIABLE_SCHEDULE_STATUS_PENDING
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "scheduledFor", Value: 1}})
+
+	cursor, err := dbService.collectionStudyVariableSchedules(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	changes := []studytypes.StudyVariableScheduledChange{}
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// remove a pending scheduled change, already applied changes are kept
+func (dbService *StudyDBService) DeleteStudyVariableScheduledChange(instanceID string, studyKey string, key string, id string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	_id, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id":      _id,
+		"studyKey": studyKey,
+		"key":      key,
+		"status":   studytypes.STUDY_VARIABLE_SCHEDULE_STATUS_PENDING,
+	}
+	res, err := dbService.collectionStudyVariableSchedules(instanceID).DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// ClaimNextDueStudyVariableScheduledChange marks the oldest pending change that is due as applying and returns it.
+// Returns mongo.ErrNoDocuments if no change is due. Claiming is atomic, so concurrent timer runs apply a change only once.
+// The change has to be marked as applied or failed once the value is written.
+func (dbService *StudyDBService) ClaimNextDueStudyVariableScheduledChange(instanceID string, studyKey string, now time.Time) (change studytypes.StudyVariableScheduledChange, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey":     studyKey,
+		"status":       studytypes.STUDY_VARIABLE_SCHEDULE_STATUS_PENDING,
+		"scheduledFor": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{
+		"status": studytypes.STUDY_VARIABLE_SCHEDULE_STATUS_APPLYING,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "scheduledFor", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	err = dbService.collectionStudyVariableSchedules(instanceID).FindOneAndUpdate(ctx, filter, update, opts).Decode(&change)
+	return change, err
+}
+
+// mark a claimed scheduled change as applied
+func (dbService *StudyDBService) MarkStudyVariableScheduledChangeApplied(instanceID string, id primitive.ObjectID, appliedAt time.Time) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"status":    studytypes.STUDY_VARIABLE_SCHEDULE_STATUS_APPLIED,
+		"appliedAt": appliedAt,
+	}}
+	res, err := dbService.collectionStudyVariableSchedules(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("scheduled change not found")
+	}
+	return nil
+}
+
+// mark a claimed scheduled change as failed
+func (dbService *StudyDBService) MarkStudyVariableScheduledChangeFailed(instanceID string, id primitive.ObjectID, reason string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"_id": id}
+	update := bson.M{"$set": bson.M{
+		"status": studytypes.STUDY_VARIABLE_SCHEDULE_STATUS_FAILED,
+		"error":  reason,
+	}}
+	res, err := dbService.collectionStudyVariableSchedules(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("scheduled change not found")
+	}
+	return nil
+}
+
+// remove pending scheduled changes of a study variable
+func (dbService *StudyDBService) DeletePendingStudyVariableScheduledChanges(instanceID string, studyKey string, key string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"studyKey": studyKey,
+		"key":      key,
+		"status":   studytypes.STUDY_VARIABLE_SCHEDULE_STATUS_PENDING,
+	}
+	_, err := dbService.collectionStudyVariableSchedules(instanceID).DeleteMany(ctx, filter)
+	return err
+}
+
+// remove history and scheduled changes of all study variables of a study
+func (dbService *StudyDBService) DeleteStudyVariableChangesByStudyKey(instanceID string, studyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"studyKey": studyKey}
+	if _, err := dbService.collectionStudyVariableHistory(instanceID).DeleteMany(ctx, filter); err != nil {
+		return err
+	}
+	_, err := dbService.collectionStudyVariableSchedules(instanceID).DeleteMany(ctx, filter)
+	return err
+}
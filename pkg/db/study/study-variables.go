@@ -128,30 +128,51 @@ func (dbService *StudyDBService) UpdateStudyVariableConfig(
 	return updated, err
 }
 
-// update a study variable's value by id
+// update a study variable's value and record the change in the variable's history
 func (dbService *StudyDBService) UpdateStudyVariableValue(
 	instanceID string,
 	studyKey string,
 	key string,
-	value any) (studytypes.StudyVariables, error) {
+	value any,
+	origin studytypes.StudyVariableChangeOrigin,
+) (studytypes.StudyVariables, error) {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
 
 	filter := bson.M{"studyKey": studyKey, "key": key}
 
+	now := time.Now().UTC()
 	update := bson.M{"$set": bson.M{
 		"value":          value,
-		"valueUpdatedAt": time.Now().UTC(),
+		"valueUpdatedAt": now,
 	}}
 
-	var updated studytypes.StudyVariables
+	var previous studytypes.StudyVariables
 	err := dbService.collectionStudyVariables(instanceID).FindOneAndUpdate(
 		ctx,
 		filter,
 		update,
-		options.FindOneAndUpdate().SetReturnDocument(options.After),
-	).Decode(&updated)
-	return updated, err
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&previous)
+	if err != nil {
+		return previous, err
+	}
+
+	if err := dbService.addStudyVariableHistoryEntry(instanceID, studytypes.StudyVariableHistoryEntry{
+		StudyKey:  studyKey,
+		Key:       key,
+		OldValue:  previous.Value,
+		NewValue:  value,
+		ChangedAt: now,
+		Origin:    origin,
+	}); err != nil {
+		slog.Error("Error saving study variable history entry", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("key", key))
+	}
+
+	updated := previous
+	updated.Value = value
+	updated.ValueUpdatedAt = now
+	return updated, nil
 }
 
 // get all study variables by studyKey (optionally only core fields)
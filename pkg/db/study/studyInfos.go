@@ -115,6 +115,19 @@ func (dbService *StudyDBService) CreateStudy(instanceID string, study studyTypes
 	// index on confidential responses
 	dbService.CreateDefaultIndexesForConfidentialResponsesCollection(instanceID, studyKey)
 
+	// index on survey response drafts (incl. TTL cleanup)
+	dbService.CreateDefaultIndexesForSurveyResponseDraftsCollection(instanceID, studyKey)
+
+	// index on survey scores
+	dbService.CreateDefaultIndexesForSurveyScoresCollection(instanceID, studyKey)
+
+	// indexes on consent documents and records
+	dbService.CreateDefaultIndexesForConsentDocumentsCollection(instanceID, studyKey)
+	dbService.CreateDefaultIndexesForConsentRecordsCollection(instanceID, studyKey)
+
+	// index on submission claims (incl. TTL cleanup)
+	dbService.CreateDefaultIndexesForSubmissionClaimsCollection(instanceID, studyKey)
+
 	return nil
 }
 
@@ -182,6 +195,55 @@ func (dbService *StudyDBService) UpdateStudyFileUploadRule(instanceID string, st
 	return nil
 }
 
+func (dbService *StudyDBService) UpdateStudyStripFileMetadata(instanceID string, studyKey string, stripFileMetadata bool) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"configs.stripFileMetadata": stripFileMetadata}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateStudyArms(instanceID string, studyKey string, arms []studyTypes.StudyArm) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionStudyInfos(instanceID)
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"arms": arms}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (dbService *StudyDBService) UpdateStudyQuotas(instanceID string, studyKey string, quotas []studyTypes.StudyQuota) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"key": studyKey}
+	update := bson.M{"$set": bson.M{"quotas": quotas}}
+
+	res, err := dbService.collectionStudyInfos(instanceID).UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (dbService *StudyDBService) UpdateStudyTrackAccount(instanceID string, studyKey string, trackAccount bool) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
@@ -302,6 +364,31 @@ func (dbService *StudyDBService) DeleteStudy(instanceID string, studyKey string)
 		slog.Error("Error deleting collection", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
 	}
 
+	err = dbService.collectionSurveyResponseDrafts(instanceID, studyKey).Drop(ctx)
+	if err != nil {
+		slog.Error("Error deleting collection", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
+	err = dbService.collectionSurveyScores(instanceID, studyKey).Drop(ctx)
+	if err != nil {
+		slog.Error("Error deleting collection", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
+	err = dbService.collectionConsentDocuments(instanceID, studyKey).Drop(ctx)
+	if err != nil {
+		slog.Error("Error deleting collection", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
+	err = dbService.collectionConsentRecords(instanceID, studyKey).Drop(ctx)
+	if err != nil {
+		slog.Error("Error deleting collection", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
+	err = dbService.collectionSubmissionClaims(instanceID, studyKey).Drop(ctx)
+	if err != nil {
+		slog.Error("Error deleting collection", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
 	err = dbService.DeleteStudyCodeListsForStudy(instanceID, studyKey)
 	if err != nil {
 		slog.Error("Error deleting study code lists", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
@@ -312,6 +399,11 @@ func (dbService *StudyDBService) DeleteStudy(instanceID string, studyKey string)
 		slog.Error("Error deleting study counters", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
 	}
 
+	err = dbService.DeleteStudyQuotaDataForStudy(instanceID, studyKey)
+	if err != nil {
+		slog.Error("Error deleting study quota data", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
 	// delete study rules for study
 	err = dbService.deleteStudyRules(instanceID, studyKey)
 	if err != nil {
@@ -328,6 +420,11 @@ func (dbService *StudyDBService) DeleteStudy(instanceID string, studyKey string)
 		slog.Error("Error deleting study variables", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
 	}
 
+	err = dbService.DeleteStudyVariableChangesByStudyKey(instanceID, studyKey)
+	if err != nil {
+		slog.Error("Error deleting study variable history and schedules", slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	}
+
 	collection := dbService.collectionStudyInfos(instanceID)
 	filter := bson.M{"key": studyKey}
 	_, err = collection.DeleteOne(ctx, filter)
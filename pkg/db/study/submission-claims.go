@@ -0,0 +1,120 @@
+package study
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// claims only need to outlive client retries, older duplicates are still detected through the stored response
+const submissionClaimTTL = 30 * 24 * time.Hour
+
+var indexesForSubmissionClaimsCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "participantID", Value: 1},
+			{Key: "submissionID", Value: 1},
+		},
+		Options: options.Index().SetName("participantID_1_submissionID_1").SetUnique(true),
+	},
+	{
+		Keys: bson.D{
+			{Key: "claimedAt", Value: 1},
+		},
+		Options: options.Index().SetName("claimedAt_1").SetExpireAfterSeconds(int32(submissionClaimTTL.Seconds())),
+	},
+}
+
+func (dbService *StudyDBService) DropIndexForSubmissionClaimsCollection(instanceID string, studyKey string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionSubmissionClaims(instanceID, studyKey)
+
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for submission claims", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+		}
+	} else {
+		for _, index := range indexesForSubmissionClaimsCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for submission claims collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for submission claims", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForSubmissionClaimsCollection(instanceID string, studyKey string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionSubmissionClaims(instanceID, studyKey)
+	_, err := collection.Indexes().CreateMany(ctx, indexesForSubmissionClaimsCollection)
+	if err != nil {
+		slog.Error("Error creating index for submission claims", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+	}
+}
+
+// ClaimSubmission atomically reserves the submission ID for the participant, returns false if another request claimed it before
+func (dbService *StudyDBService) ClaimSubmission(instanceID string, studyKey string, participantID string, submissionID string) (bool, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	claim := studyTypes.SubmissionClaim{
+		ParticipantID: participantID,
+		SubmissionID:  submissionID,
+		ClaimedAt:     time.Now().UTC(),
+	}
+	_, err := dbService.collectionSubmissionClaims(instanceID, studyKey).InsertOne(ctx, claim)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CompleteSubmissionClaim stores the assigned surveys after the submission was processed
+func (dbService *StudyDBService) CompleteSubmissionClaim(instanceID string, studyKey string, participantID string, submissionID string, assignedSurveys []studyTypes.AssignedSurvey) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": participantID, "submissionID": submissionID}
+	update := bson.M{"$set": bson.M{"completed": true, "assignedSurveys": assignedSurveys}}
+	_, err := dbService.collectionSubmissionClaims(instanceID, studyKey).UpdateOne(ctx, filter, update)
+	return err
+}
+
+// GetSubmissionClaim returns mongo.ErrNoDocuments if the submission ID was not claimed
+func (dbService *StudyDBService) GetSubmissionClaim(instanceID string, studyKey string, participantID string, submissionID string) (claim studyTypes.SubmissionClaim, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": participantID, "submissionID": submissionID}
+	err = dbService.collectionSubmissionClaims(instanceID, studyKey).FindOne(ctx, filter).Decode(&claim)
+	return claim, err
+}
+
+// ReleaseSubmissionClaim removes the claim of a submission that failed before the response was stored, so the client can retry
+func (dbService *StudyDBService) ReleaseSubmissionClaim(instanceID string, studyKey string, participantID string, submissionID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": participantID, "submissionID": submissionID, "completed": bson.M{"$ne": true}}
+	_, err := dbService.collectionSubmissionClaims(instanceID, studyKey).DeleteOne(ctx, filter)
+	return err
+}
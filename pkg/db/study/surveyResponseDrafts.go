@@ -0,0 +1,185 @@
+package study
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+var indexesForSurveyResponseDraftsCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "participantID", Value: 1},
+			{Key: "surveyKey", Value: 1},
+		},
+		Options: options.Index().SetName("participantID_1_surveyKey_1").SetUnique(true),
+	},
+	{
+		Keys: bson.D{
+			{Key: "expiresAt", Value: 1},
+		},
+		Options: options.Index().SetName("expiresAt_1").SetExpireAfterSeconds(0),
+	},
+}
+
+func (dbService *StudyDBService) DropIndexForSurveyResponseDraftsCollection(instanceID string, studyKey string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionSurveyResponseDrafts(instanceID, studyKey)
+
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for survey response drafts", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+		}
+	} else {
+		for _, index := range indexesForSurveyResponseDraftsCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for survey response drafts collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for survey response drafts", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForSurveyResponseDraftsCollection(instanceID string, studyKey string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionSurveyResponseDrafts(instanceID, studyKey)
+	_, err := collection.Indexes().CreateMany(ctx, indexesForSurveyResponseDraftsCollection)
+	if err != nil {
+		slog.Error("Error creating index for survey response drafts", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+	}
+}
+
+// SaveSurveyResponseDraft creates or replaces the draft of the participant for the survey, a draft of a previous survey version is overwritten
+func (dbService *StudyDBService) SaveSurveyResponseDraft(instanceID string, studyKey string, draft studytypes.SurveyResponseDraft, ttl time.Duration) (studytypes.SurveyResponseDraft, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"participantID": draft.ParticipantID,
+		"surveyKey":     draft.SurveyKey,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"versionID": draft.VersionID,
+			"response":  draft.Response,
+			"updatedAt": now,
+			"expiresAt": now.Add(ttl),
+		},
+		"$setOnInsert": bson.M{
+			"createdAt": now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved studytypes.SurveyResponseDraft
+	err := dbService.collectionSurveyResponseDrafts(instanceID, studyKey).FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved)
+	return saved, err
+}
+
+// GetSurveyResponseDraft returns the draft of the participant for the survey, or mongo.ErrNoDocuments
+func (dbService *StudyDBService) GetSurveyResponseDraft(instanceID string, studyKey string, participantID string, surveyKey string) (draft studytypes.SurveyResponseDraft, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"participantID": participantID,
+		"surveyKey":     surveyKey,
+		// the TTL monitor runs only periodically, so expired drafts may still be present
+		"expiresAt": bson.M{"$gt": time.Now().UTC()},
+	}
+	err = dbService.collectionSurveyResponseDrafts(instanceID, studyKey).FindOne(ctx, filter).Decode(&draft)
+	return draft, err
+}
+
+// DeleteSurveyResponseDraft removes the draft of the participant for the survey, if any
+func (dbService *StudyDBService) DeleteSurveyResponseDraft(instanceID string, studyKey string, participantID string, surveyKey string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"participantID": participantID,
+		"surveyKey":     surveyKey,
+	}
+	_, err := dbService.collectionSurveyResponseDrafts(instanceID, studyKey).DeleteOne(ctx, filter)
+	return err
+}
+
+// MoveSurveyResponseDraftsToParticipant assigns the drafts of one participant to another one (e.g. when merging participants).
+// If the target participant has a draft for the same survey already, that draft is kept and the other one removed.
+func (dbService *StudyDBService) MoveSurveyResponseDraftsToParticipant(instanceID string, studyKey string, fromParticipantID string, toParticipantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionSurveyResponseDrafts(instanceID, studyKey)
+	cursor, err := collection.Find(ctx, bson.M{"participantID": fromParticipantID})
+	if err != nil {
+		return 0, err
+	}
+	var drafts []studytypes.SurveyResponseDraft
+	if err := cursor.All(ctx, &drafts); err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, draft := range drafts {
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": draft.ID}, bson.M{"$set": bson.M{"participantID": toParticipantID}})
+		if err == nil {
+			moved++
+			continue
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return moved, err
+		}
+		if _, err := collection.DeleteOne(ctx, bson.M{"_id": draft.ID}); err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// DeleteSurveyResponseDraftsForParticipant removes all drafts of the participant
+func (dbService *StudyDBService) DeleteSurveyResponseDraftsForParticipant(instanceID string, studyKey string, participantID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": participantID}
+	res, err := dbService.collectionSurveyResponseDrafts(instanceID, studyKey).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// DeleteOutdatedSurveyResponseDrafts removes drafts of the survey that were not saved for the given version.
+// Use an empty versionID to remove all drafts of the survey.
+func (dbService *StudyDBService) DeleteOutdatedSurveyResponseDrafts(instanceID string, studyKey string, surveyKey string, versionID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"surveyKey": surveyKey}
+	if versionID != "" {
+		filter["versionID"] = bson.M{"$ne": versionID}
+	}
+	res, err := dbService.collectionSurveyResponseDrafts(instanceID, studyKey).DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,185 @@
+package study
+
+import (
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+var indexesForSurveyScoresCollection = []mongo.IndexModel{
+	{
+		Keys: bson.D{
+			{Key: "responseID", Value: 1},
+		},
+		Options: options.Index().SetName("responseID_1").SetUnique(true),
+	},
+	{
+		Keys: bson.D{
+			{Key: "participantID", Value: 1},
+			{Key: "surveyKey", Value: 1},
+			{Key: "submittedAt", Value: -1},
+		},
+		Options: options.Index().SetName("participantID_1_surveyKey_1_submittedAt_-1"),
+	},
+}
+
+func (dbService *StudyDBService) DropIndexForSurveyScoresCollection(instanceID string, studyKey string, dropAll bool) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionSurveyScores(instanceID, studyKey)
+
+	if dropAll {
+		_, err := collection.Indexes().DropAll(ctx)
+		if err != nil {
+			slog.Error("Error dropping all indexes for survey scores", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+		}
+	} else {
+		for _, index := range indexesForSurveyScoresCollection {
+			if index.Options == nil || index.Options.Name == nil {
+				slog.Error("Index name is nil for survey scores collection", slog.String("index", fmt.Sprintf("%+v", index)), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+				continue
+			}
+			indexName := *index.Options.Name
+			_, err := collection.Indexes().DropOne(ctx, indexName)
+			if err != nil {
+				slog.Error("Error dropping index for survey scores", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("indexName", indexName))
+			}
+		}
+	}
+}
+
+func (dbService *StudyDBService) CreateDefaultIndexesForSurveyScoresCollection(instanceID string, studyKey string) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	collection := dbService.collectionSurveyScores(instanceID, studyKey)
+	_, err := collection.Indexes().CreateMany(ctx, indexesForSurveyScoresCollection)
+	if err != nil {
+		slog.Error("Error creating index for survey scores", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+	}
+}
+
+// SaveSurveyScoreRecord stores the scores of a response, scores computed earlier for the same response are replaced
+func (dbService *StudyDBService) SaveSurveyScoreRecord(instanceID string, studyKey string, record studytypes.SurveyScoreRecord) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"responseID": record.ResponseID}
+	record.ID = primitive.NilObjectID
+	opts := options.Replace().SetUpsert(true)
+	_, err := dbService.collectionSurveyScores(instanceID, studyKey).ReplaceOne(ctx, filter, record, opts)
+	return err
+}
+
+func (dbService *StudyDBService) GetSurveyScoreRecordByResponseID(instanceID string, studyKey string, responseID string) (record studytypes.SurveyScoreRecord, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"responseID": responseID}
+	err = dbService.collectionSurveyScores(instanceID, studyKey).FindOne(ctx, filter).Decode(&record)
+	return record, err
+}
+
+// GetSurveyScoreRecordsForParticipant returns the score records of the participant, newest first. Use an empty surveyKey for all surveys.
+func (dbService *StudyDBService) GetSurveyScoreRecordsForParticipant(instanceID string, studyKey string, participantID string, surveyKey string, limit int64) (records []studytypes.SurveyScoreRecord, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": participantID}
+	if surveyKey != "" {
+		filter["surveyKey"] = surveyKey
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "submittedAt", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+
+	cursor, err := dbService.collectionSurveyScores(instanceID, studyKey).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	records = []studytypes.SurveyScoreRecord{}
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (dbService *StudyDBService) UpdateParticipantIDonSurveyScores(instanceID string, studyKey string, oldID string, newID string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"participantID": oldID}
+	update := bson.M{"$set": bson.M{"participantID": newID}}
+	res, err := dbService.collectionSurveyScores(instanceID, studyKey).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+func (dbService *StudyDBService) DeleteSurveyScoreRecordByResponseID(instanceID string, studyKey string, responseID string) error {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{"responseID": responseID}
+	_, err := dbService.collectionSurveyScores(instanceID, studyKey).DeleteOne(ctx, filter)
+	return err
+}
+
+// DeleteOrphanedSurveyScoreRecords removes the score records of the survey whose response does not exist anymore
+func (dbService *StudyDBService) DeleteOrphanedSurveyScoreRecords(instanceID string, studyKey string, surveyKey string) (int64, error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"surveyKey": surveyKey}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": collectionNameWithStudyKeyPrefix(studyKey, COLLECTION_NAME_SUFFIX_RESPONSES),
+			"let":  bson.M{"responseID": bson.M{"$toObjectId": "$responseID"}},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$responseID"}}}},
+				bson.M{"$project": bson.M{"_id": 1}},
+			},
+			"as": "response",
+		}}},
+		{{Key: "$match", Value: bson.M{"response": bson.M{"$size": 0}}}},
+		{{Key: "$project", Value: bson.M{"_id": 1}}},
+	}
+
+	collection := dbService.collectionSurveyScores(instanceID, studyKey)
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var orphaned []struct {
+		ID interface{} `bson:"_id"`
+	}
+	if err = cursor.All(ctx, &orphaned); err != nil {
+		return 0, err
+	}
+	if len(orphaned) == 0 {
+		return 0, nil
+	}
+
+	ids := make(bson.A, len(orphaned))
+	for i, o := range orphaned {
+		ids[i] = o.ID
+	}
+	res, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
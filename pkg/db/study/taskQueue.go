@@ -121,6 +121,30 @@ func (dbService *StudyDBService) GetTaskByFilename(instanceID string, filename s
 	return task, nil
 }
 
+// GetTasksCreatedBy returns the tasks of the creator with the given result file type, newest first
+func (dbService *StudyDBService) GetTasksCreatedBy(instanceID string, createdBy string, fileType string) (tasks []studyTypes.Task, err error) {
+	ctx, cancel := dbService.getContext()
+	defer cancel()
+
+	filter := bson.M{
+		"createdBy": createdBy,
+		"fileType":  fileType,
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := dbService.collectionTaskQueue(instanceID).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tasks = []studyTypes.Task{}
+	if err = cursor.All(ctx, &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
 func (dbService *StudyDBService) UpdateTaskTotalCount(instanceID string, taskID string, totalCount int) error {
 	ctx, cancel := dbService.getContext()
 	defer cancel()
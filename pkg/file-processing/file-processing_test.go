@@ -0,0 +1,244 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+func testJPEGWithExif(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(64, 32), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	payload := append([]byte("Exif\x00\x00"), []byte("GPS-DATA")...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk[0:4], uint32(len(data)))
+	copy(chunk[4:8], chunkType)
+	chunk = append(chunk, data...)
+	crc := crc32.ChecksumIEEE(chunk[4:])
+	return binary.BigEndian.AppendUint32(chunk, crc)
+}
+
+func testPNGWithExif(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage(32, 64)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// insert after signature and IHDR chunk
+	ihdrEnd := len(pngSignature) + 12 + int(binary.BigEndian.Uint32(data[8:12]))
+	out := append([]byte{}, data[:ihdrEnd]...)
+	out = append(out, pngChunk("eXIf", []byte("MM\x00\x2aGPS-DATA"))...)
+	out = append(out, pngChunk("tEXt", []byte("Comment\x00GPS-DATA"))...)
+	return append(out, data[ihdrEnd:]...)
+}
+
+func webpChunk(fourCC string, data []byte) []byte {
+	chunk := []byte(fourCC)
+	chunk = binary.LittleEndian.AppendUint32(chunk, uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func TestStripMetadata(t *testing.T) {
+	t.Run("jpeg", func(t *testing.T) {
+		data := testJPEGWithExif(t)
+		out, stripped, err := StripMetadata(data, "image/jpeg")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !stripped || bytes.Contains(out, []byte("GPS-DATA")) {
+			t.Error("exif data not removed")
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+			t.Errorf("stripped image not decodable: %v", err)
+		}
+
+		again, stripped, err := StripMetadata(out, "image/jpeg")
+		if err != nil || stripped || !bytes.Equal(again, out) {
+			t.Error("clean image should not be changed")
+		}
+	})
+
+	t.Run("png", func(t *testing.T) {
+		data := testPNGWithExif(t)
+		out, stripped, err := StripMetadata(data, "image/png")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !stripped || bytes.Contains(out, []byte("GPS-DATA")) {
+			t.Error("metadata not removed")
+		}
+		if _, err := png.Decode(bytes.NewReader(out)); err != nil {
+			t.Errorf("stripped image not decodable: %v", err)
+		}
+	})
+
+	t.Run("webp", func(t *testing.T) {
+		vp8x := make([]byte, 10)
+		vp8x[0] = 0x08 | 0x04
+		body := []byte("WEBP")
+		body = append(body, webpChunk("VP8X", vp8x)...)
+		body = append(body, webpChunk("VP8L", []byte("pixels"))...)
+		body = append(body, webpChunk("EXIF", []byte("GPS-DATA!"))...)
+		body = append(body, webpChunk("XMP ", []byte("<xmp/>"))...)
+		data := binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body)))
+		data = append(data, body...)
+
+		out, stripped, err := StripMetadata(data, "image/webp")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !stripped || bytes.Contains(out, []byte("GPS-DATA")) || bytes.Contains(out, []byte("<xmp/>")) {
+			t.Error("metadata not removed")
+		}
+		if int(binary.LittleEndian.Uint32(out[4:8])) != len(out)-8 {
+			t.Error("riff size not updated")
+		}
+		if out[20]&(0x08|0x04) != 0 {
+			t.Error("vp8x flags not cleared")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		if _, _, err := StripMetadata([]byte("not an image"), "image/jpeg"); err == nil {
+			t.Error("expected error")
+		}
+		if _, _, err := StripMetadata([]byte("%PDF-1.4"), "application/pdf"); err != ErrUnsupportedFileType {
+			t.Error("expected unsupported file type")
+		}
+	})
+}
+
+func TestGeneratePreview(t *testing.T) {
+	t.Run("downscale image", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, testImage(800, 200)); err != nil {
+			t.Fatal(err)
+		}
+		var preview bytes.Buffer
+		if err := GeneratePreview(buf.Bytes(), "image/png", 400, &preview); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		img, err := jpeg.Decode(&preview)
+		if err != nil {
+			t.Fatalf("preview not decodable: %v", err)
+		}
+		if img.Bounds().Dx() != 400 || img.Bounds().Dy() != 100 {
+			t.Errorf("unexpected preview size: %v", img.Bounds())
+		}
+	})
+
+	t.Run("small image is not enlarged", func(t *testing.T) {
+		var preview bytes.Buffer
+		if err := GeneratePreview(testJPEGWithExif(t), "image/jpeg", 400, &preview); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		img, err := jpeg.Decode(&preview)
+		if err != nil {
+			t.Fatalf("preview not decodable: %v", err)
+		}
+		if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 32 {
+			t.Errorf("unexpected preview size: %v", img.Bounds())
+		}
+	})
+
+	t.Run("pdf with embedded jpeg", func(t *testing.T) {
+		var jpg bytes.Buffer
+		if err := jpeg.Encode(&jpg, testImage(200, 300), nil); err != nil {
+			t.Fatal(err)
+		}
+		var pdf bytes.Buffer
+		pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+		pdf.WriteString("4 0 obj\n<< /Length 10 >>\nstream\nq 1 0 0 1 cm\nendstream\nendobj\n")
+		pdf.WriteString("5 0 obj\n<< /Type /XObject /Subtype /Image /Width 200 /Height 300 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /DecodeParms << /Quality 80 >> >>\nstream\n")
+		pdf.Write(jpg.Bytes())
+		pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+		var preview bytes.Buffer
+		if err := GeneratePreview(pdf.Bytes(), "application/pdf", 150, &preview); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		img, err := jpeg.Decode(&preview)
+		if err != nil {
+			t.Fatalf("preview not decodable: %v", err)
+		}
+		if img.Bounds().Dx() != 100 || img.Bounds().Dy() != 150 {
+			t.Errorf("unexpected preview size: %v", img.Bounds())
+		}
+	})
+
+	t.Run("declared dimensions above limit", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, testImage(1, 1)); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		// IHDR data starts after the signature, chunk length and type
+		binary.BigEndian.PutUint32(data[16:20], 100000)
+		binary.BigEndian.PutUint32(data[20:24], 100000)
+		binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+
+		var preview bytes.Buffer
+		if err := GeneratePreview(data, "image/png", 400, &preview); err != ErrImageTooLarge {
+			t.Errorf("expected ErrImageTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("pdf with oversized stream dictionary", func(t *testing.T) {
+		var jpg bytes.Buffer
+		if err := jpeg.Encode(&jpg, testImage(200, 300), nil); err != nil {
+			t.Fatal(err)
+		}
+		var pdf bytes.Buffer
+		pdf.WriteString("%PDF-1.4\n5 0 obj\n<< /Subtype /Image /Filter /DCTDecode /Padding (" + strings.Repeat("x", maxPDFStreamDictionaryLength) + ") >>\nstream\n")
+		pdf.Write(jpg.Bytes())
+		pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+		var preview bytes.Buffer
+		if err := GeneratePreview(pdf.Bytes(), "application/pdf", 150, &preview); err != ErrNoPreviewAvailable {
+			t.Errorf("expected ErrNoPreviewAvailable, got %v", err)
+		}
+	})
+
+	t.Run("pdf without images", func(t *testing.T) {
+		pdf := []byte("%PDF-1.4\n4 0 obj\n<< /Length 10 >>\nstream\nq 1 0 0 1 cm\nendstream\nendobj\n%%EOF\n")
+		var preview bytes.Buffer
+		if err := GeneratePreview(pdf, "application/pdf", 150, &preview); err != ErrNoPreviewAvailable {
+			t.Errorf("expected ErrNoPreviewAvailable, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+	ErrInvalidFormat       = errors.New("invalid file format")
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// StripMetadata removes EXIF (including GPS), XMP and textual metadata from JPEG, PNG and WebP images without re-encoding them.
+// Returns the cleaned content and whether anything was removed.
+func StripMetadata(data []byte, contentType string) ([]byte, bool, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEGMetadata(data)
+	case "image/png":
+		return stripPNGMetadata(data)
+	case "image/webp":
+		return stripWebPMetadata(data)
+	default:
+		return data, false, ErrUnsupportedFileType
+	}
+}
+
+// IsMetadataStrippingSupported checks if StripMetadata can handle the content type
+func IsMetadataStrippingSupported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	}
+	return false
+}
+
+func stripJPEGMetadata(data []byte) ([]byte, bool, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return data, false, ErrInvalidFormat
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	stripped := false
+
+	i := 2
+	for i < len(data) {
+		if data[i] != 0xFF {
+			return data, false, ErrInvalidFormat
+		}
+		// skip fill bytes
+		for i+1 < len(data) && data[i+1] == 0xFF {
+			i++
+		}
+		if i+1 >= len(data) {
+			return data, false, ErrInvalidFormat
+		}
+		marker := data[i+1]
+
+		// start of scan or end of image: compressed data follows, copy the rest unchanged
+		if marker == 0xDA || marker == 0xD9 {
+			out = append(out, data[i:]...)
+			return out, stripped, nil
+		}
+		// markers without payload
+		if marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+
+		if i+4 > len(data) {
+			return data, false, ErrInvalidFormat
+		}
+		segmentEnd := i + 2 + int(binary.BigEndian.Uint16(data[i+2:i+4]))
+		if segmentEnd > len(data) {
+			return data, false, ErrInvalidFormat
+		}
+
+		// APP1 holds EXIF and XMP, APP13 holds Photoshop/IPTC data
+		if marker == 0xE1 || marker == 0xED {
+			stripped = true
+		} else {
+			out = append(out, data[i:segmentEnd]...)
+		}
+		i = segmentEnd
+	}
+	return data, false, ErrInvalidFormat
+}
+
+func stripPNGMetadata(data []byte) ([]byte, bool, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return data, false, ErrInvalidFormat
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	stripped := false
+
+	i := len(pngSignature)
+	for i < len(data) {
+		if i+8 > len(data) {
+			return data, false, ErrInvalidFormat
+		}
+		chunkLen := int(binary.BigEndian.Uint32(data[i : i+4]))
+		chunkType := string(data[i+4 : i+8])
+		chunkEnd := i + 12 + chunkLen // length, type, data, crc
+		if chunkLen < 0 || chunkEnd > len(data) {
+			return data, false, ErrInvalidFormat
+		}
+
+		switch chunkType {
+		case "eXIf", "tEXt", "zTXt", "iTXt":
+			stripped = true
+		default:
+			out = append(out, data[i:chunkEnd]...)
+		}
+		i = chunkEnd
+
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out, stripped, nil
+}
+
+func stripWebPMetadata(data []byte) ([]byte, bool, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return data, false, ErrInvalidFormat
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[0:12]...)
+	stripped := false
+	vp8xFlagsPos := -1
+
+	i := 12
+	for i < len(data) {
+		if i+8 > len(data) {
+			return data, false, ErrInvalidFormat
+		}
+		fourCC := string(data[i : i+4])
+		chunkLen := int(binary.LittleEndian.Uint32(data[i+4 : i+8]))
+		chunkEnd := i + 8 + chunkLen + chunkLen%2 // chunks are padded to even size
+		if chunkLen < 0 || chunkEnd > len(data) {
+			return data, false, ErrInvalidFormat
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+			stripped = true
+		default:
+			if fourCC == "VP8X" && chunkLen > 0 {
+				vp8xFlagsPos = len(out) + 8
+			}
+			out = append(out, data[i:chunkEnd]...)
+		}
+		i = chunkEnd
+	}
+
+	if stripped && vp8xFlagsPos >= 0 {
+		// clear EXIF (0x08) and XMP (0x04) flags of the extended header
+		out[vp8xFlagsPos] &^= 0x08 | 0x04
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, stripped, nil
+}
@@ -0,0 +1,89 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"regexp"
+)
+
+var (
+	pdfStreamStart = []byte("stream")
+	pdfStreamEnd   = []byte("endstream")
+
+	pdfImageSubtype = regexp.MustCompile(`/Subtype\s*/Image\b`)
+	pdfDCTFilter    = regexp.MustCompile(`/DCTDecode\b`)
+)
+
+// stream dictionaries are searched at most this far in front of the stream keyword,
+// so the search stays linear in the file size for PDFs with many streams
+const maxPDFStreamDictionaryLength = 4096
+
+// firstPDFImage returns the first JPEG encoded image stream of the PDF in file order.
+// Rendering pages would need a full PDF renderer, but scanned documents and photos consist of a single page-sized image.
+// The image is not resolved through the page tree, so it is not necessarily shown on the first page: a PDF whose first
+// page has no JPEG image, or whose objects are not stored in page order, gets the preview of an image from another page.
+func firstPDFImage(data []byte) (image.Image, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, ErrInvalidFormat
+	}
+
+	offset := 0
+	for {
+		idx := bytes.Index(data[offset:], pdfStreamStart)
+		if idx < 0 {
+			return nil, ErrNoPreviewAvailable
+		}
+		start := offset + idx
+		offset = start + len(pdfStreamStart)
+
+		// skip "endstream" and keywords that only end with "stream"
+		if start >= 3 && bytes.Equal(data[start-3:start], []byte("end")) {
+			continue
+		}
+
+		dict := pdfStreamDictionary(data[max(0, start-maxPDFStreamDictionaryLength):start])
+		if dict == nil || !pdfImageSubtype.Match(dict) || !pdfDCTFilter.Match(dict) {
+			continue
+		}
+
+		content := data[offset:]
+		// stream keyword is followed by CRLF or LF
+		content = bytes.TrimPrefix(content, []byte("\r"))
+		content = bytes.TrimPrefix(content, []byte("\n"))
+		end := bytes.Index(content, pdfStreamEnd)
+		if end < 0 {
+			return nil, ErrInvalidFormat
+		}
+
+		img, err := decodeWithinLimit(content[:end], jpeg.DecodeConfig, jpeg.Decode)
+		if err == nil || err == ErrImageTooLarge {
+			return img, err
+		}
+	}
+}
+
+// pdfStreamDictionary returns the dictionary directly in front of a stream keyword, supporting nested dictionaries.
+// Returns nil if the dictionary does not start within before.
+func pdfStreamDictionary(before []byte) []byte {
+	end := len(bytes.TrimRight(before, " \t\r\n"))
+	if end < 2 || !bytes.Equal(before[end-2:end], []byte(">>")) {
+		return nil
+	}
+
+	depth := 0
+	for i := end - 2; i >= 1; i-- {
+		switch {
+		case before[i] == '>' && before[i+1] == '>':
+			depth++
+			i--
+		case before[i-1] == '<' && before[i] == '<':
+			depth--
+			if depth == 0 {
+				return before[i-1 : end]
+			}
+			i--
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package fileprocessing
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+const (
+	DefaultPreviewMaxSize = 400 // longest edge in pixels
+	PreviewContentType    = "image/jpeg"
+	PreviewFileExtension  = ".jpg"
+
+	// MaxPreviewSourcePixels limits the decoded size of source images, the declared dimensions are
+	// checked before decoding so small, highly compressed files cannot allocate huge bitmaps
+	MaxPreviewSourcePixels = 50_000_000
+
+	previewJPEGQuality = 80
+)
+
+var (
+	ErrNoPreviewAvailable = errors.New("no preview could be generated for the file")
+	ErrImageTooLarge      = errors.New("image dimensions exceed the preview limit")
+)
+
+// IsPreviewSupported checks if GeneratePreview can handle the content type
+func IsPreviewSupported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/webp", "application/pdf":
+		return true
+	}
+	return false
+}
+
+// GeneratePreview creates a JPEG preview that fits into maxSize x maxSize pixels.
+// For PDFs the first embedded JPEG image in file order is used, which covers scanned documents but is not necessarily
+// the first page; other PDFs return ErrNoPreviewAvailable.
+func GeneratePreview(data []byte, contentType string, maxSize int, w io.Writer) error {
+	var (
+		img image.Image
+		err error
+	)
+	switch contentType {
+	case "image/jpeg":
+		img, err = decodeWithinLimit(data, jpeg.DecodeConfig, jpeg.Decode)
+	case "image/png":
+		img, err = decodeWithinLimit(data, png.DecodeConfig, png.Decode)
+	case "image/webp":
+		img, err = decodeWithinLimit(data, webp.DecodeConfig, webp.Decode)
+	case "application/pdf":
+		img, err = firstPDFImage(data)
+	default:
+		return ErrUnsupportedFileType
+	}
+	if err != nil {
+		return err
+	}
+
+	return jpeg.Encode(w, downscale(img, maxSize), &jpeg.Options{Quality: previewJPEGQuality})
+}
+
+// decodeWithinLimit reads the image header first and only decodes images up to MaxPreviewSourcePixels
+func decodeWithinLimit(
+	data []byte,
+	decodeConfig func(io.Reader) (image.Config, error),
+	decode func(io.Reader) (image.Image, error),
+) (image.Image, error) {
+	cfg, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > MaxPreviewSourcePixels {
+		return nil, ErrImageTooLarge
+	}
+	return decode(bytes.NewReader(data))
+}
+
+// downscale keeps the aspect ratio, images smaller than maxSize are not enlarged.
+// Transparent areas are drawn on white, since JPEG has no alpha channel.
+func downscale(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxSize <= 0 {
+		maxSize = DefaultPreviewMaxSize
+	}
+	if width > maxSize || height > maxSize {
+		if width >= height {
+			height = max(1, height*maxSize/width)
+			width = maxSize
+		} else {
+			width = max(1, width*maxSize/height)
+			height = maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+	return dst
+}
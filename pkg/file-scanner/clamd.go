@@ -0,0 +1,114 @@
+package filescanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const clamdChunkSize = 64 * 1024
+
+// ClamdScanner streams the content to a clamd daemon using the INSTREAM command
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+func NewClamdScanner(rawURL string, timeout time.Duration) (*ClamdScanner, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "tcp":
+		if u.Host == "" {
+			return nil, errors.New("clamd address is missing")
+		}
+		return &ClamdScanner{network: "tcp", address: u.Host, timeout: timeout}, nil
+	case "unix":
+		if u.Path == "" {
+			return nil, errors.New("clamd socket path is missing")
+		}
+		return &ClamdScanner{network: "unix", address: u.Path, timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unsupported clamd url scheme: %s", u.Scheme)
+	}
+}
+
+func (s *ClamdScanner) Scan(ctx context.Context, content io.Reader) (Result, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return Result{}, err
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, err
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	sizeHeader := make([]byte, 4)
+	for {
+		n, err := content.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(sizeHeader, uint32(n))
+			if _, err := conn.Write(sizeHeader); err != nil {
+				return Result{}, err
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return Result{}, err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	// zero length chunk terminates the stream
+	binary.BigEndian.PutUint32(sizeHeader, 0)
+	if _, err := conn.Write(sizeHeader); err != nil {
+		return Result{}, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Result{}, err
+	}
+	return parseClamdReply(reply)
+}
+
+// parseClamdReply interprets replies like "stream: OK" or "stream: Eicar-Signature FOUND"
+func parseClamdReply(reply string) (Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	_, status, found := strings.Cut(reply, ": ")
+	if !found {
+		return Result{}, fmt.Errorf("unexpected clamd reply: %s", reply)
+	}
+
+	switch {
+	case status == "OK":
+		return Result{Scanner: SCANNER_TYPE_CLAMD}, nil
+	case strings.HasSuffix(status, " FOUND"):
+		return Result{
+			Infected: true,
+			Threat:   strings.TrimSuffix(status, " FOUND"),
+			Scanner:  SCANNER_TYPE_CLAMD,
+		}, nil
+	default:
+		return Result{}, fmt.Errorf("clamd error: %s", status)
+	}
+}
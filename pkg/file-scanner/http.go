@@ -0,0 +1,74 @@
+package filescanner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+)
+
+// HTTPScanner posts the raw content to a scanning service that replies with {"infected": bool, "threat": "..."}
+type HTTPScanner struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+type httpScanResponse struct {
+	Infected bool   `json:"infected"`
+	Threat   string `json:"threat"`
+}
+
+func NewHTTPScanner(url string, apiKey string, mTLSConfig *studyengine.MutualTLSConfig, timeout time.Duration) (*HTTPScanner, error) {
+	if url == "" {
+		return nil, errors.New("scanner url is missing")
+	}
+
+	client := &http.Client{Timeout: timeout}
+	if mTLSConfig != nil {
+		tlsConfig, err := apihelpers.LoadTLSConfig(apihelpers.CertificatePaths{
+			CACertPath:     mTLSConfig.CAFile,
+			ServerCertPath: mTLSConfig.CertFile,
+			ServerKeyPath:  mTLSConfig.KeyFile,
+		})
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	return &HTTPScanner{url: url, apiKey: apiKey, client: client}, nil
+}
+
+func (s *HTTPScanner) Scan(ctx context.Context, content io.Reader) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, content)
+	if err != nil {
+		return Result{}, err
+	}
+	if s.apiKey != "" {
+		req.Header.Set("Api-Key", s.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("scanner responded with status %d", resp.StatusCode)
+	}
+
+	var res httpScanResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return Result{}, err
+	}
+	return Result{Infected: res.Infected, Threat: res.Threat, Scanner: SCANNER_TYPE_HTTP}, nil
+}
@@ -0,0 +1,137 @@
+package filescanner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const icapDefaultPort = "1344"
+
+// ICAPScanner sends the content to an ICAP antivirus service (RFC 3507) as RESPMOD request
+type ICAPScanner struct {
+	serviceURL *url.URL
+	timeout    time.Duration
+}
+
+func NewICAPScanner(rawURL string, timeout time.Duration) (*ICAPScanner, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "icap" {
+		return nil, fmt.Errorf("unsupported icap url scheme: %s", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("icap host is missing")
+	}
+	return &ICAPScanner{serviceURL: u, timeout: timeout}, nil
+}
+
+func (s *ICAPScanner) Scan(ctx context.Context, content io.Reader) (Result, error) {
+	address := s.serviceURL.Host
+	if s.serviceURL.Port() == "" {
+		address = net.JoinHostPort(s.serviceURL.Hostname(), icapDefaultPort)
+	}
+
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return Result{}, err
+	}
+
+	// encapsulated HTTP response that carries the file as body
+	resHeader := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nTransfer-Encoding: chunked\r\n\r\n"
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", s.serviceURL.String())
+	fmt.Fprintf(w, "Host: %s\r\n", s.serviceURL.Host)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Connection: close\r\n")
+	fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(resHeader))
+	w.WriteString(resHeader)
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := content.Read(buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			w.WriteString("\r\n")
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Result{}, err
+		}
+	}
+	w.WriteString("0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return Result{}, err
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	statusLine, err := reader.ReadLine()
+	if err != nil {
+		return Result{}, err
+	}
+	headers, err := reader.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Result{}, err
+	}
+	return parseICAPResponse(statusLine, headers)
+}
+
+// parseICAPResponse: 204 means unmodified (clean), 200 means the service replaced the content, i.e. blocked it
+func parseICAPResponse(statusLine string, headers textproto.MIMEHeader) (Result, error) {
+	parts := strings.SplitN(statusLine, " ", 3)
+	if len(parts) < 2 || !strings.HasPrefix(parts[0], "ICAP/") {
+		return Result{}, fmt.Errorf("unexpected icap response: %s", statusLine)
+	}
+	code, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected icap response: %s", statusLine)
+	}
+
+	threat := icapThreatFromHeaders(headers)
+	switch code {
+	case 204:
+		return Result{Scanner: SCANNER_TYPE_ICAP}, nil
+	case 200:
+		if threat == "" {
+			threat = "unknown"
+		}
+		return Result{Infected: true, Threat: threat, Scanner: SCANNER_TYPE_ICAP}, nil
+	default:
+		return Result{}, fmt.Errorf("icap error: %s", statusLine)
+	}
+}
+
+func icapThreatFromHeaders(headers textproto.MIMEHeader) string {
+	// e.g. "Type=0; Resolution=2; Threat=Eicar-Test-Signature;"
+	if infection := headers.Get("X-Infection-Found"); infection != "" {
+		for _, part := range strings.Split(infection, ";") {
+			key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+			if found && strings.EqualFold(key, "Threat") {
+				return value
+			}
+		}
+	}
+	if virusID := headers.Get("X-Virus-ID"); virusID != "" {
+		return virusID
+	}
+	return ""
+}
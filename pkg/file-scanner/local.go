@@ -0,0 +1,37 @@
+package filescanner
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// EICAR anti-virus test file, detected by every scanner
+const EICAR_TEST_SIGNATURE = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// LocalScanner is a stand-in for a real scanner that only matches fixed byte signatures
+type LocalScanner struct {
+	// threat name by signature
+	Signatures map[string][]byte
+}
+
+func NewLocalScanner() *LocalScanner {
+	return &LocalScanner{
+		Signatures: map[string][]byte{
+			"Eicar-Test-Signature": []byte(EICAR_TEST_SIGNATURE),
+		},
+	}
+}
+
+func (s *LocalScanner) Scan(ctx context.Context, content io.Reader) (Result, error) {
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return Result{}, err
+	}
+	for threat, signature := range s.Signatures {
+		if bytes.Contains(data, signature) {
+			return Result{Infected: true, Threat: threat, Scanner: SCANNER_TYPE_LOCAL}, nil
+		}
+	}
+	return Result{Scanner: SCANNER_TYPE_LOCAL}, nil
+}
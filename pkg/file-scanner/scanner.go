@@ -0,0 +1,81 @@
+package filescanner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+)
+
+const (
+	SCANNER_TYPE_CLAMD = "clamd"
+	SCANNER_TYPE_ICAP  = "icap"
+	SCANNER_TYPE_HTTP  = "http"
+	SCANNER_TYPE_LOCAL = "local" // signature matching in process, for tests and local development
+)
+
+const defaultTimeout = 60 * time.Second
+
+// Config defines the scanning backend, similar to the external service definitions of the study engine
+type Config struct {
+	Type string `json:"type" yaml:"type"`
+	// clamd: tcp://host:3310 or unix:///path/to/clamd.sock
+	// icap: icap://host:1344/service
+	// http: endpoint the file content is posted to
+	URL             string                       `json:"url" yaml:"url"`
+	APIKey          string                       `json:"apiKey" yaml:"apiKey"`
+	Timeout         int                          `json:"timeout" yaml:"timeout"` // in seconds
+	MutualTLSConfig *studyengine.MutualTLSConfig `json:"mTLSConfig" yaml:"mTLSConfig"`
+}
+
+type Result struct {
+	Infected bool
+	Threat   string // name of the detected signature, if reported by the scanner
+	Scanner  string
+}
+
+type Scanner interface {
+	Scan(ctx context.Context, content io.Reader) (Result, error)
+}
+
+// NewScanner creates the scanner for the configured type, returns nil if no type is configured
+func NewScanner(conf *Config) (Scanner, error) {
+	if conf == nil || conf.Type == "" {
+		return nil, nil
+	}
+
+	timeout := defaultTimeout
+	if conf.Timeout > 0 {
+		timeout = time.Duration(conf.Timeout) * time.Second
+	}
+
+	switch conf.Type {
+	case SCANNER_TYPE_CLAMD:
+		return NewClamdScanner(conf.URL, timeout)
+	case SCANNER_TYPE_ICAP:
+		return NewICAPScanner(conf.URL, timeout)
+	case SCANNER_TYPE_HTTP:
+		return NewHTTPScanner(conf.URL, conf.APIKey, conf.MutualTLSConfig, timeout)
+	case SCANNER_TYPE_LOCAL:
+		return NewLocalScanner(), nil
+	default:
+		return nil, fmt.Errorf("unknown file scanner type: %s", conf.Type)
+	}
+}
+
+// ScanFile scans the file at the given path
+func ScanFile(ctx context.Context, scanner Scanner, path string) (Result, error) {
+	if scanner == nil {
+		return Result{}, errors.New("no scanner configured")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer f.Close()
+	return scanner.Scan(ctx, f)
+}
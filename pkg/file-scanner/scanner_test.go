@@ -0,0 +1,182 @@
+package filescanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalScanner(t *testing.T) {
+	scanner := NewLocalScanner()
+
+	res, err := scanner.Scan(context.Background(), strings.NewReader("harmless content"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Infected {
+		t.Error("clean content reported as infected")
+	}
+
+	res, err = scanner.Scan(context.Background(), strings.NewReader("prefix "+EICAR_TEST_SIGNATURE))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Infected || res.Threat != "Eicar-Test-Signature" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+func TestNewScanner(t *testing.T) {
+	s, err := NewScanner(nil)
+	if err != nil || s != nil {
+		t.Error("expected no scanner without config")
+	}
+	if _, err := NewScanner(&Config{Type: "unknown"}); err == nil {
+		t.Error("expected error for unknown type")
+	}
+	if _, err := NewScanner(&Config{Type: SCANNER_TYPE_CLAMD, URL: "http://localhost:3310"}); err == nil {
+		t.Error("expected error for invalid clamd url")
+	}
+	if _, err := NewScanner(&Config{Type: SCANNER_TYPE_ICAP, URL: "icap://localhost/avscan"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// fakeClamd reads an INSTREAM request and replies using the local scanner
+func fakeClamd(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, err := r.ReadString('\x00')
+				if err != nil || cmd != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+				var data []byte
+				sizeHeader := make([]byte, 4)
+				for {
+					if _, err := io.ReadFull(r, sizeHeader); err != nil {
+						return
+					}
+					size := binary.BigEndian.Uint32(sizeHeader)
+					if size == 0 {
+						break
+					}
+					chunk := make([]byte, size)
+					if _, err := io.ReadFull(r, chunk); err != nil {
+						return
+					}
+					data = append(data, chunk...)
+				}
+				res, _ := NewLocalScanner().Scan(context.Background(), strings.NewReader(string(data)))
+				if res.Infected {
+					conn.Write([]byte("stream: " + res.Threat + " FOUND\x00"))
+				} else {
+					conn.Write([]byte("stream: OK\x00"))
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestClamdScanner(t *testing.T) {
+	scanner, err := NewClamdScanner("tcp://"+fakeClamd(t), 5*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := scanner.Scan(context.Background(), strings.NewReader(strings.Repeat("a", 3*clamdChunkSize)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Infected {
+		t.Error("clean content reported as infected")
+	}
+
+	res, err = scanner.Scan(context.Background(), strings.NewReader(EICAR_TEST_SIGNATURE))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Infected || res.Threat != "Eicar-Test-Signature" {
+		t.Errorf("unexpected result: %+v", res)
+	}
+
+	if _, err := parseClamdReply("stream: Can't allocate memory ERROR\x00"); err == nil {
+		t.Error("expected error for clamd error reply")
+	}
+}
+
+func TestParseICAPResponse(t *testing.T) {
+	res, err := parseICAPResponse("ICAP/1.0 204 No Content", textproto.MIMEHeader{})
+	if err != nil || res.Infected {
+		t.Errorf("unexpected result: %+v, %v", res, err)
+	}
+
+	headers := textproto.MIMEHeader{}
+	headers.Set("X-Infection-Found", "Type=0; Resolution=2; Threat=Eicar-Test-Signature;")
+	res, err = parseICAPResponse("ICAP/1.0 200 OK", headers)
+	if err != nil || !res.Infected || res.Threat != "Eicar-Test-Signature" {
+		t.Errorf("unexpected result: %+v, %v", res, err)
+	}
+
+	if _, err := parseICAPResponse("ICAP/1.0 500 Server Error", textproto.MIMEHeader{}); err == nil {
+		t.Error("expected error for server error")
+	}
+}
+
+func TestHTTPScanner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Api-Key") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), EICAR_TEST_SIGNATURE) {
+			w.Write([]byte(`{"infected": true, "threat": "Eicar-Test-Signature"}`))
+			return
+		}
+		w.Write([]byte(`{"infected": false}`))
+	}))
+	defer server.Close()
+
+	scanner, err := NewHTTPScanner(server.URL, "test-key", nil, 5*time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	res, err := scanner.Scan(context.Background(), strings.NewReader("harmless"))
+	if err != nil || res.Infected {
+		t.Errorf("unexpected result: %+v, %v", res, err)
+	}
+
+	res, err = scanner.Scan(context.Background(), strings.NewReader(EICAR_TEST_SIGNATURE))
+	if err != nil || !res.Infected || res.Threat != "Eicar-Test-Signature" {
+		t.Errorf("unexpected result: %+v, %v", res, err)
+	}
+
+	wrongKey, _ := NewHTTPScanner(server.URL, "wrong", nil, 5*time.Second)
+	if _, err := wrongKey.Scan(context.Background(), strings.NewReader("harmless")); err == nil {
+		t.Error("expected error for rejected request")
+	}
+}
@@ -27,11 +27,12 @@ func InitMessageSendingVariables(
 }
 
 type SendEmailReq struct {
-	To              []string                        `json:"to"`
-	Subject         string                          `json:"subject"`
-	Content         string                          `json:"content"`
-	HighPrio        bool                            `json:"highPrio"`
-	HeaderOverrides *messagingTypes.HeaderOverrides `json:"headerOverrides"`
+	To              []string                         `json:"to"`
+	Subject         string                           `json:"subject"`
+	Content         string                           `json:"content"`
+	HighPrio        bool                             `json:"highPrio"`
+	HeaderOverrides *messagingTypes.HeaderOverrides  `json:"headerOverrides"`
+	Attachments     []messagingTypes.EmailAttachment `json:"attachments,omitempty"`
 }
 
 func SendOutgoingEmail(
@@ -47,6 +48,7 @@ func SendOutgoingEmail(
 		Content:         outgoing.Content,
 		HighPrio:        outgoing.HighPrio,
 		HeaderOverrides: outgoing.HeaderOverrides,
+		Attachments:     outgoing.Attachments,
 	}
 	resp, err := HttpClient.RunHTTPcall("/send-email", sendEmailReq)
 	if err == nil && resp != nil {
@@ -68,6 +70,7 @@ func SendInstantEmailByTemplate(
 	payload map[string]string,
 	useLowPrio bool,
 	expiresAt int64,
+	attachments ...messagingTypes.EmailAttachment,
 ) error {
 	if HttpClient == nil || HttpClient.RootURL == "" {
 		return errors.New("connection to smtp bridge not initialized")
@@ -88,6 +91,7 @@ func SendInstantEmailByTemplate(
 		return err
 	}
 	outgoingEmail.ExpiresAt = expiresAt
+	outgoingEmail.Attachments = attachments
 
 	// send email
 	err = SendOutgoingEmail(outgoingEmail)
@@ -1,6 +1,8 @@
 package emailtemplates
 
 import (
+	"fmt"
+
 	"github.com/case-framework/case-backend/pkg/messaging/templates"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 )
@@ -12,3 +14,13 @@ func GetTemplateTranslation(tDef messagingTypes.EmailTemplate, lang string) mess
 func CheckAllTranslationsParsable(tempTranslations messagingTypes.EmailTemplate) (err error) {
 	return templates.CheckAllTranslationsParsable(tempTranslations.Translations, tempTranslations.MessageType)
 }
+
+// CheckChannels makes sure the template is only configured for known delivery channels
+func CheckChannels(tDef messagingTypes.EmailTemplate) error {
+	for _, channel := range tDef.Channels {
+		if !messagingTypes.IsKnownMessageChannel(channel) {
+			return fmt.Errorf("unknown message channel: %s", channel)
+		}
+	}
+	return nil
+}
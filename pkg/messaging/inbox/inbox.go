@@ -0,0 +1,48 @@
+package inbox
+
+import (
+	"errors"
+	"time"
+
+	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	emailtemplates "github.com/case-framework/case-backend/pkg/messaging/email-templates"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+// GenerateInboxMessage renders a study message template for the inbox: the subject is used as title and the
+// resolved template as body. The message is localised the same way as emails, falling back to the default language.
+func GenerateInboxMessage(
+	template messagingTypes.EmailTemplate,
+	userID string,
+	profileID string,
+	lang string,
+	payload map[string]string,
+	surveyKey string,
+) (messagingTypes.InboxMessage, error) {
+	translation := emailtemplates.GetTemplateTranslation(template, lang)
+	if translation.Lang == "" {
+		return messagingTypes.InboxMessage{}, errors.New("no translation found for message template")
+	}
+
+	title, body, err := emailsending.GenerateEmailContent(template, translation.Lang, payload)
+	if err != nil {
+		return messagingTypes.InboxMessage{}, err
+	}
+
+	message := messagingTypes.InboxMessage{
+		UserID:      userID,
+		ProfileID:   profileID,
+		StudyKey:    template.StudyKey,
+		MessageType: template.MessageType,
+		Language:    translation.Lang,
+		Title:       title,
+		Body:        body,
+		CreatedAt:   time.Now(),
+	}
+	if surveyKey != "" {
+		message.Actions = []messagingTypes.InboxAction{
+			{Type: messagingTypes.INBOX_ACTION_TYPE_SURVEY, SurveyKey: surveyKey},
+		}
+	}
+	return message, nil
+}
@@ -0,0 +1,46 @@
+package inbox
+
+import (
+	"encoding/base64"
+	"testing"
+
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+func TestGenerateInboxMessage(t *testing.T) {
+	template := messagingTypes.EmailTemplate{
+		MessageType:     "reminder",
+		StudyKey:        "study1",
+		DefaultLanguage: "en",
+		Translations: []messagingTypes.LocalizedTemplate{
+			{Lang: "en", Subject: "Reminder", TemplateDef: base64.StdEncoding.EncodeToString([]byte("Hello {{.profileAlias}}"))},
+			{Lang: "de", Subject: "Erinnerung", TemplateDef: base64.StdEncoding.EncodeToString([]byte("Hallo {{.profileAlias}}"))},
+		},
+	}
+
+	t.Run("preferred language", func(t *testing.T) {
+		msg, err := GenerateInboxMessage(template, "user1", "profile1", "de", map[string]string{"profileAlias": "Max"}, "weekly")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg.Title != "Erinnerung" || msg.Body != "Hallo Max" || msg.Language != "de" {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+		if msg.StudyKey != "study1" || msg.UserID != "user1" || msg.ReadAt != nil {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+		if len(msg.Actions) != 1 || msg.Actions[0].Type != messagingTypes.INBOX_ACTION_TYPE_SURVEY || msg.Actions[0].SurveyKey != "weekly" {
+			t.Errorf("unexpected actions: %+v", msg.Actions)
+		}
+	})
+
+	t.Run("fallback to default language", func(t *testing.T) {
+		msg, err := GenerateInboxMessage(template, "user1", "profile1", "fr", map[string]string{"profileAlias": "Max"}, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg.Title != "Reminder" || msg.Language != "en" || len(msg.Actions) != 0 {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package sms
 
 import (
 	"encoding/base64"
+	"errors"
 	"time"
 
 	messageDB "github.com/case-framework/case-backend/pkg/db/messaging"
@@ -17,6 +18,13 @@ var (
 const (
 	SMS_MESSAGE_TYPE_VERIFY_PHONE_NUMBER = "verify-phone-number"
 	SMS_MESSAGE_TYPE_OTP                 = "otp"
+
+	// account notifications for phone accounts, same names as the email message types
+	SMS_MESSAGE_TYPE_PASSWORD_RESET                   = "password-reset"
+	SMS_MESSAGE_TYPE_PASSWORD_CHANGED                 = "password-changed"
+	SMS_MESSAGE_TYPE_ACCOUNT_DELETED                  = "account-deleted"
+	SMS_MESSAGE_TYPE_ACCOUNT_DELETED_AFTER_INACTIVITY = "account-deleted-after-inactivity"
+	SMS_MESSAGE_TYPE_ACCOUNT_INACTIVITY               = "account-inactivity"
 )
 
 func Init(
@@ -28,11 +36,31 @@ func Init(
 }
 
 func SendSMS(instanceID string, to string, userID string, messageType string, lang string, payload map[string]string) error {
+	if MessageDBService == nil {
+		return errors.New("sms sending not initialized")
+	}
+
 	templateDef, err := MessageDBService.GetSMSTemplateByType(instanceID, messageType)
 	if err != nil {
 		return err
 	}
+	return sendWithTemplate(instanceID, to, userID, *templateDef, lang, payload)
+}
+
+// SendStudySMS uses the SMS template of the study for the message type
+func SendStudySMS(instanceID string, to string, userID string, studyKey string, messageType string, lang string, payload map[string]string) error {
+	if MessageDBService == nil {
+		return errors.New("sms sending not initialized")
+	}
+
+	templateDef, err := MessageDBService.GetStudySMSTemplateByMessageType(instanceID, studyKey, messageType)
+	if err != nil {
+		return err
+	}
+	return sendWithTemplate(instanceID, to, userID, *templateDef, lang, payload)
+}
 
+func sendWithTemplate(instanceID string, to string, userID string, templateDef types.SMSTemplate, lang string, payload map[string]string) error {
 	translation := templates.GetTemplateTranslation(templateDef.Translations, lang, templateDef.DefaultLanguage)
 
 	decodedTemplate, err := base64.StdEncoding.DecodeString(translation.TemplateDef)
@@ -47,7 +75,7 @@ func SendSMS(instanceID string, to string, userID string, messageType string, la
 	payload["language"] = lang
 
 	// execute template
-	templateName := instanceID + messageType + lang
+	templateName := instanceID + templateDef.StudyKey + templateDef.MessageType + lang
 	content, err := templates.ResolveTemplate(
 		templateName,
 		string(decodedTemplate),
@@ -65,9 +93,10 @@ func SendSMS(instanceID string, to string, userID string, messageType string, la
 
 	// save sent sms
 	_, err = MessageDBService.AddToSentSMS(instanceID, types.SentSMS{
-		MessageType: messageType,
+		MessageType: templateDef.MessageType,
 		PhoneNumber: to,
 		UserID:      userID,
+		StudyKey:    templateDef.StudyKey,
 		SentAt:      time.Now(),
 	})
 	if err != nil {
@@ -0,0 +1,47 @@
+package types
+
+// channels a message can be delivered through
+const (
+	MESSAGE_CHANNEL_EMAIL = "email"
+	MESSAGE_CHANNEL_INBOX = "inbox"
+	MESSAGE_CHANNEL_PUSH  = "push"
+)
+
+func IsKnownMessageChannel(channel string) bool {
+	switch channel {
+	case MESSAGE_CHANNEL_EMAIL, MESSAGE_CHANNEL_INBOX, MESSAGE_CHANNEL_PUSH:
+		return true
+	}
+	return false
+}
+
+type DeliveryChannels struct {
+	Email bool
+	Inbox bool
+	Push  bool
+}
+
+// ResolveDeliveryChannels decides how a message is delivered: channels set for the message itself (e.g. by a study rule)
+// win over the channels configured in the template, without either the message is sent by email
+func ResolveDeliveryChannels(messageChannels []string, templateChannels []string) DeliveryChannels {
+	channels := messageChannels
+	if len(channels) == 0 {
+		channels = templateChannels
+	}
+	if len(channels) == 0 {
+		return DeliveryChannels{Email: true}
+	}
+
+	dc := DeliveryChannels{}
+	for _, channel := range channels {
+		switch channel {
+		case MESSAGE_CHANNEL_EMAIL:
+			dc.Email = true
+		case MESSAGE_CHANNEL_INBOX:
+			dc.Inbox = true
+		case MESSAGE_CHANNEL_PUSH:
+			dc.Push = true
+		}
+	}
+	return dc
+}
@@ -0,0 +1,24 @@
+package types
+
+import "testing"
+
+func TestResolveDeliveryChannels(t *testing.T) {
+	tests := []struct {
+		name             string
+		messageChannels  []string
+		templateChannels []string
+		expected         DeliveryChannels
+	}{
+		{name: "default is email", expected: DeliveryChannels{Email: true}},
+		{name: "template channels", templateChannels: []string{"email", "push"}, expected: DeliveryChannels{Email: true, Push: true}},
+		{name: "message channels win", messageChannels: []string{"inbox"}, templateChannels: []string{"email", "push"}, expected: DeliveryChannels{Inbox: true}},
+		{name: "unknown channels ignored", messageChannels: []string{"sms", "push"}, expected: DeliveryChannels{Push: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveDeliveryChannels(tt.messageChannels, tt.templateChannels); got != tt.expected {
+				t.Errorf("unexpected channels: %+v", got)
+			}
+		})
+	}
+}
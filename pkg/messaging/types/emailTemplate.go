@@ -16,6 +16,7 @@ const (
 	EMAIL_TYPE_ACCOUNT_DELETED                  = "account-deleted"
 	EMAIL_TYPE_ACCOUNT_DELETED_AFTER_INACTIVITY = "account-deleted-after-inactivity"
 	EMAIL_TYPE_ACCOUNT_INACTIVITY               = "account-inactivity"
+	EMAIL_TYPE_LOGIN_LINK                       = "login-link"
 
 	EMAIL_TYPE_PHONE_NUMBER_CHANGED = "phone-number-changed"
 )
@@ -27,6 +28,8 @@ type EmailTemplate struct {
 	DefaultLanguage string              `bson:"defaultLanguage" json:"defaultLanguage"`
 	HeaderOverrides *HeaderOverrides    `bson:"headerOverrides" json:"headerOverrides"`
 	Translations    []LocalizedTemplate `bson:"translations" json:"translations"`
+	Channels        []string            `bson:"channels,omitempty" json:"channels,omitempty"`             // delivery channels for this message type, email if empty
+	AttachCalendar  bool                `bson:"attachCalendar,omitempty" json:"attachCalendar,omitempty"` // study templates only: attach the participant's schedule as .ics
 }
 
 type HeaderOverrides struct {
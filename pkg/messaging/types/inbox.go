@@ -0,0 +1,32 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	INBOX_ACTION_TYPE_SURVEY = "survey"
+	INBOX_ACTION_TYPE_LINK   = "link"
+)
+
+type InboxMessage struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string             `bson:"userID" json:"-"`
+	ProfileID   string             `bson:"profileID,omitempty" json:"profileId,omitempty"`
+	StudyKey    string             `bson:"studyKey,omitempty" json:"studyKey,omitempty"`
+	MessageType string             `bson:"messageType" json:"messageType"`
+	Language    string             `bson:"language" json:"language"`
+	Title       string             `bson:"title" json:"title"`
+	Body        string             `bson:"body" json:"body"`
+	Actions     []InboxAction      `bson:"actions,omitempty" json:"actions,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	ReadAt      *time.Time         `bson:"readAt,omitempty" json:"readAt,omitempty"`
+}
+
+type InboxAction struct {
+	Type      string `bson:"type" json:"type"`
+	SurveyKey string `bson:"surveyKey,omitempty" json:"surveyKey,omitempty"`
+	URL       string `bson:"url,omitempty" json:"url,omitempty"`
+}
@@ -19,4 +19,11 @@ type OutgoingEmail struct {
 	ExpiresAt       int64              `bson:"expiresAt" json:"expiresAt"`
 	HighPrio        bool               `bson:"highPrio" json:"highPrio"`
 	LastSendAttempt int64              `bson:"lastSendAttempt" json:"lastSendAttempt"`
+	Attachments     []EmailAttachment  `bson:"attachments,omitempty" json:"attachments,omitempty"`
+}
+
+type EmailAttachment struct {
+	Filename    string `bson:"filename" json:"filename"`
+	ContentType string `bson:"contentType" json:"contentType"`
+	Content     []byte `bson:"content" json:"content"`
 }
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PushSubscription is a Web Push subscription of one device or browser, as created by PushManager.subscribe()
+type PushSubscription struct {
+	ID         primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID     string               `bson:"userID" json:"-"`
+	Endpoint   string               `bson:"endpoint" json:"endpoint"`
+	Keys       PushSubscriptionKeys `bson:"keys" json:"keys"`
+	UserAgent  string               `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	CreatedAt  time.Time            `bson:"createdAt" json:"createdAt"`
+	LastUsedAt *time.Time           `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
+
+type PushSubscriptionKeys struct {
+	P256dh string `bson:"p256dh" json:"p256dh"`
+	Auth   string `bson:"auth" json:"auth"`
+}
+
+// PushNotificationPayload is the content of the encrypted push message, the service worker decides how to display it
+type PushNotificationPayload struct {
+	Title          string `json:"title"`
+	MessageType    string `json:"messageType"`
+	StudyKey       string `json:"studyKey,omitempty"`
+	Language       string `json:"language,omitempty"`
+	InboxMessageID string `json:"inboxMessageId,omitempty"`
+}
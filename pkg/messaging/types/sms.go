@@ -12,11 +12,13 @@ type SentSMS struct {
 	MessageType string             `bson:"messageType" json:"messageType"`
 	SentAt      time.Time          `bson:"sentAt" json:"sentAt"`
 	PhoneNumber string             `bson:"phoneNumber" json:"phoneNumber"`
+	StudyKey    string             `bson:"studyKey,omitempty" json:"studyKey,omitempty"`
 }
 
 type SMSTemplate struct {
 	ID              primitive.ObjectID  `bson:"_id" json:"id,omitempty"`
 	MessageType     string              `bson:"messageType" json:"messageType"`
+	StudyKey        string              `bson:"studyKey,omitempty" json:"studyKey,omitempty"` // empty for global templates
 	DefaultLanguage string              `bson:"defaultLanguage" json:"defaultLanguage"`
 	From            string              `bson:"from" json:"from"`
 	Translations    []LocalizedTemplate `bson:"translations" json:"translations"`
@@ -0,0 +1,125 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	recordSize = 4096
+	saltLength = 16
+	authLength = 16
+	tagLength  = 16
+
+	// header: salt, record size, key id length and the 65 byte sender public key
+	headerLength = saltLength + 4 + 1 + 65
+
+	// MaxPayloadSize is the largest payload fitting into a single record (one delimiter byte, no padding)
+	MaxPayloadSize = recordSize - tagLength - 1
+)
+
+var ErrPayloadTooLarge = errors.New("push payload too large")
+
+// encryptPayload encrypts the payload for the subscription as defined in RFC 8291 using the aes128gcm
+// content encoding of RFC 8188, with a new ephemeral sender key and salt for every message
+func encryptPayload(payload []byte, sub Subscription) ([]byte, error) {
+	uaPublic, authSecret, err := sub.decodeKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return encrypt(payload, uaPublic, authSecret, asPrivate, salt)
+}
+
+func encrypt(payload []byte, uaPublic *ecdh.PublicKey, authSecret []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	keyInfo := "WebPush: info\x00" + string(uaPublic.Bytes()) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, 0, headerLength+len(payload)+1+tagLength)
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(asPublic)))
+	body = append(body, asPublic...)
+
+	// single record, terminated by the last record delimiter
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+func (sub Subscription) decodeKeys() (*ecdh.PublicKey, []byte, error) {
+	rawPublic, err := decodeBase64(sub.P256dh)
+	if err != nil {
+		return nil, nil, errors.New("invalid p256dh key")
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(rawPublic)
+	if err != nil {
+		return nil, nil, errors.New("invalid p256dh key")
+	}
+
+	authSecret, err := decodeBase64(sub.Auth)
+	if err != nil || len(authSecret) != authLength {
+		return nil, nil, errors.New("invalid auth secret")
+	}
+	return uaPublic, authSecret, nil
+}
+
+// decodeBase64 accepts url-safe and standard encoding with or without padding, browsers and libraries differ here
+func decodeBase64(value string) ([]byte, error) {
+	for _, enc := range []*base64.Encoding{base64.RawURLEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.StdEncoding} {
+		if data, err := enc.DecodeString(value); err == nil {
+			return data, nil
+		}
+	}
+	return nil, errors.New("invalid base64 value")
+}
@@ -0,0 +1,97 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const vapidTokenLifetime = 12 * time.Hour
+
+// VAPIDConfig holds the application server keys (RFC 8292), both encoded as base64url
+type VAPIDConfig struct {
+	PublicKey  string `json:"public_key" yaml:"public_key"`   // uncompressed P-256 point, shared with the browser as applicationServerKey
+	PrivateKey string `json:"private_key" yaml:"private_key"` // P-256 private scalar
+	Subject    string `json:"subject" yaml:"subject"`         // contact of the operator, "mailto:" or "https:" URL
+}
+
+type vapidKeys struct {
+	privateKey *ecdsa.PrivateKey
+	publicKey  string
+	subject    string
+}
+
+func parseVAPIDConfig(config VAPIDConfig) (*vapidKeys, error) {
+	if config.Subject == "" {
+		return nil, errors.New("vapid subject is required")
+	}
+	rawPrivate, err := decodeBase64(config.PrivateKey)
+	if err != nil {
+		return nil, errors.New("invalid vapid private key")
+	}
+	privateKey, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), rawPrivate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	rawPublic, err := privateKey.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	publicKey := base64.RawURLEncoding.EncodeToString(rawPublic)
+	if config.PublicKey != "" {
+		configured, err := decodeBase64(config.PublicKey)
+		if err != nil || base64.RawURLEncoding.EncodeToString(configured) != publicKey {
+			return nil, errors.New("vapid public key does not match private key")
+		}
+	}
+
+	return &vapidKeys{
+		privateKey: privateKey,
+		publicKey:  publicKey,
+		subject:    config.Subject,
+	}, nil
+}
+
+// authorizationHeader returns the "vapid" authorization for the push service of the endpoint
+func (k *vapidKeys) authorizationHeader(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", errors.New("invalid endpoint")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenLifetime).Unix(),
+		"sub": k.subject,
+	})
+	signed, err := token.SignedString(k.privateKey)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + signed + ", k=" + k.publicKey, nil
+}
+
+// GenerateVAPIDKeys creates a new key pair, encoded as expected in VAPIDConfig
+func GenerateVAPIDKeys() (publicKey string, privateKey string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	rawPrivate, err := key.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+	rawPublic, err := key.PublicKey.Bytes()
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(rawPublic), base64.RawURLEncoding.EncodeToString(rawPrivate), nil
+}
@@ -0,0 +1,186 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	URGENCY_VERY_LOW = "very-low"
+	URGENCY_LOW      = "low"
+	URGENCY_NORMAL   = "normal"
+	URGENCY_HIGH     = "high"
+
+	DefaultTTL = 24 * time.Hour
+)
+
+var (
+	// ErrSubscriptionGone is returned when the push service reports the subscription as expired or unknown (404/410)
+	ErrSubscriptionGone = errors.New("push subscription is no longer valid")
+
+	// ErrNonPublicEndpoint is returned when an endpoint resolves to a loopback, private or link-local address
+	ErrNonPublicEndpoint = errors.New("push endpoint does not resolve to a public address")
+)
+
+type Config struct {
+	VAPID   VAPIDConfig   `json:"vapid" yaml:"vapid"`
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// endpoints are registered by participants, so only public addresses are dialed unless this is set for local push service stand-ins
+	AllowPrivateEndpoints bool `json:"allow_private_endpoints" yaml:"allow_private_endpoints"`
+}
+
+// Subscription as returned by PushManager.subscribe() in the browser
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+type Options struct {
+	TTL     time.Duration // how long the push service should keep the message if the device is offline
+	Urgency string
+	Topic   string // messages with the same topic replace each other while pending
+}
+
+// ValidateSubscription checks the endpoint and keys before a subscription is stored.
+// Endpoints must use https and a host name unless allowInsecure is set (for local push service stand-ins),
+// push services never hand out IP addresses or local host names.
+func ValidateSubscription(sub Subscription, allowInsecure bool) error {
+	u, err := url.Parse(sub.Endpoint)
+	if err != nil || u.Hostname() == "" {
+		return errors.New("invalid endpoint")
+	}
+	if u.Scheme != "https" && !(allowInsecure && u.Scheme == "http") {
+		return errors.New("endpoint must use https")
+	}
+	if !allowInsecure && !isPublicHostName(u.Hostname()) {
+		return errors.New("endpoint must use a public host name")
+	}
+	_, _, err = sub.decodeKeys()
+	return err
+}
+
+func isPublicHostName(host string) bool {
+	if net.ParseIP(host) != nil {
+		return false
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	return strings.Contains(host, ".") && host != "localhost" && !strings.HasSuffix(host, ".localhost") &&
+		!strings.HasSuffix(host, ".local") && !strings.HasSuffix(host, ".internal")
+}
+
+// isPublicIP rejects addresses of the local network, so participant-provided endpoints cannot reach internal services
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		// carrier-grade NAT range 100.64.0.0/10
+		if ip[0] == 100 && ip[1]&0xc0 == 64 {
+			return false
+		}
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// publicAddressOnly is used as dialer control, it checks the resolved address of every connection including redirects
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return ErrNonPublicEndpoint
+	}
+	return nil
+}
+
+type Client struct {
+	vapid      *vapidKeys
+	httpClient *http.Client
+}
+
+func NewClient(config Config) (*Client, error) {
+	keys, err := parseVAPIDConfig(config.VAPID)
+	if err != nil {
+		return nil, err
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !config.AllowPrivateEndpoints {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: publicAddressOnly}
+		transport.DialContext = dialer.DialContext
+	}
+	return &Client{
+		vapid:      keys,
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+// PublicKey returns the application server key the browser needs to create subscriptions
+func (c *Client) PublicKey() string {
+	return c.vapid.publicKey
+}
+
+// Send encrypts the payload for the subscription and delivers it to the push service.
+// Returns ErrSubscriptionGone if the subscription should be deleted.
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte, opts Options) error {
+	body, err := encryptPayload(payload, sub)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := c.vapid.authorizationHeader(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.FormatInt(int64(ttl.Seconds()), 10))
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("push service responded with status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+}
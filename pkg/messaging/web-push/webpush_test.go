@@ -0,0 +1,260 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// decrypt is the user agent side of RFC 8291
+func decrypt(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, authSecret []byte) []byte {
+	t.Helper()
+	if len(body) < headerLength {
+		t.Fatal("body too short")
+	}
+	salt := body[:saltLength]
+	if rs := binary.BigEndian.Uint32(body[saltLength : saltLength+4]); rs != recordSize {
+		t.Fatalf("unexpected record size %d", rs)
+	}
+	asPublic, err := ecdh.P256().NewPublicKey(body[saltLength+5 : headerLength])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecdhSecret, _ := uaPrivate.ECDH(asPublic)
+	keyInfo := "WebPush: info\x00" + string(uaPrivate.PublicKey().Bytes()) + string(asPublic.Bytes())
+	ikm, _ := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	prk, _ := hkdf.Extract(sha256.New, ikm, salt)
+	cek, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, body[headerLength:], nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatal("missing record delimiter")
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestEncryptRFC8291Example(t *testing.T) {
+	// example from RFC 8291, Appendix A
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(mustDecode(t, "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := encrypt(
+		[]byte("When I grow up, I want to be a watermelon"),
+		uaPublic,
+		mustDecode(t, "BTBZMqHH6r4Tts7J_aSIgg"),
+		asPrivate,
+		mustDecode(t, "DGv6ra1nlYgDCS1FRnbzlw"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+	if got := base64.RawURLEncoding.EncodeToString(body); got != expected {
+		t.Errorf("unexpected result:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestSend(t *testing.T) {
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the test push service listens on localhost
+	client, err := NewClient(Config{VAPID: VAPIDConfig{PublicKey: publicKey, PrivateKey: privateKey, Subject: "mailto:admin@example.com"}, AllowPrivateEndpoints: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uaPrivate, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, authLength)
+	rand.Read(authSecret)
+
+	var received []byte
+	pushService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "aes128gcm" || r.Header.Get("TTL") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// verify the vapid authorization with the key from the header
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "vapid ")
+		var tokenStr, keyStr string
+		for _, part := range strings.Split(auth, ",") {
+			part = strings.TrimSpace(part)
+			if v, ok := strings.CutPrefix(part, "t="); ok {
+				tokenStr = v
+			} else if v, ok := strings.CutPrefix(part, "k="); ok {
+				keyStr = v
+			}
+		}
+		rawKey, _ := base64.RawURLEncoding.DecodeString(keyStr)
+		vapidKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), rawKey)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		token, err := jwt.Parse(tokenStr, func(*jwt.Token) (any, error) { return vapidKey, nil }, jwt.WithValidMethods([]string{"ES256"}))
+		if err != nil || token.Claims.(jwt.MapClaims)["aud"] != "http://"+r.Host {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer pushService.Close()
+
+	sub := Subscription{
+		Endpoint: pushService.URL + "/push/abc",
+		P256dh:   base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(authSecret),
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		if err := client.Send(context.Background(), sub, []byte(`{"title":"Hello"}`), Options{Urgency: URGENCY_HIGH}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := string(decrypt(t, received, uaPrivate, authSecret)); got != `{"title":"Hello"}` {
+			t.Errorf("unexpected payload: %s", got)
+		}
+	})
+
+	t.Run("subscription gone", func(t *testing.T) {
+		goneSub := sub
+		goneSub.Endpoint = pushService.URL + "/gone"
+		if err := client.Send(context.Background(), goneSub, []byte("x"), Options{}); !errors.Is(err, ErrSubscriptionGone) {
+			t.Errorf("expected ErrSubscriptionGone, got %v", err)
+		}
+	})
+
+	t.Run("invalid keys", func(t *testing.T) {
+		badSub := sub
+		badSub.Auth = "short"
+		if err := client.Send(context.Background(), badSub, []byte("x"), Options{}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("payload too large", func(t *testing.T) {
+		if err := client.Send(context.Background(), sub, make([]byte, MaxPayloadSize+1), Options{}); !errors.Is(err, ErrPayloadTooLarge) {
+			t.Errorf("expected ErrPayloadTooLarge, got %v", err)
+		}
+	})
+}
+
+func TestValidateSubscription(t *testing.T) {
+	uaPrivate, _ := ecdh.P256().GenerateKey(rand.Reader)
+	sub := Subscription{
+		Endpoint: "https://push.example.com/send/abc",
+		P256dh:   base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes()),
+		Auth:     base64.URLEncoding.EncodeToString(make([]byte, authLength)), // padded encoding is accepted as well
+	}
+	if err := ValidateSubscription(sub, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	insecure := sub
+	insecure.Endpoint = "http://localhost:8080/push"
+	if err := ValidateSubscription(insecure, false); err == nil {
+		t.Error("expected error for http endpoint")
+	}
+	if err := ValidateSubscription(insecure, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, endpoint := range []string{"https://127.0.0.1/push", "https://[::1]/push", "https://10.0.0.5:8443/push", "https://localhost/push", "https://metadata.internal/push", "https://intranet/push"} {
+		internal := sub
+		internal.Endpoint = endpoint
+		if err := ValidateSubscription(internal, false); err == nil {
+			t.Errorf("expected error for endpoint %s", endpoint)
+		}
+	}
+
+	invalidKey := sub
+	invalidKey.P256dh = base64.RawURLEncoding.EncodeToString([]byte("not a key"))
+	if err := ValidateSubscription(invalidKey, false); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(Config{VAPID: VAPIDConfig{PublicKey: publicKey, PrivateKey: privateKey, Subject: "mailto:admin@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	called := false
+	internalService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer internalService.Close()
+
+	uaPrivate, _ := ecdh.P256().GenerateKey(rand.Reader)
+	sub := Subscription{
+		Endpoint: internalService.URL + "/push",
+		P256dh:   base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes()),
+		Auth:     base64.RawURLEncoding.EncodeToString(make([]byte, authLength)),
+	}
+	if err := client.Send(context.Background(), sub, []byte("x"), Options{}); !errors.Is(err, ErrNonPublicEndpoint) {
+		t.Errorf("expected ErrNonPublicEndpoint, got %v", err)
+	}
+	if called {
+		t.Error("request reached the local service")
+	}
+}
+
+func TestVAPIDKeyMismatch(t *testing.T) {
+	publicKey, _, _ := GenerateVAPIDKeys()
+	_, privateKey, _ := GenerateVAPIDKeys()
+	if _, err := NewClient(Config{VAPID: VAPIDConfig{PublicKey: publicKey, PrivateKey: privateKey, Subject: "mailto:admin@example.com"}}); err == nil {
+		t.Error("expected error for mismatching keys")
+	}
+}
@@ -0,0 +1,120 @@
+package participantevents
+
+import (
+	"sync"
+	"sync/atomic"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	EVENT_TYPE_SURVEYS = "surveys" // participant state changed, assigned surveys may be different
+	EVENT_TYPE_REPORT  = "report"
+	EVENT_TYPE_INBOX   = "inbox"
+	EVENT_TYPE_RESYNC  = "resync" // events may have been lost, clients should reload everything
+)
+
+// Event is a change relevant for a participant user, published to all subscriptions of the hub
+type Event struct {
+	Type       string
+	InstanceID string
+	StudyKey   string
+
+	// surveys and report events
+	ParticipantID   string
+	AssignedSurveys []studyTypes.AssignedSurvey
+
+	// report events
+	ReportKey string
+	ReportID  string
+
+	// inbox events
+	UserID         string
+	InboxMessageID string
+	Title          string
+}
+
+// Filter selects the events a subscription receives, it is called while publishing and must be cheap
+type Filter func(Event) bool
+
+// Hub distributes events to subscriptions in the same process. Each API instance runs its own hub fed by its own
+// change streams, so no coordination between replicas is needed.
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	hub        *Hub
+	filter     Filter
+	events     chan Event
+	overflowed atomic.Bool
+	closeOnce  sync.Once
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subs: map[*Subscription]struct{}{},
+	}
+}
+
+// Subscribe registers a new subscription, buffer is the number of events kept for slow consumers
+func (h *Hub) Subscribe(filter Filter, buffer int) *Subscription {
+	if buffer < 1 {
+		buffer = 1
+	}
+	sub := &Subscription{
+		hub:    h,
+		filter: filter,
+		events: make(chan Event, buffer),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// Publish hands the event to all matching subscriptions without blocking.
+// If a subscription's buffer is full the event is dropped and the subscription is marked as overflowed.
+func (h *Hub) Publish(event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.overflowed.Store(true)
+		}
+	}
+}
+
+// SubscriberCount returns the number of open subscriptions
+func (h *Hub) SubscriberCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Overflowed reports (and resets) whether events were dropped since the last call
+func (s *Subscription) Overflowed() bool {
+	return s.overflowed.Swap(false)
+}
+
+// Close removes the subscription from the hub and closes the event channel
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		s.hub.mu.Lock()
+		defer s.hub.mu.Unlock()
+		delete(s.hub.subs, s)
+		close(s.events)
+	})
+}
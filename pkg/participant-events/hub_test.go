@@ -0,0 +1,56 @@
+package participantevents
+
+import (
+	"testing"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+	subA := hub.Subscribe(func(e Event) bool { return e.UserID == "a" }, 10)
+	subAll := hub.Subscribe(nil, 10)
+
+	hub.Publish(Event{Type: EVENT_TYPE_INBOX, UserID: "a"})
+	hub.Publish(Event{Type: EVENT_TYPE_INBOX, UserID: "b"})
+
+	if len(subA.Events()) != 1 {
+		t.Errorf("expected 1 event for filtered subscription, got %d", len(subA.Events()))
+	}
+	if len(subAll.Events()) != 2 {
+		t.Errorf("expected 2 events for unfiltered subscription, got %d", len(subAll.Events()))
+	}
+	if e := <-subA.Events(); e.UserID != "a" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	subA.Close()
+	subA.Close() // closing twice is fine
+	if hub.SubscriberCount() != 1 {
+		t.Errorf("expected 1 subscriber, got %d", hub.SubscriberCount())
+	}
+	if _, ok := <-subA.Events(); ok {
+		t.Error("expected closed channel")
+	}
+
+	// publishing after close must not panic
+	hub.Publish(Event{Type: EVENT_TYPE_INBOX, UserID: "a"})
+}
+
+func TestHubOverflow(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(nil, 2)
+	defer sub.Close()
+
+	for range 5 {
+		hub.Publish(Event{Type: EVENT_TYPE_SURVEYS})
+	}
+
+	if len(sub.Events()) != 2 {
+		t.Errorf("expected buffered events only, got %d", len(sub.Events()))
+	}
+	if !sub.Overflowed() {
+		t.Error("expected overflow")
+	}
+	if sub.Overflowed() {
+		t.Error("overflow flag should be reset after reading")
+	}
+}
@@ -0,0 +1,97 @@
+package participantevents
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+)
+
+const (
+	watcherMinBackoff = time.Second
+	watcherMaxBackoff = time.Minute
+)
+
+// RunChangeStreamWatchers follows participant states, reports and inbox messages of the instances and publishes
+// the changes to the hub. Failed change streams are restarted with a backoff and a resync event is published,
+// since changes may have been missed in between. Returns immediately, the watchers stop when the context is cancelled.
+func RunChangeStreamWatchers(
+	ctx context.Context,
+	hub *Hub,
+	instanceIDs []string,
+	studyDBService *studyDB.StudyDBService,
+	messagingDBService *messagingDB.MessagingDBService,
+) {
+	for _, instanceID := range instanceIDs {
+		go watchWithRetry(ctx, hub, instanceID, "study", func(ctx context.Context) error {
+			return studyDBService.WatchParticipantChanges(ctx, instanceID, func(change studyDB.ParticipantChange) {
+				event := Event{
+					InstanceID:    instanceID,
+					StudyKey:      change.StudyKey,
+					ParticipantID: change.ParticipantID,
+				}
+				switch change.Collection {
+				case studyDB.COLLECTION_NAME_SUFFIX_PARTICIPANTS:
+					event.Type = EVENT_TYPE_SURVEYS
+					event.AssignedSurveys = change.AssignedSurveys
+				case studyDB.COLLECTION_NAME_SUFFIX_REPORTS:
+					event.Type = EVENT_TYPE_REPORT
+					event.ReportKey = change.ReportKey
+					event.ReportID = change.ReportID
+				default:
+					return
+				}
+				hub.Publish(event)
+			})
+		})
+
+		go watchWithRetry(ctx, hub, instanceID, "inbox", func(ctx context.Context) error {
+			return messagingDBService.WatchInboxMessages(ctx, instanceID, func(msg messagingTypes.InboxMessage) {
+				hub.Publish(Event{
+					Type:           EVENT_TYPE_INBOX,
+					InstanceID:     instanceID,
+					StudyKey:       msg.StudyKey,
+					UserID:         msg.UserID,
+					InboxMessageID: msg.ID.Hex(),
+					Title:          msg.Title,
+				})
+			})
+		})
+	}
+}
+
+func watchWithRetry(ctx context.Context, hub *Hub, instanceID string, name string, watch func(ctx context.Context) error) {
+	backoff := watcherMinBackoff
+	for {
+		started := time.Now()
+		err := watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// a stream that ran for a while is not failing repeatedly
+		if time.Since(started) > watcherMaxBackoff {
+			backoff = watcherMinBackoff
+		}
+		errMsg := "change stream closed"
+		if err != nil {
+			errMsg = err.Error()
+		}
+		slog.Error("Change stream failed, restarting", slog.String("instanceID", instanceID), slog.String("stream", name), slog.String("error", errMsg), slog.Duration("retryIn", backoff))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, watcherMaxBackoff)
+
+		hub.Publish(Event{
+			Type:       EVENT_TYPE_RESYNC,
+			InstanceID: instanceID,
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package smtp_client
 
 import (
+	"bytes"
 	"errors"
 	"log/slog"
 	"net/textproto"
@@ -16,6 +17,7 @@ func (sc *SmtpClients) SendMail(
 	subject string,
 	htmlContent string,
 	overrides *messagingTypes.HeaderOverrides,
+	attachments []messagingTypes.EmailAttachment,
 ) error {
 	n := atomic.AddUint64(&sc.counter, 1)
 	if len(sc.connectionPool) < 1 {
@@ -56,6 +58,11 @@ func (sc *SmtpClients) SendMail(
 		HTML:    []byte(htmlContent),
 		Headers: textproto.MIMEHeader{},
 	}
+	for _, a := range attachments {
+		if _, err := e.Attach(bytes.NewReader(a.Content), a.Filename, a.ContentType); err != nil {
+			return err
+		}
+	}
 
 	start := time.Now()
 	err := selectedServer.Send(e)
@@ -0,0 +1,123 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func localised(code string, text string) studyTypes.LocalisedObject {
+	return studyTypes.LocalisedObject{
+		Code:  code,
+		Parts: []studyTypes.ExpressionArg{{DType: "str", Str: text}},
+	}
+}
+
+func TestEncode(t *testing.T) {
+	start := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	cal := Calendar{
+		Name:            "Study, schedule",
+		RefreshInterval: 90 * time.Minute,
+		Stamp:           start,
+		Events: []Event{
+			{
+				UID:         "abc@case-backend",
+				Summary:     "Weekly; survey",
+				Description: "Line 1\nLine 2",
+				Start:       start,
+				Reminder:    true,
+			},
+			{
+				UID:     "long@case-backend",
+				Summary: strings.Repeat("ä", 60),
+				Start:   start,
+				End:     start.Add(24 * time.Hour),
+			},
+		},
+	}
+	ics := string(cal.Encode())
+
+	for _, expected := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Study\\, schedule\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT1H30M\r\n",
+		"DTSTART:20250301T090000Z\r\nDTEND:20250301T100000Z\r\n",
+		"SUMMARY:Weekly\\; survey\r\n",
+		"DESCRIPTION:Line 1\\nLine 2\r\n",
+		"BEGIN:VALARM\r\nACTION:DISPLAY\r\n",
+		"DTEND:20250302T090000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, expected) {
+			t.Errorf("missing %q in:\n%s", expected, ics)
+		}
+	}
+	if strings.Count(ics, "BEGIN:VALARM") != 1 {
+		t.Error("expected one alarm")
+	}
+
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line too long (%d): %q", len(line), line)
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("folding split a character: %q", line)
+		}
+	}
+}
+
+func TestParticipantEvents(t *testing.T) {
+	study := studyTypes.Study{
+		Key: "study1",
+		Props: studyTypes.StudyProps{
+			Name: []studyTypes.LocalisedObject{localised("en", "Flu study"), localised("de", "Grippestudie")},
+		},
+	}
+	pState := studyTypes.Participant{
+		AssignedSurveys: []studyTypes.AssignedSurvey{
+			{SurveyKey: "intake"}, // no window
+			{SurveyKey: "weekly", ValidFrom: 1735722000, ValidUntil: 1735808400},
+			{SurveyKey: "unknown", ValidUntil: 1735808400},
+		},
+		Visits: []studyTypes.ParticipantVisit{
+			{Key: "baseline", Start: 1735894800, Location: "Room 2"},
+		},
+	}
+	lookup := func(surveyKey string) (studyTypes.SurveyProps, error) {
+		if surveyKey != "weekly" {
+			return studyTypes.SurveyProps{}, errors.New("not found")
+		}
+		return studyTypes.SurveyProps{
+			Name:            []studyTypes.LocalisedObject{localised("en", "Weekly"), localised("de", "Wöchentlich")},
+			TypicalDuration: []studyTypes.LocalisedObject{localised("en", "5 minutes"), localised("de", "5 Minuten")},
+		}, nil
+	}
+
+	events := ParticipantEvents(study, pState, "profile1", "Kid", "de", lookup)
+	if len(events) != 3 {
+		t.Fatalf("unexpected number of events: %d", len(events))
+	}
+	if events[0].Summary != "Wöchentlich (Kid)" || events[0].Description != "5 Minuten" {
+		t.Errorf("unexpected survey event: %+v", events[0])
+	}
+	if events[1].Summary != "unknown (Kid)" || !events[1].End.Equal(time.Unix(1735808400, 0)) || !events[1].Start.Before(events[1].End) {
+		t.Errorf("unexpected deadline event: %+v", events[1])
+	}
+	if events[2].Summary != "Grippestudie (Kid)" || events[2].Location != "Room 2" {
+		t.Errorf("unexpected visit event: %+v", events[2])
+	}
+
+	// UIDs are stable and differ between profiles
+	again := ParticipantEvents(study, pState, "profile1", "", "en", lookup)
+	if again[0].UID != events[0].UID || again[0].Summary != "Weekly" {
+		t.Errorf("unexpected event: %+v", again[0])
+	}
+	other := ParticipantEvents(study, pState, "profile2", "", "en", lookup)
+	if other[0].UID == events[0].UID {
+		t.Error("expected different UIDs for different profiles")
+	}
+}
@@ -0,0 +1,145 @@
+package calendar
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	CONTENT_TYPE   = "text/calendar; charset=utf-8"
+	FILE_EXTENSION = ".ics"
+
+	productID       = "-//case-framework//case-backend//EN"
+	maxLineLength   = 75 // octets, without the line break
+	defaultDuration = time.Hour
+)
+
+// Event is a VEVENT of the calendar
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	End         time.Time // Start + 1h if not set
+	Reminder    bool      // adds a display alarm at the start
+}
+
+// Calendar is an RFC 5545 iCalendar object with published events
+type Calendar struct {
+	Name            string
+	RefreshInterval time.Duration // hint for subscribed feeds, not written if zero
+	Events          []Event
+	Stamp           time.Time // DTSTAMP of the events, now if zero
+}
+
+// Encode writes the calendar in iCalendar format (CRLF line breaks, folded lines)
+func (c Calendar) Encode() []byte {
+	stamp := c.Stamp
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+
+	w := &icsWriter{}
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", productID)
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		w.line("NAME", escapeText(c.Name))
+		w.line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		w.line("REFRESH-INTERVAL;VALUE=DURATION", formatDuration(c.RefreshInterval))
+		w.line("X-PUBLISHED-TTL", formatDuration(c.RefreshInterval))
+	}
+
+	for _, e := range c.Events {
+		end := e.End
+		if !end.After(e.Start) {
+			end = e.Start.Add(defaultDuration)
+		}
+
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", e.UID)
+		w.line("DTSTAMP", formatTime(stamp))
+		w.line("DTSTART", formatTime(e.Start))
+		w.line("DTEND", formatTime(end))
+		w.line("SUMMARY", escapeText(e.Summary))
+		if e.Description != "" {
+			w.line("DESCRIPTION", escapeText(e.Description))
+		}
+		if e.Location != "" {
+			w.line("LOCATION", escapeText(e.Location))
+		}
+		if e.Reminder {
+			w.line("BEGIN", "VALARM")
+			w.line("ACTION", "DISPLAY")
+			w.line("DESCRIPTION", escapeText(e.Summary))
+			w.line("TRIGGER", "PT0S")
+			w.line("END", "VALARM")
+		}
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+	return w.buf.Bytes()
+}
+
+type icsWriter struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folded after 75 octets without splitting UTF-8 characters
+func (w *icsWriter) line(name string, value string) {
+	content := name + ":" + value
+	lineLength := 0
+	for len(content) > 0 {
+		_, size := utf8.DecodeRuneInString(content)
+		if lineLength+size > maxLineLength {
+			w.buf.WriteString("\r\n ")
+			lineLength = 1
+		}
+		w.buf.WriteString(content[:size])
+		lineLength += size
+		content = content[size:]
+	}
+	w.buf.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", "",
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// formatDuration returns the RFC 5545 duration in minutes precision, e.g. PT1H30M
+func formatDuration(d time.Duration) string {
+	minutes := int64(d.Round(time.Minute) / time.Minute)
+	hours := minutes / 60
+	minutes = minutes % 60
+
+	s := "PT"
+	if hours > 0 {
+		s += strconv.FormatInt(hours, 10) + "H"
+	}
+	if minutes > 0 || hours == 0 {
+		s += strconv.FormatInt(minutes, 10) + "M"
+	}
+	return s
+}
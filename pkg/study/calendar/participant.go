@@ -0,0 +1,170 @@
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// ATTACHMENT_FILENAME is used when the schedule is attached to emails
+const ATTACHMENT_FILENAME = "study-schedule" + FILE_EXTENSION
+
+// SurveyPropsLookup returns the display properties of the current version of a survey
+type SurveyPropsLookup func(surveyKey string) (studyTypes.SurveyProps, error)
+
+// SurveyPropsFromDB looks up the props of the current survey versions in the study DB
+func SurveyPropsFromDB(dbService *studyDB.StudyDBService, instanceID string, studyKey string) SurveyPropsLookup {
+	return func(surveyKey string) (studyTypes.SurveyProps, error) {
+		survey, err := dbService.GetCurrentSurveyVersion(instanceID, studyKey, surveyKey)
+		if err != nil {
+			return studyTypes.SurveyProps{}, err
+		}
+		return survey.Props, nil
+	}
+}
+
+// ParticipantEvents lists the assigned surveys with a time window and the scheduled visits of the participant.
+// Names are localised for lang (falling back to the first translation), profileAlias is added to the summaries if set.
+func ParticipantEvents(
+	study studyTypes.Study,
+	pState studyTypes.Participant,
+	profileID string,
+	profileAlias string,
+	lang string,
+	lookup SurveyPropsLookup,
+) []Event {
+	events := []Event{}
+
+	propsCache := map[string]*studyTypes.SurveyProps{}
+	for _, survey := range pState.AssignedSurveys {
+		if survey.ValidFrom <= 0 && survey.ValidUntil <= 0 {
+			// always available, nothing to schedule
+			continue
+		}
+
+		props, ok := propsCache[survey.SurveyKey]
+		if !ok {
+			props = nil
+			if lookup != nil {
+				if p, err := lookup(survey.SurveyKey); err == nil {
+					props = &p
+				}
+			}
+			propsCache[survey.SurveyKey] = props
+		}
+
+		summary := survey.SurveyKey
+		description := ""
+		if props != nil {
+			if name := LocalisedText(props.Name, lang); name != "" {
+				summary = name
+			}
+			description = joinNonEmpty("\n",
+				LocalisedText(props.Description, lang),
+				LocalisedText(props.TypicalDuration, lang),
+			)
+		}
+
+		// without a start the survey is open now, the deadline is what matters
+		start := survey.ValidFrom
+		end := survey.ValidUntil
+		if start <= 0 {
+			start = end - int64(defaultDuration.Seconds())
+		}
+		if end < start {
+			end = 0
+		}
+
+		events = append(events, Event{
+			UID:         eventUID(study.Key, profileID, "survey", survey.SurveyKey, start),
+			Summary:     withAlias(summary, profileAlias),
+			Description: description,
+			Start:       time.Unix(start, 0),
+			End:         unixOrZero(end),
+			Reminder:    true,
+		})
+	}
+
+	studyName := LocalisedText(study.Props.Name, lang)
+	for _, visit := range pState.Visits {
+		summary := visit.Label
+		if summary == "" {
+			summary = studyName
+		}
+		if summary == "" {
+			summary = visit.Key
+		}
+
+		events = append(events, Event{
+			UID:         eventUID(study.Key, profileID, "visit", visit.Key, 0),
+			Summary:     withAlias(summary, profileAlias),
+			Description: studyName,
+			Location:    visit.Location,
+			Start:       time.Unix(visit.Start, 0),
+			End:         unixOrZero(visit.End),
+			Reminder:    true,
+		})
+	}
+	return events
+}
+
+// LocalisedText returns the text for lang, or the first translation if lang is missing. Expressions are left out.
+func LocalisedText(objs []studyTypes.LocalisedObject, lang string) string {
+	if len(objs) == 0 {
+		return ""
+	}
+	selected := objs[0]
+	for _, obj := range objs {
+		if obj.Code == lang {
+			selected = obj
+			break
+		}
+	}
+
+	text := ""
+	for _, part := range selected.Parts {
+		if part.DType == "" || part.DType == "str" {
+			text += part.Str
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+// eventUID is stable for the same item, so calendar clients update events instead of duplicating them.
+// Visits keep their UID when rescheduled.
+func eventUID(studyKey string, profileID string, kind string, key string, start int64) string {
+	h := sha256.New()
+	h.Write([]byte(strings.Join([]string{studyKey, profileID, kind, key}, "|")))
+	if start > 0 {
+		h.Write([]byte(time.Unix(start, 0).UTC().Format(time.RFC3339)))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16]) + "@case-backend"
+}
+
+func withAlias(summary string, alias string) string {
+	if alias == "" {
+		return summary
+	}
+	return summary + " (" + alias + ")"
+}
+
+func unixOrZero(ts int64) time.Time {
+	if ts <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	nonEmpty := []string{}
+	for _, v := range values {
+		if v != "" {
+			nonEmpty = append(nonEmpty, v)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
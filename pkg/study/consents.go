@@ -0,0 +1,175 @@
+package study
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ConsentStatus struct {
+	Document studyTypes.ConsentDocument `json:"document"`
+	// most recent acceptance of the profile, nil if never accepted
+	LatestRecord *studyTypes.ConsentRecord `json:"latestRecord,omitempty"`
+	// accepted version is still valid for the current document
+	IsValid bool `json:"isValid"`
+	// an earlier version was accepted, but the current version requires consenting again
+	NeedsReconsent bool `json:"needsReconsent"`
+}
+
+// GetConsentStatusForProfile returns the current consent documents of the study with the consent state of the profile
+func GetConsentStatusForProfile(instanceID string, studyKey string, profileID string) ([]ConsentStatus, error) {
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		slog.Error("error getting study", slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	participantID, _, err := ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	docs, err := studyDBService.GetLatestConsentDocuments(instanceID, studyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	statusList := make([]ConsentStatus, 0, len(docs))
+	for _, doc := range docs {
+		status := ConsentStatus{Document: doc}
+
+		record, err := studyDBService.GetLatestConsentRecord(instanceID, studyKey, participantID, doc.ConsentKey)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if err == nil {
+			record.ParticipantID = ""
+			status.LatestRecord = &record
+			status.IsValid = record.IsValidFor(doc)
+			status.NeedsReconsent = record.Status == studyTypes.CONSENT_RECORD_STATUS_ACCEPTED && !status.IsValid
+		}
+		statusList = append(statusList, status)
+	}
+	return statusList, nil
+}
+
+// OnAcceptConsent records that the profile accepted the current version of the consent in the given language.
+// If contentHash is set, it must match the hash of the published text the participant has seen.
+func OnAcceptConsent(instanceID string, studyKey string, profileID string, consentKey string, version int, language string, contentHash string) (record studyTypes.ConsentRecord, err error) {
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		slog.Error("error getting study", slog.String("error", err.Error()))
+		return
+	}
+
+	participantID, _, err := ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		return
+	}
+
+	doc, err := studyDBService.GetLatestConsentDocument(instanceID, studyKey, consentKey)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			err = studyTypes.ErrConsentDocumentNotFound
+		}
+		return
+	}
+	if doc.Version != version {
+		err = studyTypes.ErrConsentVersionOutdated
+		return
+	}
+
+	content, ok := doc.GetContent(language)
+	if !ok {
+		err = studyTypes.ErrConsentLanguageNotFound
+		return
+	}
+	if contentHash != "" && contentHash != content.Hash {
+		err = studyTypes.ErrConsentContentMismatch
+		return
+	}
+
+	record, err = studyDBService.AddConsentRecord(instanceID, studyKey, studyTypes.ConsentRecord{
+		ParticipantID: participantID,
+		ConsentKey:    consentKey,
+		Version:       doc.Version,
+		Language:      language,
+		ContentHash:   content.Hash,
+		Status:        studyTypes.CONSENT_RECORD_STATUS_ACCEPTED,
+		AcceptedAt:    time.Now(),
+	})
+	if err != nil {
+		slog.Error("error saving consent record", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("consentKey", consentKey), slog.String("error", err.Error()))
+		return
+	}
+
+	slog.Info("consent accepted", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("consentKey", consentKey), slog.Int("version", doc.Version))
+	record.ParticipantID = ""
+	return
+}
+
+// OnWithdrawConsent marks the accepted consent of the profile as withdrawn and fires the withdrawal event of the consent for the participant
+func OnWithdrawConsent(instanceID string, studyKey string, profileID string, consentKey string) (result []studyTypes.AssignedSurvey, err error) {
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		slog.Error("error getting study", slog.String("error", err.Error()))
+		return
+	}
+
+	participantID, confidentialID, err := ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		return
+	}
+
+	record, err := studyDBService.GetLatestConsentRecord(instanceID, studyKey, participantID, consentKey)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			err = studyTypes.ErrNoActiveConsentToWithdraw
+		}
+		return
+	}
+	if record.Status != studyTypes.CONSENT_RECORD_STATUS_ACCEPTED {
+		err = studyTypes.ErrNoActiveConsentToWithdraw
+		return
+	}
+
+	doc, err := studyDBService.GetLatestConsentDocument(instanceID, studyKey, consentKey)
+	if err != nil {
+		slog.Error("error getting consent document", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("consentKey", consentKey), slog.String("error", err.Error()))
+		return
+	}
+
+	err = studyDBService.WithdrawConsentRecord(instanceID, studyKey, record.ID, time.Now())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			err = studyTypes.ErrNoActiveConsentToWithdraw
+		}
+		return
+	}
+	slog.Info("consent withdrawn", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("consentKey", consentKey), slog.Int("version", record.Version))
+
+	if _, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID); err != nil {
+		// consent was given before entering the study, no rules to run
+		return []studyTypes.AssignedSurvey{}, nil
+	}
+
+	return onCustomStudyEventHandler(
+		instanceID,
+		study,
+		participantID,
+		confidentialID,
+		doc.GetWithdrawalEventKey(),
+		map[string]any{
+			"consentKey": consentKey,
+			"version":    float64(record.Version),
+		},
+		// the withdrawal is already stored, a full quota must not prevent its rules from running
+		false,
+	)
+}
@@ -0,0 +1,30 @@
+package study
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// DeleteDataExportsForUser removes the tasks of all data exports created by the user and their archives
+// from the participant filestore. If participantFilestorePath is empty, only the tasks are removed and the
+// archives are left to the periodic clean up of the participant API.
+func DeleteDataExportsForUser(instanceID string, userID string, participantFilestorePath string) {
+	tasks, err := studyDBService.GetTasksCreatedBy(instanceID, userID, studyTypes.TASK_FILE_TYPE_ZIP)
+	if err != nil {
+		slog.Error("failed to get data export tasks", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("error", err.Error()))
+		return
+	}
+	for _, task := range tasks {
+		if task.ResultFile != "" && participantFilestorePath != "" {
+			if err := os.Remove(filepath.Join(participantFilestorePath, task.ResultFile)); err != nil && !os.IsNotExist(err) {
+				slog.Error("failed to remove data export", slog.String("instanceID", instanceID), slog.String("taskID", task.ID.Hex()), slog.String("error", err.Error()))
+			}
+		}
+		if err := studyDBService.DeleteTaskByID(instanceID, task.ID.Hex()); err != nil {
+			slog.Error("failed to delete data export task", slog.String("instanceID", instanceID), slog.String("taskID", task.ID.Hex()), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -17,9 +17,15 @@ func SurveyDefToVersionPreview(
 		Published:   original.Published,
 		Unpublished: original.Unpublished,
 		Questions:   []SurveyQuestion{},
+		Definition:  &original.SurveyDefinition,
 	}
 
 	sp.Questions = extractQuestions(&original.SurveyDefinition, options)
+	if original.Scoring != nil {
+		for _, score := range original.Scoring.Scores {
+			sp.ScoreKeys = append(sp.ScoreKeys, score.Key)
+		}
+	}
 	return sp
 }
 
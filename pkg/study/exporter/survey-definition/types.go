@@ -1,5 +1,7 @@
 package surveydefinition
 
+import studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+
 type ExtractOptions struct {
 	IncludeItems []string
 	ExcludeItems []string
@@ -11,6 +13,10 @@ type SurveyVersionPreview struct {
 	Published   int64            `json:"published"`
 	Unpublished int64            `json:"unpublished"`
 	Questions   []SurveyQuestion `json:"questions"`
+	ScoreKeys   []string         `json:"scoreKeys,omitempty"`
+
+	// original item tree, used to evaluate item conditions of responses
+	Definition *studyTypes.SurveyItem `json:"-"`
 }
 
 type SurveyQuestion struct {
@@ -46,6 +46,7 @@ func (re *ResponseExporter) init() error {
 		record = append(record, re.parser.columns.FixedColumns...)
 		record = append(record, re.parser.columns.ContextColumns...)
 		record = append(record, re.parser.columns.ResponseColumns...)
+		record = append(record, re.parser.columns.ScoreColumns...)
 		record = append(record, re.parser.columns.MetaColumns...)
 		err = re.csvWriter.Write(record)
 		if err != nil {
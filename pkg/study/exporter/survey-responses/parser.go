@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	studydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	"github.com/case-framework/case-backend/pkg/study/surveyengine"
 	studytypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
@@ -13,11 +14,13 @@ var defaultCtxColNames = []string{
 	"language",
 	"engineVersion",
 	"session",
+	studytypes.RESPONSE_CONTEXT_KEY_ARM,
 }
 
 const (
 	accountIDColumn   = "accountID"
 	mainProfileColumn = "mainProfile"
+	scoreColumnPrefix = "score"
 )
 
 type ResponseParser struct {
@@ -28,6 +31,8 @@ type ResponseParser struct {
 	includeMeta       *IncludeMeta
 	questionOptionSep string
 	accountTracking   bool
+	notShownMarker    string
+	scoreProvider     ScoreProvider
 }
 
 func NewResponseParser(
@@ -106,6 +111,46 @@ func (rp *ResponseParser) EnableAccountTracking() {
 	rp.columns.FixedColumns = append(rp.columns.FixedColumns, accountIDColumn, mainProfileColumn)
 }
 
+// EnableScoreColumns adds a column for every score defined in the survey versions, filled with the values from the provider
+func (rp *ResponseParser) EnableScoreColumns(provider ScoreProvider) {
+	if rp.scoreProvider != nil || provider == nil {
+		return
+	}
+	rp.scoreProvider = provider
+
+	scoreCols := []string{}
+	for _, sv := range rp.surveyVersions {
+		for _, key := range sv.ScoreKeys {
+			colName := scoreColumnPrefix + rp.questionOptionSep + key
+			if !slices.Contains(scoreCols, colName) {
+				scoreCols = append(scoreCols, colName)
+			}
+		}
+	}
+	slices.Sort(scoreCols)
+	rp.columns.ScoreColumns = scoreCols
+}
+
+// EnableNotShownMarker fills the response columns of questions that were hidden by their conditions
+// with the marker, so they can be told apart from questions the participant skipped (empty columns).
+func (rp *ResponseParser) EnableNotShownMarker(marker string) {
+	rp.notShownMarker = marker
+}
+
+// findHiddenItems evaluates the item conditions of the survey version for the response, using the full item keys
+func (rp *ResponseParser) findHiddenItems(rawResp *studytypes.SurveyResponse, version studydefinition.SurveyVersionPreview) map[string]bool {
+	if rp.notShownMarker == "" || version.Definition == nil {
+		return nil
+	}
+	hidden := surveyengine.FindHiddenItems(*version.Definition, surveyengine.NewResponseOnlyEvalContext(*rawResp))
+	for _, r := range rawResp.Responses {
+		if r.Hidden {
+			hidden[r.Key] = true
+		}
+	}
+	return hidden
+}
+
 func (rp *ResponseParser) ParseResponse(
 	rawResp *studytypes.SurveyResponse,
 	trackingInfo ...AccountTrackingInfo,
@@ -119,6 +164,7 @@ func (rp *ResponseParser) ParseResponse(
 		ArrivedAt:     rawResp.ArrivedAt,
 		Context:       rawResp.Context,
 		Responses:     map[string]interface{}{},
+		Scores:        map[string]interface{}{},
 		Meta: ResponseMeta{
 			Initialised: map[string][]int64{},
 			Displayed:   map[string][]int64{},
@@ -131,6 +177,12 @@ func (rp *ResponseParser) ParseResponse(
 		parsedResponse.MainProfile = trackingInfo[0].MainProfile
 	}
 
+	if rp.scoreProvider != nil {
+		for key, value := range rp.scoreProvider(parsedResponse.ID) {
+			parsedResponse.Scores[scoreColumnPrefix+rp.questionOptionSep+key] = value
+		}
+	}
+
 	currentVersion, err := findSurveyVersion(rawResp.VersionID, rawResp.ArrivedAt, rp.surveyVersions)
 	if err != nil {
 		return parsedResponse, err
@@ -143,6 +195,8 @@ func (rp *ResponseParser) ParseResponse(
 		}
 	}
 
+	hiddenItems := rp.findHiddenItems(rawResp, currentVersion)
+
 	if rp.removeRootKey {
 		for i, r := range rawResp.Responses {
 			rawResp.Responses[i].Key = strings.TrimPrefix(r.Key, rp.surveyKey+".")
@@ -152,7 +206,15 @@ func (rp *ResponseParser) ParseResponse(
 	for _, question := range currentVersion.Questions {
 		resp := findResponse(rawResp.Responses, question.ID)
 
-		responseColumns := getResponseColumns(question, resp, rp.questionOptionSep)
+		var responseColumns map[string]interface{}
+		if hiddenItems[question.ID] || (rp.removeRootKey && hiddenItems[rp.surveyKey+"."+question.ID]) {
+			responseColumns = map[string]interface{}{}
+			for _, colName := range getResponseColNamesForQuestion(question, rp.questionOptionSep) {
+				responseColumns[colName] = rp.notShownMarker
+			}
+		} else {
+			responseColumns = getResponseColumns(question, resp, rp.questionOptionSep)
+		}
 		for k, v := range responseColumns {
 			_, hasKey := parsedResponse.Responses[k]
 			if hasKey {
@@ -219,6 +281,11 @@ func (rp *ResponseParser) ResponseToStrList(
 		out = append(out, str)
 	}
 
+	// add score columns
+	for _, colName := range rp.columns.ScoreColumns {
+		out = append(out, valueToStr(result[colName]))
+	}
+
 	// add meta columns
 	for _, colName := range rp.columns.MetaColumns {
 		out = append(out, valueToStr(result[colName]))
@@ -256,6 +323,14 @@ func (rp *ResponseParser) ResponseToLongFormat(
 		out = append(out, currentRespLine)
 	}
 
+	for _, colName := range rp.columns.ScoreColumns {
+		currentRespLine := []string{}
+		currentRespLine = append(currentRespLine, fixedValues...)
+		currentRespLine = append(currentRespLine, colName)
+		currentRespLine = append(currentRespLine, valueToStr(result[colName]))
+		out = append(out, currentRespLine)
+	}
+
 	for _, colName := range rp.columns.MetaColumns {
 		currentRespLine := []string{}
 		currentRespLine = append(currentRespLine, fixedValues...)
@@ -274,6 +349,7 @@ func (rp *ResponseParser) ResponseToFlatObj(
 	result := rp.initWithFixedColumnsWithValues(&parsedResponse)
 	result = rp.addContextColumnsWithValues(&parsedResponse, result)
 	result = rp.addResponseItemColumnsWithValues(&parsedResponse, result)
+	result = rp.addScoreColumnsWithValues(&parsedResponse, result)
 	result = rp.addMetaColumnsWithValues(&parsedResponse, result)
 
 	return result, nil
@@ -331,6 +407,21 @@ func (rp ResponseParser) addResponseItemColumnsWithValues(
 	return res
 }
 
+func (rp ResponseParser) addScoreColumnsWithValues(
+	parsedResponse *ParsedResponse,
+	res map[string]interface{},
+) map[string]interface{} {
+	for _, colName := range rp.columns.ScoreColumns {
+		v, ok := parsedResponse.Scores[colName]
+		if !ok {
+			res[colName] = ""
+		} else {
+			res[colName] = v
+		}
+	}
+	return res
+}
+
 func (rp ResponseParser) addMetaColumnsWithValues(
 	parsedResponse *ParsedResponse,
 	res map[string]interface{},
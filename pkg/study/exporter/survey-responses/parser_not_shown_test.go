@@ -0,0 +1,87 @@
+package surveyresponses
+
+import (
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func numberQuestion(key string, condition *studytypes.Expression) studytypes.SurveyItem {
+	return studytypes.SurveyItem{
+		Key:       key,
+		Condition: condition,
+		Components: &studytypes.ItemComponent{Role: "root", Items: []studytypes.ItemComponent{
+			{Role: "responseGroup", Key: "rg", Items: []studytypes.ItemComponent{
+				{Role: "numberInput", Key: "num", Dtype: "number"},
+			}},
+		}},
+	}
+}
+
+func TestParseResponseNotShownMarker(t *testing.T) {
+	q1Answered := studytypes.Expression{Name: "hasResponse", Data: []studytypes.ExpressionArg{
+		{DType: "str", Str: "s.q1"},
+		{DType: "str", Str: "rg"},
+	}}
+	survey := &studytypes.Survey{
+		VersionID: "v1",
+		Published: 1,
+		SurveyDefinition: studytypes.SurveyItem{
+			Key: "s",
+			Items: []studytypes.SurveyItem{
+				numberQuestion("s.q1", nil),
+				numberQuestion("s.q2", &q1Answered),
+				numberQuestion("s.q3", nil),
+			},
+		},
+	}
+
+	parser, err := NewResponseParser(
+		"s",
+		[]sd.SurveyVersionPreview{sd.SurveyDefToVersionPreview(survey, nil)},
+		true,
+		nil,
+		"-",
+		nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.EnableNotShownMarker("NOT_SHOWN")
+
+	response := &studytypes.SurveyResponse{
+		ID:          primitive.NewObjectID(),
+		Key:         "s",
+		VersionID:   "v1",
+		SubmittedAt: 100,
+		ArrivedAt:   100,
+		Responses:   []studytypes.SurveyItemResponse{},
+	}
+
+	parsed, err := parser.ParseResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Responses["q2"] != "NOT_SHOWN" {
+		t.Errorf("expected hidden question to be marked: %v", parsed.Responses)
+	}
+	for _, col := range []string{"q1", "q3"} {
+		if v, ok := parsed.Responses[col]; ok && v == "NOT_SHOWN" {
+			t.Errorf("skipped question must not be marked: %s", col)
+		}
+	}
+
+	response.Responses = []studytypes.SurveyItemResponse{
+		{Key: "s.q1", Response: &studytypes.ResponseItem{Key: "rg", Items: []*studytypes.ResponseItem{{Key: "num", Value: "4", Dtype: "number"}}}},
+	}
+	parsed, err = parser.ParseResponse(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := parsed.Responses["q2"]; ok && v == "NOT_SHOWN" {
+		t.Errorf("displayed question must not be marked: %v", parsed.Responses)
+	}
+}
@@ -0,0 +1,72 @@
+package surveyresponses
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	sd "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	studytypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResponseExporterScoreColumns(t *testing.T) {
+	survey := &studytypes.Survey{
+		VersionID: "v1",
+		Published: 1,
+		SurveyDefinition: studytypes.SurveyItem{
+			Key:   "s",
+			Items: []studytypes.SurveyItem{numberQuestion("s.q1", nil)},
+		},
+		Scoring: &studytypes.SurveyScoring{Scores: []studytypes.ScoreDefinition{
+			{Key: "total", Items: []studytypes.ScoreItem{{ItemKey: "s.q1", ResponseKey: "rg.num"}}},
+		}},
+	}
+	parser, err := NewResponseParser("s", []sd.SurveyVersionPreview{sd.SurveyDefToVersionPreview(survey, nil)}, true, nil, "-", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scored := primitive.NewObjectID()
+	parser.EnableScoreColumns(func(responseID string) map[string]float64 {
+		if responseID == scored.Hex() {
+			return map[string]float64{"total": 7}
+		}
+		return nil
+	})
+
+	var output bytes.Buffer
+	exporter, err := NewResponseExporter(parser, &output, "wide")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []primitive.ObjectID{scored, primitive.NewObjectID()} {
+		if err := exporter.WriteResponse(&studytypes.SurveyResponse{ID: id, Key: "s", VersionID: "v1", ArrivedAt: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := exporter.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(output.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	col := -1
+	for i, h := range rows[0] {
+		if h == "score-total" {
+			col = i
+		}
+	}
+	if col < 0 {
+		t.Fatalf("score column missing from header: %v", rows[0])
+	}
+	if rows[1][col] != "7.000000" {
+		t.Errorf("unexpected score value: %q", rows[1][col])
+	}
+	if rows[2][col] != "" {
+		t.Errorf("expected empty score for unscored response: %q", rows[2][col])
+	}
+}
@@ -13,6 +13,7 @@ type ParsedResponse struct {
 	MainProfile   *bool
 	Context       map[string]string // e.g. Language, or engine version
 	Responses     map[string]interface{}
+	Scores        map[string]interface{}
 	Meta          ResponseMeta
 }
 
@@ -38,6 +39,9 @@ type ResponseMeta struct {
 	Position    map[string]int32
 }
 
+// ScoreProvider returns the scores stored for a response by score key
+type ScoreProvider func(responseID string) map[string]float64
+
 type IncludeMeta struct {
 	Postion        bool
 	InitTimes      bool
@@ -49,5 +53,6 @@ type ColumnNames struct {
 	FixedColumns    []string
 	ContextColumns  []string
 	ResponseColumns []string
+	ScoreColumns    []string
 	MetaColumns     []string
 }
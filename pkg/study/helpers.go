@@ -8,6 +8,18 @@ import (
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
 	"github.com/case-framework/case-backend/pkg/study/types"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	MAX_SUBMISSION_ID_LENGTH = 128
+)
+
+var (
+	ErrInvalidSubmissionID = errors.New("invalid submission ID")
+
+	// returned by onSubmitResponseHandler together with the result of the first submission
+	errDuplicateSubmission = errors.New("duplicate submission")
 )
 
 /* func checkIfParticipantExists(instanceID string, studyKey string, participantID string, withStatus string) bool {
@@ -45,6 +57,7 @@ func getAndPerformStudyRules(instanceID, studyKey string, pState studyTypes.Part
 	for _, rule := range rulesObj.Rules {
 		newState, err = studyengine.ActionEval(rule, newState, currentEvent)
 		if err != nil {
+			finaliseStudyCodeReservations(instanceID, studyKey, newState, currentEvent, err)
 			return
 		}
 	}
@@ -52,6 +65,24 @@ func getAndPerformStudyRules(instanceID, studyKey string, pState studyTypes.Part
 	return newState, nil
 }
 
+// finaliseStudyCodeReservations confirms the study codes reserved while handling the event if the participant
+// state could be saved, otherwise the reservations are released so the codes can be used by others
+func finaliseStudyCodeReservations(instanceID string, studyKey string, actionResult studyengine.ActionData, event studyengine.StudyEvent, saveErr error) {
+	participantID := actionResult.PState.ParticipantID
+	for _, reserved := range actionResult.ReservedStudyCodes {
+		if saveErr != nil {
+			if err := studyDBService.ReleaseStudyCodeReservation(instanceID, studyKey, reserved.ListKey, reserved.Code, participantID); err != nil {
+				slog.Error("Error releasing study code reservation", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("listKey", reserved.ListKey), slog.String("error", err.Error()))
+			}
+			continue
+		}
+
+		if err := studyDBService.ConfirmStudyCodeReservation(instanceID, studyKey, reserved.ListKey, reserved.Code, studyengine.StudyCodeClaimForEvent(actionResult.PState, event)); err != nil {
+			slog.Error("Error confirming study code reservation", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("listKey", reserved.ListKey), slog.String("error", err.Error()))
+		}
+	}
+}
+
 func saveResponses(instanceID string, studyKey string, response studyTypes.SurveyResponse, pState studyTypes.Participant, confidentialID string) (string, error) {
 	nonConfidentialResponses := []studyTypes.SurveyItemResponse{}
 	confidentialResponses := []studyTypes.SurveyItemResponse{}
@@ -71,11 +102,15 @@ func saveResponses(instanceID string, studyKey string, response studyTypes.Surve
 		response.Context = map[string]string{}
 	}
 	response.Context["session"] = pState.CurrentStudySession
+	if armKey := pState.CurrentArmKey(); armKey != "" {
+		response.Context[studyTypes.RESPONSE_CONTEXT_KEY_ARM] = armKey
+	}
 
 	var rID string
 	var err error
 	if len(nonConfidentialResponses) > 0 || len(confidentialResponses) < 1 {
-		// Save responses only if non empty or there were no confidential responses
+		// Save responses only if non empty or there were no confidential responses.
+		// Duplicates are detected through the submission claim, so responses with only confidential items are not stored for the submission ID.
 		rID, err = studyDBService.AddSurveyResponse(instanceID, studyKey, response)
 		if err != nil {
 			return "", err
@@ -113,6 +148,80 @@ func saveResponses(instanceID string, studyKey string, response studyTypes.Surve
 	return rID, nil
 }
 
+// claimSubmission atomically reserves the client-generated submission ID for the participant before any study rules run.
+// Returns false if the ID was claimed by another request or a response with the ID was already stored.
+func claimSubmission(instanceID string, studyKey string, participantID string, submissionID string) (bool, error) {
+	if submissionID == "" {
+		return true, nil
+	}
+	if len(submissionID) > MAX_SUBMISSION_ID_LENGTH {
+		return false, ErrInvalidSubmissionID
+	}
+
+	claimed, err := studyDBService.ClaimSubmission(instanceID, studyKey, participantID, submissionID)
+	if err != nil {
+		slog.Error("Error claiming submission ID", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+		return false, err
+	}
+	if claimed {
+		// responses stored before the claim expired or before claims existed
+		_, err = studyDBService.GetResponseBySubmissionID(instanceID, studyKey, participantID, submissionID)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return true, nil
+			}
+			slog.Error("Error checking submission ID", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+			releaseSubmissionClaim(instanceID, studyKey, participantID, submissionID)
+			return false, err
+		}
+	}
+
+	slog.Info("duplicate submission, response was already processed", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("submissionID", submissionID))
+	return false, nil
+}
+
+// finishSubmissionClaim stores the result for duplicates of a processed submission, or releases the claim if processing failed so the client can retry
+func finishSubmissionClaim(instanceID string, studyKey string, participantID string, submissionID string, result []studyTypes.AssignedSurvey, processingErr error) {
+	if submissionID == "" {
+		return
+	}
+	if processingErr != nil {
+		releaseSubmissionClaim(instanceID, studyKey, participantID, submissionID)
+		return
+	}
+	if err := studyDBService.CompleteSubmissionClaim(instanceID, studyKey, participantID, submissionID, result); err != nil {
+		slog.Error("Error completing submission claim", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+	}
+}
+
+func releaseSubmissionClaim(instanceID string, studyKey string, participantID string, submissionID string) {
+	if err := studyDBService.ReleaseSubmissionClaim(instanceID, studyKey, participantID, submissionID); err != nil {
+		slog.Error("Error releasing submission claim", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+	}
+}
+
+// duplicateSubmissionResult returns the assigned surveys stored by the request that processed the submission,
+// or the current ones of the participant while it is still in progress
+func duplicateSubmissionResult(instanceID string, studyKey string, pState studyTypes.Participant, submissionID string) []studyTypes.AssignedSurvey {
+	claim, err := studyDBService.GetSubmissionClaim(instanceID, studyKey, pState.ParticipantID, submissionID)
+	if err == nil && claim.Completed {
+		return claim.AssignedSurveys
+	}
+	if current, err := studyDBService.GetParticipantByID(instanceID, studyKey, pState.ParticipantID); err == nil {
+		pState = current
+	}
+	return assignedSurveysForSubmitResult(studyKey, pState)
+}
+
+func assignedSurveysForSubmitResult(studyKey string, pState studyTypes.Participant) []studyTypes.AssignedSurvey {
+	result := make([]studyTypes.AssignedSurvey, len(pState.AssignedSurveys))
+	for i, survey := range pState.AssignedSurveys {
+		result[i] = survey
+		result[i].StudyKey = studyKey
+	}
+	return result
+}
+
 func saveReports(instanceID string, studyKey string, reports []studyTypes.Report, withResponseID string) {
 	// save reports
 	for _, report := range reports {
@@ -16,6 +16,7 @@ type AssignedSurveyWithContext struct {
 	Survey  *studyTypes.Survey         `json:"survey"`
 	Context *SurveyContext             `json:"context,omitempty" `
 	Prefill *studyTypes.SurveyResponse `json:"prefill,omitempty"`
+	Draft   *studyTypes.SurveyResponse `json:"draft,omitempty"`
 }
 
 type StudyVariableValue struct {
@@ -177,7 +178,7 @@ func GetAssignedSurveysForTempParticipant(instanceID string, studyKey string, pa
 	return
 }
 
-func GetAssignedSurveyWithContext(instanceID string, studyKey string, surveyKey string, profileID string) (surveyWithContent AssignedSurveyWithContext, err error) {
+func GetAssignedSurveyWithContext(instanceID string, studyKey string, surveyKey string, profileID string, includeDraft bool) (surveyWithContent AssignedSurveyWithContext, err error) {
 	study, err := getStudyIfActive(instanceID, studyKey)
 	if err != nil {
 		slog.Error("error getting study", slog.String("error", err.Error()))
@@ -243,6 +244,15 @@ func GetAssignedSurveyWithContext(instanceID string, studyKey string, surveyKey
 		Context: surveyContext,
 		Prefill: prefill,
 	}
+
+	if includeDraft {
+		draft, err := getCurrentSurveyResponseDraft(instanceID, studyKey, participantID, surveyKey, surveyDef.VersionID)
+		if err != nil {
+			slog.Error("error getting survey response draft", slog.String("error", err.Error()))
+		} else if draft != nil {
+			surveyWithContent.Draft = &draft.Response
+		}
+	}
 	return
 }
 
@@ -0,0 +1,160 @@
+package study
+
+import (
+	"errors"
+	"log/slog"
+	"maps"
+
+	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// claimRefusingStudyQuotas counts the participant towards the quotas that refuse participants when full, before the
+// study rules run, so a refused event leaves no side effects (counters, study variables, ID mappings).
+// The conditions of these quotas are evaluated against the participant state before the event.
+// Returns the keys of the quotas claimed by this call, or ErrStudyQuotaFull after releasing them if a quota is full.
+func claimRefusingStudyQuotas(instanceID string, study studyTypes.Study, pState studyTypes.Participant, event studyengine.StudyEvent) (claimed []string, err error) {
+	participantID := pState.ParticipantID
+
+	for _, quota := range study.Quotas {
+		if quota.OnFull != studyTypes.STUDY_QUOTA_ON_FULL_REFUSE {
+			continue
+		}
+
+		entry, err := studyDBService.GetStudyQuotaEntry(instanceID, study.Key, quota.Key, participantID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			releaseStudyQuotas(instanceID, study.Key, participantID, claimed)
+			return nil, err
+		}
+		if err == nil && entry.Status == studyTypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED {
+			continue
+		}
+
+		if !participantMatchesStudyQuota(quota, pState, event) {
+			continue
+		}
+
+		ok, err := studyDBService.ClaimStudyQuotaSlot(instanceID, study.Key, quota.Key, participantID, quota.Limit)
+		if errors.Is(err, studyTypes.ErrStudyQuotaSlotAlreadyHeld) {
+			// counted by a concurrent event, which also releases the slot if it fails
+			continue
+		}
+		if err != nil {
+			releaseStudyQuotas(instanceID, study.Key, participantID, claimed)
+			return nil, err
+		}
+		if !ok {
+			slog.Info("Study quota is full", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("quotaKey", quota.Key), slog.String("onFull", quota.OnFull))
+			releaseStudyQuotas(instanceID, study.Key, participantID, claimed)
+			return nil, studyTypes.ErrStudyQuotaFull
+		}
+		claimed = append(claimed, quota.Key)
+	}
+	return claimed, nil
+}
+
+// applyStudyQuotas evaluates the study's quotas against the participant state produced by the study rules.
+// Matching participants are counted atomically; if a matching quota is full, its onFull behaviour is applied.
+// Quotas that refuse participants were already checked by claimRefusingStudyQuotas, their slots are passed as
+// claimedBeforeRules. Returns the keys of all quotas claimed for the event, so they can be released if the participant
+// state cannot be saved. On error all of them are released.
+func applyStudyQuotas(instanceID string, study studyTypes.Study, actionResult *studyengine.ActionData, event studyengine.StudyEvent, claimedBeforeRules []string) (claimed []string, err error) {
+	participantID := actionResult.PState.ParticipantID
+	claimed = claimedBeforeRules
+
+	for _, quota := range study.Quotas {
+		entry, err := studyDBService.GetStudyQuotaEntry(instanceID, study.Key, quota.Key, participantID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			releaseStudyQuotas(instanceID, study.Key, participantID, claimed)
+			return nil, err
+		}
+		if err == nil && entry.Status == studyTypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED {
+			setStudyQuotaFlag(actionResult, quota, studyTypes.STUDY_QUOTA_FLAG_VALUE_COUNTED)
+			continue
+		}
+
+		if quota.OnFull == studyTypes.STUDY_QUOTA_ON_FULL_REFUSE {
+			// decided before the study rules ran
+			continue
+		}
+
+		if !participantMatchesStudyQuota(quota, actionResult.PState, event) {
+			continue
+		}
+
+		ok, err := studyDBService.ClaimStudyQuotaSlot(instanceID, study.Key, quota.Key, participantID, quota.Limit)
+		if errors.Is(err, studyTypes.ErrStudyQuotaSlotAlreadyHeld) {
+			// counted by a concurrent event, which also releases the slot if it fails
+			setStudyQuotaFlag(actionResult, quota, studyTypes.STUDY_QUOTA_FLAG_VALUE_COUNTED)
+			continue
+		}
+		if err != nil {
+			releaseStudyQuotas(instanceID, study.Key, participantID, claimed)
+			return nil, err
+		}
+		if ok {
+			claimed = append(claimed, quota.Key)
+			setStudyQuotaFlag(actionResult, quota, studyTypes.STUDY_QUOTA_FLAG_VALUE_COUNTED)
+			continue
+		}
+
+		slog.Info("Study quota is full", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("quotaKey", quota.Key), slog.String("onFull", quota.OnFull))
+		switch quota.OnFull {
+		case studyTypes.STUDY_QUOTA_ON_FULL_FLAG:
+			setStudyQuotaFlag(actionResult, quota, studyTypes.STUDY_QUOTA_FLAG_VALUE_FULL)
+		case studyTypes.STUDY_QUOTA_ON_FULL_WAITLIST:
+			if err := studyDBService.AddParticipantToStudyQuotaWaitlist(instanceID, study.Key, quota.Key, participantID); err != nil {
+				releaseStudyQuotas(instanceID, study.Key, participantID, claimed)
+				return nil, err
+			}
+			setStudyQuotaFlag(actionResult, quota, studyTypes.STUDY_QUOTA_FLAG_VALUE_WAITLISTED)
+		}
+	}
+	return claimed, nil
+}
+
+func participantMatchesStudyQuota(quota studyTypes.StudyQuota, pState studyTypes.Participant, event studyengine.StudyEvent) bool {
+	if quota.Condition == nil {
+		return true
+	}
+
+	result, err := studyengine.ExpressionEval(*quota.Condition, studyengine.EvalContext{
+		Event:            event,
+		ParticipantState: pState,
+	})
+	if err != nil {
+		slog.Error("Error evaluating study quota condition", slog.String("instanceID", event.InstanceID), slog.String("studyKey", event.StudyKey), slog.String("quotaKey", quota.Key), slog.String("error", err.Error()))
+		return false
+	}
+	matches, ok := result.(bool)
+	return ok && matches
+}
+
+func setStudyQuotaFlag(actionResult *studyengine.ActionData, quota studyTypes.StudyQuota, value string) {
+	flags := make(map[string]string, len(actionResult.PState.Flags)+1)
+	maps.Copy(flags, actionResult.PState.Flags)
+	flags[quota.FlagKey()] = value
+	actionResult.PState.Flags = flags
+}
+
+// releaseStudyQuotas frees quota slots claimed for a participant whose state could not be saved
+func releaseStudyQuotas(instanceID string, studyKey string, participantID string, quotaKeys []string) {
+	for _, quotaKey := range quotaKeys {
+		if err := studyDBService.ReleaseStudyQuotaSlot(instanceID, studyKey, quotaKey, participantID); err != nil {
+			slog.Error("Error releasing study quota slot", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("quotaKey", quotaKey), slog.String("error", err.Error()))
+		}
+	}
+}
+
+// releaseStudyQuotasForParticipant frees all quota slots and waitlist places of a participant who left the study or was removed
+func releaseStudyQuotasForParticipant(instanceID string, studyKey string, participantID string) {
+	count, err := studyDBService.ReleaseStudyQuotaEntriesForParticipant(instanceID, studyKey, participantID)
+	if err != nil {
+		slog.Error("Error releasing study quota entries of participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+		return
+	}
+	if count > 0 {
+		slog.Debug("released study quota entries of participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.Int64("count", count))
+	}
+}
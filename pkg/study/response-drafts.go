@@ -0,0 +1,172 @@
+package study
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	SURVEY_RESPONSE_DRAFT_TTL = 30 * 24 * time.Hour // drafts not updated within this period are removed
+)
+
+var ErrSurveyResponseDraftOutdated = errors.New("draft was created for a different survey version")
+
+// SaveSurveyResponseDraft stores the partial response of the profile for the current version of the survey
+func SaveSurveyResponseDraft(instanceID string, studyKey string, profileID string, response studyTypes.SurveyResponse) (draft studyTypes.SurveyResponseDraft, err error) {
+	participantID, surveyDef, err := getParticipantAndSurveyForDraft(instanceID, studyKey, profileID, response.Key)
+	if err != nil {
+		return
+	}
+
+	if response.VersionID != "" && response.VersionID != surveyDef.VersionID {
+		err = ErrSurveyResponseDraftOutdated
+		return
+	}
+	response.VersionID = surveyDef.VersionID
+	response.Responses = withoutConfidentialItems(response.Responses, confidentialItemKeys(surveyDef.SurveyDefinition))
+	response.ParticipantID = ""
+	response.SubmittedAt = 0
+	response.ArrivedAt = 0
+
+	draft, err = studyDBService.SaveSurveyResponseDraft(instanceID, studyKey, studyTypes.SurveyResponseDraft{
+		ParticipantID: participantID,
+		SurveyKey:     response.Key,
+		VersionID:     surveyDef.VersionID,
+		Response:      response,
+	}, SURVEY_RESPONSE_DRAFT_TTL)
+	if err != nil {
+		slog.Error("error saving survey response draft", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", response.Key), slog.String("error", err.Error()))
+		return
+	}
+	draft.ParticipantID = ""
+	return
+}
+
+// GetSurveyResponseDraft returns the draft of the profile for the survey, or nil if there is no draft for the current survey version
+func GetSurveyResponseDraft(instanceID string, studyKey string, profileID string, surveyKey string) (*studyTypes.SurveyResponseDraft, error) {
+	participantID, surveyDef, err := getParticipantAndSurveyForDraft(instanceID, studyKey, profileID, surveyKey)
+	if err != nil {
+		return nil, err
+	}
+	return getCurrentSurveyResponseDraft(instanceID, studyKey, participantID, surveyKey, surveyDef.VersionID)
+}
+
+// DiscardSurveyResponseDraft removes the draft of the profile for the survey
+func DiscardSurveyResponseDraft(instanceID string, studyKey string, profileID string, surveyKey string) error {
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		slog.Error("error getting study", slog.String("error", err.Error()))
+		return err
+	}
+
+	participantID, _, err := ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		return err
+	}
+
+	return studyDBService.DeleteSurveyResponseDraft(instanceID, studyKey, participantID, surveyKey)
+}
+
+// withoutConfidentialItems removes answers to confidential items. Drafts are stored under the participant ID,
+// so they must not contain answers that are only stored under the confidential ID once submitted.
+func withoutConfidentialItems(items []studyTypes.SurveyItemResponse, confidentialKeys map[string]bool) []studyTypes.SurveyItemResponse {
+	result := make([]studyTypes.SurveyItemResponse, 0, len(items))
+	for _, item := range items {
+		if item.ConfidentialMode != "" || confidentialKeys[item.Key] {
+			continue
+		}
+		if len(item.Items) > 0 {
+			item.Items = withoutConfidentialItems(item.Items, confidentialKeys)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// confidentialItemKeys collects the keys of all items of the survey definition with a confidential mode
+func confidentialItemKeys(root studyTypes.SurveyItem) map[string]bool {
+	keys := map[string]bool{}
+	var collect func(item studyTypes.SurveyItem)
+	collect = func(item studyTypes.SurveyItem) {
+		if item.ConfidentialMode != "" {
+			keys[item.Key] = true
+		}
+		for _, child := range item.Items {
+			collect(child)
+		}
+	}
+	collect(root)
+	return keys
+}
+
+func getParticipantAndSurveyForDraft(instanceID string, studyKey string, profileID string, surveyKey string) (participantID string, surveyDef *studyTypes.Survey, err error) {
+	if surveyKey == "" {
+		err = errors.New("survey key is required")
+		return
+	}
+
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		slog.Error("error getting study", slog.String("error", err.Error()))
+		return
+	}
+
+	surveyDef, err = studyDBService.GetCurrentSurveyVersion(instanceID, studyKey, surveyKey)
+	if err != nil {
+		slog.Error("error getting survey", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+		return
+	}
+
+	participantID, _, err = ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		return
+	}
+
+	pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+	if err != nil {
+		if surveyDef.AvailableFor != studyTypes.SURVEY_AVAILABLE_FOR_PUBLIC {
+			slog.Error("participant not found", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID))
+			return
+		}
+		err = nil
+	} else if surveyDef.AvailableFor == studyTypes.SURVEY_AVAILABLE_FOR_PARTICIPANTS_IF_ASSIGNED && !isSurveyAssignedAndActive(pState, surveyKey) {
+		slog.Error("survey is not assigned or inactive", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("surveyKey", surveyKey))
+		err = errors.New("survey is not assigned or inactive")
+		return
+	}
+	return
+}
+
+// getCurrentSurveyResponseDraft returns the participant's draft if it matches the current survey version, outdated drafts are removed
+func getCurrentSurveyResponseDraft(instanceID string, studyKey string, participantID string, surveyKey string, versionID string) (*studyTypes.SurveyResponseDraft, error) {
+	draft, err := studyDBService.GetSurveyResponseDraft(instanceID, studyKey, participantID, surveyKey)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		slog.Error("error getting survey response draft", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		return nil, err
+	}
+
+	if draft.VersionID != versionID {
+		if err := studyDBService.DeleteSurveyResponseDraft(instanceID, studyKey, participantID, surveyKey); err != nil {
+			slog.Error("error deleting outdated survey response draft", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		}
+		return nil, nil
+	}
+	draft.ParticipantID = ""
+	return &draft, nil
+}
+
+// removeSurveyResponseDraft is called after the response has been submitted
+func removeSurveyResponseDraft(instanceID string, studyKey string, participantID string, surveyKey string) {
+	if err := studyDBService.DeleteSurveyResponseDraft(instanceID, studyKey, participantID, surveyKey); err != nil {
+		slog.Error("error deleting survey response draft", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("surveyKey", surveyKey), slog.String("error", err.Error()))
+	}
+}
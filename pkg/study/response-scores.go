@@ -0,0 +1,78 @@
+package study
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/study/surveyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// computeResponseScores evaluates the scoring of the survey version for the response, nil if the survey has no scores
+func computeResponseScores(surveyDef *studyTypes.Survey, response studyTypes.SurveyResponse) []studyTypes.ScoreValue {
+	if surveyDef == nil || surveyDef.Scoring == nil {
+		return nil
+	}
+	return surveyengine.ComputeScores(surveyDef.Scoring, response)
+}
+
+// scoresToMap returns the computed scores by key, for the evaluation of study rules
+func scoresToMap(scores []studyTypes.ScoreValue) map[string]float64 {
+	if scores == nil {
+		return nil
+	}
+	return studyTypes.SurveyScoreRecord{Scores: scores}.Map()
+}
+
+// saveResponseScores stores the score record linked to the saved response and returns the score report, if the survey defines one.
+// The report gets the time of the submit event, like reports created by the study rules.
+func saveResponseScores(
+	instanceID string,
+	studyKey string,
+	surveyDef *studyTypes.Survey,
+	response studyTypes.SurveyResponse,
+	participantID string,
+	responseID string,
+	scores []studyTypes.ScoreValue,
+	eventTime time.Time,
+) []studyTypes.Report {
+	if len(scores) == 0 || responseID == "" {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	record := studyTypes.SurveyScoreRecord{
+		ResponseID:    responseID,
+		ParticipantID: participantID,
+		SurveyKey:     response.Key,
+		VersionID:     surveyDef.VersionID,
+		SubmittedAt:   response.SubmittedAt,
+		ComputedAt:    now,
+		Scores:        scores,
+	}
+	if err := studyDBService.SaveSurveyScoreRecord(instanceID, studyKey, record); err != nil {
+		slog.Error("Error saving survey scores", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("responseID", responseID), slog.String("error", err.Error()))
+	}
+
+	if surveyDef.Scoring.ReportKey == "" {
+		return nil
+	}
+	report := studyTypes.Report{
+		Key:           surveyDef.Scoring.ReportKey,
+		ParticipantID: participantID,
+		Timestamp:     eventTime.Unix(),
+		Data:          []studyTypes.ReportData{},
+	}
+	for _, s := range scores {
+		if s.Value == nil {
+			continue
+		}
+		report.Data = append(report.Data, studyTypes.ReportData{
+			Key:   s.Key,
+			Value: fmt.Sprintf("%f", *s.Value),
+			Dtype: "float",
+		})
+	}
+	return []studyTypes.Report{report}
+}
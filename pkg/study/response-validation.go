@@ -0,0 +1,84 @@
+package study
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/study/surveyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// prepareSubmittedResponse checks the response against its survey version, if the survey is in strict mode,
+// and handles answers to items that were hidden given the final responses.
+// Returns the survey version of the response (nil if unknown), or surveyengine.ValidationErrors if the response must be rejected.
+func prepareSubmittedResponse(instanceID string, studyKey string, pState studyTypes.Participant, response studyTypes.SurveyResponse, eventTime int64) (studyTypes.SurveyResponse, *studyTypes.Survey, error) {
+	surveyDef, err := getSurveyVersionForResponse(instanceID, studyKey, response)
+	if err != nil {
+		return response, nil, err
+	}
+	if surveyDef == nil {
+		// survey not found, nothing to check against (e.g. surveys submitted by management)
+		return response, nil, nil
+	}
+	if !surveyDef.StrictResponseValidation && surveyDef.HiddenResponses == studyTypes.SURVEY_HIDDEN_RESPONSES_KEEP {
+		return response, surveyDef, nil
+	}
+
+	now := time.Now()
+	if eventTime > 0 {
+		now = time.Unix(eventTime, 0)
+	}
+	evalCtx := surveyengine.NewEvalContext(
+		response,
+		pState.Flags,
+		pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_TEMPORARY,
+		now,
+	)
+
+	if surveyDef.StrictResponseValidation {
+		if vErrs := surveyengine.ValidateResponse(surveyDef, response, evalCtx); len(vErrs) > 0 {
+			slog.Warn("response rejected by strict validation", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", pState.ParticipantID), slog.String("surveyKey", response.Key), slog.Int("errorCount", len(vErrs)))
+			return response, surveyDef, vErrs
+		}
+	}
+
+	switch surveyDef.HiddenResponses {
+	case studyTypes.SURVEY_HIDDEN_RESPONSES_FLAG, studyTypes.SURVEY_HIDDEN_RESPONSES_PRUNE:
+		hidden := surveyengine.FindHiddenResponses(surveyDef.SurveyDefinition, response, evalCtx)
+		if len(hidden) == 0 {
+			break
+		}
+		slog.Debug("response contains hidden answers", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", response.Key), slog.Int("count", len(hidden)), slog.String("mode", surveyDef.HiddenResponses))
+		if surveyDef.HiddenResponses == studyTypes.SURVEY_HIDDEN_RESPONSES_PRUNE {
+			surveyengine.PruneHiddenResponses(&response, hidden)
+		} else {
+			surveyengine.FlagHiddenResponses(&response, hidden)
+		}
+	default:
+		if surveyDef.HiddenResponses != studyTypes.SURVEY_HIDDEN_RESPONSES_KEEP {
+			slog.Warn("unknown hidden responses mode", slog.String("surveyKey", response.Key), slog.String("mode", surveyDef.HiddenResponses))
+		}
+	}
+	return response, surveyDef, nil
+}
+
+// getSurveyVersionForResponse returns the survey version the response was filled out for, or nil if the survey is unknown.
+// If the version cannot be found, the current version is used, unless it requires strict validation.
+func getSurveyVersionForResponse(instanceID string, studyKey string, response studyTypes.SurveyResponse) (*studyTypes.Survey, error) {
+	if response.VersionID != "" {
+		surveyDef, err := studyDBService.GetSurveyVersion(instanceID, studyKey, response.Key, response.VersionID)
+		if err == nil {
+			return surveyDef, nil
+		}
+	}
+
+	current, err := studyDBService.GetCurrentSurveyVersion(instanceID, studyKey, response.Key)
+	if err != nil {
+		slog.Debug("no survey definition found for response", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", response.Key))
+		return nil, nil
+	}
+	if current.StrictResponseValidation {
+		return nil, surveyengine.ValidationErrors{{Code: surveyengine.VALIDATION_ERROR_UNKNOWN_SURVEY_VERSION, Message: "response does not reference a known survey version"}}
+	}
+	return current, nil
+}
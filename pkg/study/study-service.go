@@ -14,6 +14,7 @@ import (
 	studyUtils "github.com/case-framework/case-backend/pkg/study/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 var (
@@ -82,6 +83,20 @@ func OnEnterStudy(instanceID string, studyKey string, profileID string, accountI
 		}
 	}
 
+	currentEvent := studyengine.StudyEvent{
+		Type:                                  studyengine.STUDY_EVENT_TYPE_ENTER,
+		InstanceID:                            instanceID,
+		StudyKey:                              studyKey,
+		ParticipantIDForConfidentialResponses: confidentialID,
+	}
+
+	// refusing quotas are checked before anything is stored for the participant
+	claimedBeforeRules, err := claimRefusingStudyQuotas(instanceID, study, pState, currentEvent)
+	if err != nil {
+		slog.Error("Error applying study quotas", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+		return
+	}
+
 	if isNewParticipant {
 		// save particicpant id profile lookup
 		if err = studyDBService.AddConfidentialIDMapEntry(instanceID, confidentialID, profileID, studyKey); err != nil {
@@ -99,21 +114,25 @@ func OnEnterStudy(instanceID string, studyKey string, profileID string, accountI
 		}
 	}
 
-	currentEvent := studyengine.StudyEvent{
-		Type:                                  studyengine.STUDY_EVENT_TYPE_ENTER,
-		InstanceID:                            instanceID,
-		StudyKey:                              studyKey,
-		ParticipantIDForConfidentialResponses: confidentialID,
-	}
 	actionResult, err := getAndPerformStudyRules(instanceID, studyKey, pState, currentEvent)
 	if err != nil {
+		releaseStudyQuotas(instanceID, studyKey, participantID, claimedBeforeRules)
 		slog.Error("Error getting and performing study rules", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
 	}
 
+	claimedQuotas, err := applyStudyQuotas(instanceID, study, &actionResult, currentEvent, claimedBeforeRules)
+	if err != nil {
+		finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
+		slog.Error("Error applying study quotas", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+		return
+	}
+
 	// save participant state
 	pState, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
 	if err != nil {
+		releaseStudyQuotas(instanceID, studyKey, participantID, claimedQuotas)
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
 	}
@@ -164,6 +183,7 @@ func OnRegisterTempParticipant(instanceID string, studyKey string) (pState *stud
 
 	// save participant state
 	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -207,6 +227,7 @@ func OnRegisterVirtualParticipant(instanceID string, studyKey string) (pState *s
 
 	// save participant state
 	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -231,11 +252,12 @@ func OnCustomStudyEvent(instanceID string, studyKey string, profileID string, ev
 
 	result, err = onCustomStudyEventHandler(
 		instanceID,
-		studyKey,
+		study,
 		participantID,
 		confidentialID,
 		eventKey,
 		payload,
+		true,
 	)
 	if err != nil {
 		slog.Error("Error handling custom study event", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
@@ -260,11 +282,12 @@ func OnCustomStudyEventOnBehalfOfParticipant(instanceID string, studyKey string,
 
 	result, err = onCustomStudyEventHandler(
 		instanceID,
-		studyKey,
+		study,
 		participantID,
 		confidentialID,
 		eventKey,
 		payload,
+		true,
 	)
 	if err != nil {
 		slog.Error("Error handling custom study event", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
@@ -276,12 +299,14 @@ func OnCustomStudyEventOnBehalfOfParticipant(instanceID string, studyKey string,
 
 func onCustomStudyEventHandler(
 	instanceID string,
-	studyKey string,
+	study studyTypes.Study,
 	participantID string,
 	confidentialID string,
 	eventKey string,
 	payload map[string]any,
+	checkRefusingQuotas bool, // false for events that must not be refused, e.g. a consent withdrawal that is already stored
 ) (result []studyTypes.AssignedSurvey, err error) {
+	studyKey := study.Key
 	pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
 	if err != nil {
 		slog.Error("Error getting participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
@@ -297,15 +322,34 @@ func onCustomStudyEventHandler(
 		Payload:                               payload,
 	}
 
+	var claimedBeforeRules []string
+	if checkRefusingQuotas {
+		claimedBeforeRules, err = claimRefusingStudyQuotas(instanceID, study, pState, currentEvent)
+		if err != nil {
+			slog.Error("Error applying study quotas", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+			return
+		}
+	}
+
 	actionResult, err := getAndPerformStudyRules(instanceID, studyKey, pState, currentEvent)
 	if err != nil {
+		releaseStudyQuotas(instanceID, studyKey, participantID, claimedBeforeRules)
 		slog.Error("Error getting and performing study rules", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
 	}
 
+	claimedQuotas, err := applyStudyQuotas(instanceID, study, &actionResult, currentEvent, claimedBeforeRules)
+	if err != nil {
+		finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
+		slog.Error("Error applying study quotas", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+		return
+	}
+
 	// save participant state
 	pState, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
 	if err != nil {
+		releaseStudyQuotas(instanceID, studyKey, participantID, claimedQuotas)
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
 	}
@@ -515,6 +559,7 @@ func mergeParticipants(
 
 	// save participant state
 	targetParticipant, err = studyDBService.SaveParticipantState(instanceID, studyKey, targetParticipant)
+	finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.String("error", err.Error()))
 		return
@@ -528,6 +573,20 @@ func mergeParticipants(
 		slog.Debug("updated responses for participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.Int64("count", count))
 	}
 
+	count, err = studyDBService.UpdateParticipantIDonSurveyScores(instanceID, studyKey, withParticipant.ParticipantID, targetParticipant.ParticipantID)
+	if err != nil {
+		slog.Error("Error updating participant ID on survey scores", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.String("error", err.Error()))
+	} else {
+		slog.Debug("updated survey scores for participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.Int64("count", count))
+	}
+
+	count, err = studyDBService.UpdateParticipantIDonConsentRecords(instanceID, studyKey, withParticipant.ParticipantID, targetParticipant.ParticipantID)
+	if err != nil {
+		slog.Error("Error updating participant ID on consent records", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.String("error", err.Error()))
+	} else {
+		slog.Debug("updated consent records for participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.Int64("count", count))
+	}
+
 	// update participant ID to all history object
 	count, err = studyDBService.UpdateParticipantIDonReports(instanceID, studyKey, withParticipant.ParticipantID, targetParticipant.ParticipantID)
 	if err != nil {
@@ -549,6 +608,21 @@ func mergeParticipants(
 		slog.Debug("updated confidential responses for participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.Int64("count", count))
 	}
 
+	count, err = studyDBService.MoveSurveyResponseDraftsToParticipant(instanceID, studyKey, withParticipant.ParticipantID, targetParticipant.ParticipantID)
+	if err != nil {
+		slog.Error("Error moving survey response drafts", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.String("error", err.Error()))
+	} else {
+		slog.Debug("moved survey response drafts to participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.Int64("count", count))
+	}
+
+	// quota slots and waitlist places of the merged participant belong to the target now
+	count, err = studyDBService.TransferStudyQuotaEntries(instanceID, studyKey, withParticipant.ParticipantID, targetParticipant.ParticipantID)
+	if err != nil {
+		slog.Error("Error transferring study quota entries", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.String("error", err.Error()))
+	} else {
+		slog.Debug("transferred study quota entries to participant", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", targetParticipant.ParticipantID), slog.Int64("count", count))
+	}
+
 	// delete temporary participant
 	err = studyDBService.DeleteParticipantByID(instanceID, studyKey, withParticipant.ParticipantID)
 	if err != nil {
@@ -582,7 +656,11 @@ func OnSubmitResponse(instanceID string, studyKey string, profileID string, resp
 		confidentialID,
 		response,
 		&statusFilter,
+		0,
 	)
+	if errors.Is(err, errDuplicateSubmission) {
+		err = nil
+	}
 	if err != nil {
 		slog.Error("Error submitting response", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -601,6 +679,7 @@ func onSubmitResponseHandler(
 	confidentialID string,
 	response studyTypes.SurveyResponse,
 	statusFilter *string,
+	eventTime int64, // 0 to use the processing time
 ) (result []studyTypes.AssignedSurvey, err error) {
 	response.ArrivedAt = time.Now().Unix()
 
@@ -618,12 +697,41 @@ func onSubmitResponseHandler(
 		}
 	}
 
+	submissionID := response.SubmissionID
+	claimed, err := claimSubmission(instanceID, studyKey, participantID, submissionID)
+	if err != nil {
+		return
+	}
+	if !claimed {
+		result = duplicateSubmissionResult(instanceID, studyKey, pState, submissionID)
+		err = errDuplicateSubmission
+		return
+	}
+	// once the participant state is saved the study rules were applied, so the claim is kept even if a later step fails
+	stateSaved := false
+	var processedResult []studyTypes.AssignedSurvey
+	defer func() {
+		if stateSaved {
+			finishSubmissionClaim(instanceID, studyKey, participantID, submissionID, processedResult, nil)
+			return
+		}
+		finishSubmissionClaim(instanceID, studyKey, participantID, submissionID, result, err)
+	}()
+
+	response, surveyDef, err := prepareSubmittedResponse(instanceID, studyKey, pState, response, eventTime)
+	if err != nil {
+		return
+	}
+	scores := computeResponseScores(surveyDef, response)
+
 	currentEvent := studyengine.StudyEvent{
 		Type:                                  studyengine.STUDY_EVENT_TYPE_SUBMIT,
 		InstanceID:                            instanceID,
 		StudyKey:                              studyKey,
 		ParticipantIDForConfidentialResponses: confidentialID,
 		Response:                              response,
+		Timestamp:                             eventTime,
+		ResponseScores:                        scoresToMap(scores),
 	}
 
 	actionResult, err := getAndPerformStudyRules(instanceID, studyKey, pState, currentEvent)
@@ -634,24 +742,32 @@ func onSubmitResponseHandler(
 
 	// save participant state
 	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
 	}
+	stateSaved = true
+	processedResult = assignedSurveysForSubmitResult(studyKey, actionResult.PState)
 
 	responseId, err := saveResponses(instanceID, studyKey, response, pState, confidentialID)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// a concurrent request with the same submission ID stored the response first
+			slog.Warn("duplicate submission detected while saving response", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("submissionID", response.SubmissionID))
+			err = nil
+			result = assignedSurveysForSubmitResult(studyKey, actionResult.PState)
+			return
+		}
 		slog.Error("Error saving responses", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
 	}
+	removeSurveyResponseDraft(instanceID, studyKey, participantID, response.Key)
 
-	saveReports(instanceID, studyKey, actionResult.ReportsToCreate, responseId)
+	scoreReports := saveResponseScores(instanceID, studyKey, surveyDef, response, participantID, responseId, scores, currentEvent.CurrentTime())
+	saveReports(instanceID, studyKey, append(actionResult.ReportsToCreate, scoreReports...), responseId)
 
-	result = make([]studyTypes.AssignedSurvey, len(actionResult.PState.AssignedSurveys))
-	for i, survey := range actionResult.PState.AssignedSurveys {
-		result[i] = survey
-		result[i].StudyKey = studyKey
-	}
+	result = assignedSurveysForSubmitResult(studyKey, actionResult.PState)
 	return
 }
 
@@ -680,7 +796,11 @@ func OnSubmitResponseOnBehalfOfParticipant(instanceID string, studyKey string, p
 		confidentialID,
 		response,
 		nil,
+		0,
 	)
+	if errors.Is(err, errDuplicateSubmission) {
+		err = nil
+	}
 	if err != nil {
 		slog.Error("Error submitting response", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -718,6 +838,32 @@ func OnSubmitResponseForTempParticipant(instanceID string, studyKey string, part
 		return
 	}
 
+	submissionID := response.SubmissionID
+	claimed, err := claimSubmission(instanceID, studyKey, participantID, submissionID)
+	if err != nil {
+		return
+	}
+	if !claimed {
+		result = duplicateSubmissionResult(instanceID, studyKey, pState, submissionID)
+		return
+	}
+	// once the participant state is saved the study rules were applied, so the claim is kept even if a later step fails
+	stateSaved := false
+	var processedResult []studyTypes.AssignedSurvey
+	defer func() {
+		if stateSaved {
+			finishSubmissionClaim(instanceID, studyKey, participantID, submissionID, processedResult, nil)
+			return
+		}
+		finishSubmissionClaim(instanceID, studyKey, participantID, submissionID, result, err)
+	}()
+
+	response, surveyDef, err := prepareSubmittedResponse(instanceID, studyKey, pState, response, 0)
+	if err != nil {
+		return
+	}
+	scores := computeResponseScores(surveyDef, response)
+
 	confidentialID, err := ComputeConfidentialIDForParticipant(study, participantID)
 	if err != nil {
 		slog.Error("Error computing confidential ID", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
@@ -730,6 +876,7 @@ func OnSubmitResponseForTempParticipant(instanceID string, studyKey string, part
 		StudyKey:                              studyKey,
 		Response:                              response,
 		ParticipantIDForConfidentialResponses: confidentialID,
+		ResponseScores:                        scoresToMap(scores),
 	}
 	actionResult, err := getAndPerformStudyRules(instanceID, studyKey, pState, currentEvent)
 	if err != nil {
@@ -739,24 +886,32 @@ func OnSubmitResponseForTempParticipant(instanceID string, studyKey string, part
 
 	// save participant state
 	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
 	}
+	stateSaved = true
+	processedResult = assignedSurveysForSubmitResult(studyKey, actionResult.PState)
 
 	responseId, err := saveResponses(instanceID, studyKey, response, pState, confidentialID)
 	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// a concurrent request with the same submission ID stored the response first
+			slog.Warn("duplicate submission detected while saving response", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("submissionID", response.SubmissionID))
+			err = nil
+			result = assignedSurveysForSubmitResult(studyKey, actionResult.PState)
+			return
+		}
 		slog.Error("Error saving responses", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
 	}
+	removeSurveyResponseDraft(instanceID, studyKey, participantID, response.Key)
 
-	saveReports(instanceID, studyKey, actionResult.ReportsToCreate, responseId)
+	scoreReports := saveResponseScores(instanceID, studyKey, surveyDef, response, participantID, responseId, scores, currentEvent.CurrentTime())
+	saveReports(instanceID, studyKey, append(actionResult.ReportsToCreate, scoreReports...), responseId)
 
-	result = make([]studyTypes.AssignedSurvey, len(actionResult.PState.AssignedSurveys))
-	for i, survey := range actionResult.PState.AssignedSurveys {
-		result[i] = survey
-		result[i].StudyKey = studyKey
-	}
+	result = assignedSurveysForSubmitResult(studyKey, actionResult.PState)
 	return
 }
 
@@ -985,6 +1140,12 @@ func OnRunStudyActionForPreviousResponses(req RunStudyActionReq, surveyKeys []st
 						ReportsToCreate: []studyTypes.Report{},
 					}
 
+					// scores stored when the response was submitted
+					var responseScores map[string]float64
+					if scoreRecord, err := dbService.GetSurveyScoreRecordByResponseID(instanceID, studyKey, r.ID.Hex()); err == nil {
+						responseScores = scoreRecord.Map()
+					}
+
 					for _, rule := range req.Rules {
 						event := studyengine.StudyEvent{
 							InstanceID:                            instanceID,
@@ -992,6 +1153,7 @@ func OnRunStudyActionForPreviousResponses(req RunStudyActionReq, surveyKeys []st
 							Type:                                  studyengine.STUDY_EVENT_TYPE_SUBMIT,
 							ParticipantIDForConfidentialResponses: confidentialID,
 							Response:                              r,
+							ResponseScores:                        responseScores,
 						}
 
 						newState, err := studyengine.ActionEval(rule, participantData, event)
@@ -1094,6 +1256,7 @@ func OnStudyTimer(instanceID string, study *studyTypes.Study) {
 
 			// save participant state
 			_, err = studyDBService.SaveParticipantState(instanceID, studyKey, newState.PState)
+			finaliseStudyCodeReservations(instanceID, studyKey, newState, currentEvent, err)
 			if err != nil {
 				slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", p.ParticipantID), slog.String("error", err.Error()))
 				return err
@@ -1150,6 +1313,7 @@ func OnLeaveStudy(instanceID string, studyKey string, profileID string) (result
 	}
 
 	_, err = studyDBService.SaveParticipantState(instanceID, studyKey, actionResult.PState)
+	finaliseStudyCodeReservations(instanceID, studyKey, actionResult, currentEvent, err)
 	if err != nil {
 		slog.Error("Error saving participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
 		return
@@ -1157,6 +1321,8 @@ func OnLeaveStudy(instanceID string, studyKey string, profileID string) (result
 
 	saveReports(instanceID, studyKey, actionResult.ReportsToCreate, studyengine.STUDY_EVENT_TYPE_LEAVE)
 
+	releaseStudyQuotasForParticipant(instanceID, studyKey, participantID)
+
 	_, err = studyDBService.DeleteConfidentialResponses(instanceID, studyKey, confidentialID, "")
 	if err != nil {
 		slog.Error("Error deleting confidential responses", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
@@ -1233,6 +1399,14 @@ func OnProfileDeleted(instanceID, profileID string, exitSurveyResp *studyTypes.S
 			studyengine.STUDY_EVENT_TYPE_LEAVE,
 		)
 
+		releaseStudyQuotasForParticipant(instanceID, studyKey, participantID)
+
+		// drafts are not kept after the account is removed
+		_, err = studyDBService.DeleteSurveyResponseDraftsForParticipant(instanceID, studyKey, participantID)
+		if err != nil {
+			slog.Error("Error deleting survey response drafts", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+		}
+
 		// delete confidential data
 		_, err = studyDBService.DeleteConfidentialResponses(instanceID, studyKey, confidentialID, "")
 		if err != nil {
@@ -7,15 +7,18 @@ import (
 	"fmt"
 	"log/slog"
 	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/case-framework/case-backend/pkg/apihelpers"
 	httpclient "github.com/case-framework/case-backend/pkg/http-client"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func ActionEval(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
@@ -59,10 +62,16 @@ func ActionEval(action studyTypes.Expression, oldState ActionData, event StudyEv
 		newState, err = removeAllMessages(oldState)
 	case "REMOVE_MESSAGES_BY_TYPE":
 		newState, err = removeMessagesByType(action, oldState, event)
+	case "SCHEDULE_VISIT":
+		newState, err = scheduleVisit(action, oldState, event)
+	case "CANCEL_VISIT":
+		newState, err = cancelVisit(action, oldState, event)
 	case "NOTIFY_RESEARCHER":
 		newState, err = notifyResearcher(action, oldState, event)
 	case "SEND_MESSAGE_NOW":
 		newState, err = sendMessageNow(action, oldState, event)
+	case "SEND_INBOX_MESSAGE":
+		newState, err = sendInboxMessage(action, oldState, event)
 	case "INIT_REPORT":
 		newState, err = initReport(action, oldState, event)
 	case "UPDATE_REPORT_DATA":
@@ -81,12 +90,20 @@ func ActionEval(action studyTypes.Expression, oldState ActionData, event StudyEv
 		newState, err = removeStudyCode(action, oldState, event)
 	case "DRAW_STUDY_CODE_AS_LINKING_CODE":
 		newState, err = drawStudyCodeAsLinkingCode(action, oldState, event)
+	case "RESERVE_STUDY_CODE_AS_LINKING_CODE":
+		newState, err = reserveStudyCodeAsLinkingCode(action, oldState, event)
+	case "CLAIM_STUDY_CODE":
+		newState, err = claimStudyCode(action, oldState, event)
 	case "GET_NEXT_STUDY_COUNTER_AS_FLAG":
 		newState, err = getNextStudyCounterAsFlag(action, oldState, event)
 	case "GET_NEXT_STUDY_COUNTER_AS_LINKING_CODE":
 		newState, err = getNextStudyCounterAsLinkingCode(action, oldState, event)
 	case "RESET_STUDY_COUNTER":
 		newState, err = resetStudyCounter(action, oldState, event)
+	case "ASSIGN_STUDY_ARM":
+		newState, err = assignStudyArm(action, oldState, event)
+	case "REMOVE_STUDY_ARM":
+		newState, err = removeStudyArm(action, oldState, event)
 	case "UPDATE_STUDY_VARIABLE_BOOLEAN":
 		newState, err = updateStudyVariableBoolean(action, oldState, event)
 	case "UPDATE_STUDY_VARIABLE_INT":
@@ -116,10 +133,7 @@ func updateLastSubmissionForSurvey(oldState ActionData, event StudyEvent) (newSt
 		newState.PState.LastSubmissions = map[string]int64{}
 	}
 
-	if event.Response.ArrivedAt == 0 {
-		event.Response.ArrivedAt = Now().Unix()
-	}
-	newState.PState.LastSubmissions[event.Response.Key] = event.Response.ArrivedAt
+	newState.PState.LastSubmissions[event.Response.Key] = event.CurrentTime().Unix()
 	return
 }
 
@@ -526,11 +540,12 @@ func removeSurveysByKey(action studyTypes.Expression, oldState ActionData, event
 	return
 }
 
-// addMessage
+// addMessage schedules a message for the participant, optional arguments are the delivery channels
+// (comma separated, e.g. "email,inbox,push", default are the channels of the template) and a survey key the inbox message should link to
 func addMessage(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
 	newState = oldState
-	if len(action.Data) != 2 {
-		return newState, errors.New("addMessage must have exactly two arguments")
+	if len(action.Data) < 2 || len(action.Data) > 4 {
+		return newState, errors.New("addMessage must have two to four arguments")
 	}
 	EvalContext := EvalContext{
 		Event:            event,
@@ -557,6 +572,32 @@ func addMessage(action studyTypes.Expression, oldState ActionData, event StudyEv
 		Type:         messageType,
 		ScheduledFor: int64(timestamp),
 	}
+
+	if len(action.Data) > 2 {
+		arg3, err := EvalContext.ExpressionArgResolver(action.Data[2])
+		if err != nil {
+			return newState, err
+		}
+		channels, ok := arg3.(string)
+		if !ok {
+			return newState, errors.New("could not parse channels")
+		}
+		newMessage.Channels, err = parseMessageChannels(channels)
+		if err != nil {
+			return newState, err
+		}
+	}
+	if len(action.Data) > 3 {
+		arg4, err := EvalContext.ExpressionArgResolver(action.Data[3])
+		if err != nil {
+			return newState, err
+		}
+		surveyKey, ok := arg4.(string)
+		if !ok {
+			return newState, errors.New("could not parse survey key")
+		}
+		newMessage.SurveyKey = strings.TrimSpace(surveyKey)
+	}
 	newState.PState.Messages = make([]studyTypes.ParticipantMessage, len(oldState.PState.Messages))
 	copy(newState.PState.Messages, oldState.PState.Messages)
 
@@ -564,6 +605,26 @@ func addMessage(action studyTypes.Expression, oldState ActionData, event StudyEv
 	return
 }
 
+func parseMessageChannels(value string) ([]string, error) {
+	channels := []string{}
+	for _, c := range strings.Split(value, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !messagingTypes.IsKnownMessageChannel(c) {
+			return nil, fmt.Errorf("unknown message channel: %s", c)
+		}
+		if !slices.Contains(channels, c) {
+			channels = append(channels, c)
+		}
+	}
+	if len(channels) == 0 {
+		return nil, nil
+	}
+	return channels, nil
+}
+
 // removeAllMessages
 func removeAllMessages(oldState ActionData) (newState ActionData, err error) {
 	newState = oldState
@@ -603,6 +664,86 @@ func removeMessagesByType(action studyTypes.Expression, oldState ActionData, eve
 	return
 }
 
+// scheduleVisit adds an appointment or replaces the one with the same key - arguments: key, start, [end], [location], [label]
+func scheduleVisit(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+	if len(action.Data) < 2 || len(action.Data) > 5 {
+		return newState, errors.New("scheduleVisit must have between two and five arguments")
+	}
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+	args := make([]interface{}, len(action.Data))
+	for i, arg := range action.Data {
+		args[i], err = EvalContext.ExpressionArgResolver(arg)
+		if err != nil {
+			return newState, err
+		}
+	}
+
+	key, ok1 := args[0].(string)
+	start, ok2 := args[1].(float64)
+	if !ok1 || !ok2 || key == "" || start <= 0 {
+		return newState, errors.New("could not parse arguments")
+	}
+	visit := studyTypes.ParticipantVisit{
+		Key:   key,
+		Start: int64(start),
+	}
+	if len(args) > 2 {
+		end, _ := args[2].(float64)
+		if end > 0 && int64(end) < visit.Start {
+			return newState, errors.New("visit end must not be before its start")
+		}
+		visit.End = int64(end)
+	}
+	if len(args) > 3 {
+		visit.Location, _ = args[3].(string)
+	}
+	if len(args) > 4 {
+		visit.Label, _ = args[4].(string)
+	}
+
+	visits := []studyTypes.ParticipantVisit{}
+	for _, v := range oldState.PState.Visits {
+		if v.Key != key {
+			visits = append(visits, v)
+		}
+	}
+	newState.PState.Visits = append(visits, visit)
+	return
+}
+
+// cancelVisit removes the appointment with the given key
+func cancelVisit(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+	if len(action.Data) != 1 {
+		return newState, errors.New("cancelVisit must have exactly one argument")
+	}
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+	k, err := EvalContext.ExpressionArgResolver(action.Data[0])
+	if err != nil {
+		return newState, err
+	}
+	key, ok := k.(string)
+	if !ok {
+		return newState, errors.New("could not parse arguments")
+	}
+
+	visits := []studyTypes.ParticipantVisit{}
+	for _, v := range oldState.PState.Visits {
+		if v.Key != key {
+			visits = append(visits, v)
+		}
+	}
+	newState.PState.Visits = visits
+	return
+}
+
 // notifyResearcher can save a specific message with a payload, that should be sent out to the researcher
 func notifyResearcher(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
 	newState = oldState
@@ -738,6 +879,7 @@ func sendMessageNow(action studyTypes.Expression, oldState ActionData, event Stu
 		SendOptions{
 			LanguageOverride: languageOverride,
 			ExpiresAt:        Now().Add(time.Hour * 24).Unix(),
+			ParticipantState: &newState.PState,
 		},
 	)
 	if err != nil {
@@ -749,6 +891,85 @@ func sendMessageNow(action studyTypes.Expression, oldState ActionData, event Stu
 
 }
 
+// sendInboxMessage renders a study message template into the inbox of the participant's account right away.
+// Arguments: message type, optional survey key the message links to, optional language override
+func sendInboxMessage(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+
+	if event.ParticipantIDForConfidentialResponses == "" {
+		slog.Debug("SEND_INBOX_MESSAGE: missing participantID for confidential responses")
+		return newState, errors.New("SEND_INBOX_MESSAGE: missing participantID for confidential responses")
+	}
+
+	if CurrentStudyEngine.messageSender == nil {
+		slog.Error("message sender for study engine not registered")
+		return newState, errors.New("message sender for study engine not registered")
+	}
+
+	if len(action.Data) < 1 {
+		slog.Debug("SEND_INBOX_MESSAGE: must have at least one argument")
+		return newState, errors.New("SEND_INBOX_MESSAGE: must have at least one argument")
+	}
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+	arg1, err := EvalContext.ExpressionArgResolver(action.Data[0])
+	if err != nil {
+		return newState, err
+	}
+
+	messageType, ok := arg1.(string)
+	messageType = strings.TrimSpace(messageType)
+	if !ok || messageType == "" {
+		return newState, errors.New("could not parse arguments")
+	}
+
+	surveyKey := ""
+	if len(action.Data) > 1 {
+		arg2, err := EvalContext.ExpressionArgResolver(action.Data[1])
+		if err != nil {
+			return newState, err
+		}
+		surveyKey, ok = arg2.(string)
+		if !ok {
+			return newState, errors.New("could not parse survey key")
+		}
+		surveyKey = strings.TrimSpace(surveyKey)
+	}
+
+	languageOverride := ""
+	if len(action.Data) > 2 {
+		arg3, err := EvalContext.ExpressionArgResolver(action.Data[2])
+		if err != nil {
+			return newState, err
+		}
+		languageOverride, ok = arg3.(string)
+		if !ok {
+			slog.Debug("could not parse language override")
+		}
+	}
+
+	extraPayload := getExtraPayload(newState.PState, event)
+
+	err = CurrentStudyEngine.messageSender.SendInboxMessage(
+		event.InstanceID,
+		event.StudyKey,
+		event.ParticipantIDForConfidentialResponses,
+		messageType,
+		extraPayload,
+		SendOptions{
+			LanguageOverride: languageOverride,
+			SurveyKey:        surveyKey,
+		},
+	)
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
+	return
+}
+
 // findMostRecentReportByKey finds the first report (most recent) with the given key in the slice
 // Returns the index and a pointer to the report, or -1 and nil if not found
 func findMostRecentReportByKey(reports []studyTypes.Report, key string) (int, *studyTypes.Report) {
@@ -768,12 +989,12 @@ func removeReportAtIndex(reports []studyTypes.Report, index int) []studyTypes.Re
 	return append(reports[:index], reports[index+1:]...)
 }
 
-// newReport creates a new report with the given key and participant ID
-func newReport(reportKey string, participantID string) studyTypes.Report {
+// newReport creates a new report with the given key and participant ID at the time of the event
+func newReport(reportKey string, participantID string, event StudyEvent) studyTypes.Report {
 	return studyTypes.Report{
 		Key:           reportKey,
 		ParticipantID: participantID,
-		Timestamp:     Now().Unix(),
+		Timestamp:     event.CurrentTime().Unix(),
 	}
 }
 
@@ -797,7 +1018,7 @@ func initReport(action studyTypes.Expression, oldState ActionData, event StudyEv
 		return newState, errors.New("could not parse arguments")
 	}
 
-	newReport := newReport(reportKey, oldState.PState.ParticipantID)
+	newReport := newReport(reportKey, oldState.PState.ParticipantID, event)
 
 	// Prepend to slice to maintain most-recent-first order
 	newState.ReportsToCreate = append([]studyTypes.Report{newReport}, newState.ReportsToCreate...)
@@ -829,7 +1050,7 @@ func updateReportData(action studyTypes.Expression, oldState ActionData, event S
 	reportIndex, report := findMostRecentReportByKey(newState.ReportsToCreate, reportKey)
 	if report == nil {
 		// If report not initialized yet, init report and prepend to slice
-		newReport := newReport(reportKey, oldState.PState.ParticipantID)
+		newReport := newReport(reportKey, oldState.PState.ParticipantID, event)
 		newState.ReportsToCreate = append([]studyTypes.Report{newReport}, newState.ReportsToCreate...)
 		report = &newState.ReportsToCreate[0]
 		reportIndex = 0
@@ -1155,11 +1376,9 @@ func removeStudyCode(action studyTypes.Expression, oldState ActionData, event St
 		return newState, errors.New("could not parse arguments")
 	}
 
-	err = CurrentStudyEngine.studyDBService.DeleteStudyCodeListEntry(event.InstanceID, event.StudyKey, listKey, code)
-	if err != nil {
-		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
-	}
-	return
+	// the code is kept in the list as claimed, so it stays traceable who used it
+	err = claimStudyCodeForParticipant(action, listKey, code, newState.PState, event)
+	return newState, err
 }
 
 func drawStudyCodeAsLinkingCode(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
@@ -1205,7 +1424,7 @@ func drawStudyCodeAsLinkingCode(action studyTypes.Expression, oldState ActionDat
 	}
 
 	// draw code
-	code, err := CurrentStudyEngine.studyDBService.DrawStudyCode(event.InstanceID, event.StudyKey, listKey)
+	code, err := CurrentStudyEngine.studyDBService.DrawStudyCode(event.InstanceID, event.StudyKey, listKey, StudyCodeClaimForEvent(newState.PState, event))
 	if err != nil {
 		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
 		return newState, err
@@ -1222,6 +1441,175 @@ func drawStudyCodeAsLinkingCode(action studyTypes.Expression, oldState ActionDat
 	return
 }
 
+// reserveStudyCodeAsLinkingCode reserves the next available code for the participant. The code is only consumed
+// once the participant state is saved successfully, otherwise the reservation is released or expires.
+func reserveStudyCodeAsLinkingCode(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+
+	// args: listKey, linkingCodeKey (optional), reservation duration in seconds (optional)
+	if len(action.Data) < 1 {
+		return newState, errors.New("RESERVE_STUDY_CODE_AS_LINKING_CODE must have at least one argument")
+	}
+
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+	arg1, err := EvalContext.ExpressionArgResolver(action.Data[0])
+	if err != nil {
+		return newState, err
+	}
+
+	listKey, ok1 := arg1.(string)
+	if !ok1 {
+		return newState, errors.New("could not parse arguments")
+	}
+
+	linkingCodeKey := listKey
+	if len(action.Data) > 1 {
+		arg2, err := EvalContext.ExpressionArgResolver(action.Data[1])
+		if err != nil {
+			return newState, err
+		}
+		var ok2 bool
+		linkingCodeKey, ok2 = arg2.(string)
+		if !ok2 {
+			return newState, errors.New("could not parse arguments")
+		}
+	}
+
+	reservationDuration := DEFAULT_STUDY_CODE_RESERVATION_DURATION
+	if len(action.Data) > 2 {
+		arg3, err := EvalContext.ExpressionArgResolver(action.Data[2])
+		if err != nil {
+			return newState, err
+		}
+		seconds, ok3 := arg3.(float64)
+		if !ok3 || seconds <= 0 {
+			return newState, errors.New("could not parse reservation duration")
+		}
+		reservationDuration = time.Duration(seconds) * time.Second
+	}
+
+	newState.PState.LinkingCodes = make(map[string]string)
+	maps.Copy(newState.PState.LinkingCodes, oldState.PState.LinkingCodes)
+
+	reservedAt := event.CurrentTime()
+	code, err := CurrentStudyEngine.studyDBService.ReserveStudyCode(event.InstanceID, event.StudyKey, listKey, newState.PState.ParticipantID, reservedAt, reservedAt.Add(reservationDuration))
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
+
+	if code == "" {
+		slog.Debug("no study code available, removing linking code")
+		delete(newState.PState.LinkingCodes, linkingCodeKey)
+		return newState, nil
+	}
+
+	newState.PState.LinkingCodes[linkingCodeKey] = code
+	newState.ReservedStudyCodes = append(slices.Clone(oldState.ReservedStudyCodes), ReservedStudyCode{
+		ListKey: listKey,
+		Code:    code,
+	})
+	return newState, nil
+}
+
+// claimStudyCode marks a specific code (e.g. entered by the participant) as used by the participant
+func claimStudyCode(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+
+	if len(action.Data) != 2 {
+		return newState, errors.New("CLAIM_STUDY_CODE must have exactly two arguments")
+	}
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+	arg1, err := EvalContext.ExpressionArgResolver(action.Data[0])
+	if err != nil {
+		return newState, err
+	}
+
+	listKey, ok1 := arg1.(string)
+	if !ok1 {
+		return newState, errors.New("could not parse arguments")
+	}
+
+	arg2, err := EvalContext.ExpressionArgResolver(action.Data[1])
+	if err != nil {
+		return newState, err
+	}
+
+	code, ok2 := arg2.(string)
+	if !ok2 {
+		return newState, errors.New("could not parse arguments")
+	}
+
+	err = claimStudyCodeForParticipant(action, listKey, code, newState.PState, event)
+	return newState, err
+}
+
+// claimStudyCodeForParticipant marks the code as claimed by the participant. A code that is not available (unknown,
+// already used or expired) is logged and the remaining rules continue, only database errors are returned.
+func claimStudyCodeForParticipant(action studyTypes.Expression, listKey string, code string, pState studyTypes.Participant, event StudyEvent) error {
+	err := CurrentStudyEngine.studyDBService.ClaimStudyCode(event.InstanceID, event.StudyKey, listKey, code, StudyCodeClaimForEvent(pState, event))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			slog.Warn("study code is not available", slog.String("action", action.Name), slog.String("studyKey", event.StudyKey), slog.String("listKey", listKey), slog.String("participantID", pState.ParticipantID))
+			return nil
+		}
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// StudyCodeClaimForEvent describes the participant and event consuming a study code
+func StudyCodeClaimForEvent(pState studyTypes.Participant, event StudyEvent) studyTypes.StudyCodeClaim {
+	return studyTypes.StudyCodeClaim{
+		ParticipantID: pState.ParticipantID,
+		Event:         eventLabel(event),
+	}
+}
+
+// StudyCounterIncrementOriginForEvent describes the participant and event receiving a counter value
+func StudyCounterIncrementOriginForEvent(pState studyTypes.Participant, event StudyEvent) studyTypes.StudyCounterIncrementOrigin {
+	return studyTypes.StudyCounterIncrementOrigin{
+		ParticipantID: pState.ParticipantID,
+		Event:         eventLabel(event),
+	}
+}
+
+func eventLabel(event StudyEvent) string {
+	label := event.Type
+	if event.EventKey != "" {
+		label += ":" + event.EventKey
+	}
+	return label
+}
+
+// formatStudyCounterValue uses prefix and padding from the action if given, otherwise the counter's config
+func formatStudyCounterValue(event StudyEvent, scope string, value int64, prefix *string, padding *int) (string, error) {
+	config := studyTypes.StudyCounterConfig{}
+	if prefix == nil || padding == nil {
+		c, err := CurrentStudyEngine.studyDBService.GetStudyCounterConfig(event.InstanceID, event.StudyKey, scope)
+		if err != nil {
+			return "", err
+		}
+		if c != nil {
+			config = *c
+		}
+	}
+	if prefix != nil {
+		config.Prefix = *prefix
+	}
+	if padding != nil {
+		config.Padding = *padding
+	}
+	return config.FormatValue(value), nil
+}
+
 func getNextStudyCounterAsFlag(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
 	newState = oldState
 
@@ -1258,20 +1646,22 @@ func getNextStudyCounterAsFlag(action studyTypes.Expression, oldState ActionData
 		return newState, errors.New("could not parse flagKey")
 	}
 
-	// arg 2: prefix (optional)
-	prefix := ""
+	// arg 2: prefix (optional, defaults to the counter's config)
+	var prefix *string
 	if len(action.Data) > 2 {
 		arg2, err := EvalContext.ExpressionArgResolver(action.Data[2])
 		if err != nil {
 			return newState, err
 		}
-		prefix, ok = arg2.(string)
+		p, ok := arg2.(string)
 		if !ok {
 			return newState, errors.New("could not parse prefix")
 		}
+		prefix = &p
 	}
 
-	padding := 0
+	// arg 3: padding (optional, defaults to the counter's config)
+	var padding *int
 	if len(action.Data) > 3 {
 		arg3, err := EvalContext.ExpressionArgResolver(action.Data[3])
 		if err != nil {
@@ -1281,16 +1671,21 @@ func getNextStudyCounterAsFlag(action studyTypes.Expression, oldState ActionData
 		if !ok {
 			return newState, errors.New("could not parse padding")
 		}
-		padding = int(arg3Value)
+		p := int(arg3Value)
+		padding = &p
 	}
 
-	value, err := CurrentStudyEngine.studyDBService.IncrementAndGetStudyCounterValue(event.InstanceID, event.StudyKey, scope)
+	value, err := CurrentStudyEngine.studyDBService.IncrementAndGetStudyCounterValue(event.InstanceID, event.StudyKey, scope, StudyCounterIncrementOriginForEvent(oldState.PState, event))
 	if err != nil {
 		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
 		return newState, err
 	}
 
-	newValue := fmt.Sprintf("%s%0*d", prefix, padding, value)
+	newValue, err := formatStudyCounterValue(event, scope, value, prefix, padding)
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
 	newState.PState.Flags = updateMapValue(oldState.PState.Flags, flagKey, newValue)
 
 	return newState, nil
@@ -1331,20 +1726,22 @@ func getNextStudyCounterAsLinkingCode(action studyTypes.Expression, oldState Act
 		return newState, errors.New("could not parse linkingCodeKey")
 	}
 
-	// arg 2: prefix (optional)
-	prefix := ""
+	// arg 2: prefix (optional, defaults to the counter's config)
+	var prefix *string
 	if len(action.Data) > 2 {
 		arg2, err := EvalContext.ExpressionArgResolver(action.Data[2])
 		if err != nil {
 			return newState, err
 		}
-		prefix, ok = arg2.(string)
+		p, ok := arg2.(string)
 		if !ok {
 			return newState, errors.New("could not parse prefix")
 		}
+		prefix = &p
 	}
 
-	padding := 0
+	// arg 3: padding (optional, defaults to the counter's config)
+	var padding *int
 	if len(action.Data) > 3 {
 		arg3, err := EvalContext.ExpressionArgResolver(action.Data[3])
 		if err != nil {
@@ -1354,16 +1751,21 @@ func getNextStudyCounterAsLinkingCode(action studyTypes.Expression, oldState Act
 		if !ok {
 			return newState, errors.New("could not parse padding")
 		}
-		padding = int(arg3Value)
+		p := int(arg3Value)
+		padding = &p
 	}
 
-	value, err := CurrentStudyEngine.studyDBService.IncrementAndGetStudyCounterValue(event.InstanceID, event.StudyKey, scope)
+	value, err := CurrentStudyEngine.studyDBService.IncrementAndGetStudyCounterValue(event.InstanceID, event.StudyKey, scope, StudyCounterIncrementOriginForEvent(oldState.PState, event))
 	if err != nil {
 		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
 		return newState, err
 	}
 
-	newValue := fmt.Sprintf("%s%0*d", prefix, padding, value)
+	newValue, err := formatStudyCounterValue(event, scope, value, prefix, padding)
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
 	newState.PState.LinkingCodes = updateMapValue(oldState.PState.LinkingCodes, linkingCodeKey, newValue)
 
 	return newState, nil
@@ -1400,6 +1802,85 @@ func resetStudyCounter(action studyTypes.Expression, oldState ActionData, event
 	return newState, nil
 }
 
+// assignStudyArm moves the participant into an arm defined on the study
+func assignStudyArm(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+
+	// args: armKey, reason (optional)
+	if len(action.Data) < 1 || len(action.Data) > 2 {
+		return newState, errors.New("ASSIGN_STUDY_ARM must have one or two arguments")
+	}
+
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+
+	arg0, err := EvalContext.ExpressionArgResolver(action.Data[0])
+	if err != nil {
+		return newState, err
+	}
+	armKey, ok := arg0.(string)
+	if !ok || armKey == "" {
+		return newState, errors.New("could not parse arm key")
+	}
+
+	reason, err := studyArmReasonArg(action, EvalContext, 1)
+	if err != nil {
+		return newState, err
+	}
+
+	study, err := CurrentStudyEngine.studyDBService.GetStudy(event.InstanceID, event.StudyKey)
+	if err != nil {
+		slog.Error("unexpected error during action", slog.String("action", action.Name), slog.String("error", err.Error()))
+		return newState, err
+	}
+	if !study.HasArm(armKey) {
+		return newState, fmt.Errorf("arm %s is not defined for the study", armKey)
+	}
+
+	newState.PState = oldState.PState.WithArm(armKey, reason, event.CurrentTime().Unix())
+	return newState, nil
+}
+
+// removeStudyArm removes the participant from the current arm, the assignment is kept in the arm history
+func removeStudyArm(action studyTypes.Expression, oldState ActionData, event StudyEvent) (newState ActionData, err error) {
+	newState = oldState
+
+	// args: reason (optional)
+	if len(action.Data) > 1 {
+		return newState, errors.New("REMOVE_STUDY_ARM must have at most one argument")
+	}
+
+	EvalContext := EvalContext{
+		Event:            event,
+		ParticipantState: newState.PState,
+	}
+	reason, err := studyArmReasonArg(action, EvalContext, 0)
+	if err != nil {
+		return newState, err
+	}
+
+	newState.PState = oldState.PState.WithArm("", reason, event.CurrentTime().Unix())
+	return newState, nil
+}
+
+// studyArmReasonArg reads the optional reason argument, defaults to the event that triggered the change
+func studyArmReasonArg(action studyTypes.Expression, EvalContext EvalContext, index int) (string, error) {
+	if len(action.Data) <= index {
+		return eventLabel(EvalContext.Event), nil
+	}
+	arg, err := EvalContext.ExpressionArgResolver(action.Data[index])
+	if err != nil {
+		return "", err
+	}
+	reason, ok := arg.(string)
+	if !ok {
+		return "", errors.New("could not parse reason")
+	}
+	return reason, nil
+}
+
 func updateStudyVariable(action studyTypes.Expression, oldState ActionData, event StudyEvent, asType studyTypes.StudyVariablesType) (newState ActionData, err error) {
 	newState = oldState
 
@@ -1459,7 +1940,13 @@ func updateStudyVariable(action studyTypes.Expression, oldState ActionData, even
 		value = time.Unix(int64(fV), 0)
 	}
 
-	_, err = CurrentStudyEngine.studyDBService.UpdateStudyVariableValue(event.InstanceID, event.StudyKey, variableKey, value)
+	origin := studyTypes.StudyVariableChangeOrigin{
+		Source:    studyTypes.STUDY_VARIABLE_CHANGE_SOURCE_RULE,
+		ChangedBy: newState.PState.ParticipantID,
+		EventType: event.Type,
+		EventKey:  event.EventKey,
+	}
+	_, err = CurrentStudyEngine.studyDBService.UpdateStudyVariableValue(event.InstanceID, event.StudyKey, variableKey, value, origin)
 	if err != nil {
 		return newState, err
 	}
@@ -1,11 +1,13 @@
 package studyengine
 
 import (
+	"errors"
 	"strconv"
 	"testing"
 	"time"
 
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 const (
@@ -484,6 +486,84 @@ func TestActions(t *testing.T) {
 		actionData = newState
 	})
 
+	t.Run("ADD_MESSAGE with channels", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "ADD_MESSAGE",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "testMessage"},
+				{DType: "num", Num: float64(time.Now().Unix())},
+				{DType: "str", Str: "inbox, email,inbox"},
+				{DType: "str", Str: "weekly"},
+			},
+		}
+		newState, err := ActionEval(action, ActionData{PState: actionData.PState}, event)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+			return
+		}
+		msg := newState.PState.Messages[len(newState.PState.Messages)-1]
+		if len(msg.Channels) != 2 || msg.Channels[0] != "inbox" || msg.Channels[1] != "email" {
+			t.Errorf("unexpected channels: %v", msg.Channels)
+		}
+		if msg.SurveyKey != "weekly" {
+			t.Errorf("unexpected survey key: %s", msg.SurveyKey)
+		}
+
+		action.Data[2] = studyTypes.ExpressionArg{DType: "str", Str: "sms"}
+		if _, err := ActionEval(action, ActionData{PState: actionData.PState}, event); err == nil {
+			t.Error("expected error for unknown channel")
+		}
+	})
+
+	t.Run("SCHEDULE_VISIT and CANCEL_VISIT", func(t *testing.T) {
+		start := time.Now().Add(48 * time.Hour).Unix()
+		action := studyTypes.Expression{
+			Name: "SCHEDULE_VISIT",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "baseline"},
+				{DType: "num", Num: float64(start)},
+				{DType: "num", Num: float64(start + 3600)},
+				{DType: "str", Str: "Study centre, room 2"},
+			},
+		}
+		newState, err := ActionEval(action, ActionData{PState: actionData.PState}, event)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+			return
+		}
+
+		// rescheduling replaces the visit
+		action.Data[1] = studyTypes.ExpressionArg{DType: "num", Num: float64(start + 600)}
+		newState, err = ActionEval(action, newState, event)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+			return
+		}
+		if len(newState.PState.Visits) != 1 {
+			t.Errorf("unexpected number of visits: %d", len(newState.PState.Visits))
+			return
+		}
+		if v := newState.PState.Visits[0]; v.Start != start+600 || v.End != start+3600 || v.Location != "Study centre, room 2" {
+			t.Errorf("unexpected visit: %+v", v)
+		}
+
+		action.Data[2] = studyTypes.ExpressionArg{DType: "num", Num: float64(start - 3600)}
+		if _, err := ActionEval(action, newState, event); err == nil {
+			t.Error("expected error for end before start")
+		}
+
+		newState, err = ActionEval(studyTypes.Expression{
+			Name: "CANCEL_VISIT",
+			Data: []studyTypes.ExpressionArg{{DType: "str", Str: "baseline"}},
+		}, newState, event)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+		if len(newState.PState.Visits) != 0 {
+			t.Errorf("unexpected number of visits: %d", len(newState.PState.Visits))
+		}
+	})
+
 	t.Run("REMOVE_ALL_MESSAGES", func(t *testing.T) {
 		action := studyTypes.Expression{
 			Name: "REMOVE_ALL_MESSAGES",
@@ -572,6 +652,27 @@ func TestReportActions(t *testing.T) {
 		}
 	})
 
+	t.Run("INIT_REPORT uses the time of a past event", func(t *testing.T) {
+		action := studyTypes.Expression{
+			Name: "INIT_REPORT",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "pastKey"},
+			},
+		}
+		pastEvent := event
+		pastEvent.Timestamp = 1609372800 // one day before the fixed time
+
+		newData, err := ActionEval(action, actionData, pastEvent)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		_, report := findMostRecentReportByKey(newData.ReportsToCreate, "pastKey")
+		if report == nil || report.Timestamp != pastEvent.Timestamp {
+			t.Errorf("expected report with event timestamp, got %+v", report)
+		}
+	})
+
 	t.Run("UPDATE_REPORT_DATA with no report there yet", func(t *testing.T) {
 		action := studyTypes.Expression{
 			Name: "UPDATE_REPORT_DATA",
@@ -701,7 +802,7 @@ func TestUpdateStudyVariableActions(t *testing.T) {
 		PState:          studyTypes.Participant{ParticipantID: "p1"},
 		ReportsToCreate: []studyTypes.Report{},
 	}
-	event := StudyEvent{InstanceID: "i1", StudyKey: "s1"}
+	event := StudyEvent{InstanceID: "i1", StudyKey: "s1", Type: STUDY_EVENT_TYPE_CUSTOM, EventKey: "closeRecruitment"}
 
 	t.Run("UPDATE_STUDY_VARIABLE_BOOLEAN", func(t *testing.T) {
 		action := studyTypes.Expression{
@@ -724,6 +825,10 @@ func TestUpdateStudyVariableActions(t *testing.T) {
 		if v, ok := mock.Updated[0].Value.(bool); !ok || v != true {
 			t.Fatalf("unexpected value: %#v", mock.Updated[0].Value)
 		}
+		origin := mock.Updated[0].Origin
+		if origin.Source != studyTypes.STUDY_VARIABLE_CHANGE_SOURCE_RULE || origin.ChangedBy != "p1" || origin.EventType != STUDY_EVENT_TYPE_CUSTOM || origin.EventKey != "closeRecruitment" {
+			t.Fatalf("unexpected origin: %#v", origin)
+		}
 	})
 
 	t.Run("UPDATE_STUDY_VARIABLE_INT", func(t *testing.T) {
@@ -808,3 +913,235 @@ func TestUpdateStudyVariableActions(t *testing.T) {
 		}
 	})
 }
+
+func TestReserveStudyCodeAsLinkingCodeAction(t *testing.T) {
+	actionData := ActionData{
+		PState: studyTypes.Participant{
+			ParticipantID: "p1",
+			LinkingCodes:  map[string]string{"other": "value"},
+		},
+		ReportsToCreate: []studyTypes.Report{},
+	}
+	event := StudyEvent{InstanceID: "i1", StudyKey: "s1", Type: STUDY_EVENT_TYPE_ENTER}
+	action := studyTypes.Expression{
+		Name: "RESERVE_STUDY_CODE_AS_LINKING_CODE",
+		Data: []studyTypes.ExpressionArg{
+			{DType: "str", Str: "invitations"},
+			{DType: "str", Str: "invitationCode"},
+		},
+	}
+
+	t.Run("code available", func(t *testing.T) {
+		CurrentStudyEngine = &StudyEngine{studyDBService: &MockStudyDBService{AvailableStudyCode: "ABC123"}}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if newState.PState.LinkingCodes["invitationCode"] != "ABC123" {
+			t.Errorf("unexpected linking codes: %v", newState.PState.LinkingCodes)
+		}
+		if newState.PState.LinkingCodes["other"] != "value" {
+			t.Errorf("existing linking codes should be kept: %v", newState.PState.LinkingCodes)
+		}
+		if len(newState.ReservedStudyCodes) != 1 || newState.ReservedStudyCodes[0].ListKey != "invitations" || newState.ReservedStudyCodes[0].Code != "ABC123" {
+			t.Errorf("unexpected reserved codes: %v", newState.ReservedStudyCodes)
+		}
+		if len(actionData.ReservedStudyCodes) != 0 {
+			t.Errorf("old state should not be modified")
+		}
+	})
+
+	t.Run("no code available", func(t *testing.T) {
+		CurrentStudyEngine = &StudyEngine{studyDBService: &MockStudyDBService{}}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, ok := newState.PState.LinkingCodes["invitationCode"]; ok {
+			t.Errorf("linking code should not be set")
+		}
+		if len(newState.ReservedStudyCodes) != 0 {
+			t.Errorf("no code should be reserved")
+		}
+	})
+}
+
+func TestClaimStudyCodeActions(t *testing.T) {
+	actionData := ActionData{
+		PState: studyTypes.Participant{
+			ParticipantID: "p1",
+		},
+		ReportsToCreate: []studyTypes.Report{},
+	}
+	event := StudyEvent{InstanceID: "i1", StudyKey: "s1", Type: STUDY_EVENT_TYPE_CUSTOM, EventKey: "enrol"}
+
+	for _, actionName := range []string{"CLAIM_STUDY_CODE", "REMOVE_STUDY_CODE"} {
+		action := studyTypes.Expression{
+			Name: actionName,
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "invitations"},
+				{DType: "str", Str: "ABC123"},
+			},
+		}
+
+		t.Run(actionName+" code claimed", func(t *testing.T) {
+			CurrentStudyEngine = &StudyEngine{studyDBService: &MockStudyDBService{}}
+			if _, err := ActionEval(action, actionData, event); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+
+		t.Run(actionName+" code not available", func(t *testing.T) {
+			CurrentStudyEngine = &StudyEngine{studyDBService: &MockStudyDBService{ClaimStudyCodeErr: mongo.ErrNoDocuments}}
+			if _, err := ActionEval(action, actionData, event); err != nil {
+				t.Fatalf("unavailable code should not stop the rules: %v", err)
+			}
+		})
+
+		t.Run(actionName+" database error", func(t *testing.T) {
+			CurrentStudyEngine = &StudyEngine{studyDBService: &MockStudyDBService{ClaimStudyCodeErr: errors.New("connection lost")}}
+			if _, err := ActionEval(action, actionData, event); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestGetNextStudyCounterAsFlagAction(t *testing.T) {
+	actionData := ActionData{
+		PState: studyTypes.Participant{
+			ParticipantID: "p1",
+			Flags:         map[string]string{},
+		},
+		ReportsToCreate: []studyTypes.Report{},
+	}
+	event := StudyEvent{InstanceID: "i1", StudyKey: "s1", Type: STUDY_EVENT_TYPE_ENTER}
+
+	t.Run("format from counter config", func(t *testing.T) {
+		CurrentStudyEngine = &StudyEngine{studyDBService: &MockStudyDBService{
+			CounterValue:  42,
+			CounterConfig: &studyTypes.StudyCounterConfig{Prefix: "SITE-A-", Padding: 4},
+		}}
+		action := studyTypes.Expression{
+			Name: "GET_NEXT_STUDY_COUNTER_AS_FLAG",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "siteA"},
+				{DType: "str", Str: "participantNumber"},
+			},
+		}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if newState.PState.Flags["participantNumber"] != "SITE-A-0042" {
+			t.Errorf("unexpected flags: %v", newState.PState.Flags)
+		}
+	})
+
+	t.Run("action arguments override config", func(t *testing.T) {
+		CurrentStudyEngine = &StudyEngine{studyDBService: &MockStudyDBService{
+			CounterValue:  7,
+			CounterConfig: &studyTypes.StudyCounterConfig{Prefix: "SITE-A-", Padding: 4},
+		}}
+		action := studyTypes.Expression{
+			Name: "GET_NEXT_STUDY_COUNTER_AS_FLAG",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "siteA"},
+				{DType: "str", Str: "participantNumber"},
+				{DType: "str", Str: "B"},
+				{DType: "num", Num: 2},
+			},
+		}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if newState.PState.Flags["participantNumber"] != "B07" {
+			t.Errorf("unexpected flags: %v", newState.PState.Flags)
+		}
+	})
+}
+
+func TestStudyArmActions(t *testing.T) {
+	CurrentStudyEngine = &StudyEngine{studyDBService: &MockStudyDBService{
+		Study: studyTypes.Study{Arms: []studyTypes.StudyArm{{Key: "control"}, {Key: "intervention"}}},
+	}}
+	event := StudyEvent{InstanceID: "i1", StudyKey: "s1", Type: STUDY_EVENT_TYPE_CUSTOM, EventKey: "randomise"}
+
+	t.Run("assign without reason", func(t *testing.T) {
+		actionData := ActionData{PState: studyTypes.Participant{ParticipantID: "p1"}}
+		action := studyTypes.Expression{
+			Name: "ASSIGN_STUDY_ARM",
+			Data: []studyTypes.ExpressionArg{{DType: "str", Str: "control"}},
+		}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if newState.PState.Arm == nil || newState.PState.Arm.Key != "control" {
+			t.Fatalf("unexpected arm: %v", newState.PState.Arm)
+		}
+		if newState.PState.Arm.Reason != "CUSTOM:randomise" {
+			t.Errorf("unexpected reason: %s", newState.PState.Arm.Reason)
+		}
+		if newState.PState.Arm.AssignedAt == 0 {
+			t.Error("assignedAt should be set")
+		}
+	})
+
+	t.Run("move to other arm", func(t *testing.T) {
+		actionData := ActionData{PState: studyTypes.Participant{
+			ParticipantID: "p1",
+			Arm:           &studyTypes.ParticipantArmAssignment{Key: "control", AssignedAt: 10},
+		}}
+		action := studyTypes.Expression{
+			Name: "ASSIGN_STUDY_ARM",
+			Data: []studyTypes.ExpressionArg{
+				{DType: "str", Str: "intervention"},
+				{DType: "str", Str: "crossover"},
+			},
+		}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if newState.PState.Arm.Key != "intervention" || newState.PState.Arm.Reason != "crossover" {
+			t.Errorf("unexpected arm: %v", newState.PState.Arm)
+		}
+		if len(newState.PState.ArmHistory) != 1 || newState.PState.ArmHistory[0].Key != "control" || newState.PState.ArmHistory[0].RemovedAt == 0 {
+			t.Errorf("unexpected arm history: %v", newState.PState.ArmHistory)
+		}
+		if actionData.PState.Arm.Key != "control" || len(actionData.PState.ArmHistory) != 0 {
+			t.Error("old state should not be modified")
+		}
+	})
+
+	t.Run("unknown arm", func(t *testing.T) {
+		actionData := ActionData{PState: studyTypes.Participant{ParticipantID: "p1"}}
+		action := studyTypes.Expression{
+			Name: "ASSIGN_STUDY_ARM",
+			Data: []studyTypes.ExpressionArg{{DType: "str", Str: "placebo"}},
+		}
+		if _, err := ActionEval(action, actionData, event); err == nil {
+			t.Error("expected error for arm not defined on the study")
+		}
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		actionData := ActionData{PState: studyTypes.Participant{
+			ParticipantID: "p1",
+			Arm:           &studyTypes.ParticipantArmAssignment{Key: "control", AssignedAt: 10},
+		}}
+		action := studyTypes.Expression{Name: "REMOVE_STUDY_ARM"}
+		newState, err := ActionEval(action, actionData, event)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if newState.PState.Arm != nil {
+			t.Errorf("arm should be removed: %v", newState.PState.Arm)
+		}
+		if len(newState.PState.ArmHistory) != 1 {
+			t.Errorf("unexpected arm history: %v", newState.PState.ArmHistory)
+		}
+	})
+}
@@ -14,6 +14,7 @@ import (
 	httpclient "github.com/case-framework/case-backend/pkg/http-client"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func ExpressionEval(expression studyTypes.Expression, evalCtx EvalContext) (val interface{}, err error) {
@@ -41,6 +42,11 @@ func ExpressionEval(expression studyTypes.Expression, evalCtx EvalContext) (val
 		val, err = evalCtx.hasResponseKey(expression)
 	case "hasResponseKeyWithValue":
 		val, err = evalCtx.hasResponseKeyWithValue(expression)
+	// Scores of the submitted response:
+	case "hasResponseScore":
+		val, err = evalCtx.hasResponseScore(expression)
+	case "getResponseScore":
+		val, err = evalCtx.getResponseScore(expression)
 	// Old responses:
 	case "checkConditionForOldResponses":
 		val, err = evalCtx.checkConditionForOldResponses(expression)
@@ -52,6 +58,16 @@ func ExpressionEval(expression studyTypes.Expression, evalCtx EvalContext) (val
 		val, err = evalCtx.getCurrentStudyCounterValue(expression)
 	case "getNextStudyCounterValue":
 		val, err = evalCtx.getNextStudyCounterValue(expression)
+	// Study quotas:
+	case "getStudyQuotaRemaining":
+		val, err = evalCtx.getStudyQuotaRemaining(expression)
+	case "isStudyQuotaFull":
+		val, err = evalCtx.isStudyQuotaFull(expression)
+	// Consents:
+	case "hasValidConsent":
+		val, err = evalCtx.hasValidConsent(expression)
+	case "getAcceptedConsentVersion":
+		val, err = evalCtx.getAcceptedConsentVersion(expression)
 	// Study variables:
 	case "getStudyVariableBoolean":
 		val, err = evalCtx.getStudyVariableBoolean(expression)
@@ -105,6 +121,12 @@ func ExpressionEval(expression studyTypes.Expression, evalCtx EvalContext) (val
 		val, err = evalCtx.getMessageNextTime(expression, false)
 	case "getCurrentStudySession":
 		val, err = evalCtx.getCurrentStudySession(false)
+	case "getStudyArm":
+		val, err = evalCtx.getStudyArm()
+	case "isInStudyArm":
+		val, err = evalCtx.isInStudyArm(expression)
+	case "getStudyArmAssignedAt":
+		val, err = evalCtx.getStudyArmAssignedAt()
 	// exprssions for merge participant states:
 	case "incomingState:getStudyEntryTime":
 		val, err = evalCtx.getStudyEntryTime(true)
@@ -345,13 +367,122 @@ func (ctx EvalContext) getNextStudyCounterValue(exp studyTypes.Expression) (val
 	if !ok {
 		return val, errors.New("could not cast arguments")
 	}
-	value, err := CurrentStudyEngine.studyDBService.IncrementAndGetStudyCounterValue(ctx.Event.InstanceID, ctx.Event.StudyKey, scope)
+	value, err := CurrentStudyEngine.studyDBService.IncrementAndGetStudyCounterValue(ctx.Event.InstanceID, ctx.Event.StudyKey, scope, StudyCounterIncrementOriginForEvent(ctx.ParticipantState, ctx.Event))
 	if err != nil {
 		return val, err
 	}
 	return float64(value), nil
 }
 
+func (ctx EvalContext) getStudyArm() (string, error) {
+	return ctx.ParticipantState.CurrentArmKey(), nil
+}
+
+func (ctx EvalContext) isInStudyArm(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) != 1 {
+		return val, errors.New("isInStudyArm: invalid number of arguments")
+	}
+
+	arg1, err := ctx.ExpressionArgResolver(exp.Data[0])
+	if err != nil {
+		return val, err
+	}
+	armKey, ok := arg1.(string)
+	if !ok {
+		return val, errors.New("could not cast arguments")
+	}
+	return ctx.ParticipantState.Arm != nil && ctx.ParticipantState.Arm.Key == armKey, nil
+}
+
+// getStudyArmAssignedAt returns the time of the current arm assignment, 0 if the participant is not in an arm
+func (ctx EvalContext) getStudyArmAssignedAt() (float64, error) {
+	if ctx.ParticipantState.Arm == nil {
+		return 0, nil
+	}
+	return float64(ctx.ParticipantState.Arm.AssignedAt), nil
+}
+
+func (ctx EvalContext) getStudyQuotaRemaining(exp studyTypes.Expression) (val float64, err error) {
+	if CurrentStudyEngine == nil || CurrentStudyEngine.studyDBService == nil {
+		return val, errors.New("getStudyQuotaRemaining: DB connection not available in the context")
+	}
+
+	if len(exp.Data) != 1 {
+		return val, errors.New("getStudyQuotaRemaining: invalid number of arguments")
+	}
+
+	arg1, err := ctx.ExpressionArgResolver(exp.Data[0])
+	if err != nil {
+		return val, err
+	}
+	quotaKey, ok := arg1.(string)
+	if !ok {
+		return val, errors.New("could not cast arguments")
+	}
+	remaining, err := CurrentStudyEngine.studyDBService.GetStudyQuotaRemainingCapacity(ctx.Event.InstanceID, ctx.Event.StudyKey, quotaKey)
+	if err != nil {
+		return val, err
+	}
+	return float64(remaining), nil
+}
+
+func (ctx EvalContext) isStudyQuotaFull(exp studyTypes.Expression) (val bool, err error) {
+	remaining, err := ctx.getStudyQuotaRemaining(exp)
+	if err != nil {
+		return false, err
+	}
+	return remaining <= 0, nil
+}
+
+// getActiveConsentRecord returns the participant's latest consent record if it is still accepted, nil otherwise
+func (ctx EvalContext) getActiveConsentRecord(exp studyTypes.Expression) (*studyTypes.ConsentRecord, string, error) {
+	if CurrentStudyEngine == nil || CurrentStudyEngine.studyDBService == nil {
+		return nil, "", errors.New("consent expressions: DB connection not available in the context")
+	}
+
+	if len(exp.Data) != 1 {
+		return nil, "", errors.New("consent expressions: invalid number of arguments")
+	}
+	consentKey, err := ctx.mustGetStrValue(exp.Data[0])
+	if err != nil {
+		return nil, "", err
+	}
+
+	record, err := CurrentStudyEngine.studyDBService.GetLatestConsentRecord(ctx.Event.InstanceID, ctx.Event.StudyKey, ctx.ParticipantState.ParticipantID, consentKey)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, consentKey, nil
+		}
+		return nil, consentKey, err
+	}
+	if record.Status != studyTypes.CONSENT_RECORD_STATUS_ACCEPTED {
+		return nil, consentKey, nil
+	}
+	return &record, consentKey, nil
+}
+
+// hasValidConsent checks if the participant accepted a version of the consent that satisfies the current version
+func (ctx EvalContext) hasValidConsent(exp studyTypes.Expression) (val bool, err error) {
+	record, consentKey, err := ctx.getActiveConsentRecord(exp)
+	if err != nil || record == nil {
+		return false, err
+	}
+
+	doc, err := CurrentStudyEngine.studyDBService.GetLatestConsentDocument(ctx.Event.InstanceID, ctx.Event.StudyKey, consentKey)
+	if err != nil {
+		return false, err
+	}
+	return record.IsValidFor(doc), nil
+}
+
+func (ctx EvalContext) getAcceptedConsentVersion(exp studyTypes.Expression) (val float64, err error) {
+	record, _, err := ctx.getActiveConsentRecord(exp)
+	if err != nil || record == nil {
+		return 0, err
+	}
+	return float64(record.Version), nil
+}
+
 func (ctx EvalContext) getStudyVariable(exp studyTypes.Expression, asType studyTypes.StudyVariablesType) (val studyTypes.StudyVariables, err error) {
 	if CurrentStudyEngine == nil || CurrentStudyEngine.studyDBService == nil {
 		return val, errors.New("getStudyVariable: DB connection not available in the context")
@@ -1311,6 +1442,43 @@ func (ctx EvalContext) hasResponseKey(exp studyTypes.Expression) (val bool, err
 	return true, nil
 }
 
+func (ctx EvalContext) resolveScoreKey(exp studyTypes.Expression) (string, error) {
+	if len(exp.Data) != 1 {
+		return "", errors.New("unexpected numbers of arguments")
+	}
+	arg1, err := ctx.ExpressionArgResolver(exp.Data[0])
+	if err != nil {
+		return "", err
+	}
+	scoreKey, ok := arg1.(string)
+	if !ok {
+		return "", errors.New("could not cast arguments")
+	}
+	return scoreKey, nil
+}
+
+// hasResponseScore checks if the score could be computed for the submitted response
+func (ctx EvalContext) hasResponseScore(exp studyTypes.Expression) (val bool, err error) {
+	scoreKey, err := ctx.resolveScoreKey(exp)
+	if err != nil {
+		return val, err
+	}
+	_, ok := ctx.Event.ResponseScores[scoreKey]
+	return ok, nil
+}
+
+func (ctx EvalContext) getResponseScore(exp studyTypes.Expression) (val float64, err error) {
+	scoreKey, err := ctx.resolveScoreKey(exp)
+	if err != nil {
+		return val, err
+	}
+	val, ok := ctx.Event.ResponseScores[scoreKey]
+	if !ok {
+		return 0, errors.New("score not available")
+	}
+	return val, nil
+}
+
 func (ctx EvalContext) hasResponseKeyWithValue(exp studyTypes.Expression) (val bool, err error) {
 	if len(exp.Data) != 3 {
 		return val, errors.New("unexpected numbers of arguments")
@@ -1686,7 +1854,7 @@ func (ctx EvalContext) timestampWithOffset(exp studyTypes.Expression) (t float64
 	}
 	delta := int64(arg1.(float64))
 
-	referenceTime := Now().Unix()
+	referenceTime := ctx.Event.CurrentTime().Unix()
 	if len(exp.Data) == 2 {
 		arg2, err2 := ctx.ExpressionArgResolver(exp.Data[1])
 		if err2 != nil {
@@ -1756,7 +1924,7 @@ func (ctx EvalContext) getTsForNextStartOfMonth(exp studyTypes.Expression) (t fl
 		return t, errors.New("argument 1 should be a month name (string) or month number (float64)")
 	}
 
-	referenceTime := Now()
+	referenceTime := ctx.Event.CurrentTime()
 	if len(exp.Data) == 2 {
 		arg2, err2 := ctx.ExpressionArgResolver(exp.Data[1])
 		if err2 != nil {
@@ -1811,7 +1979,7 @@ func (ctx EvalContext) getTsForNextISOWeek(exp studyTypes.Expression) (t float64
 		return t, errors.New("argument 1 should be between 1 and 53")
 	}
 
-	referenceTime := Now()
+	referenceTime := ctx.Event.CurrentTime()
 	if len(exp.Data) == 2 {
 		arg2, err2 := ctx.ExpressionArgResolver(exp.Data[1])
 		if err2 != nil {
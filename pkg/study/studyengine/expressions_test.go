@@ -1,6 +1,7 @@
 package studyengine
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 	"time"
@@ -8,6 +9,7 @@ import (
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Reference/Lookup methods
@@ -192,11 +194,20 @@ func TestEvalHasEventPayload(t *testing.T) {
 }
 
 type MockStudyDBService struct {
-	Responses []studyTypes.SurveyResponse
-	Variables map[string]studyTypes.StudyVariables
-	Updated   []struct {
-		Key   string
-		Value any
+	Responses          []studyTypes.SurveyResponse
+	AvailableStudyCode string
+	ClaimStudyCodeErr  error
+	CounterValue       int64
+	CounterConfig      *studyTypes.StudyCounterConfig
+	QuotaRemaining     map[string]int64
+	ConsentDocuments   map[string]studyTypes.ConsentDocument
+	ConsentRecords     map[string]studyTypes.ConsentRecord // by participantID + "/" + consentKey
+	Study              studyTypes.Study
+	Variables          map[string]studyTypes.StudyVariables
+	Updated            []struct {
+		Key    string
+		Value  any
+		Origin studyTypes.StudyVariableChangeOrigin
 	}
 }
 
@@ -223,30 +234,66 @@ func (db MockStudyDBService) SaveResearcherMessage(instanceID string, studyKey s
 	return nil
 }
 
+func (db MockStudyDBService) GetStudy(instanceID string, studyKey string) (studyTypes.Study, error) {
+	return db.Study, nil
+}
+
 func (db MockStudyDBService) StudyCodeListEntryExists(instanceID string, studyKey string, listKey string, code string) (bool, error) {
 	return false, nil
 }
 
-func (db MockStudyDBService) DeleteStudyCodeListEntry(instanceID string, studyKey string, listKey string, code string) error {
-	return nil
+func (db MockStudyDBService) DrawStudyCode(instanceID string, studyKey string, listKey string, claim studyTypes.StudyCodeClaim) (string, error) {
+	return "", nil
 }
 
-func (db MockStudyDBService) DrawStudyCode(instanceID string, studyKey string, listKey string) (string, error) {
-	return "", nil
+func (db MockStudyDBService) ReserveStudyCode(instanceID string, studyKey string, listKey string, participantID string, reservedAt time.Time, reservedUntil time.Time) (string, error) {
+	return db.AvailableStudyCode, nil
+}
+
+func (db MockStudyDBService) ClaimStudyCode(instanceID string, studyKey string, listKey string, code string, claim studyTypes.StudyCodeClaim) error {
+	return db.ClaimStudyCodeErr
 }
 
 func (db MockStudyDBService) GetCurrentStudyCounterValue(instanceID string, studyKey string, scope string) (int64, error) {
 	return 0, nil
 }
 
-func (db MockStudyDBService) IncrementAndGetStudyCounterValue(instanceID string, studyKey string, scope string) (int64, error) {
-	return 0, nil
+func (db MockStudyDBService) IncrementAndGetStudyCounterValue(instanceID string, studyKey string, scope string, origin studyTypes.StudyCounterIncrementOrigin) (int64, error) {
+	return db.CounterValue, nil
+}
+
+func (db MockStudyDBService) GetStudyCounterConfig(instanceID string, studyKey string, scope string) (*studyTypes.StudyCounterConfig, error) {
+	return db.CounterConfig, nil
 }
 
 func (db MockStudyDBService) RemoveStudyCounterValue(instanceID string, studyKey string, scope string) error {
 	return nil
 }
 
+func (db MockStudyDBService) GetLatestConsentDocument(instanceID string, studyKey string, consentKey string) (studyTypes.ConsentDocument, error) {
+	doc, ok := db.ConsentDocuments[consentKey]
+	if !ok {
+		return doc, mongo.ErrNoDocuments
+	}
+	return doc, nil
+}
+
+func (db MockStudyDBService) GetLatestConsentRecord(instanceID string, studyKey string, participantID string, consentKey string) (studyTypes.ConsentRecord, error) {
+	record, ok := db.ConsentRecords[participantID+"/"+consentKey]
+	if !ok {
+		return record, mongo.ErrNoDocuments
+	}
+	return record, nil
+}
+
+func (db MockStudyDBService) GetStudyQuotaRemainingCapacity(instanceID string, studyKey string, quotaKey string) (int64, error) {
+	remaining, ok := db.QuotaRemaining[quotaKey]
+	if !ok {
+		return 0, errors.New("quota not found")
+	}
+	return remaining, nil
+}
+
 func (db MockStudyDBService) GetStudyVariableByStudyKeyAndKey(instanceID string, studyKey string, key string, onlyValue bool) (studyTypes.StudyVariables, error) {
 	if db.Variables == nil {
 		return studyTypes.StudyVariables{}, nil
@@ -258,12 +305,13 @@ func (db MockStudyDBService) GetStudyVariableByStudyKeyAndKey(instanceID string,
 	return v, nil
 }
 
-func (db *MockStudyDBService) UpdateStudyVariableValue(instanceID string, studyKey string, key string, value any) (studyTypes.StudyVariables, error) {
+func (db *MockStudyDBService) UpdateStudyVariableValue(instanceID string, studyKey string, key string, value any, origin studyTypes.StudyVariableChangeOrigin) (studyTypes.StudyVariables, error) {
 	// Note: Using value receiver; copy then append to Updated for assertions
 	entry := struct {
-		Key   string
-		Value any
-	}{Key: key, Value: value}
+		Key    string
+		Value  any
+		Origin studyTypes.StudyVariableChangeOrigin
+	}{Key: key, Value: value, Origin: origin}
 	db.Updated = append(db.Updated, entry)
 	return studyTypes.StudyVariables{}, nil
 }
@@ -1213,6 +1261,47 @@ func TestEvalHasResponseKeyWithValue(t *testing.T) {
 		}
 	})
 }
+func TestEvalResponseScores(t *testing.T) {
+	testEvalContext := EvalContext{
+		Event: StudyEvent{
+			Type:           "SUBMIT",
+			ResponseScores: map[string]float64{"phq9": 12},
+		},
+	}
+
+	t.Run("has score", func(t *testing.T) {
+		exp := studyTypes.Expression{Name: "hasResponseScore", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "phq9"}}}
+		ret, err := ExpressionEval(exp, testEvalContext)
+		if err != nil || !ret.(bool) {
+			t.Errorf("unexpected result: %v, %v", ret, err)
+		}
+	})
+
+	t.Run("missing score", func(t *testing.T) {
+		exp := studyTypes.Expression{Name: "hasResponseScore", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "gad7"}}}
+		ret, err := ExpressionEval(exp, testEvalContext)
+		if err != nil || ret.(bool) {
+			t.Errorf("unexpected result: %v, %v", ret, err)
+		}
+	})
+
+	t.Run("get score", func(t *testing.T) {
+		exp := studyTypes.Expression{Name: "getResponseScore", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "phq9"}}}
+		ret, err := ExpressionEval(exp, testEvalContext)
+		if err != nil || ret.(float64) != 12 {
+			t.Errorf("unexpected result: %v, %v", ret, err)
+		}
+	})
+
+	t.Run("get missing score", func(t *testing.T) {
+		exp := studyTypes.Expression{Name: "getResponseScore", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "gad7"}}}
+		_, err := ExpressionEval(exp, testEvalContext)
+		if err == nil {
+			t.Error("should return an error")
+		}
+	})
+}
+
 func TestEvalGetResponseValueAsNum(t *testing.T) {
 	testEvalContext := EvalContext{
 		Event: StudyEvent{
@@ -2704,6 +2793,22 @@ func TestEvalTimestampWithOffset(t *testing.T) {
 		}
 	})
 
+	t.Run("T + 10 with event timestamp", func(t *testing.T) {
+		eventTime := time.Now().Unix() - 86400
+		exp := studyTypes.Expression{Name: "timestampWithOffset", Data: []studyTypes.ExpressionArg{
+			{DType: "num", Num: 10},
+		}}
+		EvalContext := EvalContext{Event: StudyEvent{Timestamp: eventTime}}
+		ret, err := ExpressionEval(exp, EvalContext)
+		if err != nil {
+			t.Errorf("unexpected error: %s", err.Error())
+			return
+		}
+		if int64(ret.(float64)) != eventTime+10 {
+			t.Errorf("unexpected value: %d - expected %d", ret, eventTime+10)
+		}
+	})
+
 	t.Run("T + No num", func(t *testing.T) {
 		exp := studyTypes.Expression{Name: "timestampWithOffset", Data: []studyTypes.ExpressionArg{
 			{DType: "str", Str: "0"},
@@ -3604,6 +3709,142 @@ func TestStudyVariableExpressions(t *testing.T) {
 	})
 }
 
+func TestStudyArmExpressions(t *testing.T) {
+	evalCtx := EvalContext{
+		ParticipantState: studyTypes.Participant{
+			Arm: &studyTypes.ParticipantArmAssignment{Key: "control", AssignedAt: 1700000000},
+		},
+	}
+
+	t.Run("getStudyArm", func(t *testing.T) {
+		v, err := ExpressionEval(studyTypes.Expression{Name: "getStudyArm"}, evalCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v != "control" {
+			t.Errorf("unexpected value: %#v", v)
+		}
+	})
+
+	t.Run("isInStudyArm", func(t *testing.T) {
+		exp := studyTypes.Expression{Name: "isInStudyArm", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "control"}}}
+		v, err := ExpressionEval(exp, evalCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vb, ok := v.(bool); !ok || !vb {
+			t.Errorf("unexpected value: %#v", v)
+		}
+
+		exp.Data[0].Str = "intervention"
+		v, err = ExpressionEval(exp, evalCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vb, ok := v.(bool); !ok || vb {
+			t.Errorf("unexpected value: %#v", v)
+		}
+	})
+
+	t.Run("getStudyArmAssignedAt without arm", func(t *testing.T) {
+		v, err := ExpressionEval(studyTypes.Expression{Name: "getStudyArmAssignedAt"}, EvalContext{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v != float64(0) {
+			t.Errorf("unexpected value: %#v", v)
+		}
+	})
+}
+
+func TestConsentExpressions(t *testing.T) {
+	CurrentStudyEngine = &StudyEngine{
+		studyDBService: &MockStudyDBService{
+			ConsentDocuments: map[string]studyTypes.ConsentDocument{
+				"main":     {ConsentKey: "main", Version: 3, MinimumAcceptedVersion: 2},
+				"optional": {ConsentKey: "optional", Version: 1, MinimumAcceptedVersion: 1},
+			},
+			ConsentRecords: map[string]studyTypes.ConsentRecord{
+				"p1/main":     {ConsentKey: "main", Version: 2, Status: studyTypes.CONSENT_RECORD_STATUS_ACCEPTED},
+				"p2/main":     {ConsentKey: "main", Version: 1, Status: studyTypes.CONSENT_RECORD_STATUS_ACCEPTED},
+				"p1/optional": {ConsentKey: "optional", Version: 1, Status: studyTypes.CONSENT_RECORD_STATUS_WITHDRAWN},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		participantID string
+		expName       string
+		consentKey    string
+		expected      any
+	}{
+		{name: "accepted version still valid", participantID: "p1", expName: "hasValidConsent", consentKey: "main", expected: true},
+		{name: "re-consent required", participantID: "p2", expName: "hasValidConsent", consentKey: "main", expected: false},
+		{name: "withdrawn", participantID: "p1", expName: "hasValidConsent", consentKey: "optional", expected: false},
+		{name: "never accepted", participantID: "p3", expName: "hasValidConsent", consentKey: "main", expected: false},
+		{name: "accepted version", participantID: "p2", expName: "getAcceptedConsentVersion", consentKey: "main", expected: 1.0},
+		{name: "no accepted version after withdrawal", participantID: "p1", expName: "getAcceptedConsentVersion", consentKey: "optional", expected: 0.0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			evalCtx := EvalContext{
+				Event:            StudyEvent{InstanceID: "i1", StudyKey: "s1"},
+				ParticipantState: studyTypes.Participant{ParticipantID: tc.participantID},
+			}
+			exp := studyTypes.Expression{Name: tc.expName, Data: []studyTypes.ExpressionArg{{DType: "str", Str: tc.consentKey}}}
+			v, err := ExpressionEval(exp, evalCtx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if v != tc.expected {
+				t.Errorf("unexpected value: %#v, expected %#v", v, tc.expected)
+			}
+		})
+	}
+}
+
+func TestStudyQuotaExpressions(t *testing.T) {
+	CurrentStudyEngine = &StudyEngine{
+		studyDBService: &MockStudyDBService{
+			QuotaRemaining: map[string]int64{"siteA": 3, "siteB": 0},
+		},
+	}
+
+	evalCtx := EvalContext{Event: StudyEvent{InstanceID: "i1", StudyKey: "s1"}}
+
+	t.Run("getStudyQuotaRemaining", func(t *testing.T) {
+		exp := studyTypes.Expression{Name: "getStudyQuotaRemaining", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "siteA"}}}
+		v, err := ExpressionEval(exp, evalCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vf, ok := v.(float64); !ok || vf != 3 {
+			t.Fatalf("unexpected value: %#v", v)
+		}
+	})
+
+	t.Run("isStudyQuotaFull", func(t *testing.T) {
+		exp := studyTypes.Expression{Name: "isStudyQuotaFull", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "siteB"}}}
+		v, err := ExpressionEval(exp, evalCtx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if vb, ok := v.(bool); !ok || !vb {
+			t.Fatalf("unexpected value: %#v", v)
+		}
+	})
+
+	t.Run("unknown quota", func(t *testing.T) {
+		exp := studyTypes.Expression{Name: "getStudyQuotaRemaining", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "siteC"}}}
+		_, err := ExpressionEval(exp, evalCtx)
+		if err == nil {
+			t.Fatal("expected error for unknown quota")
+		}
+	})
+}
+
 func TestNow(t *testing.T) {
 	t.Run("testing now", func(t *testing.T) {
 		cur := time.Now()
@@ -11,7 +11,12 @@ import (
 	participantuser "github.com/case-framework/case-backend/pkg/db/participant-user"
 	studydb "github.com/case-framework/case-backend/pkg/db/study"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/inbox"
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/study/calendar"
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	umTypes "github.com/case-framework/case-backend/pkg/user-management/types"
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
 )
@@ -49,6 +54,7 @@ func NewStudyMessageSender(
 }
 
 // SendInstantStudyEmail prepares and sends an email immediately using a study template.
+// Participants without email address receive the SMS template with the same message type instead.
 func (s *StudyMessageSender) SendInstantStudyEmail(
 	instanceID string,
 	studyKey string,
@@ -61,38 +67,20 @@ func (s *StudyMessageSender) SendInstantStudyEmail(
 		return errors.New("sender not initialized correctly")
 	}
 
-	// Map confidential participant id to profile id
-	profileID, err := s.studyDB.GetProfileIDFromConfidentialID(instanceID, confidentialPID, studyKey)
-	if err != nil || profileID == "" {
-		slog.Error("profileID lookup failed", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("confidentialPID", confidentialPID))
-		return errors.New("profileID lookup failed")
-	}
-
-	// Load user
-	user, err := s.participantUserDB.GetUserByProfileID(instanceID, profileID)
+	user, currentProfile, err := s.getUserAndProfile(instanceID, studyKey, confidentialPID)
 	if err != nil {
-		slog.Error("user lookup failed", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("profileID", profileID), slog.String("error", err.Error()))
 		return err
 	}
 
-	// Determine recipient and profile info
+	// Determine recipient, participants without email address receive the SMS template of the study
 	email, err := user.GetEmail()
+	phone := ""
 	if err != nil {
-		slog.Error("email lookup failed", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("profileID", profileID), slog.String("error", err.Error()))
-		return err
-	}
-	to := []string{email.Email}
-
-	if len(user.Profiles) == 0 {
-		slog.Error("user has no profiles", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("userID", user.ID.Hex()))
-		return errors.New("no profiles found for user")
-	}
-
-	currentProfile := user.Profiles[0]
-	for _, p := range user.Profiles {
-		if p.ID.Hex() == profileID {
-			currentProfile = p
-			break
+		var phoneErr error
+		phone, phoneErr = user.GetMessagingPhone()
+		if phoneErr != nil {
+			slog.Error("email lookup failed", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("profileID", currentProfile.ID.Hex()), slog.String("error", err.Error()))
+			return err
 		}
 	}
 
@@ -122,11 +110,30 @@ func (s *StudyMessageSender) SendInstantStudyEmail(
 		lang = opts.LanguageOverride
 	}
 
+	if phone != "" {
+		err = sms.SendStudySMS(instanceID, phone, user.ID.Hex(), studyKey, messageType, lang, payload)
+		if err != nil {
+			slog.Error("failed to send study SMS", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("messageType", messageType), slog.String("error", err.Error()))
+		}
+		return err
+	}
+	to := []string{email.Email}
+
 	expiresAt := opts.ExpiresAt
 	if expiresAt == 0 {
 		expiresAt = time.Now().Add(time.Hour * 24).Unix()
 	}
 
+	attachments := []messagingTypes.EmailAttachment{}
+	template, err := s.messagingDB.GetStudyEmailTemplateByMessageType(instanceID, studyKey, messageType)
+	if err == nil && template.AttachCalendar {
+		if attachment, err := s.getCalendarAttachment(instanceID, studyKey, currentProfile, lang, opts.ParticipantState); err != nil {
+			slog.Error("failed to create calendar attachment", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("messageType", messageType), slog.String("error", err.Error()))
+		} else {
+			attachments = append(attachments, attachment)
+		}
+	}
+
 	// Send immediately using the templating system; default to high priority
 	err = emailsending.SendInstantEmailByTemplate(
 		instanceID,
@@ -138,6 +145,7 @@ func (s *StudyMessageSender) SendInstantStudyEmail(
 		payload,
 		false, // useLowPrio
 		expiresAt,
+		attachments...,
 	)
 	if err != nil {
 		// The email-sending module already stores to outgoing on error
@@ -146,6 +154,103 @@ func (s *StudyMessageSender) SendInstantStudyEmail(
 	return nil
 }
 
+// SendInboxMessage renders a study template into the inbox of the participant's account.
+func (s *StudyMessageSender) SendInboxMessage(
+	instanceID string,
+	studyKey string,
+	confidentialPID string,
+	messageType string,
+	extraPayload map[string]string,
+	opts studyengine.SendOptions,
+) error {
+	if s.studyDB == nil || s.participantUserDB == nil || s.messagingDB == nil {
+		return errors.New("sender not initialized correctly")
+	}
+
+	user, currentProfile, err := s.getUserAndProfile(instanceID, studyKey, confidentialPID)
+	if err != nil {
+		return err
+	}
+
+	template, err := s.messagingDB.GetStudyEmailTemplateByMessageType(instanceID, studyKey, messageType)
+	if err != nil {
+		slog.Error("failed to fetch message template", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("messageType", messageType), slog.String("error", err.Error()))
+		return err
+	}
+
+	// no login token here: the inbox is only visible to logged in users and the message is stored
+	payload := map[string]string{
+		"profileAlias": currentProfile.Alias,
+		"profileId":    currentProfile.ID.Hex(),
+	}
+	maps.Copy(payload, s.globalEmailTemplateConstants)
+	maps.Copy(payload, extraPayload)
+
+	lang := user.Account.PreferredLanguage
+	if opts.LanguageOverride != "" {
+		lang = opts.LanguageOverride
+	}
+
+	message, err := inbox.GenerateInboxMessage(*template, user.ID.Hex(), currentProfile.ID.Hex(), lang, payload, opts.SurveyKey)
+	if err != nil {
+		slog.Error("failed to generate inbox message", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("messageType", messageType), slog.String("error", err.Error()))
+		return err
+	}
+
+	_, err = s.messagingDB.AddInboxMessage(instanceID, message)
+	return err
+}
+
+// getUserAndProfile maps the confidential participant id to the account and the profile in it
+func (s *StudyMessageSender) getUserAndProfile(instanceID string, studyKey string, confidentialPID string) (umTypes.User, umTypes.Profile, error) {
+	profileID, err := s.studyDB.GetProfileIDFromConfidentialID(instanceID, confidentialPID, studyKey)
+	if err != nil || profileID == "" {
+		slog.Error("profileID lookup failed", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("confidentialPID", confidentialPID))
+		return umTypes.User{}, umTypes.Profile{}, errors.New("profileID lookup failed")
+	}
+
+	user, err := s.participantUserDB.GetUserByProfileID(instanceID, profileID)
+	if err != nil {
+		slog.Error("user lookup failed", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("profileID", profileID), slog.String("error", err.Error()))
+		return umTypes.User{}, umTypes.Profile{}, err
+	}
+
+	if len(user.Profiles) == 0 {
+		slog.Error("user has no profiles", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("userID", user.ID.Hex()))
+		return user, umTypes.Profile{}, errors.New("no profiles found for user")
+	}
+
+	currentProfile := user.Profiles[0]
+	for _, p := range user.Profiles {
+		if p.ID.Hex() == profileID {
+			currentProfile = p
+			break
+		}
+	}
+	return user, currentProfile, nil
+}
+
+// getCalendarAttachment renders the schedule of the profile in the study as .ics
+func (s *StudyMessageSender) getCalendarAttachment(instanceID string, studyKey string, profile umTypes.Profile, lang string, pState *studyTypes.Participant) (messagingTypes.EmailAttachment, error) {
+	if pState == nil {
+		return messagingTypes.EmailAttachment{}, errors.New("participant state not available")
+	}
+	study, err := s.studyDB.GetStudy(instanceID, studyKey)
+	if err != nil {
+		return messagingTypes.EmailAttachment{}, err
+	}
+
+	cal := calendar.Calendar{
+		Name:   calendar.LocalisedText(study.Props.Name, lang),
+		Events: calendar.ParticipantEvents(study, *pState, profile.ID.Hex(), "", lang, calendar.SurveyPropsFromDB(s.studyDB, instanceID, studyKey)),
+	}
+	return messagingTypes.EmailAttachment{
+		Filename:    calendar.ATTACHMENT_FILENAME,
+		ContentType: calendar.CONTENT_TYPE,
+		Content:     cal.Encode(),
+	}, nil
+}
+
 func (s *StudyMessageSender) getTemploginToken(instanceID string, user umTypes.User, studyKey string) (string, error) {
 	tempTokenInfos := umTypes.TempToken{
 		UserID:     user.ID.Hex(),
@@ -2,6 +2,7 @@ package studyengine
 
 import (
 	"log/slog"
+	"time"
 
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
@@ -18,6 +19,10 @@ const (
 	STUDY_EVENT_TYPE_LEAVE  = "LEAVE"
 )
 
+const (
+	DEFAULT_STUDY_CODE_RESERVATION_DURATION = 15 * time.Minute
+)
+
 type StudyEngine struct {
 	studyDBService   StudyDBService
 	externalServices []ExternalService
@@ -49,23 +54,38 @@ type StudyDBService interface {
 	GetResponses(instanceID string, studyKey string, filter bson.M, sort bson.M, page int64, limit int64) (responses []studyTypes.SurveyResponse, paginationInfo *studyDB.PaginationInfos, err error)
 	DeleteConfidentialResponses(instanceID string, studyKey string, participantID string, key string) (count int64, err error)
 	SaveResearcherMessage(instanceID string, studyKey string, message studyTypes.StudyMessage) error
+	GetStudy(instanceID string, studyKey string) (studyTypes.Study, error)
 	// Study code lists:
 	StudyCodeListEntryExists(instanceID string, studyKey string, listKey string, code string) (bool, error)
-	DeleteStudyCodeListEntry(instanceID string, studyKey string, listKey string, code string) error
-	DrawStudyCode(instanceID string, studyKey string, listKey string) (string, error)
+	DrawStudyCode(instanceID string, studyKey string, listKey string, claim studyTypes.StudyCodeClaim) (string, error)
+	ReserveStudyCode(instanceID string, studyKey string, listKey string, participantID string, reservedAt time.Time, reservedUntil time.Time) (string, error)
+	ClaimStudyCode(instanceID string, studyKey string, listKey string, code string, claim studyTypes.StudyCodeClaim) error
 	// Study counters:
 	GetCurrentStudyCounterValue(instanceID string, studyKey string, scope string) (int64, error)
-	IncrementAndGetStudyCounterValue(instanceID string, studyKey string, scope string) (int64, error)
+	IncrementAndGetStudyCounterValue(instanceID string, studyKey string, scope string, origin studyTypes.StudyCounterIncrementOrigin) (int64, error)
+	GetStudyCounterConfig(instanceID string, studyKey string, scope string) (*studyTypes.StudyCounterConfig, error)
 	RemoveStudyCounterValue(instanceID string, studyKey string, scope string) error
+	// Study quotas:
+	GetStudyQuotaRemainingCapacity(instanceID string, studyKey string, quotaKey string) (int64, error)
+	// Consents:
+	GetLatestConsentDocument(instanceID string, studyKey string, consentKey string) (studyTypes.ConsentDocument, error)
+	GetLatestConsentRecord(instanceID string, studyKey string, participantID string, consentKey string) (studyTypes.ConsentRecord, error)
 
 	// Study variables:
 	GetStudyVariableByStudyKeyAndKey(instanceID string, studyKey string, key string, onlyValue bool) (studyTypes.StudyVariables, error)
-	UpdateStudyVariableValue(instanceID string, studyKey string, key string, value any) (studyTypes.StudyVariables, error)
+	UpdateStudyVariableValue(instanceID string, studyKey string, key string, value any, origin studyTypes.StudyVariableChangeOrigin) (studyTypes.StudyVariables, error)
 }
 
 type ActionData struct {
 	PState          studyTypes.Participant
 	ReportsToCreate []studyTypes.Report
+	// study codes reserved while handling the event, to be confirmed once the participant state is saved
+	ReservedStudyCodes []ReservedStudyCode
+}
+
+type ReservedStudyCode struct {
+	ListKey string
+	Code    string
 }
 
 type ExternalService struct {
@@ -91,6 +111,16 @@ type StudyEvent struct {
 	EventKey                              string                    // key of the event	(for custom events)
 	MergeWithParticipant                  studyTypes.Participant    // if need to merge with other participant state, is added here
 	ParticipantIDForConfidentialResponses string
+	Timestamp                             int64              // unix time when the event happened, if it differs from the processing time (e.g. offline submissions)
+	ResponseScores                        map[string]float64 // scores computed for the submitted response
+}
+
+// CurrentTime returns the time of the event, which is the processing time unless the event carries its own timestamp
+func (e StudyEvent) CurrentTime() time.Time {
+	if e.Timestamp > 0 {
+		return time.Unix(e.Timestamp, 0)
+	}
+	return Now()
 }
 
 // EvalContext contains all the data that can be looked up by expressions
@@ -99,10 +129,12 @@ type EvalContext struct {
 	ParticipantState studyTypes.Participant
 }
 
-// SendOptions defines optional parameters for sending study emails.
+// SendOptions defines optional parameters for sending study messages.
 type SendOptions struct {
 	ExpiresAt        int64 // if message could not sent until this time, it will be discarded
 	LanguageOverride string
+	SurveyKey        string                  // inbox messages only: survey the message links to
+	ParticipantState *studyTypes.Participant // current state of the participant, e.g. for the calendar attachment
 }
 
 // StudyMessageSender abstracts immediate message sending from the study engine.
@@ -116,4 +148,12 @@ type StudyMessageSender interface {
 		extraPayload map[string]string,
 		opts SendOptions,
 	) error
+	SendInboxMessage(
+		instanceID string,
+		studyKey string,
+		confidentialPID string,
+		messageType string,
+		extraPayload map[string]string,
+		opts SendOptions,
+	) error
 }
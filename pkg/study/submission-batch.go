@@ -0,0 +1,151 @@
+package study
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/study/surveyengine"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	MAX_SUBMISSION_BATCH_SIZE = 50
+	// submittedAt values further in the future than this are rejected, small offsets are tolerated as device clock skew
+	MAX_SUBMISSION_CLOCK_SKEW = 5 * time.Minute
+	// submittedAt values older than this are rejected, responses are not kept on the device for longer
+	MAX_SUBMISSION_OFFLINE_AGE = 30 * 24 * time.Hour
+)
+
+const (
+	SUBMISSION_BATCH_ITEM_STATUS_SUBMITTED = "submitted"
+	SUBMISSION_BATCH_ITEM_STATUS_DUPLICATE = "duplicate" // already processed, e.g. by a previous attempt to upload the batch
+	SUBMISSION_BATCH_ITEM_STATUS_FAILED    = "failed"
+)
+
+var ErrSubmissionBatchTooLarge = errors.New("submission batch is too large")
+
+type SubmissionBatchItemResult struct {
+	Index        int    `json:"index"`
+	SubmissionID string `json:"submissionId"`
+	SurveyKey    string `json:"surveyKey"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+
+	ValidationErrors surveyengine.ValidationErrors `json:"validationErrors,omitempty"`
+}
+
+type SubmissionBatchResult struct {
+	Items           []SubmissionBatchItemResult `json:"items"`
+	AssignedSurveys []studyTypes.AssignedSurvey `json:"assignedSurveys"`
+}
+
+// OnSubmitResponseBatch processes responses collected offline in the given order.
+// Study rules are evaluated with the original submittedAt time of each response as the event time.
+// Every response needs a submission ID, so uploading the same batch again skips the already processed items.
+func OnSubmitResponseBatch(instanceID string, studyKey string, profileID string, responses []studyTypes.SurveyResponse) (result SubmissionBatchResult, err error) {
+	if len(responses) > MAX_SUBMISSION_BATCH_SIZE {
+		err = ErrSubmissionBatchTooLarge
+		return
+	}
+
+	study, err := getStudyIfActive(instanceID, studyKey)
+	if err != nil {
+		slog.Error("error getting study", slog.String("error", err.Error()))
+		return
+	}
+
+	participantID, confidentialID, err := ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		return
+	}
+
+	// responses cannot be submitted before the participant entered the study
+	enteredAt := int64(0)
+	if pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID); err == nil {
+		enteredAt = pState.EnteredAt
+	}
+
+	statusFilter := studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE
+	result.Items = make([]SubmissionBatchItemResult, len(responses))
+
+	for i, response := range responses {
+		item := SubmissionBatchItemResult{
+			Index:        i,
+			SubmissionID: response.SubmissionID,
+			SurveyKey:    response.Key,
+		}
+
+		if err := validateBatchItem(response, enteredAt); err != nil {
+			item.Status = SUBMISSION_BATCH_ITEM_STATUS_FAILED
+			item.Error = err.Error()
+			result.Items[i] = item
+			continue
+		}
+
+		_, err := onSubmitResponseHandler(
+			instanceID,
+			studyKey,
+			participantID,
+			confidentialID,
+			response,
+			&statusFilter,
+			response.SubmittedAt,
+		)
+		if errors.Is(err, errDuplicateSubmission) {
+			item.Status = SUBMISSION_BATCH_ITEM_STATUS_DUPLICATE
+			result.Items[i] = item
+			continue
+		}
+		if err != nil {
+			slog.Error("Error submitting response of batch", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.Int("index", i), slog.String("error", err.Error()))
+			item.Status = SUBMISSION_BATCH_ITEM_STATUS_FAILED
+			item.Error = err.Error()
+			var vErrs surveyengine.ValidationErrors
+			if errors.As(err, &vErrs) {
+				item.ValidationErrors = vErrs
+			}
+			result.Items[i] = item
+			continue
+		}
+		item.Status = SUBMISSION_BATCH_ITEM_STATUS_SUBMITTED
+		result.Items[i] = item
+	}
+
+	pState, err := studyDBService.GetParticipantByID(instanceID, studyKey, participantID)
+	if err != nil {
+		slog.Error("error getting participant state", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("participantID", participantID), slog.String("error", err.Error()))
+		return
+	}
+	result.AssignedSurveys = assignedSurveysForSubmitResult(studyKey, pState)
+	for i := range result.AssignedSurveys {
+		result.AssignedSurveys[i].ProfileID = profileID
+	}
+	return
+}
+
+func validateBatchItem(response studyTypes.SurveyResponse, enteredAt int64) error {
+	if response.SubmissionID == "" {
+		return errors.New("submission ID is required")
+	}
+	if len(response.SubmissionID) > MAX_SUBMISSION_ID_LENGTH {
+		return ErrInvalidSubmissionID
+	}
+	if response.Key == "" {
+		return errors.New("survey key is required")
+	}
+	if response.SubmittedAt <= 0 {
+		return errors.New("submittedAt is required")
+	}
+	if time.Unix(response.SubmittedAt, 0).After(time.Now().Add(MAX_SUBMISSION_CLOCK_SKEW)) {
+		return errors.New("submittedAt is in the future")
+	}
+	if time.Unix(response.SubmittedAt, 0).Before(time.Now().Add(-MAX_SUBMISSION_OFFLINE_AGE)) {
+		return errors.New("submittedAt is too old")
+	}
+	if response.SubmittedAt < enteredAt {
+		return errors.New("submittedAt is before the participant entered the study")
+	}
+	return nil
+}
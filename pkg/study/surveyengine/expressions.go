@@ -0,0 +1,770 @@
+package surveyengine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// ErrUnsupportedExpression is returned for expressions that are only available in the survey client (e.g. rendering state)
+var ErrUnsupportedExpression = errors.New("expression is not supported by the backend evaluator")
+
+// ErrMissingParticipantContext is returned for participant related expressions if the context was created from the response only
+var ErrMissingParticipantContext = errors.New("participant context is not available")
+
+// EvalContext contains the data survey item expressions (conditions, validations) can look up.
+// It mirrors the survey engine of the client, so submitted responses can be checked with the same semantics.
+type EvalContext struct {
+	Responses        map[string]*studyTypes.SurveyItemResponse // submitted item responses by item key
+	ParticipantFlags map[string]string
+	IsLoggedIn       bool
+	Now              time.Time
+
+	// item the currently evaluated validation belongs to, to resolve "this"
+	currentItem *studyTypes.SurveyItem
+	// participant flags and login state are unknown, e.g. when evaluating stored responses for an export
+	responseOnly bool
+}
+
+// NewEvalContext indexes the submitted item responses by their key
+func NewEvalContext(response studyTypes.SurveyResponse, participantFlags map[string]string, isLoggedIn bool, now time.Time) EvalContext {
+	responses := map[string]*studyTypes.SurveyItemResponse{}
+	var addItems func(items []studyTypes.SurveyItemResponse)
+	addItems = func(items []studyTypes.SurveyItemResponse) {
+		for i := range items {
+			responses[items[i].Key] = &items[i]
+			addItems(items[i].Items)
+		}
+	}
+	addItems(response.Responses)
+
+	return EvalContext{
+		Responses:        responses,
+		ParticipantFlags: participantFlags,
+		IsLoggedIn:       isLoggedIn,
+		Now:              now,
+	}
+}
+
+// NewResponseOnlyEvalContext can be used when the participant state at the time of the submission is not known.
+// Expressions depending on the participant return ErrMissingParticipantContext.
+func NewResponseOnlyEvalContext(response studyTypes.SurveyResponse) EvalContext {
+	ctx := NewEvalContext(response, nil, false, time.Unix(response.SubmittedAt, 0))
+	ctx.responseOnly = true
+	return ctx
+}
+
+// withOwnResponses copies the response index, so it can be modified without affecting the original context
+func (ctx EvalContext) withOwnResponses() EvalContext {
+	responses := make(map[string]*studyTypes.SurveyItemResponse, len(ctx.Responses))
+	for k, v := range ctx.Responses {
+		responses[k] = v
+	}
+	ctx.Responses = responses
+	return ctx
+}
+
+func (ctx EvalContext) withCurrentItem(item *studyTypes.SurveyItem) EvalContext {
+	ctx.currentItem = item
+	return ctx
+}
+
+// EvalBool evaluates an expression and interprets the result like the survey client does (nil, false, 0 and "" are false)
+func (ctx EvalContext) EvalBool(exp *studyTypes.Expression) (bool, error) {
+	if exp == nil {
+		return true, nil
+	}
+	val, err := ExpressionEval(*exp, ctx)
+	if err != nil {
+		return false, err
+	}
+	return isTruthy(val), nil
+}
+
+func ExpressionEval(expression studyTypes.Expression, evalCtx EvalContext) (val interface{}, err error) {
+	switch expression.Name {
+	// Logical and comparisions:
+	case "and":
+		val, err = evalCtx.and(expression)
+	case "or":
+		val, err = evalCtx.or(expression)
+	case "not":
+		val, err = evalCtx.not(expression)
+	case "eq":
+		val, err = evalCtx.compare(expression, func(c int) bool { return c == 0 })
+	case "lt":
+		val, err = evalCtx.compare(expression, func(c int) bool { return c < 0 })
+	case "lte":
+		val, err = evalCtx.compare(expression, func(c int) bool { return c <= 0 })
+	case "gt":
+		val, err = evalCtx.compare(expression, func(c int) bool { return c > 0 })
+	case "gte":
+		val, err = evalCtx.compare(expression, func(c int) bool { return c >= 0 })
+	case "isDefined":
+		val, err = evalCtx.isDefined(expression)
+	// Response checkers:
+	case "hasResponse":
+		val, err = evalCtx.hasResponse(expression)
+	case "responseHasKeysAny":
+		val, err = evalCtx.responseHasKeys(expression, false)
+	case "responseHasKeysAll":
+		val, err = evalCtx.responseHasKeys(expression, true)
+	case "responseHasOnlyKeysOtherThan":
+		val, err = evalCtx.responseHasOnlyKeysOtherThan(expression)
+	case "getResponseValueAsNum":
+		val, err = evalCtx.getResponseValueAsNum(expression)
+	case "getResponseValueAsStr":
+		val, err = evalCtx.getResponseValueAsStr(expression)
+	case "countResponseItems":
+		val, err = evalCtx.countResponseItems(expression)
+	case "checkResponseValueWithRegex":
+		val, err = evalCtx.checkResponseValueWithRegex(expression)
+	case "dateResponseDiffFromNow":
+		val, err = evalCtx.dateResponseDiffFromNow(expression)
+	case "validateSelectedOptionHasValueDefined":
+		val, err = evalCtx.validateSelectedOptionHasValueDefined(expression)
+	case "getSurveyItemValidation":
+		val, err = evalCtx.getSurveyItemValidation(expression)
+	// Context:
+	case "isLoggedIn":
+		if evalCtx.responseOnly {
+			err = ErrMissingParticipantContext
+			return
+		}
+		val = evalCtx.IsLoggedIn
+	case "hasParticipantFlagKey":
+		val, err = evalCtx.hasParticipantFlagKey(expression)
+	case "hasParticipantFlagKeyAndValue":
+		val, err = evalCtx.hasParticipantFlagKeyAndValue(expression)
+	case "getParticipantFlagValue":
+		val, err = evalCtx.getParticipantFlagValue(expression)
+	// Math and other:
+	case "sum":
+		val, err = evalCtx.sum(expression)
+	case "neg":
+		val, err = evalCtx.neg(expression)
+	case "parseValueAsNum":
+		val, err = evalCtx.parseValueAsNum(expression)
+	case "timestampWithOffset":
+		val, err = evalCtx.timestampWithOffset(expression)
+	case "getSecondsSince":
+		val, err = evalCtx.getSecondsSince(expression)
+	case "regexp":
+		val, err = evalCtx.regexp(expression)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedExpression, expression.Name)
+		slog.Debug("unexpected error during survey expression eval", slog.String("error", err.Error()))
+		return
+	}
+	return
+}
+
+func (ctx EvalContext) ExpressionArgResolver(arg studyTypes.ExpressionArg) (interface{}, error) {
+	switch arg.DType {
+	case "num":
+		return arg.Num, nil
+	case "exp":
+		if arg.Exp == nil {
+			return nil, errors.New("missing argument - expected expression, but was empty")
+		}
+		return ExpressionEval(*arg.Exp, ctx)
+	case "str":
+		return arg.Str, nil
+	default:
+		return arg.Str, nil
+	}
+}
+
+func (ctx EvalContext) resolveStrArg(exp studyTypes.Expression, index int) (string, error) {
+	if len(exp.Data) <= index {
+		return "", errors.New("unexpected numbers of arguments")
+	}
+	arg, err := ctx.ExpressionArgResolver(exp.Data[index])
+	if err != nil {
+		return "", err
+	}
+	s, ok := arg.(string)
+	if !ok {
+		return "", errors.New("could not cast arguments")
+	}
+	return s, nil
+}
+
+func (ctx EvalContext) resolveNumArg(exp studyTypes.Expression, index int) (float64, error) {
+	if len(exp.Data) <= index {
+		return 0, errors.New("unexpected numbers of arguments")
+	}
+	arg, err := ctx.ExpressionArgResolver(exp.Data[index])
+	if err != nil {
+		return 0, err
+	}
+	n, ok := arg.(float64)
+	if !ok {
+		return 0, errors.New("could not cast arguments")
+	}
+	return n, nil
+}
+
+// findResponseItem resolves the item key ("this" for the current item) and the dot separated response path, e.g. "rg.scg"
+func (ctx EvalContext) findResponseItem(exp studyTypes.Expression) (*studyTypes.ResponseItem, error) {
+	itemKey, err := ctx.resolveStrArg(exp, 0)
+	if err != nil {
+		return nil, err
+	}
+	path, err := ctx.resolveStrArg(exp, 1)
+	if err != nil {
+		return nil, err
+	}
+	if itemKey == "this" && ctx.currentItem != nil {
+		itemKey = ctx.currentItem.Key
+	}
+
+	itemResponse, ok := ctx.Responses[itemKey]
+	if !ok || itemResponse.Response == nil {
+		return nil, nil
+	}
+	return findResponseObject(itemResponse.Response, path), nil
+}
+
+func findResponseObject(root *studyTypes.ResponseItem, path string) *studyTypes.ResponseItem {
+	keys := strings.Split(path, ".")
+	if root == nil || root.Key != keys[0] {
+		return nil
+	}
+	current := root
+	for _, k := range keys[1:] {
+		var next *studyTypes.ResponseItem
+		for _, item := range current.Items {
+			if item != nil && item.Key == k {
+				next = item
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+func isTruthy(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+func (ctx EvalContext) and(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) < 1 {
+		return val, errors.New("should have at least one argument")
+	}
+	for _, d := range exp.Data {
+		arg, err := ctx.ExpressionArgResolver(d)
+		if err != nil {
+			return val, err
+		}
+		if !isTruthy(arg) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (ctx EvalContext) or(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) < 1 {
+		return val, errors.New("should have at least one argument")
+	}
+	for _, d := range exp.Data {
+		arg, err := ctx.ExpressionArgResolver(d)
+		if err != nil {
+			return val, err
+		}
+		if isTruthy(arg) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (ctx EvalContext) not(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) != 1 {
+		return val, errors.New("should have one argument")
+	}
+	arg, err := ctx.ExpressionArgResolver(exp.Data[0])
+	if err != nil {
+		return val, err
+	}
+	return !isTruthy(arg), nil
+}
+
+func (ctx EvalContext) compare(exp studyTypes.Expression, check func(int) bool) (val bool, err error) {
+	if len(exp.Data) != 2 {
+		return val, errors.New("not expected numbers of arguments")
+	}
+	arg1, err := ctx.ExpressionArgResolver(exp.Data[0])
+	if err != nil {
+		return val, err
+	}
+	arg2, err := ctx.ExpressionArgResolver(exp.Data[1])
+	if err != nil {
+		return val, err
+	}
+
+	switch arg1Val := arg1.(type) {
+	case string:
+		arg2Val, ok := arg2.(string)
+		if !ok {
+			return val, errors.New("could not cast arguments")
+		}
+		return check(strings.Compare(arg1Val, arg2Val)), nil
+	case float64:
+		arg2Val, ok := arg2.(float64)
+		if !ok {
+			return val, errors.New("could not cast arguments")
+		}
+		c := 0
+		if arg1Val < arg2Val {
+			c = -1
+		} else if arg1Val > arg2Val {
+			c = 1
+		}
+		return check(c), nil
+	case nil:
+		// missing values (e.g. unanswered number inputs) never satisfy a comparison
+		return false, nil
+	default:
+		return val, fmt.Errorf("unexpected type %T", arg1Val)
+	}
+}
+
+func (ctx EvalContext) isDefined(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) != 1 {
+		return val, errors.New("should have one argument")
+	}
+	arg, err := ctx.ExpressionArgResolver(exp.Data[0])
+	if err != nil {
+		return false, nil
+	}
+	return arg != nil, nil
+}
+
+func (ctx EvalContext) hasResponse(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) != 2 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	return rItem != nil, nil
+}
+
+func (ctx EvalContext) responseHasKeys(exp studyTypes.Expression, all bool) (val bool, err error) {
+	if len(exp.Data) < 3 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	if rItem == nil {
+		return false, nil
+	}
+
+	for i := 2; i < len(exp.Data); i++ {
+		key, err := ctx.resolveStrArg(exp, i)
+		if err != nil {
+			return val, err
+		}
+		found := hasChildKey(rItem, key)
+		if all && !found {
+			return false, nil
+		}
+		if !all && found {
+			return true, nil
+		}
+	}
+	return all, nil
+}
+
+func (ctx EvalContext) responseHasOnlyKeysOtherThan(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) < 3 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	if rItem == nil || len(rItem.Items) == 0 {
+		return false, nil
+	}
+
+	for i := 2; i < len(exp.Data); i++ {
+		key, err := ctx.resolveStrArg(exp, i)
+		if err != nil {
+			return val, err
+		}
+		if hasChildKey(rItem, key) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func hasChildKey(rItem *studyTypes.ResponseItem, key string) bool {
+	for _, item := range rItem.Items {
+		if item != nil && item.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (ctx EvalContext) getResponseValueAsNum(exp studyTypes.Expression) (val interface{}, err error) {
+	if len(exp.Data) != 2 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	if rItem == nil || rItem.Value == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(rItem.Value, 64)
+	if err != nil {
+		return nil, nil
+	}
+	return n, nil
+}
+
+func (ctx EvalContext) getResponseValueAsStr(exp studyTypes.Expression) (val interface{}, err error) {
+	if len(exp.Data) != 2 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	if rItem == nil {
+		return nil, nil
+	}
+	return rItem.Value, nil
+}
+
+func (ctx EvalContext) countResponseItems(exp studyTypes.Expression) (val float64, err error) {
+	if len(exp.Data) != 2 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	if rItem == nil {
+		return -1, nil
+	}
+	return float64(len(rItem.Items)), nil
+}
+
+func (ctx EvalContext) checkResponseValueWithRegex(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) != 3 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	pattern, err := ctx.resolveStrArg(exp, 2)
+	if err != nil {
+		return val, err
+	}
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	if rItem == nil {
+		return false, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return val, err
+	}
+	return re.MatchString(rItem.Value), nil
+}
+
+func (ctx EvalContext) regexp(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) != 2 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	value, err := ctx.resolveStrArg(exp, 0)
+	if err != nil {
+		return val, err
+	}
+	pattern, err := ctx.resolveStrArg(exp, 1)
+	if err != nil {
+		return val, err
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return val, err
+	}
+	return re.MatchString(value), nil
+}
+
+func (ctx EvalContext) dateResponseDiffFromNow(exp studyTypes.Expression) (val interface{}, err error) {
+	if len(exp.Data) != 3 && len(exp.Data) != 4 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	unit, err := ctx.resolveStrArg(exp, 2)
+	if err != nil {
+		return val, err
+	}
+	absolute := false
+	if len(exp.Data) == 4 {
+		arg, err := ctx.ExpressionArgResolver(exp.Data[3])
+		if err != nil {
+			return val, err
+		}
+		absolute = isTruthy(arg)
+	}
+
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	if rItem == nil || rItem.Value == "" {
+		return nil, nil
+	}
+	ts, err := strconv.ParseInt(rItem.Value, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	diff, err := dateDiff(time.Unix(ts, 0).UTC(), ctx.now().UTC(), unit)
+	if err != nil {
+		return val, err
+	}
+	if absolute {
+		diff = math.Abs(diff)
+	}
+	return diff, nil
+}
+
+// dateDiff returns the number of full units between from and to, negative if from is before to
+func dateDiff(from time.Time, to time.Time, unit string) (float64, error) {
+	switch unit {
+	case "years":
+		months, _ := dateDiff(from, to, "months")
+		return float64(int(months) / 12), nil
+	case "months":
+		sign := 1
+		a, b := to, from
+		if from.Before(to) {
+			sign = -1
+			a, b = from, to
+		}
+		months := (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+		if a.AddDate(0, months, 0).After(b) {
+			months--
+		}
+		return float64(sign * months), nil
+	case "weeks":
+		return math.Trunc(from.Sub(to).Hours() / (24 * 7)), nil
+	case "days":
+		return math.Trunc(from.Sub(to).Hours() / 24), nil
+	case "hours":
+		return math.Trunc(from.Sub(to).Hours()), nil
+	case "minutes":
+		return math.Trunc(from.Sub(to).Minutes()), nil
+	case "seconds":
+		return math.Trunc(from.Sub(to).Seconds()), nil
+	default:
+		return 0, fmt.Errorf("unknown unit: %s", unit)
+	}
+}
+
+// validateSelectedOptionHasValueDefined checks that selected options carrying an input (e.g. "other, please specify") have a value
+func (ctx EvalContext) validateSelectedOptionHasValueDefined(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) != 2 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	rItem, err := ctx.findResponseItem(exp)
+	if err != nil {
+		return val, err
+	}
+	if rItem == nil {
+		return true, nil
+	}
+	for _, item := range rItem.Items {
+		if item != nil && item.Dtype != "" && item.Value == "" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (ctx EvalContext) getSurveyItemValidation(exp studyTypes.Expression) (val bool, err error) {
+	if len(exp.Data) != 2 {
+		return val, errors.New("unexpected numbers of arguments")
+	}
+	itemKey, err := ctx.resolveStrArg(exp, 0)
+	if err != nil {
+		return val, err
+	}
+	validationKey, err := ctx.resolveStrArg(exp, 1)
+	if err != nil {
+		return val, err
+	}
+	if ctx.currentItem == nil || (itemKey != "this" && itemKey != ctx.currentItem.Key) {
+		return val, fmt.Errorf("%w: validation of other items", ErrUnsupportedExpression)
+	}
+	for _, v := range ctx.currentItem.Validations {
+		if v.Key == validationKey {
+			rule := v.Rule
+			return ctx.EvalBool(&rule)
+		}
+	}
+	return val, fmt.Errorf("validation not found: %s", validationKey)
+}
+
+func (ctx EvalContext) hasParticipantFlagKey(exp studyTypes.Expression) (val bool, err error) {
+	if ctx.responseOnly {
+		return val, ErrMissingParticipantContext
+	}
+	key, err := ctx.resolveStrArg(exp, 0)
+	if err != nil {
+		return val, err
+	}
+	_, ok := ctx.ParticipantFlags[key]
+	return ok, nil
+}
+
+func (ctx EvalContext) hasParticipantFlagKeyAndValue(exp studyTypes.Expression) (val bool, err error) {
+	if ctx.responseOnly {
+		return val, ErrMissingParticipantContext
+	}
+	key, err := ctx.resolveStrArg(exp, 0)
+	if err != nil {
+		return val, err
+	}
+	value, err := ctx.resolveStrArg(exp, 1)
+	if err != nil {
+		return val, err
+	}
+	v, ok := ctx.ParticipantFlags[key]
+	return ok && v == value, nil
+}
+
+func (ctx EvalContext) getParticipantFlagValue(exp studyTypes.Expression) (val interface{}, err error) {
+	if ctx.responseOnly {
+		return val, ErrMissingParticipantContext
+	}
+	key, err := ctx.resolveStrArg(exp, 0)
+	if err != nil {
+		return val, err
+	}
+	v, ok := ctx.ParticipantFlags[key]
+	if !ok {
+		return nil, nil
+	}
+	return v, nil
+}
+
+func (ctx EvalContext) sum(exp studyTypes.Expression) (val float64, err error) {
+	for _, d := range exp.Data {
+		arg, err := ctx.ExpressionArgResolver(d)
+		if err != nil {
+			return val, err
+		}
+		switch v := arg.(type) {
+		case float64:
+			val += v
+		case nil:
+		default:
+			return val, errors.New("could not cast arguments")
+		}
+	}
+	return val, nil
+}
+
+func (ctx EvalContext) neg(exp studyTypes.Expression) (val float64, err error) {
+	n, err := ctx.resolveNumArg(exp, 0)
+	if err != nil {
+		return val, err
+	}
+	return -n, nil
+}
+
+func (ctx EvalContext) parseValueAsNum(exp studyTypes.Expression) (val interface{}, err error) {
+	if len(exp.Data) != 1 {
+		return val, errors.New("should have one argument")
+	}
+	arg, err := ctx.ExpressionArgResolver(exp.Data[0])
+	if err != nil {
+		return val, err
+	}
+	switch v := arg.(type) {
+	case float64:
+		return v, nil
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, nil
+		}
+		return n, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (ctx EvalContext) timestampWithOffset(exp studyTypes.Expression) (val float64, err error) {
+	if len(exp.Data) != 1 && len(exp.Data) != 2 {
+		return val, errors.New("should have one or two arguments")
+	}
+	delta, err := ctx.resolveNumArg(exp, 0)
+	if err != nil {
+		return val, err
+	}
+	reference := float64(ctx.now().Unix())
+	if len(exp.Data) == 2 {
+		reference, err = ctx.resolveNumArg(exp, 1)
+		if err != nil {
+			return val, err
+		}
+	}
+	return reference + delta, nil
+}
+
+func (ctx EvalContext) getSecondsSince(exp studyTypes.Expression) (val interface{}, err error) {
+	if len(exp.Data) != 1 {
+		return val, errors.New("should have one argument")
+	}
+	arg, err := ctx.ExpressionArgResolver(exp.Data[0])
+	if err != nil {
+		return val, err
+	}
+	ts, ok := arg.(float64)
+	if !ok {
+		return nil, nil
+	}
+	return float64(ctx.now().Unix()) - ts, nil
+}
+
+func (ctx EvalContext) now() time.Time {
+	if ctx.Now.IsZero() {
+		return time.Now()
+	}
+	return ctx.Now
+}
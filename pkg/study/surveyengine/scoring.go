@@ -0,0 +1,157 @@
+package surveyengine
+
+import (
+	"strconv"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// ComputeScores evaluates the score definitions of a survey version for the response.
+// Answers of items in confidential mode are not used, they are stored separately from the participant's data.
+func ComputeScores(scoring *studyTypes.SurveyScoring, response studyTypes.SurveyResponse) []studyTypes.ScoreValue {
+	if scoring == nil || len(scoring.Scores) == 0 {
+		return nil
+	}
+
+	responses := map[string]*studyTypes.SurveyItemResponse{}
+	var addItems func(items []studyTypes.SurveyItemResponse)
+	addItems = func(items []studyTypes.SurveyItemResponse) {
+		for i := range items {
+			if items[i].ConfidentialMode == "" {
+				responses[items[i].Key] = &items[i]
+			}
+			addItems(items[i].Items)
+		}
+	}
+	addItems(response.Responses)
+
+	defs := map[string]studyTypes.ScoreDefinition{}
+	for _, s := range scoring.Scores {
+		defs[s.Key] = s
+	}
+
+	computed := map[string]studyTypes.ScoreValue{}
+	inProgress := map[string]bool{}
+	var compute func(key string) studyTypes.ScoreValue
+	compute = func(key string) studyTypes.ScoreValue {
+		if v, ok := computed[key]; ok {
+			return v
+		}
+		def, ok := defs[key]
+		if !ok || inProgress[key] {
+			// unknown or circular reference, prevented by ValidateSurveyScoring
+			return studyTypes.ScoreValue{Key: key}
+		}
+		inProgress[key] = true
+		v := computeScore(def, responses, compute)
+		delete(inProgress, key)
+		computed[key] = v
+		return v
+	}
+
+	values := make([]studyTypes.ScoreValue, len(scoring.Scores))
+	for i, s := range scoring.Scores {
+		values[i] = compute(s.Key)
+	}
+	return values
+}
+
+func computeScore(
+	def studyTypes.ScoreDefinition,
+	responses map[string]*studyTypes.SurveyItemResponse,
+	scoreByKey func(key string) studyTypes.ScoreValue,
+) studyTypes.ScoreValue {
+	result := studyTypes.ScoreValue{Key: def.Key}
+
+	sum := 0.0
+	answered := 0
+	for _, item := range def.Items {
+		var v float64
+		var ok bool
+		if item.ScoreKey != "" {
+			s := scoreByKey(item.ScoreKey)
+			if s.Value != nil {
+				v, ok = *s.Value, true
+			}
+		} else {
+			v, ok = itemValue(item, responses[item.ItemKey])
+		}
+		if !ok {
+			result.Missing++
+			continue
+		}
+		if item.Reverse {
+			v = item.Min + item.Max - v
+		}
+		if item.Weight != nil {
+			v *= *item.Weight
+		}
+		sum += v
+		answered++
+	}
+
+	if answered == 0 ||
+		(def.MaxMissing >= 0 && result.Missing > def.MaxMissing) ||
+		(def.Missing == studyTypes.SCORE_MISSING_INVALID && result.Missing > 0) {
+		return result
+	}
+
+	raw := sum
+	switch {
+	case def.Aggregation == studyTypes.SCORE_AGGREGATION_MEAN:
+		raw = sum / float64(answered)
+	case def.Missing == studyTypes.SCORE_MISSING_PRORATE:
+		raw = sum * float64(len(def.Items)) / float64(answered)
+	}
+
+	if len(def.Lookup) == 0 {
+		result.Value = &raw
+		return result
+	}
+	result.RawValue = &raw
+	for _, l := range def.Lookup {
+		if raw >= l.From && raw <= l.To {
+			value := l.Value
+			result.Value = &value
+			break
+		}
+	}
+	return result
+}
+
+// itemValue reads the value of an answer: the configured values of the selected options,
+// or the value of the response (e.g. number input) or the key of the selected option as number.
+func itemValue(item studyTypes.ScoreItem, itemResponse *studyTypes.SurveyItemResponse) (float64, bool) {
+	if itemResponse == nil || itemResponse.Response == nil {
+		return 0, false
+	}
+	rObj := findResponseObject(itemResponse.Response, item.ResponseKey)
+	if rObj == nil {
+		return 0, false
+	}
+
+	if len(item.OptionValues) > 0 {
+		sum := 0.0
+		found := false
+		for _, option := range rObj.Items {
+			if option == nil {
+				continue
+			}
+			if v, ok := item.OptionValues[option.Key]; ok {
+				sum += v
+				found = true
+			}
+		}
+		return sum, found
+	}
+
+	if rObj.Value != "" {
+		v, err := strconv.ParseFloat(rObj.Value, 64)
+		return v, err == nil
+	}
+	if len(rObj.Items) == 1 && rObj.Items[0] != nil {
+		v, err := strconv.ParseFloat(rObj.Items[0].Key, 64)
+		return v, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,119 @@
+package surveyengine
+
+import (
+	"testing"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func singleChoice(key string, option string) studyTypes.SurveyItemResponse {
+	return studyTypes.SurveyItemResponse{Key: key, Response: &studyTypes.ResponseItem{Key: "rg", Items: []*studyTypes.ResponseItem{
+		{Key: "scg", Items: []*studyTypes.ResponseItem{{Key: option}}},
+	}}}
+}
+
+func scoreByKey(t *testing.T, scores []studyTypes.ScoreValue, key string) studyTypes.ScoreValue {
+	t.Helper()
+	for _, s := range scores {
+		if s.Key == key {
+			return s
+		}
+	}
+	t.Fatalf("score %s not found", key)
+	return studyTypes.ScoreValue{}
+}
+
+func expectScore(t *testing.T, s studyTypes.ScoreValue, expected float64) {
+	t.Helper()
+	if s.Value == nil {
+		t.Errorf("score %s: expected %v, got nil", s.Key, expected)
+		return
+	}
+	if *s.Value != expected {
+		t.Errorf("score %s: expected %v, got %v", s.Key, expected, *s.Value)
+	}
+}
+
+func TestComputeScores(t *testing.T) {
+	double := 2.0
+	item := func(key string) studyTypes.ScoreItem {
+		return studyTypes.ScoreItem{ItemKey: key, ResponseKey: "rg.scg"}
+	}
+
+	scoring := &studyTypes.SurveyScoring{
+		Scores: []studyTypes.ScoreDefinition{
+			{Key: "total", MaxMissing: -1, Items: []studyTypes.ScoreItem{{ScoreKey: "subA"}, {ScoreKey: "subB"}}},
+			{Key: "subA", MaxMissing: 0, Items: []studyTypes.ScoreItem{item("s.q1"), item("s.q2")}},
+			{Key: "subB", MaxMissing: 1, Missing: studyTypes.SCORE_MISSING_PRORATE, Items: []studyTypes.ScoreItem{
+				{ItemKey: "s.q3", ResponseKey: "rg.scg", Reverse: true, Min: 0, Max: 3},
+				{ItemKey: "s.q4", ResponseKey: "rg.scg", Weight: &double},
+			}},
+			{Key: "mean", MaxMissing: -1, Aggregation: studyTypes.SCORE_AGGREGATION_MEAN, Items: []studyTypes.ScoreItem{item("s.q1"), item("s.q2")}},
+			{Key: "labels", MaxMissing: 0, Items: []studyTypes.ScoreItem{
+				{ItemKey: "s.q5", ResponseKey: "rg.scg", OptionValues: map[string]float64{"never": 0, "often": 5}},
+			}},
+			{Key: "tscore", MaxMissing: 0, Items: []studyTypes.ScoreItem{item("s.q1"), item("s.q2")}, Lookup: []studyTypes.ScoreLookupEntry{
+				{From: 0, To: 2, Value: 40},
+				{From: 3, To: 6, Value: 60},
+			}},
+		},
+	}
+
+	t.Run("all items answered", func(t *testing.T) {
+		response := studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{
+			singleChoice("s.q1", "1"),
+			singleChoice("s.q2", "3"),
+			singleChoice("s.q3", "1"),
+			singleChoice("s.q4", "2"),
+			singleChoice("s.q5", "often"),
+		}}
+		scores := ComputeScores(scoring, response)
+		expectScore(t, scoreByKey(t, scores, "subA"), 4)
+		expectScore(t, scoreByKey(t, scores, "subB"), 2+4)
+		expectScore(t, scoreByKey(t, scores, "total"), 10)
+		expectScore(t, scoreByKey(t, scores, "mean"), 2)
+		expectScore(t, scoreByKey(t, scores, "labels"), 5)
+		tscore := scoreByKey(t, scores, "tscore")
+		expectScore(t, tscore, 60)
+		if tscore.RawValue == nil || *tscore.RawValue != 4 {
+			t.Errorf("unexpected raw value: %v", tscore.RawValue)
+		}
+	})
+
+	t.Run("missing items", func(t *testing.T) {
+		response := studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{
+			singleChoice("s.q1", "1"),
+			singleChoice("s.q4", "1"),
+			singleChoice("s.q5", "sometimes"),
+		}}
+		scores := ComputeScores(scoring, response)
+
+		subA := scoreByKey(t, scores, "subA")
+		if subA.Value != nil || subA.Missing != 1 {
+			t.Errorf("subA should not be computed: %+v", subA)
+		}
+		// prorated: 2 * 2 / 1
+		expectScore(t, scoreByKey(t, scores, "subB"), 4)
+		// subA is missing, no limit for the total
+		expectScore(t, scoreByKey(t, scores, "total"), 4)
+		expectScore(t, scoreByKey(t, scores, "mean"), 1)
+		if s := scoreByKey(t, scores, "labels"); s.Value != nil {
+			t.Errorf("option without value should count as missing: %+v", s)
+		}
+	})
+
+	t.Run("confidential items are ignored", func(t *testing.T) {
+		q5 := singleChoice("s.q5", "often")
+		q5.ConfidentialMode = "replace"
+		scores := ComputeScores(scoring, studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{q5}})
+		if s := scoreByKey(t, scores, "labels"); s.Value != nil {
+			t.Errorf("confidential item should not be scored: %+v", s)
+		}
+	})
+
+	t.Run("no scoring", func(t *testing.T) {
+		if scores := ComputeScores(nil, studyTypes.SurveyResponse{}); scores != nil {
+			t.Errorf("unexpected scores: %v", scores)
+		}
+	})
+}
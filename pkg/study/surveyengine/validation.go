@@ -0,0 +1,294 @@
+package surveyengine
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+const (
+	VALIDATION_ERROR_UNKNOWN_SURVEY_VERSION = "unknown_survey_version"
+	VALIDATION_ERROR_UNKNOWN_ITEM           = "unknown_item"
+	VALIDATION_ERROR_UNKNOWN_OPTION         = "unknown_option"
+	VALIDATION_ERROR_TYPE_MISMATCH          = "type_mismatch"
+	VALIDATION_ERROR_OUT_OF_RANGE           = "out_of_range"
+	VALIDATION_ERROR_INVALID_SELECTION      = "invalid_selection"
+	VALIDATION_ERROR_HARD_VALIDATION        = "hard_validation_failed"
+)
+
+const (
+	SURVEY_ITEM_COMPONENT_ROLE_RESPONSE_GROUP = "responseGroup"
+	VALIDATION_TYPE_HARD                      = "hard"
+)
+
+// ValidationError describes why a part of a submitted response was rejected
+type ValidationError struct {
+	ItemKey       string `json:"itemKey,omitempty"`
+	ResponseKey   string `json:"responseKey,omitempty"` // path inside the item response, e.g. "rg.scg.2"
+	ValidationKey string `json:"validationKey,omitempty"`
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+}
+
+// ValidationErrors is returned when a response is rejected in strict mode
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ve := range e {
+		msgs[i] = fmt.Sprintf("%s: %s", ve.ItemKey, ve.Message)
+	}
+	return "invalid response: " + strings.Join(msgs, "; ")
+}
+
+// roles with option children that must match the survey definition one to one
+var optionGroupRoles = map[string]bool{
+	SURVEY_ITEM_COMPONENT_ROLE_RESPONSE_GROUP: true,
+	"singleChoiceGroup":                       true,
+	"multipleChoiceGroup":                     true,
+	"dropDownGroup":                           true,
+	"likert":                                  true,
+	"likertGroup":                             true,
+	"cloze":                                   true,
+}
+
+// roles where at most one option can be selected
+var singleSelectionRoles = map[string]bool{
+	"singleChoiceGroup": true,
+	"dropDownGroup":     true,
+	"likert":            true,
+}
+
+// roles with a numeric value
+var numericValueRoles = map[string]bool{
+	"numberInput":           true,
+	"sliderNumeric":         true,
+	"timeInput":             true,
+	"dateInput":             true,
+	"eq5d-health-indicator": true,
+}
+
+// ValidateResponse checks a submitted response against the survey version it was filled out for:
+// item and option keys must exist in the definition, values must match the component types,
+// and hard validations of displayed items must hold.
+func ValidateResponse(survey *studyTypes.Survey, response studyTypes.SurveyResponse, evalCtx EvalContext) ValidationErrors {
+	errs := ValidationErrors{}
+	items := IndexSurveyItems(survey.SurveyDefinition)
+
+	var checkItems func(responses []studyTypes.SurveyItemResponse)
+	checkItems = func(responses []studyTypes.SurveyItemResponse) {
+		for _, r := range responses {
+			def, ok := items[r.Key]
+			if !ok {
+				errs = append(errs, ValidationError{ItemKey: r.Key, Code: VALIDATION_ERROR_UNKNOWN_ITEM, Message: "item is not part of the survey"})
+				continue
+			}
+			checkItems(r.Items)
+			if r.Response != nil {
+				errs = append(errs, checkItemResponse(def.Item, r.Response)...)
+			}
+		}
+	}
+	checkItems(response.Responses)
+
+	for _, key := range items.Keys() {
+		entry := items[key]
+		if !hasHardValidation(entry.Item) {
+			continue
+		}
+		displayed, err := items.IsDisplayed(key, evalCtx)
+		if err != nil {
+			// if the item's visibility cannot be decided, its validations are not enforced
+			slog.Debug("cannot evaluate item condition", slog.String("itemKey", key), slog.String("error", err.Error()))
+			continue
+		}
+		if !displayed {
+			continue
+		}
+		itemCtx := evalCtx.withCurrentItem(entry.Item)
+		for _, v := range entry.Item.Validations {
+			if v.Type != VALIDATION_TYPE_HARD {
+				continue
+			}
+			rule := v.Rule
+			ok, err := itemCtx.EvalBool(&rule)
+			if err != nil {
+				slog.Debug("cannot evaluate validation", slog.String("itemKey", key), slog.String("validationKey", v.Key), slog.String("error", err.Error()))
+				continue
+			}
+			if !ok {
+				errs = append(errs, ValidationError{ItemKey: key, ValidationKey: v.Key, Code: VALIDATION_ERROR_HARD_VALIDATION, Message: "hard validation failed"})
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func hasHardValidation(item *studyTypes.SurveyItem) bool {
+	for _, v := range item.Validations {
+		if v.Type == VALIDATION_TYPE_HARD {
+			return true
+		}
+	}
+	return false
+}
+
+func getResponseGroupComponent(item *studyTypes.SurveyItem) *studyTypes.ItemComponent {
+	if item.Components == nil {
+		return nil
+	}
+	for i := range item.Components.Items {
+		if item.Components.Items[i].Role == SURVEY_ITEM_COMPONENT_ROLE_RESPONSE_GROUP {
+			return &item.Components.Items[i]
+		}
+	}
+	return nil
+}
+
+func checkItemResponse(item *studyTypes.SurveyItem, response *studyTypes.ResponseItem) ValidationErrors {
+	rg := getResponseGroupComponent(item)
+	if rg == nil || rg.Key != response.Key {
+		return ValidationErrors{{ItemKey: item.Key, ResponseKey: response.Key, Code: VALIDATION_ERROR_UNKNOWN_OPTION, Message: "item does not accept this response"}}
+	}
+	return checkResponseAgainstComponent(item.Key, rg, response, response.Key)
+}
+
+func checkResponseAgainstComponent(itemKey string, comp *studyTypes.ItemComponent, response *studyTypes.ResponseItem, path string) ValidationErrors {
+	errs := ValidationErrors{}
+	role := baseRole(comp.Role)
+
+	if response.Value != "" {
+		errs = append(errs, checkValue(itemKey, comp, response, path)...)
+	}
+
+	if len(response.Items) == 0 || !optionGroupRoles[role] {
+		// nested structures of other components (matrix, arrays, contact etc.) are not mapped one to one to their children
+		return errs
+	}
+
+	if singleSelectionRoles[role] && len(response.Items) > 1 {
+		errs = append(errs, ValidationError{ItemKey: itemKey, ResponseKey: path, Code: VALIDATION_ERROR_INVALID_SELECTION, Message: "only one option can be selected"})
+	}
+
+	for _, child := range response.Items {
+		if child == nil {
+			continue
+		}
+		childPath := path + "." + child.Key
+		childComp := findChildComponent(comp, child.Key)
+		if childComp == nil {
+			errs = append(errs, ValidationError{ItemKey: itemKey, ResponseKey: childPath, Code: VALIDATION_ERROR_UNKNOWN_OPTION, Message: "option is not part of the item"})
+			continue
+		}
+		errs = append(errs, checkResponseAgainstComponent(itemKey, childComp, child, childPath)...)
+	}
+	return errs
+}
+
+func checkValue(itemKey string, comp *studyTypes.ItemComponent, response *studyTypes.ResponseItem, path string) ValidationErrors {
+	if comp.Dtype != "" && response.Dtype != "" && comp.Dtype != response.Dtype {
+		return ValidationErrors{{ItemKey: itemKey, ResponseKey: path, Code: VALIDATION_ERROR_TYPE_MISMATCH, Message: fmt.Sprintf("expected value of type %s", comp.Dtype)}}
+	}
+	if !numericValueRoles[baseRole(comp.Role)] {
+		return nil
+	}
+
+	n, err := strconv.ParseFloat(response.Value, 64)
+	if err != nil {
+		return ValidationErrors{{ItemKey: itemKey, ResponseKey: path, Code: VALIDATION_ERROR_TYPE_MISMATCH, Message: "expected a numeric value"}}
+	}
+	if comp.Properties != nil {
+		if min, ok := numProperty(comp.Properties.Min); ok && n < min {
+			return ValidationErrors{{ItemKey: itemKey, ResponseKey: path, Code: VALIDATION_ERROR_OUT_OF_RANGE, Message: fmt.Sprintf("value is smaller than %v", min)}}
+		}
+		if max, ok := numProperty(comp.Properties.Max); ok && n > max {
+			return ValidationErrors{{ItemKey: itemKey, ResponseKey: path, Code: VALIDATION_ERROR_OUT_OF_RANGE, Message: fmt.Sprintf("value is larger than %v", max)}}
+		}
+	}
+	return nil
+}
+
+// numProperty returns constant numeric properties, properties computed by expressions are not checked
+func numProperty(arg *studyTypes.ExpressionArg) (float64, bool) {
+	if arg == nil || !arg.IsNumber() {
+		return 0, false
+	}
+	return arg.Num, true
+}
+
+func findChildComponent(comp *studyTypes.ItemComponent, key string) *studyTypes.ItemComponent {
+	for i := range comp.Items {
+		if comp.Items[i].Key == key {
+			return &comp.Items[i]
+		}
+	}
+	return nil
+}
+
+// baseRole strips the variant of custom component roles, e.g. "input:email" -> "input"
+func baseRole(role string) string {
+	if i := strings.Index(role, ":"); i > 0 {
+		return role[:i]
+	}
+	return role
+}
+
+// SurveyItemEntry is an item of the survey definition with the key of its parent group
+type SurveyItemEntry struct {
+	Item      *studyTypes.SurveyItem
+	ParentKey string
+	order     int
+}
+
+// SurveyItemIndex gives access to the items of a survey definition by their full key
+type SurveyItemIndex map[string]SurveyItemEntry
+
+func IndexSurveyItems(root studyTypes.SurveyItem) SurveyItemIndex {
+	index := SurveyItemIndex{}
+	order := 0
+	var add func(item *studyTypes.SurveyItem, parentKey string)
+	add = func(item *studyTypes.SurveyItem, parentKey string) {
+		index[item.Key] = SurveyItemEntry{Item: item, ParentKey: parentKey, order: order}
+		order++
+		for i := range item.Items {
+			add(&item.Items[i], item.Key)
+		}
+	}
+	add(&root, "")
+	return index
+}
+
+// Keys returns the item keys in the order of the survey definition
+func (index SurveyItemIndex) Keys() []string {
+	keys := make([]string, 0, len(index))
+	for k := range index {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return index[keys[i]].order < index[keys[j]].order })
+	return keys
+}
+
+// IsDisplayed evaluates the conditions of the item and its parent groups
+func (index SurveyItemIndex) IsDisplayed(key string, evalCtx EvalContext) (bool, error) {
+	for key != "" {
+		entry, ok := index[key]
+		if !ok {
+			return false, errors.New("item not found")
+		}
+		shown, err := evalCtx.EvalBool(entry.Item.Condition)
+		if err != nil || !shown {
+			return false, err
+		}
+		key = entry.ParentKey
+	}
+	return true, nil
+}
@@ -0,0 +1,166 @@
+package surveyengine
+
+import (
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func testSurvey() *studyTypes.Survey {
+	requiredQ1 := studyTypes.Expression{Name: "hasResponse", Data: []studyTypes.ExpressionArg{
+		{DType: "str", Str: "this"},
+		{DType: "str", Str: "rg"},
+	}}
+	q1Selected := studyTypes.Expression{Name: "responseHasKeysAny", Data: []studyTypes.ExpressionArg{
+		{DType: "str", Str: "s.q1"},
+		{DType: "str", Str: "rg.scg"},
+		{DType: "str", Str: "yes"},
+	}}
+
+	return &studyTypes.Survey{
+		SurveyDefinition: studyTypes.SurveyItem{
+			Key: "s",
+			Items: []studyTypes.SurveyItem{
+				{
+					Key: "s.q1",
+					Components: &studyTypes.ItemComponent{Role: "root", Items: []studyTypes.ItemComponent{
+						{Role: "responseGroup", Key: "rg", Items: []studyTypes.ItemComponent{
+							{Role: "singleChoiceGroup", Key: "scg", Items: []studyTypes.ItemComponent{
+								{Role: "option", Key: "yes"},
+								{Role: "option", Key: "no"},
+							}},
+						}},
+					}},
+					Validations: []studyTypes.Validation{{Key: "r1", Type: "hard", Rule: requiredQ1}},
+				},
+				{
+					Key:       "s.q2",
+					Condition: &q1Selected,
+					Components: &studyTypes.ItemComponent{Role: "root", Items: []studyTypes.ItemComponent{
+						{Role: "responseGroup", Key: "rg", Items: []studyTypes.ItemComponent{
+							{Role: "numberInput", Key: "num", Dtype: "number", Properties: &studyTypes.ComponentProperties{
+								Min: &studyTypes.ExpressionArg{DType: "num", Num: 0},
+								Max: &studyTypes.ExpressionArg{DType: "num", Num: 10},
+							}},
+						}},
+					}},
+					Validations: []studyTypes.Validation{{Key: "r1", Type: "hard", Rule: studyTypes.Expression{Name: "hasResponse", Data: []studyTypes.ExpressionArg{
+						{DType: "str", Str: "this"},
+						{DType: "str", Str: "rg"},
+					}}}},
+				},
+			},
+		},
+	}
+}
+
+func q1Response(option string) studyTypes.SurveyItemResponse {
+	return studyTypes.SurveyItemResponse{Key: "s.q1", Response: &studyTypes.ResponseItem{Key: "rg", Items: []*studyTypes.ResponseItem{
+		{Key: "scg", Items: []*studyTypes.ResponseItem{{Key: option}}},
+	}}}
+}
+
+func q2Response(value string) studyTypes.SurveyItemResponse {
+	return studyTypes.SurveyItemResponse{Key: "s.q2", Response: &studyTypes.ResponseItem{Key: "rg", Items: []*studyTypes.ResponseItem{
+		{Key: "num", Value: value, Dtype: "number"},
+	}}}
+}
+
+func validate(items ...studyTypes.SurveyItemResponse) ValidationErrors {
+	response := studyTypes.SurveyResponse{Key: "s", Responses: items}
+	return ValidateResponse(testSurvey(), response, NewEvalContext(response, nil, true, time.Now()))
+}
+
+func expectCodes(t *testing.T, errs ValidationErrors, codes ...string) {
+	t.Helper()
+	if len(errs) != len(codes) {
+		t.Errorf("unexpected number of errors: %v", errs)
+		return
+	}
+	for i, c := range codes {
+		if errs[i].Code != c {
+			t.Errorf("unexpected error code at %d: %s, expected %s", i, errs[i].Code, c)
+		}
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	t.Run("valid response with hidden item skipped", func(t *testing.T) {
+		errs := validate(q1Response("no"))
+		expectCodes(t, errs)
+	})
+
+	t.Run("valid response with displayed item", func(t *testing.T) {
+		errs := validate(q1Response("yes"), q2Response("5"))
+		expectCodes(t, errs)
+	})
+
+	t.Run("hard validation of displayed item", func(t *testing.T) {
+		errs := validate(q1Response("yes"))
+		expectCodes(t, errs, VALIDATION_ERROR_HARD_VALIDATION)
+		if len(errs) == 1 && errs[0].ItemKey != "s.q2" {
+			t.Errorf("unexpected item key: %s", errs[0].ItemKey)
+		}
+	})
+
+	t.Run("missing required item", func(t *testing.T) {
+		errs := validate()
+		expectCodes(t, errs, VALIDATION_ERROR_HARD_VALIDATION)
+	})
+
+	t.Run("unknown item", func(t *testing.T) {
+		errs := validate(q1Response("no"), studyTypes.SurveyItemResponse{Key: "s.q99"})
+		expectCodes(t, errs, VALIDATION_ERROR_UNKNOWN_ITEM)
+	})
+
+	t.Run("unknown option", func(t *testing.T) {
+		errs := validate(q1Response("maybe"))
+		expectCodes(t, errs, VALIDATION_ERROR_UNKNOWN_OPTION)
+	})
+
+	t.Run("multiple selections for single choice", func(t *testing.T) {
+		r := q1Response("yes")
+		r.Response.Items[0].Items = append(r.Response.Items[0].Items, &studyTypes.ResponseItem{Key: "no"})
+		errs := validate(r, q2Response("1"))
+		expectCodes(t, errs, VALIDATION_ERROR_INVALID_SELECTION)
+	})
+
+	t.Run("value out of range", func(t *testing.T) {
+		errs := validate(q1Response("yes"), q2Response("11"))
+		expectCodes(t, errs, VALIDATION_ERROR_OUT_OF_RANGE)
+	})
+
+	t.Run("value not numeric", func(t *testing.T) {
+		errs := validate(q1Response("yes"), q2Response("abc"))
+		expectCodes(t, errs, VALIDATION_ERROR_TYPE_MISMATCH)
+	})
+
+	t.Run("wrong dtype", func(t *testing.T) {
+		r := q2Response("5")
+		r.Response.Items[0].Dtype = "string"
+		errs := validate(q1Response("yes"), r)
+		expectCodes(t, errs, VALIDATION_ERROR_TYPE_MISMATCH)
+	})
+}
+
+func TestIsDisplayed(t *testing.T) {
+	survey := testSurvey()
+	index := IndexSurveyItems(survey.SurveyDefinition)
+
+	response := studyTypes.SurveyResponse{Key: "s", Responses: []studyTypes.SurveyItemResponse{q1Response("yes")}}
+	shown, err := index.IsDisplayed("s.q2", NewEvalContext(response, nil, true, time.Now()))
+	if err != nil || !shown {
+		t.Errorf("expected item to be displayed: %v, %v", shown, err)
+	}
+
+	response = studyTypes.SurveyResponse{Key: "s", Responses: []studyTypes.SurveyItemResponse{q1Response("no")}}
+	shown, err = index.IsDisplayed("s.q2", NewEvalContext(response, nil, true, time.Now()))
+	if err != nil || shown {
+		t.Errorf("expected item to be hidden: %v, %v", shown, err)
+	}
+
+	if _, err := index.IsDisplayed("s.unknown", NewEvalContext(response, nil, true, time.Now())); err == nil {
+		t.Error("expected error for unknown item")
+	}
+}
@@ -0,0 +1,181 @@
+package surveyengine
+
+import (
+	"log/slog"
+	"strings"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+// HiddenResponse points to a submitted answer that was not visible given the final responses.
+// ResponseKey is empty if the whole item was hidden, otherwise it is the path of the hidden option, e.g. "rg.scg.2"
+type HiddenResponse struct {
+	ItemKey     string `json:"itemKey"`
+	ResponseKey string `json:"responseKey,omitempty"`
+}
+
+// FindHiddenItems returns the keys of the survey items that would not have been displayed for the response.
+// Items are evaluated in definition order, answers of hidden items are not considered for the conditions of later items.
+// Items whose conditions cannot be evaluated are treated as displayed.
+func FindHiddenItems(surveyDef studyTypes.SurveyItem, evalCtx EvalContext) map[string]bool {
+	hidden := map[string]bool{}
+	evalCtx = evalCtx.withOwnResponses()
+
+	var walk func(item *studyTypes.SurveyItem)
+	walk = func(item *studyTypes.SurveyItem) {
+		shown, err := evalCtx.EvalBool(item.Condition)
+		if err != nil {
+			slog.Debug("cannot evaluate item condition", slog.String("itemKey", item.Key), slog.String("error", err.Error()))
+			shown = true
+		}
+		if !shown {
+			markHidden(item, hidden, evalCtx)
+			return
+		}
+		for i := range item.Items {
+			walk(&item.Items[i])
+		}
+	}
+	walk(&surveyDef)
+	return hidden
+}
+
+func markHidden(item *studyTypes.SurveyItem, hidden map[string]bool, evalCtx EvalContext) {
+	hidden[item.Key] = true
+	delete(evalCtx.Responses, item.Key)
+	for i := range item.Items {
+		markHidden(&item.Items[i], hidden, evalCtx)
+	}
+}
+
+// FindHiddenResponses lists the answers of the response that belong to hidden items,
+// or to options hidden by their display condition.
+func FindHiddenResponses(surveyDef studyTypes.SurveyItem, response studyTypes.SurveyResponse, evalCtx EvalContext) []HiddenResponse {
+	hiddenItems := FindHiddenItems(surveyDef, evalCtx)
+	items := IndexSurveyItems(surveyDef)
+	result := []HiddenResponse{}
+
+	var check func(responses []studyTypes.SurveyItemResponse)
+	check = func(responses []studyTypes.SurveyItemResponse) {
+		for _, r := range responses {
+			if hiddenItems[r.Key] {
+				if r.Response != nil || len(r.Items) > 0 {
+					result = append(result, HiddenResponse{ItemKey: r.Key})
+				}
+				continue
+			}
+			check(r.Items)
+
+			entry, ok := items[r.Key]
+			if !ok || r.Response == nil {
+				continue
+			}
+			rg := getResponseGroupComponent(entry.Item)
+			if rg == nil || rg.Key != r.Response.Key {
+				continue
+			}
+			for _, path := range findHiddenOptions(rg, r.Response, r.Response.Key, evalCtx.withCurrentItem(entry.Item)) {
+				result = append(result, HiddenResponse{ItemKey: r.Key, ResponseKey: path})
+			}
+		}
+	}
+	check(response.Responses)
+	return result
+}
+
+func findHiddenOptions(comp *studyTypes.ItemComponent, response *studyTypes.ResponseItem, path string, evalCtx EvalContext) []string {
+	paths := []string{}
+	for _, child := range response.Items {
+		if child == nil {
+			continue
+		}
+		childComp := findChildComponent(comp, child.Key)
+		if childComp == nil {
+			continue
+		}
+		childPath := path + "." + child.Key
+		shown, err := evalCtx.EvalBool(childComp.DisplayCondition)
+		if err != nil {
+			slog.Debug("cannot evaluate display condition", slog.String("responseKey", childPath), slog.String("error", err.Error()))
+			shown = true
+		}
+		if !shown {
+			paths = append(paths, childPath)
+			continue
+		}
+		paths = append(paths, findHiddenOptions(childComp, child, childPath, evalCtx)...)
+	}
+	return paths
+}
+
+// PruneHiddenResponses removes the hidden answers from the response
+func PruneHiddenResponses(response *studyTypes.SurveyResponse, hidden []HiddenResponse) {
+	for _, h := range hidden {
+		if h.ResponseKey == "" {
+			response.Responses = removeItemResponse(response.Responses, h.ItemKey)
+			continue
+		}
+		if r := findItemResponse(response.Responses, h.ItemKey); r != nil {
+			removeResponseOption(r.Response, h.ResponseKey)
+		}
+	}
+}
+
+// FlagHiddenResponses keeps the hidden answers, but marks them on the item responses
+func FlagHiddenResponses(response *studyTypes.SurveyResponse, hidden []HiddenResponse) {
+	for _, h := range hidden {
+		r := findItemResponse(response.Responses, h.ItemKey)
+		if r == nil {
+			continue
+		}
+		if h.ResponseKey == "" {
+			r.Hidden = true
+		} else {
+			r.HiddenResponseKeys = append(r.HiddenResponseKeys, h.ResponseKey)
+		}
+	}
+}
+
+func findItemResponse(responses []studyTypes.SurveyItemResponse, key string) *studyTypes.SurveyItemResponse {
+	for i := range responses {
+		if responses[i].Key == key {
+			return &responses[i]
+		}
+		if r := findItemResponse(responses[i].Items, key); r != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+func removeItemResponse(responses []studyTypes.SurveyItemResponse, key string) []studyTypes.SurveyItemResponse {
+	result := make([]studyTypes.SurveyItemResponse, 0, len(responses))
+	for _, r := range responses {
+		if r.Key == key {
+			continue
+		}
+		r.Items = removeItemResponse(r.Items, key)
+		result = append(result, r)
+	}
+	return result
+}
+
+func removeResponseOption(root *studyTypes.ResponseItem, path string) {
+	keys := strings.Split(path, ".")
+	if root == nil || len(keys) < 2 || root.Key != keys[0] {
+		return
+	}
+	parent := findResponseObject(root, strings.Join(keys[:len(keys)-1], "."))
+	if parent == nil {
+		return
+	}
+	lastKey := keys[len(keys)-1]
+	items := parent.Items[:0]
+	for _, item := range parent.Items {
+		if item != nil && item.Key == lastKey {
+			continue
+		}
+		items = append(items, item)
+	}
+	parent.Items = items
+}
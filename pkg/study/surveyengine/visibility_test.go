@@ -0,0 +1,84 @@
+package surveyengine
+
+import (
+	"testing"
+	"time"
+
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+)
+
+func TestFindHiddenItems(t *testing.T) {
+	survey := testSurvey()
+	q2Answered := studyTypes.Expression{Name: "hasResponse", Data: []studyTypes.ExpressionArg{
+		{DType: "str", Str: "s.q2"},
+		{DType: "str", Str: "rg"},
+	}}
+	survey.SurveyDefinition.Items = append(survey.SurveyDefinition.Items, studyTypes.SurveyItem{Key: "s.q3", Condition: &q2Answered})
+
+	t.Run("condition met", func(t *testing.T) {
+		response := studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{q1Response("yes"), q2Response("1")}}
+		hidden := FindHiddenItems(survey.SurveyDefinition, NewEvalContext(response, nil, true, time.Now()))
+		if len(hidden) != 0 {
+			t.Errorf("unexpected hidden items: %v", hidden)
+		}
+	})
+
+	t.Run("answers of hidden items are not used for later conditions", func(t *testing.T) {
+		response := studyTypes.SurveyResponse{Responses: []studyTypes.SurveyItemResponse{q1Response("no"), q2Response("1")}}
+		evalCtx := NewEvalContext(response, nil, true, time.Now())
+		hidden := FindHiddenItems(survey.SurveyDefinition, evalCtx)
+		if !hidden["s.q2"] || !hidden["s.q3"] || len(hidden) != 2 {
+			t.Errorf("unexpected hidden items: %v", hidden)
+		}
+		if _, ok := evalCtx.Responses["s.q2"]; !ok {
+			t.Error("original context should not be modified")
+		}
+	})
+
+	t.Run("participant conditions without participant context", func(t *testing.T) {
+		flagCondition := studyTypes.Expression{Name: "hasParticipantFlagKey", Data: []studyTypes.ExpressionArg{{DType: "str", Str: "group"}}}
+		def := studyTypes.SurveyItem{Key: "s", Items: []studyTypes.SurveyItem{{Key: "s.q1", Condition: &flagCondition}}}
+		hidden := FindHiddenItems(def, NewResponseOnlyEvalContext(studyTypes.SurveyResponse{}))
+		if len(hidden) != 0 {
+			t.Errorf("items with unknown visibility should be treated as displayed: %v", hidden)
+		}
+	})
+}
+
+func TestHiddenResponses(t *testing.T) {
+	survey := testSurvey()
+	q1Options := &survey.SurveyDefinition.Items[0].Components.Items[0].Items[0]
+	q1Options.Items[1].DisplayCondition = &studyTypes.Expression{Name: "isLoggedIn"}
+
+	newResponse := func() studyTypes.SurveyResponse {
+		return studyTypes.SurveyResponse{Key: "s", Responses: []studyTypes.SurveyItemResponse{q1Response("no"), q2Response("3")}}
+	}
+
+	response := newResponse()
+	hidden := FindHiddenResponses(survey.SurveyDefinition, response, NewEvalContext(response, nil, false, time.Now()))
+	if len(hidden) != 2 {
+		t.Fatalf("unexpected hidden responses: %v", hidden)
+	}
+
+	t.Run("prune", func(t *testing.T) {
+		r := newResponse()
+		PruneHiddenResponses(&r, hidden)
+		if len(r.Responses) != 1 || r.Responses[0].Key != "s.q1" {
+			t.Fatalf("unexpected responses: %v", r.Responses)
+		}
+		if len(r.Responses[0].Response.Items[0].Items) != 0 {
+			t.Errorf("hidden option should be removed: %v", r.Responses[0].Response.Items[0].Items)
+		}
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		r := newResponse()
+		FlagHiddenResponses(&r, hidden)
+		if len(r.Responses) != 2 || !r.Responses[1].Hidden || r.Responses[0].Hidden {
+			t.Fatalf("unexpected responses: %v", r.Responses)
+		}
+		if len(r.Responses[0].HiddenResponseKeys) != 1 || r.Responses[0].HiddenResponseKeys[0] != "rg.scg.no" {
+			t.Errorf("unexpected hidden response keys: %v", r.Responses[0].HiddenResponseKeys)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	CONSENT_RECORD_STATUS_ACCEPTED  = "accepted"
+	CONSENT_RECORD_STATUS_WITHDRAWN = "withdrawn"
+)
+
+// custom study event fired on withdrawal if the consent document does not define its own event key
+const DEFAULT_CONSENT_WITHDRAWAL_EVENT_KEY = "consentWithdrawn"
+
+var (
+	ErrConsentDocumentNotFound   = errors.New("consent document not found")
+	ErrConsentVersionOutdated    = errors.New("consent version is not the current version")
+	ErrConsentLanguageNotFound   = errors.New("consent document not available in the requested language")
+	ErrConsentContentMismatch    = errors.New("consent content hash does not match the published document")
+	ErrNoActiveConsentToWithdraw = errors.New("no accepted consent to withdraw")
+)
+
+// ConsentDocument is one published version of a study consent, versions are immutable once published
+type ConsentDocument struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ConsentKey  string             `bson:"consentKey" json:"consentKey"`
+	Version     int                `bson:"version" json:"version"`
+	PublishedAt time.Time          `bson:"publishedAt" json:"publishedAt"`
+	PublishedBy string             `bson:"publishedBy,omitempty" json:"publishedBy,omitempty"`
+	// if true, participants who accepted an earlier version have to consent again
+	RequiresReconsent bool `bson:"requiresReconsent" json:"requiresReconsent"`
+	// oldest version that still counts as valid consent, computed on publish
+	MinimumAcceptedVersion int `bson:"minimumAcceptedVersion" json:"minimumAcceptedVersion"`
+	// key of the custom study event fired when a participant withdraws
+	WithdrawalEventKey string                   `bson:"withdrawalEventKey,omitempty" json:"withdrawalEventKey,omitempty"`
+	Contents           []ConsentDocumentContent `bson:"contents" json:"contents"`
+}
+
+type ConsentDocumentContent struct {
+	Code    string `bson:"code" json:"code"` // language code
+	Title   string `bson:"title" json:"title"`
+	Content string `bson:"content" json:"content"`
+	Hash    string `bson:"hash" json:"hash"` // SHA-256 of the content, computed on publish
+}
+
+// ConsentRecord documents that a participant accepted a specific version and translation of a consent document
+type ConsentRecord struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ParticipantID string             `bson:"participantID" json:"participantId"`
+	ConsentKey    string             `bson:"consentKey" json:"consentKey"`
+	Version       int                `bson:"version" json:"version"`
+	Language      string             `bson:"language" json:"language"`
+	ContentHash   string             `bson:"contentHash" json:"contentHash"`
+	Status        string             `bson:"status" json:"status"`
+	AcceptedAt    time.Time          `bson:"acceptedAt" json:"acceptedAt"`
+	WithdrawnAt   *time.Time         `bson:"withdrawnAt,omitempty" json:"withdrawnAt,omitempty"`
+}
+
+// IsValidFor checks if the record is an accepted consent that satisfies the given document version
+func (r ConsentRecord) IsValidFor(doc ConsentDocument) bool {
+	if r.Status != CONSENT_RECORD_STATUS_ACCEPTED || r.ConsentKey != doc.ConsentKey {
+		return false
+	}
+	return r.Version >= doc.MinimumAcceptedVersion
+}
+
+func HashConsentContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func (doc ConsentDocument) GetContent(language string) (ConsentDocumentContent, bool) {
+	for _, c := range doc.Contents {
+		if c.Code == language {
+			return c, true
+		}
+	}
+	return ConsentDocumentContent{}, false
+}
+
+// PrepareForPublish validates the document and sets the version dependent fields based on the previous version (nil for the first version)
+func (doc *ConsentDocument) PrepareForPublish(previous *ConsentDocument, publishedBy string) error {
+	if doc.ConsentKey == "" {
+		return errors.New("consent key is required")
+	}
+	if len(doc.Contents) == 0 {
+		return errors.New("consent document needs at least one translation")
+	}
+	languages := map[string]bool{}
+	for i, c := range doc.Contents {
+		if c.Code == "" {
+			return errors.New("language code is required for each translation")
+		}
+		if languages[c.Code] {
+			return fmt.Errorf("duplicate translation for language: %s", c.Code)
+		}
+		languages[c.Code] = true
+		if c.Content == "" {
+			return fmt.Errorf("content for language %s is empty", c.Code)
+		}
+		doc.Contents[i].Hash = HashConsentContent(c.Content)
+	}
+
+	doc.ID = primitive.NilObjectID
+	doc.PublishedAt = time.Now()
+	doc.PublishedBy = publishedBy
+	if previous == nil {
+		doc.Version = 1
+		doc.MinimumAcceptedVersion = 1
+		return nil
+	}
+
+	doc.Version = previous.Version + 1
+	if doc.RequiresReconsent {
+		doc.MinimumAcceptedVersion = doc.Version
+	} else {
+		doc.MinimumAcceptedVersion = previous.MinimumAcceptedVersion
+	}
+	return nil
+}
+
+func (doc ConsentDocument) GetWithdrawalEventKey() string {
+	if doc.WithdrawalEventKey == "" {
+		return DEFAULT_CONSENT_WITHDRAWAL_EVENT_KEY
+	}
+	return doc.WithdrawalEventKey
+}
@@ -0,0 +1,70 @@
+package types
+
+import "testing"
+
+func TestConsentDocumentPrepareForPublish(t *testing.T) {
+	t.Run("invalid documents", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			doc  ConsentDocument
+		}{
+			{name: "missing key", doc: ConsentDocument{Contents: []ConsentDocumentContent{{Code: "en", Content: "text"}}}},
+			{name: "no translations", doc: ConsentDocument{ConsentKey: "main"}},
+			{name: "missing language", doc: ConsentDocument{ConsentKey: "main", Contents: []ConsentDocumentContent{{Content: "text"}}}},
+			{name: "empty content", doc: ConsentDocument{ConsentKey: "main", Contents: []ConsentDocumentContent{{Code: "en"}}}},
+			{name: "duplicate language", doc: ConsentDocument{ConsentKey: "main", Contents: []ConsentDocumentContent{
+				{Code: "en", Content: "a"},
+				{Code: "en", Content: "b"},
+			}}},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if err := tc.doc.PrepareForPublish(nil, "user"); err == nil {
+					t.Error("expected error")
+				}
+			})
+		}
+	})
+
+	t.Run("version chain", func(t *testing.T) {
+		v1 := ConsentDocument{ConsentKey: "main", Contents: []ConsentDocumentContent{{Code: "en", Content: "v1"}}}
+		if err := v1.PrepareForPublish(nil, "user"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v1.Version != 1 || v1.MinimumAcceptedVersion != 1 {
+			t.Errorf("unexpected first version: %d / %d", v1.Version, v1.MinimumAcceptedVersion)
+		}
+		if v1.Contents[0].Hash != HashConsentContent("v1") {
+			t.Error("content hash not set")
+		}
+
+		// typo fix, earlier consent stays valid
+		v2 := ConsentDocument{ConsentKey: "main", Contents: []ConsentDocumentContent{{Code: "en", Content: "v2"}}}
+		if err := v2.PrepareForPublish(&v1, "user"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v2.Version != 2 || v2.MinimumAcceptedVersion != 1 {
+			t.Errorf("unexpected second version: %d / %d", v2.Version, v2.MinimumAcceptedVersion)
+		}
+
+		v3 := ConsentDocument{ConsentKey: "main", RequiresReconsent: true, Contents: []ConsentDocumentContent{{Code: "en", Content: "v3"}}}
+		if err := v3.PrepareForPublish(&v2, "user"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if v3.Version != 3 || v3.MinimumAcceptedVersion != 3 {
+			t.Errorf("unexpected third version: %d / %d", v3.Version, v3.MinimumAcceptedVersion)
+		}
+
+		record := ConsentRecord{ConsentKey: "main", Version: 2, Status: CONSENT_RECORD_STATUS_ACCEPTED}
+		if !record.IsValidFor(v2) {
+			t.Error("record should be valid for v2")
+		}
+		if record.IsValidFor(v3) {
+			t.Error("record should require re-consent for v3")
+		}
+		record.Status = CONSENT_RECORD_STATUS_WITHDRAWN
+		if record.IsValidFor(v2) {
+			t.Error("withdrawn record should not be valid")
+		}
+	})
+}
@@ -12,23 +12,37 @@ const (
 
 // Participant defines the datamodel for current state of the participant in a study as stored in the database
 type Participant struct {
-	ID                  primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
-	ParticipantID       string               `bson:"participantID" json:"participantId"` // reference to the study specific participant ID
-	CurrentStudySession string               `bson:"currentStudySession" json:"currentStudySession"`
-	ModifiedAt          int64                `bson:"modifiedAt" json:"modifiedAt"`
-	EnteredAt           int64                `bson:"enteredAt" json:"enteredAt"`
-	StudyStatus         string               `bson:"studyStatus" json:"studyStatus"`
-	Flags               map[string]string    `bson:"flags" json:"flags"`
-	LinkingCodes        map[string]string    `bson:"linkingCodes" json:"linkingCodes"`
-	AssignedSurveys     []AssignedSurvey     `bson:"assignedSurveys" json:"assignedSurveys"`
-	LastSubmissions     map[string]int64     `bson:"lastSubmission" json:"lastSubmissions"` // surveyKey with timestamp
-	Messages            []ParticipantMessage `bson:"messages" json:"messages"`
-	HashedAccountID     *string              `bson:"hashedAccountID,omitempty" json:"hashedAccountID,omitempty"`
-	IsMainProfile       *bool                `bson:"isMainProfile,omitempty" json:"isMainProfile,omitempty"`
+	ID                  primitive.ObjectID         `bson:"_id,omitempty" json:"id,omitempty"`
+	ParticipantID       string                     `bson:"participantID" json:"participantId"` // reference to the study specific participant ID
+	CurrentStudySession string                     `bson:"currentStudySession" json:"currentStudySession"`
+	ModifiedAt          int64                      `bson:"modifiedAt" json:"modifiedAt"`
+	EnteredAt           int64                      `bson:"enteredAt" json:"enteredAt"`
+	StudyStatus         string                     `bson:"studyStatus" json:"studyStatus"`
+	Flags               map[string]string          `bson:"flags" json:"flags"`
+	LinkingCodes        map[string]string          `bson:"linkingCodes" json:"linkingCodes"`
+	AssignedSurveys     []AssignedSurvey           `bson:"assignedSurveys" json:"assignedSurveys"`
+	LastSubmissions     map[string]int64           `bson:"lastSubmission" json:"lastSubmissions"` // surveyKey with timestamp
+	Messages            []ParticipantMessage       `bson:"messages" json:"messages"`
+	HashedAccountID     *string                    `bson:"hashedAccountID,omitempty" json:"hashedAccountID,omitempty"`
+	IsMainProfile       *bool                      `bson:"isMainProfile,omitempty" json:"isMainProfile,omitempty"`
+	Arm                 *ParticipantArmAssignment  `bson:"arm,omitempty" json:"arm,omitempty"`
+	ArmHistory          []ParticipantArmAssignment `bson:"armHistory,omitempty" json:"armHistory,omitempty"`
+	Visits              []ParticipantVisit         `bson:"visits,omitempty" json:"visits,omitempty"`
 }
 
 type ParticipantMessage struct {
-	ID           string `bson:"id" json:"id"`
-	Type         string `bson:"type" json:"type"`
-	ScheduledFor int64  `bson:"scheduledFor" json:"scheduledFor"`
+	ID           string   `bson:"id" json:"id"`
+	Type         string   `bson:"type" json:"type"`
+	ScheduledFor int64    `bson:"scheduledFor" json:"scheduledFor"`
+	Channels     []string `bson:"channels,omitempty" json:"channels,omitempty"`   // delivery channels, template channels if empty
+	SurveyKey    string   `bson:"surveyKey,omitempty" json:"surveyKey,omitempty"` // survey linked from inbox messages
+}
+
+// ParticipantVisit is an appointment of the participant (e.g. at the study site), included in the calendar feed
+type ParticipantVisit struct {
+	Key      string `bson:"key" json:"key"`
+	Label    string `bson:"label,omitempty" json:"label,omitempty"`
+	Start    int64  `bson:"start" json:"start"`
+	End      int64  `bson:"end,omitempty" json:"end,omitempty"`
+	Location string `bson:"location,omitempty" json:"location,omitempty"`
 }
@@ -7,8 +7,14 @@ import (
 )
 
 const (
-	FILE_STATUS_UPLOADING = "uploading"
-	FILE_STATUS_READY     = "ready"
+	FILE_STATUS_UPLOADING   = "uploading"
+	FILE_STATUS_READY       = "ready"
+	FILE_STATUS_QUARANTINED = "quarantined" // malware scan detected a threat, not available for download
+)
+
+const (
+	FILE_SCAN_STATUS_CLEAN    = "clean"
+	FILE_SCAN_STATUS_INFECTED = "infected"
 )
 
 type FileInfo struct {
@@ -26,4 +32,16 @@ type FileInfo struct {
 	FileType             string `bson:"fileType,omitempty" json:"fileType,omitempty"`
 	VisibleToParticipant bool   `bson:"visibleToParticipant" json:"visibleToParticipant"`
 	Size                 int64  `bson:"size,omitempty" json:"size,omitempty"`
+
+	// number of bytes received so far for resumable uploads, Size is the declared total
+	UploadOffset int64 `bson:"uploadOffset,omitempty" json:"uploadOffset,omitempty"`
+
+	ScanResult *FileScanResult `bson:"scanResult,omitempty" json:"scanResult,omitempty"`
+}
+
+type FileScanResult struct {
+	Status    string    `bson:"status" json:"status"`
+	Threat    string    `bson:"threat,omitempty" json:"threat,omitempty"`
+	Scanner   string    `bson:"scanner,omitempty" json:"scanner,omitempty"`
+	ScannedAt time.Time `bson:"scannedAt" json:"scannedAt"`
 }
@@ -0,0 +1,82 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// response context key holding the participant's arm at the time of submission
+const RESPONSE_CONTEXT_KEY_ARM = "arm"
+
+type StudyArm struct {
+	Key         string            `bson:"key" json:"key"`
+	Label       []LocalisedObject `bson:"label,omitempty" json:"label,omitempty"`
+	Description []LocalisedObject `bson:"description,omitempty" json:"description,omitempty"`
+}
+
+// ParticipantArmAssignment records which arm a participant was assigned to, when and why
+type ParticipantArmAssignment struct {
+	Key        string `bson:"key" json:"key"`
+	AssignedAt int64  `bson:"assignedAt" json:"assignedAt"`
+	Reason     string `bson:"reason,omitempty" json:"reason,omitempty"`
+	// set on entries of the history, when the participant left the arm
+	RemovedAt int64 `bson:"removedAt,omitempty" json:"removedAt,omitempty"`
+}
+
+func ValidateStudyArms(arms []StudyArm) error {
+	keys := map[string]bool{}
+	for _, arm := range arms {
+		if arm.Key == "" {
+			return errors.New("arm key is required")
+		}
+		if keys[arm.Key] {
+			return fmt.Errorf("duplicate arm key: %s", arm.Key)
+		}
+		keys[arm.Key] = true
+	}
+	return nil
+}
+
+func (s Study) HasArm(armKey string) bool {
+	for _, arm := range s.Arms {
+		if arm.Key == armKey {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentArmKey returns the key of the participant's arm or an empty string if not assigned
+func (p Participant) CurrentArmKey() string {
+	if p.Arm == nil {
+		return ""
+	}
+	return p.Arm.Key
+}
+
+// WithArm returns a copy of the participant moved to the given arm. The previous assignment is kept in the arm history.
+// An empty armKey removes the participant from the current arm.
+func (p Participant) WithArm(armKey string, reason string, at int64) Participant {
+	if p.CurrentArmKey() == armKey {
+		return p
+	}
+
+	if p.Arm != nil {
+		previous := *p.Arm
+		previous.RemovedAt = at
+		history := make([]ParticipantArmAssignment, 0, len(p.ArmHistory)+1)
+		history = append(history, p.ArmHistory...)
+		p.ArmHistory = append(history, previous)
+	}
+
+	if armKey == "" {
+		p.Arm = nil
+		return p
+	}
+	p.Arm = &ParticipantArmAssignment{
+		Key:        armKey,
+		AssignedAt: at,
+		Reason:     reason,
+	}
+	return p
+}
@@ -6,10 +6,41 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	STUDY_CODE_STATUS_AVAILABLE = "available"
+	STUDY_CODE_STATUS_RESERVED  = "reserved"
+	STUDY_CODE_STATUS_CLAIMED   = "claimed"
+)
+
 type StudyCodeListEntry struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	StudyKey string             `bson:"studyKey" json:"studyKey"`
 	ListKey  string             `bson:"listKey" json:"listKey"`
 	Code     string             `bson:"code" json:"code"`
 	AddedAt  time.Time          `bson:"addedAt" json:"addedAt"`
+
+	// Lifecycle of the code, entries without status are available
+	Status    string     `bson:"status,omitempty" json:"status,omitempty"`
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+
+	ReservedAt    *time.Time `bson:"reservedAt,omitempty" json:"reservedAt,omitempty"`
+	ReservedUntil *time.Time `bson:"reservedUntil,omitempty" json:"reservedUntil,omitempty"`
+	ReservedBy    string     `bson:"reservedBy,omitempty" json:"reservedBy,omitempty"` // participant ID
+
+	ClaimedAt      *time.Time `bson:"claimedAt,omitempty" json:"claimedAt,omitempty"`
+	ClaimedBy      string     `bson:"claimedBy,omitempty" json:"claimedBy,omitempty"`           // participant ID
+	ClaimedByEvent string     `bson:"claimedByEvent,omitempty" json:"claimedByEvent,omitempty"` // event type and optional event key, e.g. "CUSTOM:enrol"
+}
+
+// StudyCodeClaim describes who consumes a study code
+type StudyCodeClaim struct {
+	ParticipantID string
+	Event         string
+}
+
+func (e StudyCodeListEntry) GetStatus() string {
+	if e.Status == "" {
+		return STUDY_CODE_STATUS_AVAILABLE
+	}
+	return e.Status
 }
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	STUDY_COUNTER_OVERFLOW_ERROR = "error"
+	STUDY_COUNTER_OVERFLOW_WRAP  = "wrap"
+)
+
+const (
+	STUDY_COUNTER_RESET_DAILY   = "daily"
+	STUDY_COUNTER_RESET_MONTHLY = "monthly"
+)
+
+// event recorded in the counter log when the study timer resets a counter
+const STUDY_COUNTER_LOG_EVENT_SCHEDULED_RESET = "SCHEDULED_RESET"
+
+// StudyCounterConfig holds the optional bounds, output format and reset schedule of a study counter
+type StudyCounterConfig struct {
+	MaxValue      int64  `bson:"maxValue,omitempty" json:"maxValue,omitempty"`     // 0 means no upper bound
+	OnOverflow    string `bson:"onOverflow,omitempty" json:"onOverflow,omitempty"` // "error" (default) or "wrap" (continue from 1)
+	Prefix        string `bson:"prefix,omitempty" json:"prefix,omitempty"`
+	Padding       int    `bson:"padding,omitempty" json:"padding,omitempty"`
+	ResetSchedule string `bson:"resetSchedule,omitempty" json:"resetSchedule,omitempty"` // "daily" or "monthly" (UTC), empty for no reset
+}
+
+var ErrStudyCounterOverflow = errors.New("study counter reached its max value")
+
+func (c StudyCounterConfig) Validate() error {
+	if c.MaxValue < 0 {
+		return errors.New("maxValue must not be negative")
+	}
+	switch c.OnOverflow {
+	case "", STUDY_COUNTER_OVERFLOW_ERROR, STUDY_COUNTER_OVERFLOW_WRAP:
+	default:
+		return fmt.Errorf("unknown overflow behaviour: %s", c.OnOverflow)
+	}
+	switch c.ResetSchedule {
+	case "", STUDY_COUNTER_RESET_DAILY, STUDY_COUNTER_RESET_MONTHLY:
+	default:
+		return fmt.Errorf("unknown reset schedule: %s", c.ResetSchedule)
+	}
+	if c.Padding < 0 || c.Padding > 20 {
+		return errors.New("padding must be between 0 and 20")
+	}
+	return nil
+}
+
+// FormatValue renders a counter value with the configured prefix and zero padding, e.g. "SITE-A-0042"
+func (c StudyCounterConfig) FormatValue(value int64) string {
+	return fmt.Sprintf("%s%0*d", c.Prefix, c.Padding, value)
+}
+
+// PeriodStart returns the start of the reset period containing t, or nil if the counter is never reset
+func (c StudyCounterConfig) PeriodStart(t time.Time) *time.Time {
+	t = t.UTC()
+	var start time.Time
+	switch c.ResetSchedule {
+	case STUDY_COUNTER_RESET_DAILY:
+		start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	case STUDY_COUNTER_RESET_MONTHLY:
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return nil
+	}
+	return &start
+}
+
+// NextValue computes the value following current. periodStart is the start of the period current belongs to.
+// A counter without a period start (e.g. the reset schedule was added later) is not reset, its value is
+// assigned to the current period instead.
+// Returns the new value, whether the counter was reset or wrapped on the way, or ErrStudyCounterOverflow.
+func (c StudyCounterConfig) NextValue(current int64, periodStart *time.Time, now time.Time) (next int64, reset bool, wrapped bool, err error) {
+	if ps := c.PeriodStart(now); ps != nil && periodStart != nil && periodStart.Before(*ps) {
+		current = 0
+		reset = true
+	}
+
+	next = current + 1
+	if c.MaxValue > 0 && next > c.MaxValue {
+		if c.OnOverflow != STUDY_COUNTER_OVERFLOW_WRAP {
+			return current, reset, false, ErrStudyCounterOverflow
+		}
+		next = 1
+		wrapped = true
+	}
+	return next, reset, wrapped, nil
+}
+
+// StudyCounterIncrementOrigin describes who received a counter value
+type StudyCounterIncrementOrigin struct {
+	ParticipantID string `bson:"participantID,omitempty" json:"participantID,omitempty"`
+	Event         string `bson:"event,omitempty" json:"event,omitempty"`
+	IncrementedBy string `bson:"incrementedBy,omitempty" json:"incrementedBy,omitempty"` // management user ID, if not triggered by a study event
+}
+
+// StudyCounterLogEntry records a single increment, so numbering can be reconstructed later
+type StudyCounterLogEntry struct {
+	ID            primitive.ObjectID          `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey      string                      `bson:"studyKey" json:"studyKey"`
+	Scope         string                      `bson:"scope" json:"scope"`
+	Value         int64                       `bson:"value" json:"value"`
+	Reset         bool                        `bson:"reset,omitempty" json:"reset,omitempty"`
+	Wrapped       bool                        `bson:"wrapped,omitempty" json:"wrapped,omitempty"`
+	IncrementedAt time.Time                   `bson:"incrementedAt" json:"incrementedAt"`
+	Origin        StudyCounterIncrementOrigin `bson:"origin" json:"origin"`
+}
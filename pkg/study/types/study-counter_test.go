@@ -0,0 +1,74 @@
+package types
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStudyCounterConfigNextValue(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	sameDay := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+	yesterday := time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)
+	sameMonth := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		config      StudyCounterConfig
+		current     int64
+		periodStart *time.Time
+		expected    int64
+		reset       bool
+		wrapped     bool
+		wantError   bool
+	}{
+		{name: "unbounded", config: StudyCounterConfig{}, current: 41, expected: 42},
+		{name: "below max", config: StudyCounterConfig{MaxValue: 10}, current: 9, expected: 10},
+		{name: "overflow error by default", config: StudyCounterConfig{MaxValue: 10}, current: 10, wantError: true},
+		{name: "overflow wrap", config: StudyCounterConfig{MaxValue: 10, OnOverflow: STUDY_COUNTER_OVERFLOW_WRAP}, current: 10, expected: 1, wrapped: true},
+		{name: "daily same period", config: StudyCounterConfig{ResetSchedule: STUDY_COUNTER_RESET_DAILY}, current: 5, periodStart: &sameDay, expected: 6},
+		{name: "daily new period", config: StudyCounterConfig{ResetSchedule: STUDY_COUNTER_RESET_DAILY}, current: 5, periodStart: &yesterday, expected: 1, reset: true},
+		{name: "daily without period", config: StudyCounterConfig{ResetSchedule: STUDY_COUNTER_RESET_DAILY}, current: 5, expected: 6},
+		{name: "monthly same period", config: StudyCounterConfig{ResetSchedule: STUDY_COUNTER_RESET_MONTHLY}, current: 5, periodStart: &sameMonth, expected: 6},
+		{name: "reset before overflow check", config: StudyCounterConfig{MaxValue: 5, ResetSchedule: STUDY_COUNTER_RESET_DAILY}, current: 5, periodStart: &yesterday, expected: 1, reset: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, reset, wrapped, err := tc.config.NextValue(tc.current, tc.periodStart, now)
+			if tc.wantError {
+				if err != ErrStudyCounterOverflow {
+					t.Fatalf("expected overflow error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if next != tc.expected || reset != tc.reset || wrapped != tc.wrapped {
+				t.Errorf("got (%d, reset=%v, wrapped=%v), expected (%d, reset=%v, wrapped=%v)", next, reset, wrapped, tc.expected, tc.reset, tc.wrapped)
+			}
+		})
+	}
+}
+
+func TestStudyCounterConfigFormatValue(t *testing.T) {
+	c := StudyCounterConfig{Prefix: "SITE-A-", Padding: 4}
+	if v := c.FormatValue(42); v != "SITE-A-0042" {
+		t.Errorf("unexpected formatted value: %s", v)
+	}
+	if v := (StudyCounterConfig{}).FormatValue(42); v != "42" {
+		t.Errorf("unexpected formatted value: %s", v)
+	}
+}
+
+func TestStudyCounterConfigValidate(t *testing.T) {
+	if err := (StudyCounterConfig{MaxValue: 100, OnOverflow: STUDY_COUNTER_OVERFLOW_WRAP, ResetSchedule: STUDY_COUNTER_RESET_MONTHLY}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (StudyCounterConfig{OnOverflow: "clamp"}).Validate(); err == nil {
+		t.Error("expected error for unknown overflow behaviour")
+	}
+	if err := (StudyCounterConfig{ResetSchedule: "weekly"}).Validate(); err == nil {
+		t.Error("expected error for unknown reset schedule")
+	}
+}
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// behaviour when a participant matches a quota that is already full
+const (
+	STUDY_QUOTA_ON_FULL_REFUSE   = "refuse"   // the event is rejected before the study rules run, the condition is evaluated on the state before the event
+	STUDY_QUOTA_ON_FULL_FLAG     = "flag"     // the event is processed, participant is flagged as over quota
+	STUDY_QUOTA_ON_FULL_WAITLIST = "waitlist" // the event is processed, participant is put on the waitlist of the quota
+)
+
+const (
+	STUDY_QUOTA_ENTRY_STATUS_COUNTED    = "counted"
+	STUDY_QUOTA_ENTRY_STATUS_WAITLISTED = "waitlisted"
+)
+
+// participant flag "quota.<quotaKey>" holds the participant's quota status
+const (
+	STUDY_QUOTA_FLAG_PREFIX           = "quota."
+	STUDY_QUOTA_FLAG_VALUE_COUNTED    = "counted"
+	STUDY_QUOTA_FLAG_VALUE_FULL       = "full"
+	STUDY_QUOTA_FLAG_VALUE_WAITLISTED = "waitlisted"
+)
+
+var ErrStudyQuotaFull = errors.New("study quota is full")
+
+// returned when a concurrent event already counted the participant, the slot belongs to that event
+var ErrStudyQuotaSlotAlreadyHeld = errors.New("participant already counts towards the study quota")
+
+type StudyQuota struct {
+	Key   string            `bson:"key" json:"key"`
+	Label []LocalisedObject `bson:"label,omitempty" json:"label,omitempty"`
+	// participants for whom the condition is true count towards the quota, nil matches every participant
+	Condition *Expression `bson:"condition,omitempty" json:"condition,omitempty"`
+	Limit     int64       `bson:"limit" json:"limit"`
+	OnFull    string      `bson:"onFull" json:"onFull"`
+}
+
+func (q StudyQuota) FlagKey() string {
+	return STUDY_QUOTA_FLAG_PREFIX + q.Key
+}
+
+func ValidateStudyQuotas(quotas []StudyQuota) error {
+	keys := map[string]bool{}
+	for _, q := range quotas {
+		if q.Key == "" {
+			return errors.New("quota key is required")
+		}
+		if keys[q.Key] {
+			return fmt.Errorf("duplicate quota key: %s", q.Key)
+		}
+		keys[q.Key] = true
+
+		if q.Limit < 0 {
+			return fmt.Errorf("limit of quota %s must not be negative", q.Key)
+		}
+		switch q.OnFull {
+		case STUDY_QUOTA_ON_FULL_REFUSE, STUDY_QUOTA_ON_FULL_FLAG, STUDY_QUOTA_ON_FULL_WAITLIST:
+		default:
+			return fmt.Errorf("unknown onFull behaviour for quota %s: %s", q.Key, q.OnFull)
+		}
+	}
+	return nil
+}
+
+// StudyQuotaEntry records that a participant counts towards or is waitlisted for a quota
+type StudyQuotaEntry struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey      string             `bson:"studyKey" json:"studyKey"`
+	QuotaKey      string             `bson:"quotaKey" json:"quotaKey"`
+	ParticipantID string             `bson:"participantID" json:"participantId"`
+	Status        string             `bson:"status" json:"status"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+type StudyQuotaFillLevel struct {
+	Key        string            `json:"key"`
+	Label      []LocalisedObject `json:"label,omitempty"`
+	Limit      int64             `json:"limit"`
+	OnFull     string            `json:"onFull"`
+	Count      int64             `json:"count"`
+	Remaining  int64             `json:"remaining"`
+	Waitlisted int64             `json:"waitlisted"`
+	IsFull     bool              `json:"isFull"`
+}
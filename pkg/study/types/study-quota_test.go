@@ -0,0 +1,36 @@
+package types
+
+import "testing"
+
+func TestValidateStudyQuotas(t *testing.T) {
+	testCases := []struct {
+		name      string
+		quotas    []StudyQuota
+		wantError bool
+	}{
+		{name: "empty", quotas: nil},
+		{name: "valid", quotas: []StudyQuota{
+			{Key: "siteA", Limit: 500, OnFull: STUDY_QUOTA_ON_FULL_REFUSE},
+			{Key: "siteB", Limit: 0, OnFull: STUDY_QUOTA_ON_FULL_WAITLIST},
+		}},
+		{name: "missing key", quotas: []StudyQuota{{Limit: 1, OnFull: STUDY_QUOTA_ON_FULL_FLAG}}, wantError: true},
+		{name: "duplicate key", quotas: []StudyQuota{
+			{Key: "siteA", Limit: 1, OnFull: STUDY_QUOTA_ON_FULL_FLAG},
+			{Key: "siteA", Limit: 2, OnFull: STUDY_QUOTA_ON_FULL_FLAG},
+		}, wantError: true},
+		{name: "negative limit", quotas: []StudyQuota{{Key: "siteA", Limit: -1, OnFull: STUDY_QUOTA_ON_FULL_FLAG}}, wantError: true},
+		{name: "unknown behaviour", quotas: []StudyQuota{{Key: "siteA", Limit: 1, OnFull: "close"}}, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateStudyQuotas(tc.quotas)
+			if tc.wantError && err == nil {
+				t.Error("expected error")
+			}
+			if !tc.wantError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	STUDY_VARIABLE_CHANGE_SOURCE_MANAGEMENT = "management"
+	STUDY_VARIABLE_CHANGE_SOURCE_RULE       = "rule"
+	STUDY_VARIABLE_CHANGE_SOURCE_SCHEDULE   = "schedule"
+)
+
+const (
+	STUDY_VARIABLE_SCHEDULE_STATUS_PENDING  = "pending"
+	STUDY_VARIABLE_SCHEDULE_STATUS_APPLYING = "applying" // claimed by a timer run, the value is being written
+	STUDY_VARIABLE_SCHEDULE_STATUS_APPLIED  = "applied"
+	STUDY_VARIABLE_SCHEDULE_STATUS_FAILED   = "failed"
+)
+
+// StudyVariableChangeOrigin describes who or what triggered a value change
+type StudyVariableChangeOrigin struct {
+	Source    string `bson:"source" json:"source"`
+	ChangedBy string `bson:"changedBy,omitempty" json:"changedBy,omitempty"` // management user ID, participant ID or schedule ID depending on the source
+	EventType string `bson:"eventType,omitempty" json:"eventType,omitempty"` // only for rule based changes
+	EventKey  string `bson:"eventKey,omitempty" json:"eventKey,omitempty"`   // only for rule based changes
+}
+
+type StudyVariableHistoryEntry struct {
+	ID        primitive.ObjectID        `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey  string                    `bson:"studyKey" json:"studyKey"`
+	Key       string                    `bson:"key" json:"key"`
+	OldValue  any                       `bson:"oldValue" json:"oldValue"`
+	NewValue  any                       `bson:"newValue" json:"newValue"`
+	ChangedAt time.Time                 `bson:"changedAt" json:"changedAt"`
+	Origin    StudyVariableChangeOrigin `bson:"origin" json:"origin"`
+}
+
+type StudyVariableScheduledChange struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	StudyKey     string             `bson:"studyKey" json:"studyKey"`
+	Key          string             `bson:"key" json:"key"`
+	Value        any                `bson:"value" json:"value"`
+	ScheduledFor time.Time          `bson:"scheduledFor" json:"scheduledFor"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"createdAt"`
+	CreatedBy    string             `bson:"createdBy" json:"createdBy"`
+	Status       string             `bson:"status" json:"status"`
+	AppliedAt    *time.Time         `bson:"appliedAt,omitempty" json:"appliedAt,omitempty"`
+	Error        string             `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// NormalizeStudyVariableValue converts a raw JSON value into the Go type expected for the given variable type.
+func NormalizeStudyVariableValue(raw json.RawMessage, t StudyVariablesType) (any, error) {
+	return normalizeStudyVariableValue(raw, t)
+}
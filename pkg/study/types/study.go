@@ -19,6 +19,8 @@ type Study struct {
 	Props                     StudyProps                 `bson:"props" json:"props"`
 	Configs                   StudyConfigs               `bson:"configs" json:"configs"`
 	NotificationSubscriptions []NotificationSubscription `bson:"notificationSubscriptions" json:"notificationSubscriptions"`
+	Quotas                    []StudyQuota               `bson:"quotas,omitempty" json:"quotas,omitempty"`
+	Arms                      []StudyArm                 `bson:"arms,omitempty" json:"arms,omitempty"`
 
 	// depracted fields potentially to be removed in the future
 	Stats          StudyStats   `bson:"studyStats" json:"stats"`
@@ -39,6 +41,8 @@ type StudyConfigs struct {
 	ParticipantFileUploadRule *Expression `bson:"participantFileUploadRule" json:"participantFileUploadRule"`
 	IdMappingMethod           string      `bson:"idMappingMethod" json:"idMappingMethod"`
 	TrackAccount              bool        `bson:"trackAccount" json:"trackAccount"`
+	// remove EXIF (incl. GPS) and other metadata from uploaded participant images
+	StripFileMetadata bool `bson:"stripFileMetadata" json:"stripFileMetadata"`
 }
 
 type StudyStats struct {
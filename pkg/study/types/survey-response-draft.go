@@ -0,0 +1,19 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SurveyResponseDraft holds the partial answers of a participant for a survey, so filling out can be resumed on another device
+type SurveyResponseDraft struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ParticipantID string             `bson:"participantID" json:"participantId"`
+	SurveyKey     string             `bson:"surveyKey" json:"surveyKey"`
+	VersionID     string             `bson:"versionID" json:"versionId"`
+	Response      SurveyResponse     `bson:"response" json:"response"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ExpiresAt     time.Time          `bson:"expiresAt" json:"expiresAt"` // removed by the TTL index after this time
+}
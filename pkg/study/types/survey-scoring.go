@@ -0,0 +1,170 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// how the item values of a score are combined
+const (
+	SCORE_AGGREGATION_SUM  = "sum"
+	SCORE_AGGREGATION_MEAN = "mean"
+)
+
+// how missing item values are handled if the score is still computed (less than maxMissing items are missing)
+const (
+	SCORE_MISSING_IGNORE  = ""        // missing items count as 0 for sums
+	SCORE_MISSING_PRORATE = "prorate" // sum is scaled up to the number of items (mean of answered items * item count)
+	SCORE_MISSING_INVALID = "invalid" // score is not computed if any item is missing
+)
+
+// SurveyScoring holds the scores computed for the responses of a survey version
+type SurveyScoring struct {
+	// if set, a report with this key is created for every scored response, with one entry per score
+	ReportKey string            `bson:"reportKey,omitempty" json:"reportKey,omitempty"`
+	Scores    []ScoreDefinition `bson:"scores" json:"scores"`
+}
+
+type ScoreDefinition struct {
+	Key         string      `bson:"key" json:"key"`
+	Items       []ScoreItem `bson:"items" json:"items"`
+	Aggregation string      `bson:"aggregation,omitempty" json:"aggregation,omitempty"` // sum if empty
+	// score is not computed if more items are missing, -1 for no limit
+	MaxMissing int    `bson:"maxMissing" json:"maxMissing"`
+	Missing    string `bson:"missing,omitempty" json:"missing,omitempty"`
+	// maps the raw score to the final score (e.g. T-scores), raw scores outside of all ranges make the score invalid
+	Lookup []ScoreLookupEntry `bson:"lookup,omitempty" json:"lookup,omitempty"`
+}
+
+// ScoreItem is a value contributing to a score: an answer of the survey, or another score (e.g. for totals of subscales)
+type ScoreItem struct {
+	ItemKey     string `bson:"itemKey,omitempty" json:"itemKey,omitempty"`
+	ResponseKey string `bson:"responseKey,omitempty" json:"responseKey,omitempty"` // path of the option group or input, e.g. "rg.scg"
+	ScoreKey    string `bson:"scoreKey,omitempty" json:"scoreKey,omitempty"`
+
+	// value of the selected option keys, if empty, the option key or input value is parsed as number
+	OptionValues map[string]float64 `bson:"optionValues,omitempty" json:"optionValues,omitempty"`
+	Weight       *float64           `bson:"weight,omitempty" json:"weight,omitempty"` // 1 if not set
+	// reverse coding: value = min + max - value
+	Reverse bool    `bson:"reverse,omitempty" json:"reverse,omitempty"`
+	Min     float64 `bson:"min,omitempty" json:"min,omitempty"`
+	Max     float64 `bson:"max,omitempty" json:"max,omitempty"`
+}
+
+// ScoreLookupEntry maps raw scores between From and To (inclusive) to Value
+type ScoreLookupEntry struct {
+	From  float64 `bson:"from" json:"from"`
+	To    float64 `bson:"to" json:"to"`
+	Value float64 `bson:"value" json:"value"`
+}
+
+// SurveyScoreRecord holds the scores computed for a stored response
+type SurveyScoreRecord struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ResponseID    string             `bson:"responseID" json:"responseId"`
+	ParticipantID string             `bson:"participantID" json:"participantId"`
+	SurveyKey     string             `bson:"surveyKey" json:"surveyKey"`
+	VersionID     string             `bson:"versionID" json:"versionId"`
+	SubmittedAt   int64              `bson:"submittedAt" json:"submittedAt"`
+	ComputedAt    int64              `bson:"computedAt" json:"computedAt"`
+	Scores        []ScoreValue       `bson:"scores" json:"scores"`
+}
+
+type ScoreValue struct {
+	Key string `bson:"key" json:"key"`
+	// nil if the score could not be computed, e.g. because of too many missing items
+	Value    *float64 `bson:"value" json:"value"`
+	RawValue *float64 `bson:"rawValue,omitempty" json:"rawValue,omitempty"` // before the lookup table was applied
+	Missing  int      `bson:"missing" json:"missing"`                       // number of items without a value
+}
+
+// Map returns the computed scores by key, scores that could not be computed are left out
+func (r SurveyScoreRecord) Map() map[string]float64 {
+	values := map[string]float64{}
+	for _, s := range r.Scores {
+		if s.Value != nil {
+			values[s.Key] = *s.Value
+		}
+	}
+	return values
+}
+
+func ValidateSurveyScoring(scoring *SurveyScoring) error {
+	if scoring == nil {
+		return nil
+	}
+	defs := map[string]ScoreDefinition{}
+	for _, s := range scoring.Scores {
+		if s.Key == "" {
+			return errors.New("score key is required")
+		}
+		if _, ok := defs[s.Key]; ok {
+			return fmt.Errorf("duplicate score key: %s", s.Key)
+		}
+		defs[s.Key] = s
+
+		switch s.Aggregation {
+		case "", SCORE_AGGREGATION_SUM, SCORE_AGGREGATION_MEAN:
+		default:
+			return fmt.Errorf("unknown aggregation of score %s: %s", s.Key, s.Aggregation)
+		}
+		switch s.Missing {
+		case SCORE_MISSING_IGNORE, SCORE_MISSING_PRORATE, SCORE_MISSING_INVALID:
+		default:
+			return fmt.Errorf("unknown missing value handling of score %s: %s", s.Key, s.Missing)
+		}
+		if len(s.Items) == 0 {
+			return fmt.Errorf("score %s has no items", s.Key)
+		}
+		for _, item := range s.Items {
+			if (item.ItemKey == "") == (item.ScoreKey == "") {
+				return fmt.Errorf("items of score %s must reference either a survey item or a score", s.Key)
+			}
+			if item.ItemKey != "" && item.ResponseKey == "" {
+				return fmt.Errorf("response key is missing for item %s of score %s", item.ItemKey, s.Key)
+			}
+			if item.Reverse && item.Min >= item.Max {
+				return fmt.Errorf("reverse coded item of score %s needs a range (min < max)", s.Key)
+			}
+		}
+		for _, l := range s.Lookup {
+			if l.From > l.To {
+				return fmt.Errorf("invalid lookup range of score %s: %v - %v", s.Key, l.From, l.To)
+			}
+		}
+	}
+
+	// score references must exist and must not be circular
+	state := map[string]int{} // 1: visiting, 2: done
+	var visit func(key string) error
+	visit = func(key string) error {
+		switch state[key] {
+		case 1:
+			return fmt.Errorf("circular score reference: %s", key)
+		case 2:
+			return nil
+		}
+		state[key] = 1
+		for _, item := range defs[key].Items {
+			if item.ScoreKey == "" {
+				continue
+			}
+			if _, ok := defs[item.ScoreKey]; !ok {
+				return fmt.Errorf("score %s references unknown score %s", key, item.ScoreKey)
+			}
+			if err := visit(item.ScoreKey); err != nil {
+				return err
+			}
+		}
+		state[key] = 2
+		return nil
+	}
+	for _, s := range scoring.Scores {
+		if err := visit(s.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package types
+
+import "testing"
+
+func TestValidateSurveyScoring(t *testing.T) {
+	item := ScoreItem{ItemKey: "s.q1", ResponseKey: "rg.scg"}
+	testCases := []struct {
+		name      string
+		scoring   *SurveyScoring
+		wantError bool
+	}{
+		{name: "no scoring", scoring: nil},
+		{name: "valid", scoring: &SurveyScoring{Scores: []ScoreDefinition{
+			{Key: "sub", Items: []ScoreItem{item}},
+			{Key: "total", Items: []ScoreItem{{ScoreKey: "sub"}}, Aggregation: SCORE_AGGREGATION_MEAN, Missing: SCORE_MISSING_PRORATE},
+		}}},
+		{name: "missing key", scoring: &SurveyScoring{Scores: []ScoreDefinition{{Items: []ScoreItem{item}}}}, wantError: true},
+		{name: "duplicate key", scoring: &SurveyScoring{Scores: []ScoreDefinition{
+			{Key: "a", Items: []ScoreItem{item}},
+			{Key: "a", Items: []ScoreItem{item}},
+		}}, wantError: true},
+		{name: "no items", scoring: &SurveyScoring{Scores: []ScoreDefinition{{Key: "a"}}}, wantError: true},
+		{name: "unknown aggregation", scoring: &SurveyScoring{Scores: []ScoreDefinition{{Key: "a", Items: []ScoreItem{item}, Aggregation: "max"}}}, wantError: true},
+		{name: "item and score reference", scoring: &SurveyScoring{Scores: []ScoreDefinition{{Key: "a", Items: []ScoreItem{{ItemKey: "s.q1", ResponseKey: "rg", ScoreKey: "b"}}}}}, wantError: true},
+		{name: "missing response key", scoring: &SurveyScoring{Scores: []ScoreDefinition{{Key: "a", Items: []ScoreItem{{ItemKey: "s.q1"}}}}}, wantError: true},
+		{name: "reverse coding without range", scoring: &SurveyScoring{Scores: []ScoreDefinition{{Key: "a", Items: []ScoreItem{{ItemKey: "s.q1", ResponseKey: "rg", Reverse: true}}}}}, wantError: true},
+		{name: "invalid lookup", scoring: &SurveyScoring{Scores: []ScoreDefinition{{Key: "a", Items: []ScoreItem{item}, Lookup: []ScoreLookupEntry{{From: 3, To: 1}}}}}, wantError: true},
+		{name: "unknown score reference", scoring: &SurveyScoring{Scores: []ScoreDefinition{{Key: "a", Items: []ScoreItem{{ScoreKey: "b"}}}}}, wantError: true},
+		{name: "circular reference", scoring: &SurveyScoring{Scores: []ScoreDefinition{
+			{Key: "a", Items: []ScoreItem{{ScoreKey: "b"}}},
+			{Key: "b", Items: []ScoreItem{{ScoreKey: "a"}}},
+		}}, wantError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateSurveyScoring(tc.scoring)
+			if tc.wantError && err == nil {
+				t.Error("expected error")
+			}
+			if !tc.wantError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -9,6 +9,13 @@ const (
 	SURVEY_AVAILABLE_FOR_PARTICIPANTS_IF_ASSIGNED = "participants_if_assigned"
 )
 
+// handling of submitted answers to items that were hidden by their condition given the final responses
+const (
+	SURVEY_HIDDEN_RESPONSES_KEEP  = ""
+	SURVEY_HIDDEN_RESPONSES_FLAG  = "flag"
+	SURVEY_HIDDEN_RESPONSES_PRUNE = "prune"
+)
+
 type Survey struct {
 	ID                           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	SurveyKey                    string             `bson:"surveyKey,omitempty" json:"surveyKey,omitempty"`
@@ -18,6 +25,12 @@ type Survey struct {
 	MaxItemsPerPage              *MaxItemsPerPage   `bson:"maxItemsPerPage,omitempty" json:"maxItemsPerPage,omitempty"`
 	AvailableFor                 string             `bson:"availableFor,omitempty" json:"availableFor,omitempty"`
 	RequireLoginBeforeSubmission bool               `bson:"requireLoginBeforeSubmission,omitempty" json:"requireLoginBeforeSubmission,omitempty"`
+	// reject submitted responses that do not match this survey version (unknown keys, type mismatches, failed hard validations)
+	StrictResponseValidation bool `bson:"strictResponseValidation,omitempty" json:"strictResponseValidation,omitempty"`
+	// SURVEY_HIDDEN_RESPONSES_FLAG or SURVEY_HIDDEN_RESPONSES_PRUNE to handle answers to items hidden by their conditions
+	HiddenResponses string `bson:"hiddenResponses,omitempty" json:"hiddenResponses,omitempty"`
+	// scores computed on submission, e.g. for validated instruments
+	Scoring *SurveyScoring `bson:"scoring,omitempty" json:"scoring,omitempty"`
 
 	Published        int64             `bson:"published,omitempty" json:"published,omitempty"`
 	Unpublished      int64             `bson:"unpublished,omitempty" json:"unpublished,omitempty"`
@@ -1,6 +1,10 @@
 package types
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type SurveyResponse struct {
 	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
@@ -12,6 +16,8 @@ type SurveyResponse struct {
 	ArrivedAt     int64                `bson:"arrivedAt" json:"arrivedAt"`
 	Responses     []SurveyItemResponse `bson:"responses" json:"responses"`
 	Context       map[string]string    `bson:"context" json:"context"`
+	// client-generated ID, a submission with an already stored ID is not processed again
+	SubmissionID string `bson:"submissionID,omitempty" json:"submissionId,omitempty"`
 }
 
 type SurveyItemResponse struct {
@@ -25,6 +31,10 @@ type SurveyItemResponse struct {
 	Response         *ResponseItem `bson:"response,omitempty" json:"response,omitempty"`
 	ConfidentialMode string        `bson:"confidentialMode,omitempty" json:"confidentialMode,omitempty"`
 	MapToKey         string        `bson:"mapToKey,omitempty" json:"mapToKey,omitempty"` // map to this key for confidential mode
+
+	// set by the backend if the survey flags answers that were not visible given the final responses
+	Hidden             bool     `bson:"hidden,omitempty" json:"hidden,omitempty"`
+	HiddenResponseKeys []string `bson:"hiddenResponseKeys,omitempty" json:"hiddenResponseKeys,omitempty"` // e.g. "rg.scg.2" for a hidden option
 }
 
 type ResponseMeta struct {
@@ -43,3 +53,14 @@ type ResponseItem struct {
 	// for response option groups
 	Items []*ResponseItem `bson:"items,omitempty" json:"items,omitempty"`
 }
+
+// SubmissionClaim reserves a submission ID before the study rules run, so concurrent requests with the same ID
+// are processed only once. The assigned surveys after processing are stored for the requests that lost the claim.
+type SubmissionClaim struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ParticipantID   string             `bson:"participantID" json:"participantId"`
+	SubmissionID    string             `bson:"submissionID" json:"submissionId"`
+	ClaimedAt       time.Time          `bson:"claimedAt" json:"claimedAt"`
+	Completed       bool               `bson:"completed,omitempty" json:"completed,omitempty"`
+	AssignedSurveys []AssignedSurvey   `bson:"assignedSurveys,omitempty" json:"assignedSurveys,omitempty"`
+}
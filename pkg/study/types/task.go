@@ -12,6 +12,7 @@ const (
 
 	TASK_FILE_TYPE_JSON = "application/json"
 	TASK_FILE_TYPE_CSV  = "text/csv"
+	TASK_FILE_TYPE_ZIP  = "application/zip"
 )
 
 type Task struct {
@@ -0,0 +1,45 @@
+package studyutils
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	studydb "github.com/case-framework/case-backend/pkg/db/study"
+)
+
+// CleanUpAbandonedUploads removes file uploads of the study that stayed in uploading status for longer than maxAge, including their partial files
+func CleanUpAbandonedUploads(
+	instanceID string,
+	studyKey string,
+	studyDBService *studydb.StudyDBService,
+	participantFilestorePath string,
+	maxAge time.Duration,
+) {
+	fileInfos, err := studyDBService.GetStaleUploadingFileInfos(instanceID, studyKey, time.Now().Add(-maxAge))
+	if err != nil {
+		slog.Error("Failed to get abandoned uploads", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey))
+		return
+	}
+
+	for _, fileInfo := range fileInfos {
+		if fileInfo.Path != "" {
+			err := os.Remove(filepath.Join(participantFilestorePath, fileInfo.Path))
+			if err != nil && !os.IsNotExist(err) {
+				slog.Error("Failed to remove file of abandoned upload", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("file", fileInfo.Path))
+				continue
+			}
+		}
+
+		err := studyDBService.DeleteParticipantFileInfoByID(instanceID, studyKey, fileInfo.ID.Hex())
+		if err != nil {
+			slog.Error("Failed to delete file info of abandoned upload", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfo.ID.Hex()))
+			continue
+		}
+	}
+
+	if len(fileInfos) > 0 {
+		slog.Info("Removed abandoned uploads", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.Int("count", len(fileInfos)))
+	}
+}
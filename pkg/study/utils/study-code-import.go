@@ -0,0 +1,92 @@
+package studyutils
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type ImportedStudyCode struct {
+	Code      string
+	ExpiresAt *time.Time
+}
+
+type StudyCodeImportResult struct {
+	Codes            []ImportedStudyCode
+	DuplicatesInFile []string
+	InvalidLines     []string
+}
+
+// ParseStudyCodeImport reads codes from a plain text (one code per line) or CSV file.
+// CSV rows may contain an optional second column with an expiry date (RFC3339 or YYYY-MM-DD).
+// A first row starting with "code" is treated as header. Codes repeated in the file are only kept once.
+func ParseStudyCodeImport(r io.Reader) (StudyCodeImportResult, error) {
+	result := StudyCodeImportResult{
+		Codes:            []ImportedStudyCode{},
+		DuplicatesInFile: []string{},
+		InvalidLines:     []string{},
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	seen := map[string]bool{}
+	lineNumber := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		lineNumber++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.InvalidLines = append(result.InvalidLines, fmt.Sprintf("line %d: %s", parseErr.Line, parseErr.Err.Error()))
+				continue
+			}
+			return result, err
+		}
+
+		if len(record) == 0 {
+			continue
+		}
+
+		code := strings.TrimSpace(record[0])
+		if lineNumber == 1 && strings.EqualFold(code, "code") {
+			continue
+		}
+		if code == "" {
+			continue
+		}
+
+		entry := ImportedStudyCode{Code: code}
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			expiresAt, err := parseStudyCodeExpiry(strings.TrimSpace(record[1]))
+			if err != nil {
+				result.InvalidLines = append(result.InvalidLines, fmt.Sprintf("line %d: invalid expiry date for code '%s'", lineNumber, code))
+				continue
+			}
+			entry.ExpiresAt = &expiresAt
+		}
+
+		if seen[code] {
+			result.DuplicatesInFile = append(result.DuplicatesInFile, code)
+			continue
+		}
+		seen[code] = true
+		result.Codes = append(result.Codes, entry)
+	}
+	return result, nil
+}
+
+func parseStudyCodeExpiry(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
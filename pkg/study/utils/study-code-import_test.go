@@ -0,0 +1,48 @@
+package studyutils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseStudyCodeImport(t *testing.T) {
+	t.Run("plain text with duplicates", func(t *testing.T) {
+		input := "ABC\n\nDEF\nABC\n  GHI  \n"
+		res, err := ParseStudyCodeImport(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(res.Codes) != 3 {
+			t.Fatalf("unexpected number of codes: %d", len(res.Codes))
+		}
+		if res.Codes[2].Code != "GHI" {
+			t.Errorf("code should be trimmed, got '%s'", res.Codes[2].Code)
+		}
+		if len(res.DuplicatesInFile) != 1 || res.DuplicatesInFile[0] != "ABC" {
+			t.Errorf("unexpected duplicates: %v", res.DuplicatesInFile)
+		}
+	})
+
+	t.Run("csv with header and expiry", func(t *testing.T) {
+		input := "code,expiresAt\nA1,2030-01-31\nA2,2030-02-01T12:00:00Z\nA3,\nA4,not-a-date\n"
+		res, err := ParseStudyCodeImport(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(res.Codes) != 3 {
+			t.Fatalf("unexpected number of codes: %d", len(res.Codes))
+		}
+		if res.Codes[0].ExpiresAt == nil || res.Codes[0].ExpiresAt.Format("2006-01-02") != "2030-01-31" {
+			t.Errorf("unexpected expiry for first code: %v", res.Codes[0].ExpiresAt)
+		}
+		if res.Codes[1].ExpiresAt == nil || res.Codes[1].ExpiresAt.Hour() != 12 {
+			t.Errorf("unexpected expiry for second code: %v", res.Codes[1].ExpiresAt)
+		}
+		if res.Codes[2].ExpiresAt != nil {
+			t.Errorf("third code should not expire")
+		}
+		if len(res.InvalidLines) != 1 {
+			t.Errorf("unexpected invalid lines: %v", res.InvalidLines)
+		}
+	})
+}
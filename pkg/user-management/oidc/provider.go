@@ -0,0 +1,298 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+const (
+	DEFAULT_HTTP_TIMEOUT = 10 * time.Second
+
+	// how long discovery documents and keys are cached
+	metadataCacheDuration = 24 * time.Hour
+	// minimum time between key set downloads when an unknown key ID is seen
+	jwksRefreshInterval = 5 * time.Minute
+
+	maxResponseSize = 1 << 20
+)
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrNonceMismatch  = errors.New("nonce does not match")
+)
+
+// ProviderConfig of an OpenID Connect provider, the client is registered with the redirect URL of the participant web app
+type ProviderConfig struct {
+	Name         string   `json:"name" yaml:"name"` // used in the API paths and stored with the linked identities, must not change
+	DisplayName  string   `json:"display_name" yaml:"display_name"`
+	Issuer       string   `json:"issuer" yaml:"issuer"` // discovery document at <issuer>/.well-known/openid-configuration
+	ClientID     string   `json:"client_id" yaml:"client_id"`
+	ClientSecret string   `json:"client_secret" yaml:"client_secret"` // can be empty for public clients
+	RedirectURL  string   `json:"redirect_url" yaml:"redirect_url"`
+	Scopes       []string `json:"scopes" yaml:"scopes"` // default: openid email profile
+}
+
+// Claims of a verified ID token
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type Provider struct {
+	config     ProviderConfig
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *discoveryDocument
+	discoveredAt  time.Time
+	keys          map[string]any
+	keysFetchedAt time.Time
+	now           func() time.Time
+}
+
+// NewProvider checks the configuration, the discovery document is fetched on first use
+func NewProvider(config ProviderConfig) (*Provider, error) {
+	if config.Name == "" || config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("name, issuer, client_id and redirect_url are required")
+	}
+	if strings.ContainsAny(config.Name, "/: ") {
+		return nil, fmt.Errorf("invalid provider name: %s", config.Name)
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = config.Name
+	}
+	return &Provider{
+		config:     config,
+		httpClient: &http.Client{Timeout: DEFAULT_HTTP_TIMEOUT},
+		now:        time.Now,
+	}, nil
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) DisplayName() string {
+	return p.config.DisplayName
+}
+
+// GenerateVerifier returns a new random PKCE code verifier
+func GenerateVerifier() string {
+	return oauth2.GenerateVerifier()
+}
+
+// AuthCodeURL returns the URL of the provider's sign-in page. The verifier is kept on the server, only its S256 challenge is sent.
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, verifier string) (string, error) {
+	oauthConfig, err := p.oauthConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	return oauthConfig.AuthCodeURL(
+		state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	), nil
+}
+
+// Exchange redeems the authorization code and returns the claims of the verified ID token
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string, nonce string) (Claims, error) {
+	oauthConfig, err := p.oauthConfig(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.httpClient)
+	token, err := oauthConfig.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Claims{}, err
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return Claims{}, fmt.Errorf("%w: missing in token response", ErrInvalidIDToken)
+	}
+	return p.VerifyIDToken(ctx, rawIDToken, nonce)
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string `json:"nonce"`
+	Email           string `json:"email"`
+	EmailVerified   any    `json:"email_verified"` // some providers send a string
+	Name            string `json:"name"`
+	AuthorizedParty string `json:"azp"`
+}
+
+// VerifyIDToken checks signature, issuer, audience, expiry and nonce of the ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (Claims, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	claims := idTokenClaims{}
+	_, err = jwt.ParseWithClaims(
+		rawIDToken,
+		&claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.getKey(ctx, discovery.JWKSURI, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "PS256"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %s", ErrInvalidIDToken, err.Error())
+	}
+
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return Claims{}, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if claims.Nonce != nonce {
+		return Claims{}, ErrNonceMismatch
+	}
+
+	return Claims{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+func (p *Provider) oauthConfig(ctx context.Context) (*oauth2.Config, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       p.config.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}, nil
+}
+
+func (p *Provider) getDiscovery(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && p.now().Sub(p.discoveredAt) < metadataCacheDuration {
+		return p.discovery, nil
+	}
+
+	var doc discoveryDocument
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.fetchJSON(ctx, wellKnown, &doc); err != nil {
+		if p.discovery != nil {
+			// keep using the previous document while the provider is unavailable
+			return p.discovery, nil
+		}
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+	if doc.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("discovery issuer %s does not match configured issuer %s", doc.Issuer, p.config.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("discovery document incomplete")
+	}
+
+	p.discovery = &doc
+	p.discoveredAt = p.now()
+	return p.discovery, nil
+}
+
+// getKey returns the verification key with the key ID, the key set is downloaded again if the key is unknown (key rotation)
+func (p *Provider) getKey(ctx context.Context, jwksURI string, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok && p.now().Sub(p.keysFetchedAt) < metadataCacheDuration {
+		return key, nil
+	}
+	if p.keys != nil && p.now().Sub(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown key id: %s", kid)
+	}
+
+	var set jsonWebKeySet
+	if err := p.fetchJSON(ctx, jwksURI, &set); err != nil {
+		if key, ok := p.lookupKey(kid); ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("fetching keys failed: %w", err)
+	}
+	p.keys = set.publicKeys()
+	p.keysFetchedAt = p.now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id: %s", kid)
+}
+
+func (p *Provider) lookupKey(kid string) (any, bool) {
+	if kid != "" {
+		key, ok := p.keys[kid]
+		return key, ok
+	}
+	// tokens without key ID are accepted if the provider has a single key
+	if len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func (p *Provider) fetchJSON(ctx context.Context, url string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(target)
+}
@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockProvider is a minimal OpenID provider issuing ID tokens for one pending authorization
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	clientID      string
+	code          string
+	codeChallenge string
+	nonce         string
+	claims        jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{t: t, key: key, clientID: "test-client"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "key-1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("code") != m.code {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		verifierHash := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(verifierHash[:]) != m.codeChallenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     m.idToken(m.claims),
+		})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// authorize simulates the user signing in at the provider
+func (m *mockProvider) authorize(authURL string, claims jwt.MapClaims) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != m.clientID {
+		m.t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+	m.code = "code-123"
+	m.codeChallenge = q.Get("code_challenge")
+	m.nonce = q.Get("nonce")
+
+	m.claims = jwt.MapClaims{
+		"iss":   m.server.URL,
+		"aud":   m.clientID,
+		"sub":   "user-1",
+		"nonce": m.nonce,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		m.claims[k] = v
+	}
+}
+
+func (m *mockProvider) idToken(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return signed
+}
+
+func (m *mockProvider) newProvider(t *testing.T) *Provider {
+	p, err := NewProvider(ProviderConfig{
+		Name:        "mock",
+		Issuer:      m.server.URL,
+		ClientID:    m.clientID,
+		RedirectURL: "https://study.example.com/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	mock := newMockProvider(t)
+	p := mock.newProvider(t)
+
+	verifier := GenerateVerifier()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("verified email", func(t *testing.T) {
+		mock.authorize(authURL, jwt.MapClaims{"email": "a@example.com", "email_verified": true})
+		claims, err := p.Exchange(ctx, mock.code, verifier, "nonce-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if claims.Subject != "user-1" || claims.Email != "a@example.com" || !claims.EmailVerified {
+			t.Errorf("unexpected claims: %+v", claims)
+		}
+	})
+
+	t.Run("email verified as string", func(t *testing.T) {
+		mock.authorize(authURL, jwt.MapClaims{"email": "a@example.com", "email_verified": "true"})
+		claims, err := p.Exchange(ctx, mock.code, verifier, "nonce-1")
+		if err != nil || !claims.EmailVerified {
+			t.Errorf("unexpected result: %+v, %v", claims, err)
+		}
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		mock.authorize(authURL, jwt.MapClaims{"email": "a@example.com"})
+		claims, err := p.Exchange(ctx, mock.code, verifier, "nonce-1")
+		if err != nil || claims.EmailVerified {
+			t.Errorf("unexpected result: %+v, %v", claims, err)
+		}
+	})
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		mock.authorize(authURL, nil)
+		if _, err := p.Exchange(ctx, mock.code, GenerateVerifier(), "nonce-1"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("wrong nonce", func(t *testing.T) {
+		mock.authorize(authURL, nil)
+		if _, err := p.Exchange(ctx, mock.code, verifier, "other"); !errors.Is(err, ErrNonceMismatch) {
+			t.Errorf("expected nonce error, got %v", err)
+		}
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		mock.authorize(authURL, jwt.MapClaims{"aud": "other-client"})
+		if _, err := p.Exchange(ctx, mock.code, verifier, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("expected id token error, got %v", err)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		mock.authorize(authURL, jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()})
+		if _, err := p.Exchange(ctx, mock.code, verifier, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("expected id token error, got %v", err)
+		}
+	})
+}
+
+func TestVerifyIDTokenRejectsForeignKey(t *testing.T) {
+	mock := newMockProvider(t)
+	p := mock.newProvider(t)
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": mock.server.URL,
+		"aud": mock.clientID,
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = "key-1"
+	signed, _ := token.SignedString(otherKey)
+
+	if _, err := p.VerifyIDToken(context.Background(), signed, ""); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("expected id token error, got %v", err)
+	}
+}
+
+func TestNewProviderValidation(t *testing.T) {
+	if _, err := NewProvider(ProviderConfig{Name: "x"}); err == nil {
+		t.Error("expected error for incomplete config")
+	}
+	if _, err := NewProvider(ProviderConfig{Name: "a/b", Issuer: "https://x", ClientID: "c", RedirectURL: "https://y"}); err == nil {
+		t.Error("expected error for invalid name")
+	}
+}
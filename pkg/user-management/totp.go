@@ -0,0 +1,198 @@
+package usermanagement
+
+import (
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/case-framework/case-backend/pkg/user-management/totp"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// accepted clock difference between server and authenticator app, in time steps
+const TOTP_ALLOWED_SKEW = 1
+
+var (
+	ErrTOTPNotConfigured   = errors.New("totp not configured")
+	ErrTOTPNotEnrolled     = errors.New("no authenticator app enrolled")
+	ErrTOTPAlreadyEnrolled = errors.New("authenticator app already enrolled")
+	ErrInvalidTOTPCode     = errors.New("invalid code")
+)
+
+type TOTPConfig struct {
+	Issuer        string `json:"issuer" yaml:"issuer"`                 // shown in the authenticator app
+	EncryptionKey string `json:"encryption_key" yaml:"encryption_key"` // for the secrets stored with the accounts, TOTP is disabled if empty
+}
+
+var totpSettings struct {
+	issuer    string
+	secretBox *totp.SecretBox
+}
+
+func InitTOTP(conf TOTPConfig) error {
+	box, err := totp.NewSecretBox(conf.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	totpSettings.issuer = conf.Issuer
+	totpSettings.secretBox = box
+	return nil
+}
+
+func TOTPEnabled() bool {
+	return totpSettings.secretBox != nil
+}
+
+// StartTOTPEnrolment creates a new secret for the user, replacing an unverified enrolment.
+// Returns the secret and the provisioning URI for the QR code.
+func StartTOTPEnrolment(instanceID, userID string) (secret string, uri string, err error) {
+	if !TOTPEnabled() {
+		return "", "", ErrTOTPNotConfigured
+	}
+
+	user, err := pUserDBService.GetUser(instanceID, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.Account.HasTOTP() {
+		return "", "", ErrTOTPAlreadyEnrolled
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	encrypted, err := totpSettings.secretBox.Encrypt(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = pUserDBService.UpdateUser(instanceID, userID, bson.M{"$set": bson.M{
+		"account.totp": userTypes.TOTPSettings{
+			Secret:        encrypted,
+			CreatedAt:     time.Now().Unix(),
+			RecoveryCodes: []string{},
+		},
+	}})
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.ProvisioningURI(totpSettings.issuer, user.Account.AccountID, secret), nil
+}
+
+// ConfirmTOTPEnrolment activates the enrolment with a first code from the app and returns new recovery codes
+func ConfirmTOTPEnrolment(instanceID, userID, code string) (recoveryCodes []string, err error) {
+	if !TOTPEnabled() {
+		return nil, ErrTOTPNotConfigured
+	}
+
+	user, err := pUserDBService.GetUser(instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Account.TOTP == nil {
+		return nil, ErrTOTPNotEnrolled
+	}
+	if user.Account.HasTOTP() {
+		return nil, ErrTOTPAlreadyEnrolled
+	}
+
+	secret, err := totpSettings.secretBox.Decrypt(user.Account.TOTP.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), TOTP_ALLOWED_SKEW)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	recoveryCodes, hashes, err := totp.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = pUserDBService.UpdateUser(instanceID, userID, bson.M{"$set": bson.M{
+		"account.totp.confirmedAt":   time.Now().Unix(),
+		"account.totp.lastUsedStep":  step,
+		"account.totp.recoveryCodes": hashes,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return recoveryCodes, nil
+}
+
+// VerifyTOTP checks a code from the authenticator app or one of the recovery codes.
+// Each code can be used only once.
+func VerifyTOTP(instanceID, userID, code string) (*userTypes.OTP, error) {
+	if !TOTPEnabled() {
+		return nil, ErrTOTPNotConfigured
+	}
+
+	user, err := pUserDBService.GetUser(instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Account.HasTOTP() {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	otp := &userTypes.OTP{
+		UserID:    userID,
+		CreatedAt: time.Now(),
+		Type:      userTypes.TOTP,
+	}
+
+	if hash, ok := totp.FindRecoveryCode(code, user.Account.TOTP.RecoveryCodes); ok {
+		removed, err := pUserDBService.RemoveTOTPRecoveryCode(instanceID, userID, hash)
+		if err != nil {
+			return nil, err
+		}
+		if !removed {
+			return nil, ErrInvalidTOTPCode
+		}
+		slog.Info("recovery code used", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.Int("remaining", len(user.Account.TOTP.RecoveryCodes)-1))
+		return otp, nil
+	}
+
+	secret, err := totpSettings.secretBox.Decrypt(user.Account.TOTP.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, code, time.Now(), TOTP_ALLOWED_SKEW)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	// fails for replayed codes, also with concurrent requests
+	updated, err := pUserDBService.UpdateTOTPLastUsedStep(instanceID, userID, step)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrInvalidTOTPCode
+	}
+	return otp, nil
+}
+
+// RegenerateTOTPRecoveryCodes replaces the recovery codes, the caller must have verified a code before
+func RegenerateTOTPRecoveryCodes(instanceID, userID string) ([]string, error) {
+	codes, hashes, err := totp.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = pUserDBService.UpdateUser(instanceID, userID, bson.M{"$set": bson.M{
+		"account.totp.recoveryCodes": hashes,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP removes the secret and recovery codes, the caller must have verified a code before
+func DisableTOTP(instanceID, userID string) error {
+	return pUserDBService.UpdateUser(instanceID, userID, bson.M{"$unset": bson.M{"account.totp": ""}})
+}
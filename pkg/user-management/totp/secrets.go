@@ -0,0 +1,102 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	RECOVERY_CODE_COUNT  = 10
+	recoveryCodeLength   = 10 // characters, split in two groups
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// SecretBox encrypts secrets before they are stored with the account (AES-256-GCM)
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox derives the encryption key from the configured passphrase
+func NewSecretBox(passphrase string) (*SecretBox, error) {
+	if len(passphrase) < 16 {
+		return nil, errors.New("encryption key must have at least 16 characters")
+	}
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Encrypt returns the nonce and ciphertext in base64 encoding
+func (b *SecretBox) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (b *SecretBox) Decrypt(encrypted string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < b.aead.NonceSize() {
+		return "", errors.New("invalid ciphertext")
+	}
+	plaintext, err := b.aead.Open(nil, data[:b.aead.NonceSize()], data[b.aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// GenerateRecoveryCodes returns the codes to show to the user once and their hashes to store
+func GenerateRecoveryCodes() (codes []string, hashes []string, err error) {
+	for range RECOVERY_CODE_COUNT {
+		buf := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		for i := range buf {
+			buf[i] = recoveryCodeAlphabet[int(buf[i])%len(recoveryCodeAlphabet)]
+		}
+		code := string(buf[:recoveryCodeLength/2]) + "-" + string(buf[recoveryCodeLength/2:])
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode normalises the code (case, spaces and dashes) before hashing.
+// The codes are random enough that a fast hash is sufficient.
+func HashRecoveryCode(code string) string {
+	normalised := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalised))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindRecoveryCode returns the stored hash matching the code
+func FindRecoveryCode(code string, hashes []string) (string, bool) {
+	h := HashRecoveryCode(code)
+	for _, stored := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(stored)) == 1 {
+			return stored, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,138 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	// test vectors from RFC 6238, Appendix B (SHA1)
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		if got := hotp(key, uint64(tt.unix/PERIOD), 8); got != tt.expected {
+			t.Errorf("time %d: got %s, expected %s", tt.unix, got, tt.expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+
+	code, err := GenerateCode(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "050471" {
+		t.Errorf("unexpected code %s", code)
+	}
+
+	t.Run("current step", func(t *testing.T) {
+		step, ok := Validate(secret, code, now, 1)
+		if !ok || step != TimeStep(now) {
+			t.Errorf("expected valid code for step %d, got %d %v", TimeStep(now), step, ok)
+		}
+	})
+
+	t.Run("within skew", func(t *testing.T) {
+		if _, ok := Validate(secret, "050 471", now.Add(PERIOD*time.Second), 1); !ok {
+			t.Error("expected code of previous step to be accepted")
+		}
+	})
+
+	t.Run("outside skew", func(t *testing.T) {
+		if _, ok := Validate(secret, code, now.Add(2*PERIOD*time.Second), 1); ok {
+			t.Error("expected code to be rejected")
+		}
+	})
+
+	t.Run("wrong format", func(t *testing.T) {
+		if _, ok := Validate(secret, "12345", now, 1); ok {
+			t.Error("expected code to be rejected")
+		}
+		if _, ok := Validate("not base32!", code, now, 1); ok {
+			t.Error("expected invalid secret to be rejected")
+		}
+	})
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("unexpected secret length %d", len(secret))
+	}
+
+	uri := ProvisioningURI("Example Study", "jane@example.com", secret)
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	if u.Path != "/Example Study:jane@example.com" {
+		t.Errorf("unexpected label %s", u.Path)
+	}
+	if u.Query().Get("secret") != secret || u.Query().Get("issuer") != "Example Study" || u.Query().Get("digits") != "6" {
+		t.Errorf("unexpected parameters %s", u.RawQuery)
+	}
+}
+
+func TestSecretBox(t *testing.T) {
+	if _, err := NewSecretBox("short"); err == nil {
+		t.Error("expected error for short key")
+	}
+
+	box, _ := NewSecretBox("a-long-enough-encryption-key")
+	encrypted, err := box.Encrypt("JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(encrypted, "JBSWY3DPEHPK3PXP") {
+		t.Error("secret not encrypted")
+	}
+	decrypted, err := box.Decrypt(encrypted)
+	if err != nil || decrypted != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("unexpected result %s %v", decrypted, err)
+	}
+
+	otherBox, _ := NewSecretBox("another-long-encryption-key")
+	if _, err := otherBox.Decrypt(encrypted); err == nil {
+		t.Error("expected error for wrong key")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != RECOVERY_CODE_COUNT || len(hashes) != RECOVERY_CODE_COUNT {
+		t.Fatalf("unexpected number of codes: %d", len(codes))
+	}
+
+	hash, ok := FindRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[3], "-", ""))+" ", hashes)
+	if !ok || hash != hashes[3] {
+		t.Error("expected normalised code to be found")
+	}
+	if _, ok := FindRecoveryCode("aaaaa-aaaaa", hashes); ok {
+		t.Error("expected unknown code to be rejected")
+	}
+}
@@ -1,19 +1,56 @@
 package types
 
 type Account struct {
-	Type               string           `bson:"type" json:"type"`
-	AccountID          string           `bson:"accountID" json:"accountID"`
-	AccountConfirmedAt int64            `bson:"accountConfirmedAt" json:"accountConfirmedAt"`
-	Password           string           `bson:"password" json:"password"`
-	AuthType           string           `bson:"authType" json:"authType"`
-	VerificationCode   VerificationCode `bson:"verificationCode" json:"verificationCode"`
-	PreferredLanguage  string           `bson:"preferredLanguage" json:"preferredLanguage"`
+	Type               string             `bson:"type" json:"type"`
+	AccountID          string             `bson:"accountID" json:"accountID"`
+	AccountConfirmedAt int64              `bson:"accountConfirmedAt" json:"accountConfirmedAt"`
+	Password           string             `bson:"password" json:"password"`
+	AuthType           string             `bson:"authType" json:"authType"`
+	VerificationCode   VerificationCode   `bson:"verificationCode" json:"verificationCode"`
+	PreferredLanguage  string             `bson:"preferredLanguage" json:"preferredLanguage"`
+	Passwordless       bool               `bson:"passwordless,omitempty" json:"passwordless,omitempty"` // no password is set, sign in only with login links
+	TOTP               *TOTPSettings      `bson:"totp,omitempty" json:"totp,omitempty"`
+	ExternalIdentities []ExternalIdentity `bson:"externalIdentities,omitempty" json:"externalIdentities,omitempty"` // linked OpenID Connect accounts
 
 	// Rate limiting
 	FailedLoginAttempts   []int64 `bson:"failedLoginAttempts" json:"failedLoginAttempts"`
 	PasswordResetTriggers []int64 `bson:"passwordResetTriggers" json:"passwordResetTriggers"`
 }
 
+// TOTPSettings of an authenticator app, the secret and recovery codes are never sent to clients
+type TOTPSettings struct {
+	Secret        string   `bson:"secret" json:"-"` // encrypted
+	CreatedAt     int64    `bson:"createdAt" json:"createdAt"`
+	ConfirmedAt   int64    `bson:"confirmedAt" json:"confirmedAt"` // 0 while the enrolment is not verified
+	LastUsedStep  int64    `bson:"lastUsedStep" json:"-"`          // to reject codes that were already used
+	RecoveryCodes []string `bson:"recoveryCodes" json:"-"`         // hashes of the unused recovery codes
+}
+
+// HasTOTP returns true if an authenticator app was enrolled and verified
+func (a Account) HasTOTP() bool {
+	return a.TOTP != nil && a.TOTP.ConfirmedAt > 0
+}
+
+// ExternalIdentity is an account at an OpenID Connect provider that can be used to sign in
+type ExternalIdentity struct {
+	Provider      string `bson:"provider" json:"provider"`
+	Subject       string `bson:"subject" json:"-"`
+	Email         string `bson:"email,omitempty" json:"email,omitempty"`
+	EmailVerified bool   `bson:"emailVerified" json:"emailVerified"`
+	LinkedAt      int64  `bson:"linkedAt" json:"linkedAt"`
+	LastUsedAt    int64  `bson:"lastUsedAt" json:"lastUsedAt"`
+}
+
+// FindExternalIdentity returns the linked identity of the provider
+func (a Account) FindExternalIdentity(provider string) (ExternalIdentity, bool) {
+	for _, identity := range a.ExternalIdentities {
+		if identity.Provider == provider {
+			return identity, true
+		}
+	}
+	return ExternalIdentity{}, false
+}
+
 type VerificationCode struct {
 	Code      string `bson:"code" json:"code"`
 	Attempts  int64  `bson:"attempts" json:"attempts"`
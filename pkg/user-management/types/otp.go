@@ -7,6 +7,9 @@ type OTPType string
 const (
 	EmailOTP OTPType = "email"
 	SMSOTP   OTPType = "sms"
+	TOTP     OTPType = "totp"    // authenticator app, codes are not stored
+	Passkey  OTPType = "passkey" // webauthn assertion with user verification
+	OIDC     OTPType = "oidc"    // sign-in at an external identity provider
 )
 
 type OTP struct {
@@ -14,6 +14,12 @@ const (
 	TOKEN_PURPOSE_UNSUBSCRIBE_NEWSLETTER     = "unsubscribe-newsletter"
 	TOKEN_PURPOSE_RESTORE_ACCOUNT_ID         = "restore_account_id"
 	TOKEN_PURPOSE_INACTIVE_USER_NOTIFICATION = "inactive-user-notification"
+	TOKEN_PURPOSE_CALENDAR_FEED              = "calendar-feed"
+	TOKEN_PURPOSE_LOGIN_LINK                 = "login-link"
+	TOKEN_PURPOSE_WEBAUTHN_REGISTRATION      = "webauthn-registration"
+	TOKEN_PURPOSE_WEBAUTHN_LOGIN             = "webauthn-login"
+	TOKEN_PURPOSE_OIDC_LOGIN                 = "oidc-login"
+	TOKEN_PURPOSE_OIDC_LINK                  = "oidc-link"
 )
 
 type TempToken struct {
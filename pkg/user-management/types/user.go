@@ -7,7 +7,11 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-const ACCOUNT_TYPE_EMAIL = "email"
+const (
+	ACCOUNT_TYPE_EMAIL = "email"
+	ACCOUNT_TYPE_OIDC  = "oidc"  // signed up with an external identity provider, account ID is "<provider>:<subject>"
+	ACCOUNT_TYPE_PHONE = "phone" // signed up with a phone number, account ID is the number in international format
+)
 
 type ContactInfoType string
 
@@ -62,6 +66,32 @@ func (u *User) GetEmail() (ContactInfo, error) {
 	return ContactInfo{}, errors.New("email not found")
 }
 
+// GetMessagingEmail returns the address for emails to the participant: the account ID of email accounts, otherwise a confirmed email address
+func (u *User) GetMessagingEmail() (string, error) {
+	if u.Account.Type == ACCOUNT_TYPE_EMAIL {
+		return u.Account.AccountID, nil
+	}
+	for _, ci := range u.ContactInfos {
+		if ci.Type == CONTACT_INFO_TYPE_EMAIL && ci.ConfirmedAt > 0 {
+			return ci.Email, nil
+		}
+	}
+	return "", errors.New("no confirmed email address")
+}
+
+// GetMessagingPhone returns the number for SMS to the participant: the account ID of phone accounts, otherwise a confirmed phone number
+func (u *User) GetMessagingPhone() (string, error) {
+	if u.Account.Type == ACCOUNT_TYPE_PHONE {
+		return u.Account.AccountID, nil
+	}
+	for _, ci := range u.ContactInfos {
+		if ci.Type == CONTACT_INFO_TYPE_PHONE && ci.ConfirmedAt > 0 {
+			return ci.Phone, nil
+		}
+	}
+	return "", errors.New("no confirmed phone number")
+}
+
 func (u *User) SetPhoneNumber(phone string) {
 	var newContactInfos []ContactInfo
 	for _, ci := range u.ContactInfos {
@@ -150,6 +180,9 @@ func (u *User) RemoveContactInfo(id string) error {
 			if u.Account.Type == ACCOUNT_TYPE_EMAIL && ci.Email == u.Account.AccountID {
 				return errors.New("cannot remove main address")
 			}
+			if u.Account.Type == ACCOUNT_TYPE_PHONE && ci.Phone == u.Account.AccountID {
+				return errors.New("cannot remove main phone number")
+			}
 			u.RemoveContactInfoFromContactPreferences(id)
 			u.ContactInfos = append(u.ContactInfos[:i], u.ContactInfos[i+1:]...)
 			return nil
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebAuthnCredential is a passkey registered by a participant user
+type WebAuthnCredential struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID         string             `bson:"userID" json:"userId"`
+	CredentialID   string             `bson:"credentialID" json:"credentialId"` // base64url encoded
+	PublicKey      []byte             `bson:"publicKey" json:"-"`               // COSE_Key
+	SignCount      uint32             `bson:"signCount" json:"-"`
+	AAGUID         string             `bson:"aaguid,omitempty" json:"aaguid,omitempty"`
+	Transports     []string           `bson:"transports,omitempty" json:"transports,omitempty"`
+	Name           string             `bson:"name,omitempty" json:"name,omitempty"`
+	BackupEligible bool               `bson:"backupEligible" json:"backupEligible"`
+	BackedUp       bool               `bson:"backedUp" json:"backedUp"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUsedAt     time.Time          `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	globalinfosDB "github.com/case-framework/case-backend/pkg/db/global-infos"
+	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
 	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
 	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
@@ -21,14 +22,17 @@ const (
 var (
 	pUserDBService        *userDB.ParticipantUserDBService
 	globalInfosDBServices *globalinfosDB.GlobalInfosDBService
+	messagingDBService    *messagingDB.MessagingDBService
 )
 
 func Init(
 	participantUserDBService *userDB.ParticipantUserDBService,
 	globalInfosDBService *globalinfosDB.GlobalInfosDBService,
+	messagingDBServ *messagingDB.MessagingDBService,
 ) {
 	pUserDBService = participantUserDBService
 	globalInfosDBServices = globalInfosDBService
+	messagingDBService = messagingDBServ
 }
 
 func SendOTPByEmail(
@@ -75,8 +79,14 @@ func SendOTPByEmail(
 	half := len(code) / 2
 	formattedCode := fmt.Sprintf("%s-%s", code[:half], code[half:])
 
+	email, err := user.GetMessagingEmail()
+	if err != nil {
+		slog.Error("no email address for OTP", slog.String("instanceID", instanceID), slog.String("userID", userID))
+		return err
+	}
+
 	// send OTP
-	err = sendEmail(user.Account.AccountID, formattedCode, user.Account.PreferredLanguage, time.Now().Add(time.Second*userDB.OTP_TTL).Unix())
+	err = sendEmail(email, formattedCode, user.Account.PreferredLanguage, time.Now().Add(time.Second*userDB.OTP_TTL).Unix())
 	if err != nil {
 		return err
 	}
@@ -85,6 +95,15 @@ func SendOTPByEmail(
 }
 
 func SendOTPBySMS(instanceID, userID string) error {
+	return sendSMSCode(instanceID, userID, sms.SMS_MESSAGE_TYPE_OTP)
+}
+
+// SendPasswordResetCodeBySMS sends an SMS code with the password reset template, the code is verified like other SMS OTPs
+func SendPasswordResetCodeBySMS(instanceID, userID string) error {
+	return sendSMSCode(instanceID, userID, sms.SMS_MESSAGE_TYPE_PASSWORD_RESET)
+}
+
+func sendSMSCode(instanceID, userID string, messageType string) error {
 	// check count of recent attempts
 	count, err := pUserDBService.CountOTP(instanceID, userID)
 	if err != nil {
@@ -109,18 +128,13 @@ func SendOTPBySMS(instanceID, userID string) error {
 		return err
 	}
 
-	phoneInfos, err := user.GetPhoneNumber()
+	// phone accounts receive codes before the number is confirmed, the first code confirms it
+	phone, err := user.GetMessagingPhone()
 	if err != nil {
-		slog.Error("failed to get phone number", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("error", err.Error()))
+		slog.Error("no confirmed phone number", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("error", err.Error()))
 		return err
 	}
 
-	if phoneInfos.ConfirmedAt < 1 {
-		// phone number is not confirmed yet
-		slog.Error("phone number is not confirmed", slog.String("instanceID", instanceID), slog.String("userID", userID))
-		return errors.New("phone number is not confirmed")
-	}
-
 	// generate OTP
 	code, err := utils.GenerateOTPCode(OTP_LENGTH)
 	if err != nil {
@@ -138,7 +152,7 @@ func SendOTPBySMS(instanceID, userID string) error {
 
 	// send SMS
 	return sms.SendSMS(
-		instanceID, phoneInfos.Phone, userID, sms.SMS_MESSAGE_TYPE_OTP, user.Account.PreferredLanguage, map[string]string{
+		instanceID, phone, userID, messageType, user.Account.PreferredLanguage, map[string]string{
 			"verificationCode": formattedCode,
 		},
 	)
@@ -171,6 +185,7 @@ func DeleteUser(
 	userID string,
 	notifyStudyService func(instanceID string, profiles []string) error,
 	sendEmail func(email string) error,
+	sendSMS func(phone string) error,
 ) error {
 	// find user
 	user, err := pUserDBService.GetUser(instanceID, userID)
@@ -202,13 +217,37 @@ func DeleteUser(
 		return err
 	}
 
+	// delete all passkeys
+	err = pUserDBService.DeleteWebAuthnCredentialsForUser(instanceID, userID)
+	if err != nil {
+		return err
+	}
+
+	// delete all push subscriptions
+	_, err = messagingDBService.DeletePushSubscriptionsForUser(instanceID, userID)
+	if err != nil {
+		return err
+	}
+
+	// delete personal inbox messages
+	_, err = messagingDBService.DeleteInboxMessagesForUser(instanceID, userID)
+	if err != nil {
+		return err
+	}
+
 	// delete account
 	err = pUserDBService.DeleteUser(instanceID, userID)
 	if err != nil {
 		return err
 	}
 
-	// notify user
-	err = sendEmail(user.Account.AccountID)
-	return err
+	// notify user, by SMS if there is no email address
+	if email, err := user.GetMessagingEmail(); err == nil {
+		return sendEmail(email)
+	}
+	if phone, err := user.GetMessagingPhone(); err == nil && sendSMS != nil {
+		return sendSMS(phone)
+	}
+	slog.Info("no email address or phone number to notify about account deletion", slog.String("instanceID", instanceID), slog.String("userID", userID))
+	return nil
 }
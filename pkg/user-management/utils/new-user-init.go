@@ -45,3 +45,93 @@ func InitNewEmailUser(
 
 	return newUser
 }
+
+// InitNewOIDCUser for an account created with an external identity, the email address is confirmed if the provider verified it
+func InitNewOIDCUser(
+	identity userTypes.ExternalIdentity,
+	locale string,
+) userTypes.User {
+	alias := identity.Provider
+	if identity.Email != "" {
+		alias = BlurEmailAddress(identity.Email)
+	}
+
+	newUser := userTypes.User{
+		Account: userTypes.Account{
+			Type:               userTypes.ACCOUNT_TYPE_OIDC,
+			AccountID:          identity.Provider + ":" + identity.Subject,
+			AccountConfirmedAt: time.Now().Unix(),
+			PreferredLanguage:  locale,
+			Passwordless:       true,
+			ExternalIdentities: []userTypes.ExternalIdentity{identity},
+		},
+		Profiles: []userTypes.Profile{
+			{
+				ID:                 primitive.NewObjectID(),
+				Alias:              alias,
+				MainProfile:        true,
+				AvatarID:           "default",
+				ConsentConfirmedAt: time.Now().Unix(),
+			},
+		},
+		Timestamps: userTypes.Timestamps{
+			CreatedAt: time.Now().Unix(),
+			LastLogin: time.Now().Unix(),
+		},
+	}
+
+	newUser.ContactPreferences = userTypes.ContactPreferences{
+		SubscribedToNewsletter:        true,
+		SendNewsletterTo:              []string{},
+		SubscribedToWeekly:            true,
+		ReceiveWeeklyMessageDayOfWeek: int32(CurrentWeekdayStrategy.Weekday()),
+	}
+
+	if identity.Email != "" {
+		newUser.AddNewEmail(identity.Email, identity.EmailVerified)
+		newUser.ContactPreferences.SendNewsletterTo = []string{newUser.ContactInfos[0].ID.Hex()}
+	}
+
+	return newUser
+}
+
+// InitNewPhoneUser for an account identified by its phone number, the password is optional and the number is confirmed with the first SMS code
+func InitNewPhoneUser(
+	phone string,
+	password string,
+	locale string,
+) userTypes.User {
+	newUser := userTypes.User{
+		Account: userTypes.Account{
+			Type:               userTypes.ACCOUNT_TYPE_PHONE,
+			AccountID:          phone,
+			Password:           password,
+			AccountConfirmedAt: 0,
+			PreferredLanguage:  locale,
+			Passwordless:       password == "",
+		},
+		Profiles: []userTypes.Profile{
+			{
+				ID:                 primitive.NewObjectID(),
+				Alias:              BlurPhoneNumber(phone),
+				MainProfile:        true,
+				AvatarID:           "default",
+				ConsentConfirmedAt: time.Now().Unix(),
+			},
+		},
+		Timestamps: userTypes.Timestamps{
+			CreatedAt: time.Now().Unix(),
+			LastLogin: time.Now().Unix(),
+		},
+	}
+	newUser.SetPhoneNumber(phone)
+
+	newUser.ContactPreferences = userTypes.ContactPreferences{
+		SubscribedToNewsletter:        false,
+		SendNewsletterTo:              []string{},
+		SubscribedToWeekly:            true,
+		ReceiveWeeklyMessageDayOfWeek: int32(CurrentWeekdayStrategy.Weekday()),
+	}
+
+	return newUser
+}
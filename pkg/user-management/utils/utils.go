@@ -57,6 +57,28 @@ func SanitizePhoneNumber(phone string) string {
 	return phone
 }
 
+// NormalizePhoneNumber removes formatting characters and replaces the international "00" prefix with "+"
+func NormalizePhoneNumber(phone string) string {
+	phone = SanitizePhoneNumber(phone)
+	phone = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')', '/':
+			return -1
+		}
+		return r
+	}, phone)
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	return phone
+}
+
+// CheckPhoneNumberFormat to check if input string is a phone number in international (E.164) format
+func CheckPhoneNumberFormat(phone string) bool {
+	phoneRule := regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+	return phoneRule.MatchString(phone)
+}
+
 // CheckEmailFormat to check if input string is a correct email address
 func CheckEmailFormat(email string) bool {
 	if len(email) > 254 {
@@ -82,6 +104,14 @@ func BlurEmailAddress(email string) string {
 	return blurredEmail
 }
 
+// BlurPhoneNumber keeps only the last two digits of the phone number
+func BlurPhoneNumber(phone string) string {
+	if len(phone) < 4 {
+		return "****"
+	}
+	return "****" + phone[len(phone)-2:]
+}
+
 // CheckPasswordFormat to check if password fulfills password rules
 func CheckPasswordFormat(password string) bool {
 	pl := len(password)
@@ -42,6 +42,71 @@ func TestBlurEmailAddress(t *testing.T) {
 	})
 }
 
+func TestNormalizePhoneNumber(t *testing.T) {
+	t.Run("with different formats", func(t *testing.T) {
+		phone := NormalizePhoneNumber(" +49 (0)151-234 567 \n")
+		if phone != "+490151234567" {
+			t.Errorf("unexpected phone number: %s", phone)
+		}
+
+		phone = NormalizePhoneNumber("0049 151 234567")
+		if phone != "+49151234567" {
+			t.Errorf("unexpected phone number: %s", phone)
+		}
+
+		phone = NormalizePhoneNumber("+49151234567")
+		if phone != "+49151234567" {
+			t.Errorf("unexpected phone number: %s", phone)
+		}
+	})
+}
+
+func TestCheckPhoneNumberFormat(t *testing.T) {
+	t.Run("with valid numbers", func(t *testing.T) {
+		if !CheckPhoneNumberFormat("+49151234567") {
+			t.Error("should be true")
+		}
+		if !CheckPhoneNumberFormat("+3161234567") {
+			t.Error("should be true")
+		}
+	})
+
+	t.Run("without country code", func(t *testing.T) {
+		if CheckPhoneNumberFormat("0151234567") {
+			t.Error("should be false")
+		}
+	})
+
+	t.Run("with letters", func(t *testing.T) {
+		if CheckPhoneNumberFormat("+49151abc567") {
+			t.Error("should be false")
+		}
+	})
+
+	t.Run("too short and too long", func(t *testing.T) {
+		if CheckPhoneNumberFormat("+49123") {
+			t.Error("should be false")
+		}
+		if CheckPhoneNumberFormat("+4915123456789012") {
+			t.Error("should be false")
+		}
+	})
+}
+
+func TestBlurPhoneNumber(t *testing.T) {
+	t.Run("with different lengths", func(t *testing.T) {
+		phone := BlurPhoneNumber("+49151234567")
+		if phone != "****67" {
+			t.Errorf("unexpected phone number: %s", phone)
+		}
+
+		phone = BlurPhoneNumber("+49")
+		if phone != "****" {
+			t.Errorf("unexpected phone number: %s", phone)
+		}
+	})
+}
+
 func TestCheckPasswordFormat(t *testing.T) {
 	t.Run("with a too short password", func(t *testing.T) {
 		if CheckPasswordFormat("1n34T6@") {
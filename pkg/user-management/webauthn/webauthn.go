@@ -0,0 +1,393 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DEFAULT_TIMEOUT = 5 * time.Minute
+
+	CEREMONY_CREATE = "webauthn.create"
+	CEREMONY_GET    = "webauthn.get"
+
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagBackedUp       = 0x10
+	flagAttestedData   = 0x40
+	flagExtensionData  = 0x80
+)
+
+var (
+	ErrInvalidChallenge  = errors.New("challenge does not match")
+	ErrInvalidOrigin     = errors.New("origin not allowed")
+	ErrInvalidRPID       = errors.New("relying party ID does not match")
+	ErrUserNotVerified   = errors.New("user verification missing")
+	ErrSignCountNotValid = errors.New("sign counter did not increase, the authenticator may be cloned")
+)
+
+// Config of the relying party (the participant web app)
+type Config struct {
+	RPID    string        `json:"rp_id" yaml:"rp_id"`     // domain of the web app, e.g. "study.example.com", passkeys are disabled if empty
+	RPName  string        `json:"rp_name" yaml:"rp_name"` // shown by the browser during registration
+	Origins []string      `json:"origins" yaml:"origins"` // allowed origins, e.g. "https://study.example.com"
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+func (cfg Config) Enabled() bool {
+	return cfg.RPID != ""
+}
+
+// CeremonyTimeout is how long the browser may take and the challenge stays valid
+func (cfg Config) CeremonyTimeout() time.Duration {
+	if cfg.Timeout > 0 {
+		return cfg.Timeout
+	}
+	return DEFAULT_TIMEOUT
+}
+
+// URLEncodedBytes are encoded as base64url in JSON, as used by PublicKeyCredential.toJSON()
+type URLEncodedBytes []byte
+
+func (b URLEncodedBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *URLEncodedBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := DecodeBase64URL(s)
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// DecodeBase64URL accepts base64url with or without padding
+func DecodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+type CredentialDescriptor struct {
+	Type       string          `json:"type"`
+	ID         URLEncodedBytes `json:"id"`
+	Transports []string        `json:"transports,omitempty"`
+}
+
+// CreationOptions for navigator.credentials.create()
+type CreationOptions struct {
+	Challenge URLEncodedBytes `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          URLEncodedBytes `json:"id"`
+		Name        string          `json:"name"`
+		DisplayName string          `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams []struct {
+		Type string `json:"type"`
+		Alg  int    `json:"alg"`
+	} `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		RequireResident  bool   `json:"requireResidentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// RequestOptions for navigator.credentials.get()
+type RequestOptions struct {
+	Challenge        URLEncodedBytes        `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// NewCreationOptions requests a discoverable credential with user verification, so that it can be used without a username
+func (cfg Config) NewCreationOptions(challenge []byte, userHandle []byte, userName string, exclude []CredentialDescriptor) CreationOptions {
+	opts := CreationOptions{
+		Challenge:          challenge,
+		Timeout:            cfg.CeremonyTimeout().Milliseconds(),
+		ExcludeCredentials: exclude,
+		Attestation:        "none",
+	}
+	if opts.ExcludeCredentials == nil {
+		opts.ExcludeCredentials = []CredentialDescriptor{}
+	}
+	opts.RP.ID = cfg.RPID
+	opts.RP.Name = cfg.RPName
+	if opts.RP.Name == "" {
+		opts.RP.Name = cfg.RPID
+	}
+	opts.User.ID = userHandle
+	opts.User.Name = userName
+	opts.User.DisplayName = userName
+	for _, alg := range []int{COSE_ALG_ES256, COSE_ALG_EDDSA, COSE_ALG_RS256} {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, struct {
+			Type string `json:"type"`
+			Alg  int    `json:"alg"`
+		}{Type: "public-key", Alg: alg})
+	}
+	opts.AuthenticatorSelection.ResidentKey = "required"
+	opts.AuthenticatorSelection.RequireResident = true
+	opts.AuthenticatorSelection.UserVerification = "required"
+	return opts
+}
+
+// NewRequestOptions with an empty allow list lets the user pick any passkey for the site
+func (cfg Config) NewRequestOptions(challenge []byte, allow []CredentialDescriptor) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+	return RequestOptions{
+		Challenge:        challenge,
+		RPID:             cfg.RPID,
+		Timeout:          cfg.CeremonyTimeout().Milliseconds(),
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// RegistrationResponse as returned by PublicKeyCredential.toJSON() after navigator.credentials.create()
+type RegistrationResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AttestationObject URLEncodedBytes `json:"attestationObject"`
+		Transports        []string        `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse as returned by PublicKeyCredential.toJSON() after navigator.credentials.get()
+type AssertionResponse struct {
+	ID       string          `json:"id"`
+	RawID    URLEncodedBytes `json:"rawId"`
+	Type     string          `json:"type"`
+	Response struct {
+		ClientDataJSON    URLEncodedBytes `json:"clientDataJSON"`
+		AuthenticatorData URLEncodedBytes `json:"authenticatorData"`
+		Signature         URLEncodedBytes `json:"signature"`
+		UserHandle        URLEncodedBytes `json:"userHandle"`
+	} `json:"response"`
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// ChallengeFromClientData returns the challenge the browser signed, to look up the pending ceremony
+func ChallengeFromClientData(clientDataJSON []byte) ([]byte, error) {
+	var cd clientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return nil, err
+	}
+	return DecodeBase64URL(cd.Challenge)
+}
+
+// AuthenticatorData (WebAuthn Level 2, section 6.1)
+type AuthenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte // COSE_Key
+}
+
+func (a AuthenticatorData) UserPresent() bool    { return a.Flags&flagUserPresent != 0 }
+func (a AuthenticatorData) UserVerified() bool   { return a.Flags&flagUserVerified != 0 }
+func (a AuthenticatorData) BackupEligible() bool { return a.Flags&flagBackupEligible != 0 }
+func (a AuthenticatorData) BackedUp() bool       { return a.Flags&flagBackedUp != 0 }
+
+func parseAuthenticatorData(data []byte) (AuthenticatorData, error) {
+	if len(data) < 37 {
+		return AuthenticatorData{}, errors.New("authenticator data too short")
+	}
+	ad := AuthenticatorData{
+		RPIDHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	rest := data[37:]
+
+	if ad.Flags&flagAttestedData != 0 {
+		if len(rest) < 18 {
+			return ad, errors.New("attested credential data too short")
+		}
+		ad.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength > 1023 || len(rest) < idLength {
+			return ad, errors.New("invalid credential ID length")
+		}
+		ad.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, afterKey, err := decodeCBOR(rest)
+		if err != nil {
+			return ad, err
+		}
+		ad.PublicKey = rest[:len(rest)-len(afterKey)]
+		rest = afterKey
+	}
+	if ad.Flags&flagExtensionData != 0 {
+		_, afterExtensions, err := decodeCBOR(rest)
+		if err != nil {
+			return ad, err
+		}
+		rest = afterExtensions
+	}
+	if len(rest) > 0 {
+		return ad, errors.New("unexpected data after authenticator data")
+	}
+	return ad, nil
+}
+
+// Credential is the result of a successful registration
+type Credential struct {
+	ID             []byte
+	PublicKey      []byte // COSE_Key
+	SignCount      uint32
+	AAGUID         []byte
+	Transports     []string
+	BackupEligible bool // synced passkey
+	BackedUp       bool
+}
+
+// VerifyRegistration checks the response to the creation options with the challenge.
+// Attestation statements are not verified, "none" attestation is requested.
+func (cfg Config) VerifyRegistration(resp RegistrationResponse, challenge []byte) (Credential, error) {
+	if resp.Type != "public-key" {
+		return Credential{}, errors.New("invalid credential type")
+	}
+	if err := cfg.verifyClientData(resp.Response.ClientDataJSON, CEREMONY_CREATE, challenge); err != nil {
+		return Credential{}, err
+	}
+
+	item, _, err := decodeCBOR(resp.Response.AttestationObject)
+	if err != nil {
+		return Credential{}, err
+	}
+	attestation, ok := item.(map[any]any)
+	if !ok {
+		return Credential{}, errors.New("invalid attestation object")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return Credential{}, errors.New("missing authenticator data")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if err := cfg.verifyAuthenticatorData(authData); err != nil {
+		return Credential{}, err
+	}
+	if authData.CredentialID == nil {
+		return Credential{}, errors.New("missing attested credential data")
+	}
+	if !bytes.Equal(authData.CredentialID, resp.RawID) {
+		return Credential{}, errors.New("credential ID does not match")
+	}
+	if _, err := parsePublicKey(authData.PublicKey); err != nil {
+		return Credential{}, err
+	}
+
+	return Credential{
+		ID:             authData.CredentialID,
+		PublicKey:      authData.PublicKey,
+		SignCount:      authData.SignCount,
+		AAGUID:         authData.AAGUID,
+		Transports:     resp.Response.Transports,
+		BackupEligible: authData.BackupEligible(),
+		BackedUp:       authData.BackedUp(),
+	}, nil
+}
+
+// VerifyAssertion checks the response to the request options with the challenge against the stored credential.
+// Returns the new authenticator data, its sign counter has to be stored.
+func (cfg Config) VerifyAssertion(resp AssertionResponse, challenge []byte, credentialPublicKey []byte, storedSignCount uint32) (AuthenticatorData, error) {
+	if resp.Type != "public-key" {
+		return AuthenticatorData{}, errors.New("invalid credential type")
+	}
+	if err := cfg.verifyClientData(resp.Response.ClientDataJSON, CEREMONY_GET, challenge); err != nil {
+		return AuthenticatorData{}, err
+	}
+
+	authData, err := parseAuthenticatorData(resp.Response.AuthenticatorData)
+	if err != nil {
+		return AuthenticatorData{}, err
+	}
+	if err := cfg.verifyAuthenticatorData(authData); err != nil {
+		return AuthenticatorData{}, err
+	}
+
+	key, err := parsePublicKey(credentialPublicKey)
+	if err != nil {
+		return AuthenticatorData{}, err
+	}
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	signedData := append(append([]byte{}, resp.Response.AuthenticatorData...), clientDataHash[:]...)
+	if err := key.verify(signedData, resp.Response.Signature); err != nil {
+		return AuthenticatorData{}, err
+	}
+
+	// authenticators without counter always report 0
+	if (authData.SignCount != 0 || storedSignCount != 0) && authData.SignCount <= storedSignCount {
+		return authData, ErrSignCountNotValid
+	}
+	return authData, nil
+}
+
+func (cfg Config) verifyClientData(raw []byte, ceremony string, challenge []byte) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return err
+	}
+	if cd.Type != ceremony {
+		return errors.New("invalid ceremony type")
+	}
+	received, err := DecodeBase64URL(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(received, challenge) != 1 {
+		return ErrInvalidChallenge
+	}
+	if cd.CrossOrigin || !slices.Contains(cfg.Origins, cd.Origin) {
+		return ErrInvalidOrigin
+	}
+	return nil
+}
+
+func (cfg Config) verifyAuthenticatorData(authData AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(cfg.RPID))
+	if !bytes.Equal(authData.RPIDHash, rpIDHash[:]) {
+		return ErrInvalidRPID
+	}
+	if !authData.UserPresent() || !authData.UserVerified() {
+		return ErrUserNotVerified
+	}
+	return nil
+}
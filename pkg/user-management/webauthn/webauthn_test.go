@@ -0,0 +1,253 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// minimal CBOR encoder for the test authenticator
+func cborHead(major byte, n int) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 256:
+		return []byte{major<<5 | 24, byte(n)}
+	default:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+}
+
+func cborInt(v int) []byte {
+	if v < 0 {
+		return cborHead(1, -1-v)
+	}
+	return cborHead(0, v)
+}
+
+func cborBytes(b []byte) []byte { return append(cborHead(2, len(b)), b...) }
+func cborText(s string) []byte  { return append(cborHead(3, len(s)), s...) }
+
+func cborMap(pairs ...[]byte) []byte {
+	out := cborHead(5, len(pairs)/2)
+	for _, p := range pairs {
+		out = append(out, p...)
+	}
+	return out
+}
+
+type testAuthenticator struct {
+	t            *testing.T
+	rpID         string
+	credentialID []byte
+	key          *ecdsa.PrivateKey
+	signCount    uint32
+}
+
+func newTestAuthenticator(t *testing.T, rpID string) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &testAuthenticator{t: t, rpID: rpID, credentialID: id, key: key}
+}
+
+func (a *testAuthenticator) coseKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	return cborMap(
+		cborInt(1), cborInt(coseKeyTypeEC2),
+		cborInt(3), cborInt(COSE_ALG_ES256),
+		cborInt(-1), cborInt(coseCurveP256),
+		cborInt(-2), cborBytes(x),
+		cborInt(-3), cborBytes(y),
+	)
+}
+
+func (a *testAuthenticator) authData(flags byte, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	if attested {
+		flags |= flagAttestedData
+	}
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // aaguid
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func clientDataJSON(ceremony string, challenge []byte, origin string) []byte {
+	data, _ := json.Marshal(map[string]any{
+		"type":      ceremony,
+		"challenge": base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":    origin,
+	})
+	return data
+}
+
+func (a *testAuthenticator) register(challenge []byte, origin string, flags byte) RegistrationResponse {
+	var resp RegistrationResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.credentialID)
+	resp.RawID = a.credentialID
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = clientDataJSON(CEREMONY_CREATE, challenge, origin)
+	resp.Response.AttestationObject = cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(a.authData(flags, true)),
+	)
+	return resp
+}
+
+func (a *testAuthenticator) assert(challenge []byte, origin string, flags byte) AssertionResponse {
+	a.signCount++
+	var resp AssertionResponse
+	resp.ID = base64.RawURLEncoding.EncodeToString(a.credentialID)
+	resp.RawID = a.credentialID
+	resp.Type = "public-key"
+	resp.Response.ClientDataJSON = clientDataJSON(CEREMONY_GET, challenge, origin)
+	resp.Response.AuthenticatorData = a.authData(flags, false)
+
+	clientDataHash := sha256.Sum256(resp.Response.ClientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, resp.Response.AuthenticatorData...), clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	resp.Response.Signature = sig
+	return resp
+}
+
+var testConfig = Config{RPID: "study.example.com", RPName: "Example", Origins: []string{"https://study.example.com"}}
+
+const origin = "https://study.example.com"
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	authenticator := newTestAuthenticator(t, testConfig.RPID)
+	challenge := []byte("registration-challenge")
+
+	cred, err := testConfig.VerifyRegistration(authenticator.register(challenge, origin, flagUserPresent|flagUserVerified|flagBackupEligible), challenge)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(cred.ID) != string(authenticator.credentialID) || !cred.BackupEligible || cred.BackedUp {
+		t.Errorf("unexpected credential: %+v", cred)
+	}
+
+	t.Run("assertion", func(t *testing.T) {
+		loginChallenge := []byte("login-challenge")
+		resp := authenticator.assert(loginChallenge, origin, flagUserPresent|flagUserVerified)
+
+		received, err := ChallengeFromClientData(resp.Response.ClientDataJSON)
+		if err != nil || string(received) != string(loginChallenge) {
+			t.Fatalf("unexpected challenge %s: %v", received, err)
+		}
+
+		authData, err := testConfig.VerifyAssertion(resp, loginChallenge, cred.PublicKey, cred.SignCount)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if authData.SignCount != 1 {
+			t.Errorf("unexpected sign count %d", authData.SignCount)
+		}
+
+		// replaying the counter must fail
+		if _, err := testConfig.VerifyAssertion(resp, loginChallenge, cred.PublicKey, authData.SignCount); !errors.Is(err, ErrSignCountNotValid) {
+			t.Errorf("expected sign count error, got %v", err)
+		}
+	})
+
+	t.Run("wrong challenge", func(t *testing.T) {
+		resp := authenticator.assert([]byte("other"), origin, flagUserPresent|flagUserVerified)
+		if _, err := testConfig.VerifyAssertion(resp, []byte("login-challenge"), cred.PublicKey, 0); !errors.Is(err, ErrInvalidChallenge) {
+			t.Errorf("expected challenge error, got %v", err)
+		}
+	})
+
+	t.Run("wrong origin", func(t *testing.T) {
+		resp := authenticator.assert(challenge, "https://evil.example.com", flagUserPresent|flagUserVerified)
+		if _, err := testConfig.VerifyAssertion(resp, challenge, cred.PublicKey, 0); !errors.Is(err, ErrInvalidOrigin) {
+			t.Errorf("expected origin error, got %v", err)
+		}
+	})
+
+	t.Run("without user verification", func(t *testing.T) {
+		resp := authenticator.assert(challenge, origin, flagUserPresent)
+		if _, err := testConfig.VerifyAssertion(resp, challenge, cred.PublicKey, 0); !errors.Is(err, ErrUserNotVerified) {
+			t.Errorf("expected user verification error, got %v", err)
+		}
+	})
+
+	t.Run("tampered signature", func(t *testing.T) {
+		resp := authenticator.assert(challenge, origin, flagUserPresent|flagUserVerified)
+		resp.Response.AuthenticatorData[33] ^= 0xff // sign counter
+		if _, err := testConfig.VerifyAssertion(resp, challenge, cred.PublicKey, 0); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected signature error, got %v", err)
+		}
+	})
+}
+
+func TestRegistrationRejected(t *testing.T) {
+	authenticator := newTestAuthenticator(t, "other.example.com")
+	challenge := []byte("challenge")
+	if _, err := testConfig.VerifyRegistration(authenticator.register(challenge, origin, flagUserPresent|flagUserVerified), challenge); !errors.Is(err, ErrInvalidRPID) {
+		t.Errorf("expected rp id error, got %v", err)
+	}
+
+	authenticator = newTestAuthenticator(t, testConfig.RPID)
+	resp := authenticator.register(challenge, origin, flagUserPresent|flagUserVerified)
+	resp.RawID = []byte("different")
+	if _, err := testConfig.VerifyRegistration(resp, challenge); err == nil {
+		t.Error("expected error for mismatching credential ID")
+	}
+}
+
+func TestEd25519Key(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	coseKey := cborMap(
+		cborInt(1), cborInt(coseKeyTypeOKP),
+		cborInt(3), cborInt(COSE_ALG_EDDSA),
+		cborInt(-1), cborInt(coseCurveEd25519),
+		cborInt(-2), cborBytes(pub),
+	)
+	key, err := parsePublicKey(coseKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := key.verify([]byte("data"), ed25519.Sign(priv, []byte("data"))); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := key.verify([]byte("other"), ed25519.Sign(priv, []byte("data"))); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	for _, input := range [][]byte{
+		{},
+		{0x5a, 0xff, 0xff, 0xff, 0xff}, // byte string longer than data
+		{0xbf},                         // indefinite map
+		{0xa1, 0x41, 0x00, 0x01},       // byte string as map key
+		{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // huge array
+	} {
+		if _, _, err := decodeCBOR(input); err == nil {
+			t.Errorf("expected error for %x", input)
+		}
+	}
+}
@@ -37,6 +37,13 @@ func GenerateConfidentialResponseExportSecretEnvVarName(taskName string) string
 	return "CONF_RESP_EXPORT_STUDY_GLOBAL_SECRET_FOR_" + normalizedName
 }
 
+// GenerateOIDCClientSecretEnvVarName generates an environment variable name for the client secret of an OpenID Connect
+// provider based on its name. Format: OIDC_CLIENT_SECRET_FOR_{NORMALIZED_NAME}
+func GenerateOIDCClientSecretEnvVarName(providerName string) string {
+	normalizedName := GenerateEnvVarName(providerName)
+	return "OIDC_CLIENT_SECRET_FOR_" + normalizedName
+}
+
 // GenerateSmtpServerUsernameEnvVarName generates an environment variable name for an SMTP server's username
 // based on its hostname and port. Format: SMTP_SERVER_USERNAME_FOR_{NORMALIZED_HOST}_{NORMALIZED_PORT}
 func GenerateSmtpServerUsernameEnvVarName(hostname, port string) string {
@@ -141,6 +141,24 @@ func TestGenerateConfidentialResponseExportSecretEnvVarName(t *testing.T) {
 	}
 }
 
+func TestGenerateOIDCClientSecretEnvVarName(t *testing.T) {
+	tests := []struct {
+		providerName string
+		expected     string
+	}{
+		{"google", "OIDC_CLIENT_SECRET_FOR_GOOGLE"},
+		{"uni-login", "OIDC_CLIENT_SECRET_FOR_UNI_LOGIN"},
+		{"eid_v2", "OIDC_CLIENT_SECRET_FOR_EID_V2"},
+	}
+
+	for _, test := range tests {
+		result := GenerateOIDCClientSecretEnvVarName(test.providerName)
+		if result != test.expected {
+			t.Errorf("GenerateOIDCClientSecretEnvVarName(%q) = %q, expected %q", test.providerName, result, test.expected)
+		}
+	}
+}
+
 func TestGenerateSmtpServerUsernameEnvVarName(t *testing.T) {
 	tests := []struct {
 		hostname string
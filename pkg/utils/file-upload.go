@@ -21,10 +21,16 @@ func ValidateFileTypeFromContent(fileHeader *multipart.FileHeader, allowedTypes
 	}
 	defer file.Close()
 
+	return ValidateFileTypeFromReader(file, allowedTypes)
+}
+
+// ValidateFileTypeFromReader detects the content type from the first 512 bytes of the reader
+// and checks it against the allowed MIME types.
+func ValidateFileTypeFromReader(r io.Reader, allowedTypes []string) (string, error) {
 	// Read first 512 bytes for content type detection
 	buffer := make([]byte, 512)
-	n, err := file.Read(buffer)
-	if err != nil && !errors.Is(err, io.EOF) {
+	n, err := io.ReadFull(r, buffer)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
 	if n == 0 {
@@ -45,6 +45,8 @@ type HttpEndpoints struct {
 	globalStudySecret   string
 	filestorePath       string
 	dailyFileExportPath string
+
+	participantFilestorePath string
 }
 
 func NewHTTPHandler(
@@ -59,6 +61,7 @@ func NewHTTPHandler(
 	globalStudySecret string,
 	filestorePath string,
 	dailyFileExportPath string,
+	participantFilestorePath string,
 ) *HttpEndpoints {
 	return &HttpEndpoints{
 		tokenSignKey:        tokenSignKey,
@@ -72,5 +75,7 @@ func NewHTTPHandler(
 		tokenExpiresIn:      tokenExpiresIn,
 		filestorePath:       filestorePath,
 		dailyFileExportPath: dailyFileExportPath,
+
+		participantFilestorePath: participantFilestorePath,
 	}
 }
@@ -101,6 +101,35 @@ func (h *HttpEndpoints) addMessagingSMSTemplatesAPI(rg *gin.RouterGroup) {
 		nil,
 		h.getSMSTemplate,
 	))
+
+	// SMS variants of study messages, used for participants without email address
+	smsTemplatesGroup.GET("/study-templates/:studyKey", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_STUDY_EMAIL_TEMPLATES},
+			Action:       pc.ACTION_ALL,
+		},
+		getStudyKeyLimiterFromContext,
+		h.getStudySMSTemplates,
+	))
+	smsTemplatesGroup.POST("/study-templates/:studyKey", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_STUDY_EMAIL_TEMPLATES},
+			Action:       pc.ACTION_ALL,
+		},
+		getStudyKeyLimiterFromContext,
+		h.saveStudySMSTemplate,
+	))
+	smsTemplatesGroup.DELETE("/study-templates/:studyKey/:messageType", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType: pc.RESOURCE_TYPE_MESSAGING,
+			ResourceKeys: []string{pc.RESOURCE_KEY_MESSAGING_STUDY_EMAIL_TEMPLATES},
+			Action:       pc.ACTION_ALL,
+		},
+		getStudyKeyLimiterFromContext,
+		h.deleteStudySMSTemplate,
+	))
 }
 
 func (h *HttpEndpoints) addMessagingStudyEmailTemplatesAPI(rg *gin.RouterGroup) {
@@ -317,6 +346,8 @@ func (h *HttpEndpoints) saveSMSTemplate(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
 		return
 	}
+	// study templates are saved through the study endpoints
+	template.StudyKey = ""
 
 	err := templates.CheckAllTranslationsParsable(template.Translations, template.MessageType)
 	if err != nil {
@@ -336,6 +367,78 @@ func (h *HttpEndpoints) saveSMSTemplate(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"template": savedTemplate})
 }
 
+func (h *HttpEndpoints) getStudySMSTemplates(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.Info("getting study SMS templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	messages, err := h.messagingDBConn.GetStudySMSTemplates(token.InstanceID, studyKey)
+	if err != nil {
+		slog.Error("error getting study SMS templates", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting study SMS templates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"templates": messages})
+}
+
+func (h *HttpEndpoints) saveStudySMSTemplate(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	if _, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey); err != nil {
+		slog.Error("study not found", slog.String("studyKey", studyKey), slog.String("instanceID", token.InstanceID))
+		c.JSON(http.StatusNotFound, gin.H{"error": "study not found"})
+		return
+	}
+
+	// parse body
+	var template messagingTypes.SMSTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		slog.Error("error parsing request body", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error parsing request body"})
+		return
+	}
+	template.StudyKey = studyKey
+
+	// same message type as the study email template it replaces
+	template.MessageType = templates.SanitizeMessageType(template.MessageType)
+
+	err := templates.CheckAllTranslationsParsable(template.Translations, template.MessageType)
+	if err != nil {
+		slog.Error("error parsing template", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "error while checking template validity"})
+		return
+	}
+
+	slog.Info("saving study SMS template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	savedTemplate, err := h.messagingDBConn.SaveSMSTemplate(token.InstanceID, template)
+	if err != nil {
+		slog.Error("error saving study SMS template", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving study SMS template"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"template": savedTemplate})
+}
+
+func (h *HttpEndpoints) deleteStudySMSTemplate(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	messageType := url.QueryEscape(c.Param("messageType"))
+
+	slog.Info("deleting study SMS template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("messageType", messageType))
+
+	err := h.messagingDBConn.DeleteSMSTemplate(token.InstanceID, messageType, studyKey)
+	if err != nil {
+		slog.Error("error deleting study SMS template", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error deleting study SMS template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "template deleted"})
+}
+
 func (h *HttpEndpoints) getStudyMessageTemplatesForAllStudies(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	slog.Info("getting study message templates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
@@ -408,6 +511,12 @@ func (h *HttpEndpoints) saveStudyMessageTemplate(c *gin.Context) {
 		return
 	}
 
+	if err := emailtemplates.CheckChannels(template); err != nil {
+		slog.Error("invalid template channels", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	slog.Info("saving study message template", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	savedTemplate, err := h.messagingDBConn.SaveEmailTemplate(token.InstanceID, template)
@@ -487,6 +596,12 @@ func (h *HttpEndpoints) SaveScheduledEmail(c *gin.Context) {
 		return
 	}
 
+	if err := emailtemplates.CheckChannels(schedule.Template); err != nil {
+		slog.Error("invalid template channels", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// ensure that times are in the future
 	if 0 < schedule.Until {
 		if schedule.Until < time.Now().Unix() {
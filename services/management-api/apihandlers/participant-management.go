@@ -1,8 +1,10 @@
 package apihandlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -12,6 +14,7 @@ import (
 	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
 	pc "github.com/case-framework/case-backend/pkg/permission-checker"
 	studyService "github.com/case-framework/case-backend/pkg/study"
+	"github.com/case-framework/case-backend/pkg/study/surveyengine"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 )
 
@@ -67,6 +70,19 @@ func (h *HttpEndpoints) addParticipantManagementEndpoints(rg *gin.RouterGroup) {
 			h.submitParticipantEvent,
 		))
 
+	participantGroup.PUT("/:participantID/arm",
+		mw.RequirePayload(),
+		h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_EDIT_PARTICIPANT_DATA,
+			},
+			nil,
+			h.updateParticipantArm,
+		))
+
 	participantGroup.POST("/:participantID/remove-session",
 		mw.RequirePayload(),
 		h.useAuthorisedHandler(
@@ -171,6 +187,15 @@ func (h *HttpEndpoints) submitParticipantResponse(c *gin.Context) {
 
 	result, err := studyService.OnSubmitResponseOnBehalfOfParticipant(token.InstanceID, studyKey, participantID, req, token.Subject)
 	if err != nil {
+		if errors.Is(err, studyService.ErrInvalidSubmissionID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var vErrs surveyengine.ValidationErrors
+		if errors.As(err, &vErrs) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid response", "validationErrors": vErrs})
+			return
+		}
 		slog.Error("failed to submit response", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to submit response"})
 		return
@@ -218,6 +243,64 @@ type RemoveStudySessionRequest struct {
 	ReplacementSession string `json:"replacementSession"` // optional; if empty, session association is removed
 }
 
+type UpdateParticipantArmRequest struct {
+	ArmKey string `json:"armKey"` // empty to remove the participant from the current arm
+	Reason string `json:"reason"`
+}
+
+func (h *HttpEndpoints) updateParticipantArm(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	participantID := c.Param("participantID")
+
+	var req UpdateParticipantArmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		slog.Error("failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return
+	}
+	if req.ArmKey != "" && !study.HasArm(req.ArmKey) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "arm is not defined for the study"})
+		return
+	}
+
+	p, err := h.studyDBConn.GetParticipantByID(token.InstanceID, studyKey, participantID)
+	if err != nil {
+		slog.Error("failed to get participant", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "participant not found"})
+		return
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "management:" + token.Subject
+	}
+	p, err = h.studyDBConn.UpdateParticipantArm(token.InstanceID, studyKey, p.WithArm(req.ArmKey, reason, time.Now().Unix()))
+	if err != nil {
+		slog.Error("failed to update participant arm", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update participant arm"})
+		return
+	}
+
+	slog.Info("updated participant arm",
+		slog.String("instanceID", token.InstanceID),
+		slog.String("userID", token.Subject),
+		slog.String("studyKey", studyKey),
+		slog.String("participantID", participantID),
+		slog.String("armKey", req.ArmKey),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"participant": p})
+}
+
 func (h *HttpEndpoints) removeStudySession(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
@@ -299,6 +382,10 @@ func (h *HttpEndpoints) submitParticipantEvent(c *gin.Context) {
 	result, err := studyService.OnCustomStudyEventOnBehalfOfParticipant(token.InstanceID, studyKey, participantID, req.EventKey, req.Payload)
 	if err != nil {
 		slog.Error("failed to submit event", slog.String("error", err.Error()))
+		if errors.Is(err, studyTypes.ErrStudyQuotaFull) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -27,6 +28,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
 	studyService "github.com/case-framework/case-backend/pkg/study"
@@ -370,7 +372,7 @@ func (h *HttpEndpoints) addStudyConfigEndpoints(rg *gin.RouterGroup) {
 				Action:              pc.ACTION_READ_STUDY_CONFIG,
 			},
 			nil,
-			h.getStudyCodeListEntriesHandler, // ?listKey=xxx&page=1&limit=10
+			h.getStudyCodeListEntriesHandler, // ?listKey=xxx&status=claimed&page=1&limit=10
 		))
 
 	// add study codes
@@ -385,6 +387,29 @@ func (h *HttpEndpoints) addStudyConfigEndpoints(rg *gin.RouterGroup) {
 		h.addStudyCodeListEntriesHandler,
 	))
 
+	// bulk import codes from a CSV or text file
+	studyCodeListGroup.POST("/import", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_MANAGE_STUDY_CODE_LISTS,
+		},
+		nil,
+		h.importStudyCodeListEntriesHandler,
+	))
+
+	studyCodeListGroup.GET("/export", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_READ_STUDY_CONFIG,
+		},
+		nil,
+		h.exportStudyCodeListEntriesHandler, // ?listKey=xy&format=csv|text
+	))
+
 	// remove study code
 	studyCodeListGroup.DELETE("/codes", h.useAuthorisedHandler(
 		RequiredPermission{
@@ -444,6 +469,122 @@ func (h *HttpEndpoints) addStudyConfigEndpoints(rg *gin.RouterGroup) {
 		h.removeStudyCounter,
 	))
 
+	studyCounterGroup.PUT("/:scope/config", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_MANAGE_STUDY_COUNTERS,
+		},
+		nil,
+		h.saveStudyCounterConfig,
+	))
+
+	studyCounterGroup.GET("/:scope/log", h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_READ_STUDY_CONFIG,
+		},
+		nil,
+		h.getStudyCounterLog, // ?page=1&limit=10&participantID=xy
+	))
+
+	rg.PUT("/arms", mw.RequirePayload(), h.useAuthorisedHandler(
+		RequiredPermission{
+			ResourceType:        pc.RESOURCE_TYPE_STUDY,
+			ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+			ExtractResourceKeys: getStudyKeyFromParams,
+			Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+		},
+		nil,
+		h.updateStudyArms,
+	))
+
+	studyQuotasGroup := rg.Group("/quotas")
+	{
+		studyQuotasGroup.GET("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getStudyQuotaFillLevels,
+		))
+
+		studyQuotasGroup.PUT("/", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+			},
+			nil,
+			h.updateStudyQuotas,
+		))
+
+		studyQuotasGroup.GET("/:quotaKey/entries", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getStudyQuotaEntries, // ?status=waitlisted&page=1&limit=10
+		))
+	}
+
+	studyConsentsGroup := rg.Group("/consents")
+	{
+		studyConsentsGroup.GET("/", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getLatestConsentDocuments,
+		))
+
+		studyConsentsGroup.POST("/", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_UPDATE_STUDY_PROPS,
+			},
+			nil,
+			h.publishConsentDocument,
+		))
+
+		studyConsentsGroup.GET("/:consentKey/versions", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getConsentDocumentVersions,
+		))
+
+		studyConsentsGroup.GET("/:consentKey/records", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_PARTICIPANT_STATES,
+			},
+			nil,
+			h.getConsentRecords, // ?participantID=xy&version=2&status=withdrawn&page=1&limit=10
+		))
+	}
+
 	studyVariablesGroup := rg.Group("/variables")
 	{
 		studyVariablesGroup.GET("/", h.useAuthorisedHandler(
@@ -511,6 +652,50 @@ func (h *HttpEndpoints) addStudyConfigEndpoints(rg *gin.RouterGroup) {
 			nil,
 			h.deleteStudyVariable,
 		))
+
+		studyVariablesGroup.GET("/:variableKey/history", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getStudyVariableHistory, // ?page=1&limit=10
+		))
+
+		studyVariablesGroup.GET("/:variableKey/schedules", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_READ_STUDY_CONFIG,
+			},
+			nil,
+			h.getStudyVariableSchedules, // ?onlyPending=true
+		))
+
+		studyVariablesGroup.POST("/:variableKey/schedules", mw.RequirePayload(), h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_MANAGE_STUDY_VARIABLES,
+			},
+			nil,
+			h.scheduleStudyVariableValue,
+		))
+
+		studyVariablesGroup.DELETE("/:variableKey/schedules/:scheduleID", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_MANAGE_STUDY_VARIABLES,
+			},
+			nil,
+			h.deleteStudyVariableSchedule,
+		))
 	}
 }
 
@@ -1056,6 +1241,18 @@ func (h *HttpEndpoints) addStudyDataExplorerEndpoints(rg *gin.RouterGroup) {
 			h.getStudyFile,
 		))
 
+		// get preview of a file by ID
+		filesGroup.GET("/:fileID/preview", h.useAuthorisedHandler(
+			RequiredPermission{
+				ResourceType:        pc.RESOURCE_TYPE_STUDY,
+				ResourceKeys:        []string{pc.RESOURCE_KEY_STUDY_ALL},
+				ExtractResourceKeys: getStudyKeyFromParams,
+				Action:              pc.ACTION_GET_FILES,
+			},
+			nil,
+			h.getStudyFilePreview,
+		))
+
 		// delete file by ID
 		filesGroup.DELETE("/:fileID", h.useAuthorisedHandler(
 			RequiredPermission{
@@ -1368,8 +1565,9 @@ func (h *HttpEndpoints) updateStudyDisplayProps(c *gin.Context) {
 }
 
 type FileUploadRuleUpdateReq struct {
-	SimplifiedAllow bool                   `json:"simplifiedAllowedUpload"`
-	Expression      *studyTypes.Expression `json:"expression,omitempty"`
+	SimplifiedAllow   bool                   `json:"simplifiedAllowedUpload"`
+	Expression        *studyTypes.Expression `json:"expression,omitempty"`
+	StripFileMetadata *bool                  `json:"stripFileMetadata,omitempty"`
 }
 
 func (h *HttpEndpoints) updateStudyFileUploadRule(c *gin.Context) {
@@ -1413,6 +1611,15 @@ func (h *HttpEndpoints) updateStudyFileUploadRule(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study file upload rule"})
 		return
 	}
+
+	if req.StripFileMetadata != nil {
+		err = h.studyDBConn.UpdateStudyStripFileMetadata(token.InstanceID, studyKey, *req.StripFileMetadata)
+		if err != nil {
+			slog.Error("failed to update study file metadata option", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study file metadata option"})
+			return
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "study file upload rule updated"})
 }
 
@@ -1498,6 +1705,11 @@ func (h *HttpEndpoints) createSurvey(c *gin.Context) {
 		return
 	}
 	survey.SurveyKey = survey.SurveyDefinition.Key
+	if err := studyTypes.ValidateSurveyScoring(survey.Scoring); err != nil {
+		slog.Error("invalid survey scoring", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	slog.Info("creating survey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", survey.SurveyDefinition.Key))
 
@@ -1569,6 +1781,11 @@ func (h *HttpEndpoints) updateSurvey(c *gin.Context) {
 		return
 	}
 	survey.SurveyKey = survey.SurveyDefinition.Key
+	if err := studyTypes.ValidateSurveyScoring(survey.Scoring); err != nil {
+		slog.Error("invalid survey scoring", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if survey.SurveyKey != surveyKey {
 		slog.Error("survey key in request does not match", slog.String("key", survey.SurveyKey))
@@ -1597,6 +1814,11 @@ func (h *HttpEndpoints) updateSurvey(c *gin.Context) {
 		return
 	}
 
+	// drafts of previous versions cannot be resumed with the new survey definition
+	if _, err := h.studyDBConn.DeleteOutdatedSurveyResponseDrafts(token.InstanceID, studyKey, surveyKey, survey.VersionID); err != nil {
+		slog.Error("failed to delete outdated survey response drafts", slog.String("error", err.Error()), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	}
+
 	c.JSON(http.StatusOK, gin.H{"survey": survey})
 }
 
@@ -1615,6 +1837,10 @@ func (h *HttpEndpoints) unpublishSurvey(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.studyDBConn.DeleteOutdatedSurveyResponseDrafts(token.InstanceID, studyKey, surveyKey, ""); err != nil {
+		slog.Error("failed to delete survey response drafts", slog.String("error", err.Error()), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey))
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "survey unpublished"})
 }
 
@@ -1905,7 +2131,9 @@ func (h *HttpEndpoints) getStudyCodeListEntriesHandler(c *gin.Context) {
 
 	slog.Info("getting study code list entries", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("listKey", listKey))
 
-	entries, paginationInfo, err := h.studyDBConn.GetStudyCodeListEntries(token.InstanceID, studyKey, listKey, query.Page, query.Limit)
+	status := c.DefaultQuery("status", "")
+
+	entries, paginationInfo, err := h.studyDBConn.GetStudyCodeListEntries(token.InstanceID, studyKey, listKey, status, query.Page, query.Limit)
 	if err != nil {
 		slog.Error("failed to get study code list entries", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("listKey", listKey), slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -1916,8 +2144,9 @@ func (h *HttpEndpoints) getStudyCodeListEntriesHandler(c *gin.Context) {
 }
 
 type AddStudyCodeListEntriesRequest struct {
-	ListKey string   `json:"listKey"`
-	Codes   []string `json:"codes"`
+	ListKey   string     `json:"listKey"`
+	Codes     []string   `json:"codes"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 func (h *HttpEndpoints) addStudyCodeListEntriesHandler(c *gin.Context) {
@@ -1954,147 +2183,592 @@ func (h *HttpEndpoints) addStudyCodeListEntriesHandler(c *gin.Context) {
 			continue
 		}
 
-		err := h.studyDBConn.AddStudyCodeListEntry(token.InstanceID, studyKey, req.ListKey, code)
-		if err != nil {
-			slog.Error("failed to add study code list entry", slog.String("error", err.Error()))
-			errors = append(errors, fmt.Sprintf("failed to add study code list entry '%s': %s", code, err.Error()))
-			continue
-		}
+		err := h.studyDBConn.AddStudyCodeListEntry(token.InstanceID, studyKey, req.ListKey, code, req.ExpiresAt)
+		if err != nil {
+			slog.Error("failed to add study code list entry", slog.String("error", err.Error()))
+			errors = append(errors, fmt.Sprintf("failed to add study code list entry '%s': %s", code, err.Error()))
+			continue
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"errors": errors})
+}
+
+func (h *HttpEndpoints) importStudyCodeListEntriesHandler(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	listKey := strings.TrimSpace(c.PostForm("listKey"))
+
+	if studyKey == "" || listKey == "" {
+		slog.Error("studyKey and listKey are required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "studyKey and listKey are required"})
+		return
+	}
+
+	// optional default expiry for codes without an expiry date in the file
+	var defaultExpiresAt *time.Time
+	if v := strings.TrimSpace(c.PostForm("expiresAt")); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			slog.Error("invalid expiresAt", slog.String("error", err.Error()))
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expiresAt, expected RFC3339 format"})
+			return
+		}
+		defaultExpiresAt = &t
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		slog.Error("failed to get file", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		slog.Error("failed to open file", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open file"})
+		return
+	}
+	defer f.Close()
+
+	parsed, err := studyutils.ParseStudyCodeImport(f)
+	if err != nil {
+		slog.Error("failed to parse study code import", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to parse file"})
+		return
+	}
+
+	slog.Info("importing study code list entries", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("listKey", listKey), slog.Int("count", len(parsed.Codes)))
+
+	entries := make([]studyTypes.StudyCodeListEntry, len(parsed.Codes))
+	for i, code := range parsed.Codes {
+		expiresAt := code.ExpiresAt
+		if expiresAt == nil {
+			expiresAt = defaultExpiresAt
+		}
+		entries[i] = studyTypes.StudyCodeListEntry{
+			Code:      code.Code,
+			ExpiresAt: expiresAt,
+		}
+	}
+
+	added, alreadyExisting, err := h.studyDBConn.AddStudyCodeListEntries(token.InstanceID, studyKey, listKey, entries)
+	if err != nil {
+		slog.Error("failed to import study code list entries", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import study code list entries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"added":            added,
+		"duplicatesInFile": parsed.DuplicatesInFile,
+		"alreadyExisting":  alreadyExisting,
+		"invalidLines":     parsed.InvalidLines,
+	})
+}
+
+func (h *HttpEndpoints) exportStudyCodeListEntriesHandler(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	listKey := strings.TrimSpace(c.DefaultQuery("listKey", ""))
+	format := c.DefaultQuery("format", "csv")
+
+	if studyKey == "" || listKey == "" {
+		slog.Error("studyKey and listKey are required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "studyKey and listKey are required"})
+		return
+	}
+	if format != "csv" && format != "text" {
+		slog.Error("invalid format", slog.String("format", format))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format query parameter"})
+		return
+	}
+
+	slog.Info("exporting study code list", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("listKey", listKey), slog.String("format", format))
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	if format == "text" {
+		c.Header("Content-Disposition", "attachment; filename="+fmt.Sprintf("study-codes_%s_%s.txt", studyKey, listKey))
+		c.Header("Content-Type", "text/plain")
+		c.Status(http.StatusOK)
+	} else {
+		c.Header("Content-Disposition", "attachment; filename="+fmt.Sprintf("study-codes_%s_%s.csv", studyKey, listKey))
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+	}
+
+	csvWriter := csv.NewWriter(c.Writer)
+	if format == "csv" {
+		if err := csvWriter.Write([]string{"code", "status", "addedAt", "expiresAt", "reservedAt", "reservedBy", "claimedAt", "claimedBy", "claimedByEvent"}); err != nil {
+			slog.Error("failed to write csv header", slog.String("error", err.Error()))
+			return
+		}
+	}
+
+	err := h.studyDBConn.FindAndExecuteOnStudyCodeListEntries(
+		c.Request.Context(),
+		token.InstanceID,
+		studyKey,
+		listKey,
+		func(entry studyTypes.StudyCodeListEntry) error {
+			if format == "text" {
+				_, err := c.Writer.WriteString(entry.Code + "\n")
+				return err
+			}
+			return csvWriter.Write([]string{
+				entry.Code,
+				entry.GetStatus(),
+				entry.AddedAt.UTC().Format(time.RFC3339),
+				formatTime(entry.ExpiresAt),
+				formatTime(entry.ReservedAt),
+				entry.ReservedBy,
+				formatTime(entry.ClaimedAt),
+				entry.ClaimedBy,
+				entry.ClaimedByEvent,
+			})
+		},
+	)
+	csvWriter.Flush()
+	if err != nil {
+		slog.Error("failed to export study code list", slog.String("error", err.Error()))
+	}
+}
+
+func (h *HttpEndpoints) removeStudyCodeListEntryHandler(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := strings.TrimSpace(c.Param("studyKey"))
+	listKey := strings.TrimSpace(c.DefaultQuery("listKey", ""))
+	code := strings.TrimSpace(c.DefaultQuery("code", ""))
+
+	if studyKey == "" || listKey == "" {
+		slog.Error("Missing required parameters", slog.String("studyKey", studyKey), slog.String("listKey", listKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required parameters"})
+		return
+	}
+
+	if code == "" {
+		// remove full list
+		slog.Info("deleting study code list (full)", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("listKey", listKey))
+		err := h.studyDBConn.DeleteStudyCodeListEntries(token.InstanceID, studyKey, listKey)
+		if err != nil {
+			slog.Error("Error deleting study code list", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		slog.Info("deleting study code list entry", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("listKey", listKey), slog.String("code", code))
+
+		err := h.studyDBConn.DeleteStudyCodeListEntry(token.InstanceID, studyKey, listKey, code)
+		if err != nil {
+			slog.Error("Error deleting study code list entry", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *HttpEndpoints) getStudyCounterValues(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.Info("getting study counter values", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	values, err := h.studyDBConn.GetAllStudyCounterValues(token.InstanceID, studyKey)
+	if err != nil {
+		slog.Error("failed to get study counter values", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study counter values"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"values": values})
+}
+
+func (h *HttpEndpoints) saveStudyCounterValue(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	scope := strings.TrimSpace(c.Param("scope"))
+
+	if scope == "" {
+		slog.Error("scope is required", slog.String("studyKey", studyKey), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope is required"})
+		return
+	}
+
+	var req struct {
+		Value int64 `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.Info("saving study counter value", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scope", scope), slog.String("value", fmt.Sprintf("%d", req.Value)))
+
+	value, err := h.studyDBConn.SaveStudyCounterValue(token.InstanceID, studyKey, scope, req.Value)
+	if err != nil {
+		slog.Error("failed to save study counter value", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save study counter value"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"value": value})
+}
+
+func (h *HttpEndpoints) incrementStudyCounter(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	scope := strings.TrimSpace(c.Param("scope"))
+
+	if scope == "" {
+		slog.Error("scope is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope is required"})
+		return
+	}
+
+	slog.Info("incrementing study counter", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scope", scope))
+
+	value, err := h.studyDBConn.IncrementAndGetStudyCounterValue(token.InstanceID, studyKey, scope, studyTypes.StudyCounterIncrementOrigin{
+		IncrementedBy: token.Subject,
+	})
+	if err != nil {
+		slog.Error("failed to increment study counter", slog.String("error", err.Error()))
+		if errors.Is(err, studyTypes.ErrStudyCounterOverflow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to increment study counter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"value": value})
+}
+
+func (h *HttpEndpoints) removeStudyCounter(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	scope := strings.TrimSpace(c.Param("scope"))
+
+	if scope == "" {
+		slog.Error("scope is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope is required"})
+		return
+	}
+
+	slog.Info("removing study counter", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scope", scope))
+
+	err := h.studyDBConn.RemoveStudyCounterValue(token.InstanceID, studyKey, scope)
+	if err != nil {
+		slog.Error("failed to remove study counter", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove study counter"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *HttpEndpoints) saveStudyCounterConfig(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	scope := strings.TrimSpace(c.Param("scope"))
+
+	if scope == "" {
+		slog.Error("scope is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope is required"})
+		return
+	}
+
+	var req studyTypes.StudyCounterConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		slog.Error("invalid study counter config", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.Info("saving study counter config", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scope", scope))
+
+	counter, err := h.studyDBConn.SaveStudyCounterConfig(token.InstanceID, studyKey, scope, req)
+	if err != nil {
+		slog.Error("failed to save study counter config", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save study counter config"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"counter": counter})
+}
+
+func (h *HttpEndpoints) getStudyCounterLog(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	scope := strings.TrimSpace(c.Param("scope"))
+	participantID := c.DefaultQuery("participantID", "")
+
+	if scope == "" {
+		slog.Error("scope is required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope is required"})
+		return
+	}
+
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil {
+		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.Info("getting study counter log", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scope", scope))
+
+	entries, paginationInfo, err := h.studyDBConn.GetStudyCounterLog(token.InstanceID, studyKey, scope, participantID, query.Page, query.Limit)
+	if err != nil {
+		slog.Error("failed to get study counter log", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study counter log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"log": entries, "pagination": paginationInfo})
+}
+
+type StudyArmsUpdateReq struct {
+	Arms []studyTypes.StudyArm `json:"arms"`
+}
+
+func (h *HttpEndpoints) updateStudyArms(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req StudyArmsUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if err := studyTypes.ValidateStudyArms(req.Arms); err != nil {
+		slog.Error("invalid study arms", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.Info("updating study arms", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyArms(token.InstanceID, studyKey, req.Arms)
+	if err != nil {
+		slog.Error("failed to update study arms", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study arms"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "study arms updated"})
+}
+
+func (h *HttpEndpoints) getStudyQuotaFillLevels(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	slog.Info("getting study quota fill levels", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		slog.Error("failed to get study", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return
+	}
+
+	fillLevels, err := h.studyDBConn.GetStudyQuotaFillLevels(token.InstanceID, study)
+	if err != nil {
+		slog.Error("failed to get study quota fill levels", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study quota fill levels"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quotas": fillLevels})
+}
+
+type StudyQuotasUpdateReq struct {
+	Quotas []studyTypes.StudyQuota `json:"quotas"`
+}
+
+func (h *HttpEndpoints) updateStudyQuotas(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req StudyQuotasUpdateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+	if err := studyTypes.ValidateStudyQuotas(req.Quotas); err != nil {
+		slog.Error("invalid study quotas", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.Info("updating study quotas", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+
+	err := h.studyDBConn.UpdateStudyQuotas(token.InstanceID, studyKey, req.Quotas)
+	if err != nil {
+		slog.Error("failed to update study quotas", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study quotas"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"errors": errors})
+	c.JSON(http.StatusOK, gin.H{"message": "study quotas updated"})
 }
 
-func (h *HttpEndpoints) removeStudyCodeListEntryHandler(c *gin.Context) {
+func (h *HttpEndpoints) getStudyQuotaEntries(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	quotaKey := c.Param("quotaKey")
+	status := c.DefaultQuery("status", "")
 
-	studyKey := strings.TrimSpace(c.Param("studyKey"))
-	listKey := strings.TrimSpace(c.DefaultQuery("listKey", ""))
-	code := strings.TrimSpace(c.DefaultQuery("code", ""))
+	if status != "" && status != studyTypes.STUDY_QUOTA_ENTRY_STATUS_COUNTED && status != studyTypes.STUDY_QUOTA_ENTRY_STATUS_WAITLISTED {
+		slog.Error("invalid status", slog.String("status", status))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
 
-	if studyKey == "" || listKey == "" {
-		slog.Error("Missing required parameters", slog.String("studyKey", studyKey), slog.String("listKey", listKey))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required parameters"})
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil {
+		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	if code == "" {
-		// remove full list
-		slog.Info("deleting study code list (full)", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("listKey", listKey))
-		err := h.studyDBConn.DeleteStudyCodeListEntries(token.InstanceID, studyKey, listKey)
-		if err != nil {
-			slog.Error("Error deleting study code list", slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-	} else {
-		slog.Info("deleting study code list entry", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("listKey", listKey), slog.String("code", code))
+	slog.Info("getting study quota entries", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("quotaKey", quotaKey))
 
-		err := h.studyDBConn.DeleteStudyCodeListEntry(token.InstanceID, studyKey, listKey, code)
-		if err != nil {
-			slog.Error("Error deleting study code list entry", slog.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	entries, paginationInfo, err := h.studyDBConn.GetStudyQuotaEntries(token.InstanceID, studyKey, quotaKey, status, query.Page, query.Limit)
+	if err != nil {
+		slog.Error("failed to get study quota entries", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study quota entries"})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "pagination": paginationInfo})
 }
 
-func (h *HttpEndpoints) getStudyCounterValues(c *gin.Context) {
+func (h *HttpEndpoints) getLatestConsentDocuments(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	studyKey := c.Param("studyKey")
 
-	slog.Info("getting study counter values", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+	slog.Info("getting consent documents", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
-	values, err := h.studyDBConn.GetAllStudyCounterValues(token.InstanceID, studyKey)
+	docs, err := h.studyDBConn.GetLatestConsentDocuments(token.InstanceID, studyKey)
 	if err != nil {
-		slog.Error("failed to get study counter values", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study counter values"})
+		slog.Error("failed to get consent documents", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get consent documents"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"values": values})
+	c.JSON(http.StatusOK, gin.H{"consents": docs})
 }
 
-func (h *HttpEndpoints) saveStudyCounterValue(c *gin.Context) {
+func (h *HttpEndpoints) publishConsentDocument(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 	studyKey := c.Param("studyKey")
-	scope := strings.TrimSpace(c.Param("scope"))
-
-	if scope == "" {
-		slog.Error("scope is required", slog.String("studyKey", studyKey), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "scope is required"})
-		return
-	}
 
-	var req struct {
-		Value int64 `json:"value"`
-	}
+	var req studyTypes.ConsentDocument
 	if err := c.ShouldBindJSON(&req); err != nil {
 		slog.Error("failed to bind request", slog.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("saving study counter value", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scope", scope), slog.String("value", fmt.Sprintf("%d", req.Value)))
+	slog.Info("publishing consent document", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("consentKey", req.ConsentKey))
 
-	value, err := h.studyDBConn.SaveStudyCounterValue(token.InstanceID, studyKey, scope, req.Value)
+	doc, err := h.studyDBConn.PublishConsentDocument(token.InstanceID, studyKey, req, token.Subject)
 	if err != nil {
-		slog.Error("failed to save study counter value", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save study counter value"})
+		slog.Error("failed to publish consent document", slog.String("error", err.Error()))
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "consent version was published concurrently"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"value": value})
+	c.JSON(http.StatusOK, gin.H{"consent": doc})
 }
 
-func (h *HttpEndpoints) incrementStudyCounter(c *gin.Context) {
+func (h *HttpEndpoints) getConsentDocumentVersions(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
-
 	studyKey := c.Param("studyKey")
-	scope := strings.TrimSpace(c.Param("scope"))
+	consentKey := c.Param("consentKey")
 
-	if scope == "" {
-		slog.Error("scope is required")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "scope is required"})
-		return
-	}
-
-	slog.Info("incrementing study counter", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scope", scope))
+	slog.Info("getting consent document versions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("consentKey", consentKey))
 
-	value, err := h.studyDBConn.IncrementAndGetStudyCounterValue(token.InstanceID, studyKey, scope)
+	docs, err := h.studyDBConn.GetConsentDocumentVersions(token.InstanceID, studyKey, consentKey)
 	if err != nil {
-		slog.Error("failed to increment study counter", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to increment study counter"})
+		slog.Error("failed to get consent document versions", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get consent document versions"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"value": value})
+	c.JSON(http.StatusOK, gin.H{"versions": docs})
 }
 
-func (h *HttpEndpoints) removeStudyCounter(c *gin.Context) {
+func (h *HttpEndpoints) getConsentRecords(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
-
 	studyKey := c.Param("studyKey")
-	scope := strings.TrimSpace(c.Param("scope"))
+	consentKey := c.Param("consentKey")
 
-	if scope == "" {
-		slog.Error("scope is required")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "scope is required"})
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil {
+		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 		return
 	}
 
-	slog.Info("removing study counter", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("scope", scope))
+	filter := bson.M{"consentKey": consentKey}
+	if participantID := c.DefaultQuery("participantID", ""); participantID != "" {
+		filter["participantID"] = participantID
+	}
+	if status := c.DefaultQuery("status", ""); status != "" {
+		if status != studyTypes.CONSENT_RECORD_STATUS_ACCEPTED && status != studyTypes.CONSENT_RECORD_STATUS_WITHDRAWN {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		filter["status"] = status
+	}
+	if v := c.DefaultQuery("version", ""); v != "" {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+			return
+		}
+		filter["version"] = version
+	}
 
-	err := h.studyDBConn.RemoveStudyCounterValue(token.InstanceID, studyKey, scope)
+	slog.Info("getting consent records", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("consentKey", consentKey))
+
+	records, paginationInfo, err := h.studyDBConn.GetConsentRecords(token.InstanceID, studyKey, filter, query.Page, query.Limit)
 	if err != nil {
-		slog.Error("failed to remove study counter", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove study counter"})
+		slog.Error("failed to get consent records", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get consent records"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true})
+	c.JSON(http.StatusOK, gin.H{"records": records, "pagination": paginationInfo})
 }
 
 func (h *HttpEndpoints) getStudyVariables(c *gin.Context) {
@@ -2229,7 +2903,10 @@ func (h *HttpEndpoints) updateStudyVariableValue(c *gin.Context) {
 
 	slog.Info("updating study variable value", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("variableKey", variableKey))
 
-	_, err := h.studyDBConn.UpdateStudyVariableValue(token.InstanceID, studyKey, variableKey, req.Variable.Value)
+	_, err := h.studyDBConn.UpdateStudyVariableValue(token.InstanceID, studyKey, variableKey, req.Variable.Value, studyTypes.StudyVariableChangeOrigin{
+		Source:    studyTypes.STUDY_VARIABLE_CHANGE_SOURCE_MANAGEMENT,
+		ChangedBy: token.Subject,
+	})
 	if err != nil {
 		slog.Error("failed to update study variable value", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update study variable value"})
@@ -2259,6 +2936,162 @@ func (h *HttpEndpoints) deleteStudyVariable(c *gin.Context) {
 		return
 	}
 
+	err = h.studyDBConn.DeletePendingStudyVariableScheduledChanges(token.InstanceID, studyKey, variableKey)
+	if err != nil {
+		slog.Error("failed to delete pending scheduled changes of study variable", slog.String("error", err.Error()))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (h *HttpEndpoints) getStudyVariableHistory(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	variableKey := c.Param("variableKey")
+
+	if studyKey == "" || variableKey == "" {
+		slog.Error("studyKey and variableKey are required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "studyKey and variableKey are required"})
+		return
+	}
+
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil {
+		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	slog.Info("getting study variable history", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("variableKey", variableKey))
+
+	entries, paginationInfo, err := h.studyDBConn.GetStudyVariableHistory(token.InstanceID, studyKey, variableKey, query.Page, query.Limit)
+	if err != nil {
+		slog.Error("failed to get study variable history", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study variable history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": entries, "pagination": paginationInfo})
+}
+
+func (h *HttpEndpoints) getStudyVariableSchedules(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	variableKey := c.Param("variableKey")
+
+	if studyKey == "" || variableKey == "" {
+		slog.Error("studyKey and variableKey are required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "studyKey and variableKey are required"})
+		return
+	}
+
+	onlyPending := c.DefaultQuery("onlyPending", "false") == "true"
+
+	slog.Info("getting study variable schedules", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("variableKey", variableKey))
+
+	schedules, err := h.studyDBConn.GetStudyVariableScheduledChanges(token.InstanceID, studyKey, variableKey, onlyPending)
+	if err != nil {
+		slog.Error("failed to get study variable schedules", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study variable schedules"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+type ScheduleStudyVariableValueRequest struct {
+	Value        json.RawMessage `json:"value"`
+	ScheduledFor time.Time       `json:"scheduledFor"`
+}
+
+func (h *HttpEndpoints) scheduleStudyVariableValue(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	variableKey := c.Param("variableKey")
+
+	if studyKey == "" || variableKey == "" {
+		slog.Error("studyKey and variableKey are required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "studyKey and variableKey are required"})
+		return
+	}
+
+	var req ScheduleStudyVariableValueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if !req.ScheduledFor.After(time.Now()) {
+		slog.Error("scheduledFor must be in the future", slog.String("scheduledFor", req.ScheduledFor.String()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scheduledFor must be in the future"})
+		return
+	}
+
+	variable, err := h.studyDBConn.GetStudyVariableByStudyKeyAndKey(token.InstanceID, studyKey, variableKey, true)
+	if err != nil {
+		slog.Error("failed to get study variable", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "study variable not found"})
+		return
+	}
+
+	value, err := studyTypes.NormalizeStudyVariableValue(req.Value, variable.Type)
+	if err != nil {
+		slog.Error("invalid value for study variable", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	slog.Info("scheduling study variable value", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("variableKey", variableKey), slog.String("scheduledFor", req.ScheduledFor.String()))
+
+	id, err := h.studyDBConn.CreateStudyVariableScheduledChange(token.InstanceID, studyTypes.StudyVariableScheduledChange{
+		StudyKey:     studyKey,
+		Key:          variableKey,
+		Value:        value,
+		ScheduledFor: req.ScheduledFor.UTC(),
+		CreatedBy:    token.Subject,
+	})
+	if err != nil {
+		slog.Error("failed to schedule study variable value", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to schedule study variable value"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": id})
+}
+
+func (h *HttpEndpoints) deleteStudyVariableSchedule(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+	studyKey := c.Param("studyKey")
+	variableKey := c.Param("variableKey")
+	scheduleID := c.Param("scheduleID")
+
+	if studyKey == "" || variableKey == "" || scheduleID == "" {
+		slog.Error("studyKey, variableKey and scheduleID are required")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "studyKey, variableKey and scheduleID are required"})
+		return
+	}
+	if !primitive.IsValidObjectID(scheduleID) {
+		slog.Error("invalid scheduleID", slog.String("scheduleID", scheduleID))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid scheduleID"})
+		return
+	}
+
+	slog.Info("deleting study variable schedule", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("variableKey", variableKey), slog.String("scheduleID", scheduleID))
+
+	err := h.studyDBConn.DeleteStudyVariableScheduledChange(token.InstanceID, studyKey, variableKey, scheduleID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// unknown, already applied or failed schedules cannot be deleted
+			slog.Warn("pending study variable schedule not found", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("variableKey", variableKey), slog.String("scheduleID", scheduleID))
+			c.JSON(http.StatusNotFound, gin.H{"error": "study variable schedule not found"})
+			return
+		}
+		slog.Error("failed to delete study variable schedule", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete study variable schedule"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
@@ -2917,6 +3750,12 @@ func (h *HttpEndpoints) generateResponsesExport(c *gin.Context) {
 	if study.Configs.TrackAccount {
 		respParser.EnableAccountTracking()
 	}
+	if query.NotShownMarker != "" {
+		respParser.EnableNotShownMarker(query.NotShownMarker)
+	}
+	if query.IncludeScores {
+		respParser.EnableScoreColumns(h.surveyScoreProvider(token.InstanceID, studyKey))
+	}
 
 	fileType := studyTypes.TASK_FILE_TYPE_CSV
 	if query.Format == "json" {
@@ -3066,6 +3905,8 @@ func (h *HttpEndpoints) getParticipantsCount(c *gin.Context) {
 		return
 	}
 
+	filter = apihelpers.ApplyArmFilterFromCtx(c, filter, apihelpers.ARM_FILTER_FIELD_PARTICIPANTS)
+
 	slog.Info("getting participants count", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	count, err := h.studyDBConn.GetParticipantCount(token.InstanceID, studyKey, filter)
@@ -3097,6 +3938,8 @@ func (h *HttpEndpoints) generateParticipantsExport(c *gin.Context) {
 		return
 	}
 
+	filter = apihelpers.ApplyArmFilterFromCtx(c, filter, apihelpers.ARM_FILTER_FIELD_PARTICIPANTS)
+
 	slog.Info("generating participants export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
 
 	count, err := h.studyDBConn.GetParticipantCount(token.InstanceID, studyKey, filter)
@@ -3982,6 +4825,20 @@ func (h *HttpEndpoints) getResponseAccountTrackingInfo(instanceID, studyKey, par
 	return surveyresponses.AccountTrackingInfoFromParticipant(pState)
 }
 
+// surveyScoreProvider reads the scores stored for the responses of the study, for the score columns of exports
+func (h *HttpEndpoints) surveyScoreProvider(instanceID, studyKey string) surveyresponses.ScoreProvider {
+	return func(responseID string) map[string]float64 {
+		record, err := h.studyDBConn.GetSurveyScoreRecordByResponseID(instanceID, studyKey, responseID)
+		if err != nil {
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				slog.Warn("failed to get survey scores for response", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("responseID", responseID))
+			}
+			return nil
+		}
+		return record.Map()
+	}
+}
+
 func (h *HttpEndpoints) getStudyResponses(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
@@ -4057,6 +4914,12 @@ func (h *HttpEndpoints) getStudyResponses(c *gin.Context) {
 	if study.Configs.TrackAccount {
 		respParser.EnableAccountTracking()
 	}
+	if query.NotShownMarker != "" {
+		respParser.EnableNotShownMarker(query.NotShownMarker)
+	}
+	if query.IncludeScores {
+		respParser.EnableScoreColumns(h.surveyScoreProvider(token.InstanceID, studyKey))
+	}
 
 	responses := make([]map[string]interface{}, len(rawResponses))
 	accountInfoCache := map[string]surveyresponses.AccountTrackingInfo{}
@@ -4152,6 +5015,12 @@ func (h *HttpEndpoints) getStudyResponseById(c *gin.Context) {
 	if study.Configs.TrackAccount {
 		respParser.EnableAccountTracking()
 	}
+	if query.NotShownMarker != "" {
+		respParser.EnableNotShownMarker(query.NotShownMarker)
+	}
+	if query.IncludeScores {
+		respParser.EnableScoreColumns(h.surveyScoreProvider(token.InstanceID, studyKey))
+	}
 
 	trackingInfo := surveyresponses.AccountTrackingInfo{}
 	if study.Configs.TrackAccount {
@@ -4212,6 +5081,10 @@ func (h *HttpEndpoints) deleteStudyResponses(c *gin.Context) {
 		return
 	}
 
+	if _, err := h.studyDBConn.DeleteOrphanedSurveyScoreRecords(token.InstanceID, studyKey, surveyKey); err != nil {
+		slog.Error("failed to delete survey scores of deleted responses", slog.String("error", err.Error()))
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "study responses deleted"})
 }
 
@@ -4236,6 +5109,10 @@ func (h *HttpEndpoints) deleteStudyResponse(c *gin.Context) {
 		return
 	}
 
+	if err := h.studyDBConn.DeleteSurveyScoreRecordByResponseID(token.InstanceID, studyKey, responseID); err != nil {
+		slog.Error("failed to delete survey scores of response", slog.String("error", err.Error()))
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "study response deleted"})
 }
 
@@ -4252,6 +5129,8 @@ func (h *HttpEndpoints) getStudyParticipants(c *gin.Context) {
 		return
 	}
 
+	query.Filter = apihelpers.ApplyArmFilterFromCtx(c, query.Filter, apihelpers.ARM_FILTER_FIELD_PARTICIPANTS)
+
 	participants, paginationInfo, err := h.studyDBConn.GetParticipants(
 		token.InstanceID,
 		studyKey,
@@ -4515,6 +5394,16 @@ func (h *HttpEndpoints) getStudyFile(c *gin.Context) {
 		return
 	}
 
+	switch fileInfo.Status {
+	case studyTypes.FILE_STATUS_QUARANTINED:
+		slog.Warn("download of quarantined file blocked", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileID))
+		c.JSON(http.StatusForbidden, gin.H{"error": "file is quarantined"})
+		return
+	case studyTypes.FILE_STATUS_UPLOADING:
+		c.JSON(http.StatusConflict, gin.H{"error": "file upload not completed"})
+		return
+	}
+
 	filePath := filepath.Join(h.filestorePath, fileInfo.Path)
 
 	// Check if file exists
@@ -4530,6 +5419,37 @@ func (h *HttpEndpoints) getStudyFile(c *gin.Context) {
 	c.File(filePath)
 }
 
+func (h *HttpEndpoints) getStudyFilePreview(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
+
+	studyKey := c.Param("studyKey")
+	fileID := c.Param("fileID")
+
+	slog.Info("getting study file preview", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("fileID", fileID))
+
+	fileInfo, err := h.studyDBConn.GetParticipantFileInfoByID(token.InstanceID, studyKey, fileID)
+	if err != nil {
+		slog.Error("failed to get study file info", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study file info"})
+		return
+	}
+
+	if fileInfo.PreviewPath == "" || fileInfo.Status == studyTypes.FILE_STATUS_QUARANTINED {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no preview available"})
+		return
+	}
+
+	previewPath := filepath.Join(h.filestorePath, fileInfo.PreviewPath)
+	if _, err := os.Stat(previewPath); os.IsNotExist(err) {
+		slog.Error("preview does not exist", slog.String("path", previewPath))
+		c.JSON(http.StatusNotFound, gin.H{"error": "preview does not exist"})
+		return
+	}
+
+	c.Header("Content-Disposition", "inline; filename="+filepath.Base(fileInfo.PreviewPath))
+	c.File(previewPath)
+}
+
 func (h *HttpEndpoints) deleteStudyFile(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ManagementUserClaims)
 
@@ -587,6 +587,20 @@ func (h *HttpEndpoints) requestParticipantUserDeletion(c *gin.Context) {
 		slog.Error("failed to delete temp tokens", slog.String("error", err.Error()))
 	}
 
+	if err := h.participantUserDB.DeleteWebAuthnCredentialsForUser(token.InstanceID, user.ID.Hex()); err != nil {
+		slog.Error("failed to delete passkeys", slog.String("error", err.Error()))
+	}
+
+	if _, err := h.messagingDBConn.DeleteInboxMessagesForUser(token.InstanceID, user.ID.Hex()); err != nil {
+		slog.Error("failed to delete inbox messages", slog.String("error", err.Error()))
+	}
+
+	if _, err := h.messagingDBConn.DeletePushSubscriptionsForUser(token.InstanceID, user.ID.Hex()); err != nil {
+		slog.Error("failed to delete push subscriptions", slog.String("error", err.Error()))
+	}
+
+	studyService.DeleteDataExportsForUser(token.InstanceID, user.ID.Hex(), h.participantFilestorePath)
+
 	err = h.participantUserDB.DeleteUser(token.InstanceID, user.ID.Hex())
 	if err != nil {
 		slog.Error("cannot delete user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
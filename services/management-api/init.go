@@ -100,6 +100,9 @@ type Config struct {
 
 	FilestorePath       string `json:"filestore_path" yaml:"filestore_path"`
 	DailyFileExportPath string `json:"daily_file_export_path" yaml:"daily_file_export_path"`
+
+	// filestore path of the participant API, used to remove data export archives of deleted participants
+	ParticipantFilestorePath string `json:"participant_filestore_path" yaml:"participant_filestore_path"`
 }
 
 func init() {
@@ -44,6 +44,7 @@ func main() {
 		conf.StudyConfigs.GlobalSecret,
 		conf.FilestorePath,
 		conf.DailyFileExportPath,
+		conf.ParticipantFilestorePath,
 	)
 	v1APIHandlers.AddManagementAuthAPI(v1Root)
 	v1APIHandlers.AddUserManagementAPI(v1Root)
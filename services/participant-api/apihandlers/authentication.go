@@ -43,6 +43,20 @@ func (h *HttpEndpoints) AddParticipantAuthAPI(rg *gin.RouterGroup) {
 		authGroup.POST("/login-with-temptoken", mw.RequirePayload(), h.loginWithTempToken)
 		authGroup.POST("/temptoken-info", mw.RequirePayload(), h.getTempTokenInfo)
 
+		authGroup.POST("/login-link/request", mw.RequirePayload(), h.requestLoginLink)
+		authGroup.POST("/login-link/login", mw.RequirePayload(), h.loginWithLoginLink)
+
+		authGroup.POST("/passkey/login/begin", mw.RequirePayload(), h.beginPasskeyLogin)
+		authGroup.POST("/passkey/login/finish", mw.RequirePayload(), h.loginWithPasskey)
+
+		authGroup.POST("/phone/signup", mw.RequirePayload(), h.signupWithPhone)
+		authGroup.POST("/phone/login/request", mw.RequirePayload(), h.requestPhoneLoginCode)
+		authGroup.POST("/phone/login", mw.RequirePayload(), h.loginWithPhone)
+
+		authGroup.GET("/oidc/providers", h.getOIDCProviders)
+		authGroup.POST("/oidc/:provider/begin", mw.RequirePayload(), h.beginOIDCLogin)
+		authGroup.POST("/oidc/:provider/callback", mw.RequirePayload(), h.loginWithOIDC)
+
 		authGroup.POST("/token/renew", mw.RequirePayload(), mw.GetAndValidateParticipantUserJWTWithIgnoringExpiration(h.tokenSignKey, h.globalInfosDBConn), h.refreshToken)
 		authGroup.GET("/token/validate", mw.RequirePayload(), mw.GetAndValidateParticipantUserJWT(h.tokenSignKey, h.globalInfosDBConn), h.validateToken)
 		authGroup.GET("/token/revoke", mw.GetAndValidateParticipantUserJWT(h.tokenSignKey, h.globalInfosDBConn), h.revokeRefreshTokens)
@@ -56,6 +70,8 @@ func (h *HttpEndpoints) AddParticipantAuthAPI(rg *gin.RouterGroup) {
 	{
 		otpGroup.GET("", h.requestOTP)
 		otpGroup.POST("/verify", h.verifyOTP)
+		otpGroup.POST("/passkey/begin", h.beginPasskeyOTP)
+		otpGroup.POST("/passkey/verify", mw.RequirePayload(), h.verifyPasskeyOTP)
 	}
 
 }
@@ -98,6 +114,10 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 	req.Email = umUtils.SanitizeEmail(req.Email)
 
 	user, err := h.userDBConn.GetUserByAccountID(req.InstanceID, req.Email)
+	if err == nil && user.Account.Type == userTypes.ACCOUNT_TYPE_PHONE {
+		// phone accounts sign in and reset the password with SMS codes
+		err = errors.New("phone account")
+	}
 	if err != nil {
 		slog.Warn("login attempt with wrong email address", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
 		randomWait(5, 10)
@@ -116,6 +136,16 @@ func (h *HttpEndpoints) loginWithEmail(c *gin.Context) {
 		return
 	}
 
+	if user.Account.Passwordless {
+		slog.Warn("password login attempt for passwordless account", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+		if err := h.userDBConn.SaveFailedLoginAttempt(req.InstanceID, user.ID.Hex()); err != nil {
+			slog.Error("failed to save failed login attempt", slog.String("error", err.Error()))
+		}
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid email or password"})
+		return
+	}
+
 	match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.Password)
 	if err != nil || !match {
 		if err == nil {
@@ -219,6 +249,7 @@ type SignupWithEmailReq struct {
 	InstanceID        string `json:"instanceId"`
 	InfoCheck         string `json:"infoCheck"`
 	PreferredLanguage string `json:"preferredLanguage"`
+	Passwordless      bool   `json:"passwordless"` // sign in only with login links, password must be empty
 	WithAttributes    *struct {
 		Type       string         `json:"type"`
 		Attributes map[string]any `json:"attributes"`
@@ -233,12 +264,18 @@ func (h *HttpEndpoints) signupWithEmail(c *gin.Context) {
 		return
 	}
 
-	if req.Email == "" || req.Password == "" || req.InstanceID == "" {
+	if req.Email == "" || (req.Password == "" && !req.Passwordless) || req.InstanceID == "" {
 		slog.Error("missing required fields")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
 		return
 	}
 
+	if req.Passwordless && req.Password != "" {
+		slog.Error("password set for passwordless signup")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password not allowed for passwordless account"})
+		return
+	}
+
 	if req.InfoCheck != "" {
 		slog.Warn("honeypot field filled out", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("infoCheck", req.InfoCheck))
 		randomWait(5, 10)
@@ -260,16 +297,18 @@ func (h *HttpEndpoints) signupWithEmail(c *gin.Context) {
 		return
 	}
 
-	if !umUtils.CheckPasswordFormat(req.Password) {
-		slog.Error("invalid password format")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password format"})
-		return
-	}
+	if !req.Passwordless {
+		if !umUtils.CheckPasswordFormat(req.Password) {
+			slog.Error("invalid password format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password format"})
+			return
+		}
 
-	if umUtils.IsPasswordOnBlocklist(req.Password) {
-		slog.Error("password on blocklist")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "password on blocklist"})
-		return
+		if umUtils.IsPasswordOnBlocklist(req.Password) {
+			slog.Error("password on blocklist")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "password on blocklist"})
+			return
+		}
 	}
 
 	if !umUtils.CheckLanguageCode(req.PreferredLanguage) {
@@ -293,15 +332,19 @@ func (h *HttpEndpoints) signupWithEmail(c *gin.Context) {
 	}
 
 	// hash password
-	password, err := pwhash.HashPassword(req.Password)
-	if err != nil {
-		slog.Error("failed to hash password", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+	password := ""
+	if !req.Passwordless {
+		password, err = pwhash.HashPassword(req.Password)
+		if err != nil {
+			slog.Error("failed to hash password", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
 	}
 
 	// create user
 	newUser := umUtils.InitNewEmailUser(req.Email, password, req.PreferredLanguage)
+	newUser.Account.Passwordless = req.Passwordless
 	id, err := h.userDBConn.AddUser(req.InstanceID, newUser)
 	if err != nil {
 		slog.Error("failed to create new user", slog.String("error", err.Error()))
@@ -499,24 +542,46 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 		}
 	}
 
-	// generate jwt
-	mainProfileID, otherProfileIDs := umUtils.GetMainAndOtherProfiles(user)
-
 	lastOTP := map[string]int64{
 		"email": time.Now().Unix(),
 	}
+	if tokenInfos.Info["type"] == userTypes.ACCOUNT_TYPE_PHONE {
+		// token was delivered by SMS
+		lastOTP = map[string]int64{
+			string(userTypes.SMSOTP): time.Now().Unix(),
+		}
+	}
 
-	sessionID, err := generateSessionID()
+	tokenResp, user, err := h.startSession(tokenInfos.InstanceID, user, lastOTP)
 	if err != nil {
-		slog.Error("failed to generate session ID", slog.String("error", err.Error()))
+		slog.Error("failed to start session", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 		return
 	}
 
+	// return tokens and user
+	slog.Info("login with temptoken successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID)) //
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": tokenResp,
+		"user":  user,
+	})
+}
+
+// startSession generates access and refresh token for a new session and updates the login timestamps of the user.
+// The returned user has the password and verification code removed.
+func (h *HttpEndpoints) startSession(instanceID string, user userTypes.User, lastOTP map[string]int64) (gin.H, userTypes.User, error) {
+	mainProfileID, otherProfileIDs := umUtils.GetMainAndOtherProfiles(user)
+
+	sessionID, err := generateSessionID()
+	if err != nil {
+		return nil, user, err
+	}
+
 	token, err := jwthandling.GenerateNewParticipantUserToken(
 		h.ttls.AccessToken,
 		user.ID.Hex(),
-		tokenInfos.InstanceID,
+		instanceID,
 		mainProfileID,
 		map[string]string{},
 		user.Account.AccountConfirmedAt > 0,
@@ -527,25 +592,18 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 		sessionID,
 	)
 	if err != nil {
-		slog.Error("failed to generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, user, err
 	}
 
 	// generate refresh token
 	renewToken, err := umUtils.GenerateUniqueTokenString()
 	if err != nil {
-		slog.Error("failed to generate renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, user, err
 	}
 
-	// generate refresh token
-	err = h.userDBConn.CreateRenewToken(tokenInfos.InstanceID, user.ID.Hex(), renewToken, 0, sessionID)
+	err = h.userDBConn.CreateRenewToken(instanceID, user.ID.Hex(), renewToken, 0, sessionID)
 	if err != nil {
-		slog.Error("failed to save renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, user, err
 	}
 
 	// update timestamps
@@ -555,29 +613,21 @@ func (h *HttpEndpoints) loginWithTempToken(c *gin.Context) {
 	user.Account.FailedLoginAttempts = umUtils.RemoveAttemptsOlderThan(user.Account.FailedLoginAttempts, 3600)
 	user.Account.PasswordResetTriggers = umUtils.RemoveAttemptsOlderThan(user.Account.PasswordResetTriggers, 7200)
 
-	user, err = h.userDBConn.ReplaceUser(tokenInfos.InstanceID, user)
+	user, err = h.userDBConn.ReplaceUser(instanceID, user)
 	if err != nil {
-		slog.Error("failed to update user", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, user, err
 	}
 
-	// return tokens and user
-	slog.Info("login with temptoken successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID)) //
-
 	user.Account.Password = ""
 	user.Account.VerificationCode = userTypes.VerificationCode{}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": gin.H{
-			"accessToken":     token,
-			"refreshToken":    renewToken,
-			"expiresIn":       h.ttls.AccessToken.Seconds(),
-			"selectedProfile": mainProfileID,
-			"lastOTP":         lastOTP,
-		},
-		"user": user,
-	})
+	return gin.H{
+		"accessToken":     token,
+		"refreshToken":    renewToken,
+		"expiresIn":       h.ttls.AccessToken.Seconds(),
+		"selectedProfile": mainProfileID,
+		"lastOTP":         lastOTP,
+	}, user, nil
 }
 
 type RefreshTokenReq struct {
@@ -831,7 +881,14 @@ func (h *HttpEndpoints) verifyEmail(c *gin.Context) {
 		return
 	}
 
-	if user.Account.AccountID != tokenInfos.Info["email"] {
+	expectedEmail := user.Account.AccountID
+	if user.Account.Type == userTypes.ACCOUNT_TYPE_OIDC {
+		// the address was reported by the provider and is not part of the account ID
+		if ci, found := user.FindContactInfoByTypeAndAddr(userTypes.CONTACT_INFO_TYPE_EMAIL, tokenInfos.Info["email"]); found {
+			expectedEmail = ci.Email
+		}
+	}
+	if expectedEmail != tokenInfos.Info["email"] {
 		slog.Error("user does not match token", slog.String("error", "user does not match token"), slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "user does not match token"})
 		return
@@ -928,6 +985,9 @@ func (h *HttpEndpoints) requestOTP(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
 			return
 		}
+	case string(userTypes.TOTP):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "totp codes are generated by the authenticator app"})
+		return
 	default:
 		slog.Error("invalid OTP type", slog.String("type", otpType))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid OTP type"})
@@ -938,6 +998,7 @@ func (h *HttpEndpoints) requestOTP(c *gin.Context) {
 
 type VerifyOTPReq struct {
 	Code string `json:"code"`
+	Type string `json:"type"` // optional, "totp" for authenticator app or recovery codes
 }
 
 func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
@@ -966,11 +1027,16 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 
 	// user management method to verify OTP
 	code := strings.TrimSpace(req.Code)
-	otp, err := usermanagement.VerifyOTP(
-		token.InstanceID,
-		token.Subject,
-		code,
-	)
+	var otp *userTypes.OTP
+	if req.Type == string(userTypes.TOTP) {
+		otp, err = usermanagement.VerifyTOTP(token.InstanceID, token.Subject, code)
+	} else {
+		otp, err = usermanagement.VerifyOTP(
+			token.InstanceID,
+			token.Subject,
+			code,
+		)
+	}
 	if err != nil {
 		slog.Warn("failed to verify OTP", slog.String("error", err.Error()), slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
 		if err := h.userDBConn.AddFailedOtpAttempt(token.InstanceID, token.Subject); err != nil {
@@ -1016,14 +1082,28 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 		}
 	}
 
-	mainProfileID, otherProfileIDs := umUtils.GetMainAndOtherProfiles(user)
-
 	if token.LastOTPProvided == nil {
 		token.LastOTPProvided = make(map[string]int64)
 	}
 	token.LastOTPProvided[string(otp.Type)] = time.Now().Unix()
 
-	// generate new token
+	tokenResp, err := h.renewSessionToken(token, user)
+	if err != nil {
+		slog.Error("failed to renew token", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": tokenResp,
+		"user":  user,
+	})
+}
+
+// renewSessionToken issues a new access and renew token for the same session, e.g. to include the updated LastOTPProvided
+func (h *HttpEndpoints) renewSessionToken(token *jwthandling.ParticipantUserClaims, user userTypes.User) (gin.H, error) {
+	mainProfileID, otherProfileIDs := umUtils.GetMainAndOtherProfiles(user)
+
 	newToken, err := jwthandling.GenerateNewParticipantUserToken(
 		h.ttls.AccessToken,
 		token.Subject,
@@ -1038,35 +1118,25 @@ func (h *HttpEndpoints) verifyOTP(c *gin.Context) {
 		token.SessionID,
 	)
 	if err != nil {
-		slog.Error("failed to generate token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, err
 	}
 
 	// generate refresh token
 	renewToken, err := umUtils.GenerateUniqueTokenString()
 	if err != nil {
-		slog.Error("failed to generate renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, err
 	}
 
-	// generate refresh token
 	err = h.userDBConn.CreateRenewToken(token.InstanceID, user.ID.Hex(), renewToken, 0, token.SessionID)
 	if err != nil {
-		slog.Error("failed to save renew token", slog.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
-		return
+		return nil, err
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": gin.H{
-			"accessToken":     newToken,
-			"refreshToken":    renewToken,
-			"expiresIn":       h.ttls.AccessToken.Seconds(),
-			"selectedProfile": mainProfileID,
-			"lastOTP":         token.LastOTPProvided,
-		},
-		"user": user,
-	})
+	return gin.H{
+		"accessToken":     newToken,
+		"refreshToken":    renewToken,
+		"expiresIn":       h.ttls.AccessToken.Seconds(),
+		"selectedProfile": mainProfileID,
+		"lastOTP":         token.LastOTPProvided,
+	}, nil
 }
@@ -0,0 +1,165 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	"github.com/case-framework/case-backend/pkg/study/calendar"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DEFAULT_CALENDAR_FEED_TOKEN_TTL = 365 * 24 * time.Hour
+	CALENDAR_FEED_REFRESH_INTERVAL  = time.Hour
+)
+
+// getCalendar returns the schedule of all profiles of the user as iCalendar (.ics)
+func (h *HttpEndpoints) getCalendar(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to get user", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
+		return
+	}
+
+	h.writeCalendarForUser(c, token.InstanceID, user)
+}
+
+// createCalendarFeedToken creates a token for subscribing to the calendar without logging in, previous tokens are revoked
+func (h *HttpEndpoints) createCalendarFeedToken(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if err := h.globalInfosDBConn.DeleteAllTempTokenForUser(token.InstanceID, token.Subject, userTypes.TOKEN_PURPOSE_CALENDAR_FEED); err != nil {
+		slog.Error("failed to revoke calendar feed tokens", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed token"})
+		return
+	}
+
+	expiration := umUtils.GetExpirationTime(h.calendarFeedTokenTTL())
+	feedToken, err := h.globalInfosDBConn.AddTempToken(userTypes.TempToken{
+		UserID:     token.Subject,
+		InstanceID: token.InstanceID,
+		Purpose:    userTypes.TOKEN_PURPOSE_CALENDAR_FEED,
+		Expiration: expiration,
+	})
+	if err != nil {
+		slog.Error("failed to create calendar feed token", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed token"})
+		return
+	}
+
+	slog.Info("calendar feed token created", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	c.JSON(http.StatusOK, gin.H{
+		"token":     feedToken,
+		"expiresAt": expiration.Unix(),
+	})
+}
+
+func (h *HttpEndpoints) revokeCalendarFeedToken(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if err := h.globalInfosDBConn.DeleteAllTempTokenForUser(token.InstanceID, token.Subject, userTypes.TOKEN_PURPOSE_CALENDAR_FEED); err != nil {
+		slog.Error("failed to revoke calendar feed tokens", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke calendar feed token"})
+		return
+	}
+
+	slog.Info("calendar feed token revoked", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+	c.JSON(http.StatusOK, gin.H{"message": "calendar feed token revoked"})
+}
+
+// getCalendarFeed serves the calendar for subscriptions of calendar apps, authenticated by the feed token in the path.
+// Each fetch extends the token's validity, so tokens only expire for feeds that are not in use anymore.
+func (h *HttpEndpoints) getCalendarFeed(c *gin.Context) {
+	feedToken := strings.TrimSuffix(c.Param("token"), calendar.FILE_EXTENSION)
+
+	tokenInfos, err := h.validateTempToken(feedToken, []string{userTypes.TOKEN_PURPOSE_CALENDAR_FEED})
+	if err != nil {
+		slog.Warn("invalid calendar feed token", slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar not found"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
+	if err != nil {
+		slog.Error("failed to get user", slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID), slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar not found"})
+		return
+	}
+
+	if err := h.globalInfosDBConn.UpdateTempTokenExpirationTime(feedToken, umUtils.GetExpirationTime(h.calendarFeedTokenTTL())); err != nil {
+		slog.Error("failed to extend calendar feed token", slog.String("instanceID", tokenInfos.InstanceID), slog.String("userID", tokenInfos.UserID), slog.String("error", err.Error()))
+	}
+
+	h.writeCalendarForUser(c, tokenInfos.InstanceID, user)
+}
+
+// writeCalendarForUser responds with the assigned surveys and visits of all active participations of the user's profiles.
+// Texts are localised for the "lang" query parameter or the preferred language of the user.
+func (h *HttpEndpoints) writeCalendarForUser(c *gin.Context, instanceID string, user userTypes.User) {
+	lang := c.DefaultQuery("lang", user.Account.PreferredLanguage)
+
+	studies, err := h.studyDBConn.GetStudies(instanceID, studyTypes.STUDY_STATUS_ACTIVE, false)
+	if err != nil {
+		slog.Error("error getting studies", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting studies"})
+		return
+	}
+
+	events := []calendar.Event{}
+	studyNames := []string{}
+	for _, study := range studies {
+		lookup := calendar.SurveyPropsFromDB(h.studyDBConn, instanceID, study.Key)
+		participates := false
+
+		for _, profile := range user.Profiles {
+			participantID, _, err := studyService.ComputeParticipantIDs(study, profile.ID.Hex())
+			if err != nil {
+				slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				continue
+			}
+			pState, err := h.studyDBConn.GetParticipantByID(instanceID, study.Key, participantID)
+			if err != nil || pState.StudyStatus != studyTypes.PARTICIPANT_STUDY_STATUS_ACTIVE {
+				continue
+			}
+			participates = true
+
+			// only name the profile if the events could belong to different people
+			alias := ""
+			if len(user.Profiles) > 1 {
+				alias = profile.Alias
+			}
+			events = append(events, calendar.ParticipantEvents(study, pState, profile.ID.Hex(), alias, lang, lookup)...)
+		}
+
+		if name := calendar.LocalisedText(study.Props.Name, lang); participates && name != "" {
+			studyNames = append(studyNames, name)
+		}
+	}
+
+	cal := calendar.Calendar{
+		Name:            strings.Join(studyNames, ", "),
+		RefreshInterval: CALENDAR_FEED_REFRESH_INTERVAL,
+		Events:          events,
+	}
+
+	c.Header("Content-Disposition", `inline; filename="`+calendar.ATTACHMENT_FILENAME+`"`)
+	c.Header("Cache-Control", "private, no-cache")
+	c.Data(http.StatusOK, calendar.CONTENT_TYPE, cal.Encode())
+}
+
+func (h *HttpEndpoints) calendarFeedTokenTTL() time.Duration {
+	if h.ttls.CalendarFeedToken > 0 {
+		return h.ttls.CalendarFeedToken
+	}
+	return DEFAULT_CALENDAR_FEED_TOKEN_TTL
+}
@@ -0,0 +1,670 @@
+package apihandlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	surveydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
+	surveyresponses "github.com/case-framework/case-backend/pkg/study/exporter/survey-responses"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	studyutils "github.com/case-framework/case-backend/pkg/study/utils"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	dataExportFolderName   = "participant-exports"
+	dataExportFilePageSize = 100
+
+	// exports contain all personal data, so archives are removed after this time
+	dataExportRetention = 24 * time.Hour
+	// a running export older than this is assumed to be aborted (e.g. by a restart) and not reused
+	dataExportMaxDuration     = time.Hour
+	dataExportCleanupInterval = time.Hour
+)
+
+type dataExportTarget struct {
+	Study          studyTypes.Study
+	ProfileID      string
+	ParticipantID  string
+	ConfidentialID string
+}
+
+// startDataExport creates a task that collects all data stored for the account into a zip archive
+func (h *HttpEndpoints) startDataExport(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	slog.Info("starting participant data export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to get user", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user"})
+		return
+	}
+
+	// only one export per user at a time, a running or recent export is returned instead of starting a new one
+	if task, ok := h.getReusableDataExportTask(token.InstanceID, token.Subject); ok {
+		slog.Info("returning existing participant data export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", task.ID.Hex()))
+		c.JSON(http.StatusOK, gin.H{"task": task})
+		return
+	}
+
+	studies, err := h.studyDBConn.GetStudies(token.InstanceID, "", false)
+	if err != nil {
+		slog.Error("failed to get studies", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get studies"})
+		return
+	}
+
+	targets := []dataExportTarget{}
+	for _, study := range studies {
+		for _, profile := range user.Profiles {
+			participantID, confidentialID, err := studyService.ComputeParticipantIDs(study, profile.ID.Hex())
+			if err != nil {
+				slog.Error("Error computing participant IDs", slog.String("instanceID", token.InstanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				continue
+			}
+			targets = append(targets, dataExportTarget{
+				Study:          study,
+				ProfileID:      profile.ID.Hex(),
+				ParticipantID:  participantID,
+				ConfidentialID: confidentialID,
+			})
+		}
+	}
+
+	relativeFolderName := filepath.Join(token.InstanceID, dataExportFolderName)
+	exportFolder := filepath.Join(h.filestorePath, relativeFolderName)
+	if err := os.MkdirAll(exportFolder, os.ModePerm); err != nil {
+		slog.Error("failed to create export folder", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export folder"})
+		return
+	}
+
+	exportTask, err := h.studyDBConn.CreateTask(
+		token.InstanceID,
+		token.Subject,
+		len(targets)+1, // account data + one entry per study and profile
+		studyTypes.TASK_FILE_TYPE_ZIP,
+	)
+	if err != nil {
+		slog.Error("failed to create export task", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create export task"})
+		return
+	}
+
+	instanceID := token.InstanceID
+	go func() {
+		relativeFilepath := filepath.Join(relativeFolderName, "data-export_"+exportTask.ID.Hex()+".zip")
+		exportFilePath := filepath.Join(h.filestorePath, relativeFilepath)
+
+		processed, err := h.writeDataExport(instanceID, exportTask.ID.Hex(), user, targets, exportFilePath)
+		if err != nil {
+			slog.Error("failed to export participant data", slog.String("instanceID", instanceID), slog.String("taskID", exportTask.ID.Hex()), slog.String("error", err.Error()))
+			if rmErr := os.Remove(exportFilePath); rmErr != nil && !os.IsNotExist(rmErr) {
+				slog.Error("failed to remove incomplete export file", slog.String("error", rmErr.Error()))
+			}
+			h.onDataExportFailed(instanceID, exportTask.ID.Hex(), "failed to export participant data")
+			return
+		}
+
+		err = h.studyDBConn.UpdateTaskCompleted(
+			instanceID,
+			exportTask.ID.Hex(),
+			studyTypes.TASK_STATUS_COMPLETED,
+			processed,
+			"",
+			relativeFilepath,
+		)
+		if err != nil {
+			slog.Error("failed to update task status", slog.String("error", err.Error()))
+			return
+		}
+		slog.Info("participant data export completed", slog.String("instanceID", instanceID), slog.String("taskID", exportTask.ID.Hex()))
+	}()
+
+	c.JSON(http.StatusOK, gin.H{"task": exportTask})
+}
+
+func (h *HttpEndpoints) getDataExportStatus(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	taskID := c.Param("taskID")
+
+	task, err := h.getOwnDataExportTask(token, taskID)
+	if err != nil {
+		slog.Warn("data export task not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID), slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"task": task})
+}
+
+func (h *HttpEndpoints) downloadDataExport(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	taskID := c.Param("taskID")
+
+	task, err := h.getOwnDataExportTask(token, taskID)
+	if err != nil {
+		slog.Warn("data export task not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID), slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+
+	if task.Status != studyTypes.TASK_STATUS_COMPLETED || task.ResultFile == "" {
+		slog.Warn("data export is not ready", slog.String("taskID", taskID), slog.String("status", task.Status))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "export is not ready"})
+		return
+	}
+
+	resultFilePath := filepath.Join(h.filestorePath, task.ResultFile)
+	if _, err := os.Stat(resultFilePath); os.IsNotExist(err) {
+		slog.Error("file does not exist", slog.String("path", resultFilePath))
+		c.JSON(http.StatusNotFound, gin.H{"error": "file does not exist"})
+		return
+	}
+
+	slog.Info("downloading participant data export", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("taskID", taskID))
+
+	filenameToSave := filepath.Base(task.ResultFile)
+	c.Header("Content-Disposition", "attachment; filename="+filenameToSave)
+	c.Header("Content-Type", task.FileType)
+	c.File(resultFilePath)
+}
+
+// getReusableDataExportTask returns the newest export of the user if it is still running or its archive is available
+func (h *HttpEndpoints) getReusableDataExportTask(instanceID string, userID string) (studyTypes.Task, bool) {
+	tasks, err := h.studyDBConn.GetTasksCreatedBy(instanceID, userID, studyTypes.TASK_FILE_TYPE_ZIP)
+	if err != nil {
+		slog.Error("failed to get data export tasks", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("error", err.Error()))
+		return studyTypes.Task{}, false
+	}
+	if len(tasks) == 0 {
+		return studyTypes.Task{}, false
+	}
+
+	task := tasks[0]
+	switch {
+	case task.Status == studyTypes.TASK_STATUS_IN_PROGRESS:
+		return task, time.Since(task.CreatedAt) < dataExportMaxDuration
+	case task.Error != "" || task.ResultFile == "":
+		return task, false
+	case time.Since(task.UpdatedAt) >= dataExportRetention:
+		return task, false
+	}
+	if _, err := os.Stat(filepath.Join(h.filestorePath, task.ResultFile)); err != nil {
+		return task, false
+	}
+	return task, true
+}
+
+// StartDataExportCleanup periodically removes data export archives (and their tasks) older than the retention time
+func (h *HttpEndpoints) StartDataExportCleanup() {
+	go func() {
+		ticker := time.NewTicker(dataExportCleanupInterval)
+		defer ticker.Stop()
+		for {
+			for _, instanceID := range h.allowedInstanceIDs {
+				h.cleanUpExpiredDataExports(instanceID)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+func (h *HttpEndpoints) cleanUpExpiredDataExports(instanceID string) {
+	files, err := filepath.Glob(filepath.Join(h.filestorePath, instanceID, dataExportFolderName, "data-export_*.zip"))
+	if err != nil {
+		slog.Error("failed to list data exports", slog.String("instanceID", instanceID), slog.String("error", err.Error()))
+		return
+	}
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil || time.Since(info.ModTime()) < dataExportRetention {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			slog.Error("failed to remove expired data export", slog.String("instanceID", instanceID), slog.String("file", file), slog.String("error", err.Error()))
+			continue
+		}
+		taskID := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "data-export_"), ".zip")
+		if err := h.studyDBConn.DeleteTaskByID(instanceID, taskID); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			slog.Error("failed to delete data export task", slog.String("instanceID", instanceID), slog.String("taskID", taskID), slog.String("error", err.Error()))
+		}
+		slog.Info("removed expired data export", slog.String("instanceID", instanceID), slog.String("taskID", taskID))
+	}
+}
+
+// getOwnDataExportTask returns the task only if it is a data export created by the user of the token
+func (h *HttpEndpoints) getOwnDataExportTask(token *jwthandling.ParticipantUserClaims, taskID string) (studyTypes.Task, error) {
+	task, err := h.studyDBConn.GetTaskByID(token.InstanceID, taskID)
+	if err != nil {
+		return task, err
+	}
+	if task.CreatedBy != token.Subject || task.FileType != studyTypes.TASK_FILE_TYPE_ZIP {
+		return studyTypes.Task{}, fmt.Errorf("task %s does not belong to user", taskID)
+	}
+	return task, nil
+}
+
+func (h *HttpEndpoints) onDataExportFailed(instanceID string, taskID string, errMsg string) {
+	err := h.studyDBConn.UpdateTaskCompleted(
+		instanceID,
+		taskID,
+		studyTypes.TASK_STATUS_COMPLETED,
+		0,
+		errMsg,
+		"",
+	)
+	if err != nil {
+		slog.Error("failed to update task status", slog.String("error", err.Error()), slog.String("taskID", taskID))
+	}
+}
+
+func (h *HttpEndpoints) writeDataExport(
+	instanceID string,
+	taskID string,
+	user userTypes.User,
+	targets []dataExportTarget,
+	exportFilePath string,
+) (int, error) {
+	file, err := os.Create(exportFilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+
+	if err := h.writeAccountDataToZip(zw, instanceID, user); err != nil {
+		return 0, err
+	}
+	processed := 1
+
+	for _, target := range targets {
+		if err := h.writeStudyDataToZip(zw, instanceID, target); err != nil {
+			return processed, err
+		}
+		processed += 1
+
+		if err := h.studyDBConn.UpdateTaskProgress(instanceID, taskID, processed); err != nil {
+			slog.Error("failed to update task progress", slog.String("error", err.Error()))
+			// not a big issue, so let's try next time
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return processed, err
+	}
+	return processed, nil
+}
+
+func (h *HttpEndpoints) writeAccountDataToZip(zw *zip.Writer, instanceID string, user userTypes.User) error {
+	// credentials and one-time codes are not part of the user's data
+	user.Account.Password = ""
+	user.Account.VerificationCode = userTypes.VerificationCode{}
+
+	if err := writeJSONToZip(zw, "account/user.json", user); err != nil {
+		return err
+	}
+
+	profileRows := [][]string{}
+	for _, p := range user.Profiles {
+		profileRows = append(profileRows, []string{
+			p.ID.Hex(),
+			p.Alias,
+			strconv.FormatBool(p.MainProfile),
+			formatUnixTime(p.CreatedAt),
+			formatUnixTime(p.ConsentConfirmedAt),
+		})
+	}
+	if err := writeCSVToZip(zw, "account/profiles.csv", []string{"profileID", "alias", "mainProfile", "createdAt", "consentConfirmedAt"}, profileRows); err != nil {
+		return err
+	}
+
+	contactRows := [][]string{}
+	for _, ci := range user.ContactInfos {
+		contactRows = append(contactRows, []string{
+			ci.ID.Hex(),
+			string(ci.Type),
+			ci.Email,
+			ci.Phone,
+			formatUnixTime(ci.ConfirmedAt),
+		})
+	}
+	if err := writeCSVToZip(zw, "account/contact-infos.csv", []string{"id", "type", "email", "phone", "confirmedAt"}, contactRows); err != nil {
+		return err
+	}
+
+	if err := writeJSONToZip(zw, "account/contact-preferences.json", user.ContactPreferences); err != nil {
+		return err
+	}
+
+	attributes, err := h.userDBConn.GetAttributesForUser(instanceID, user.ID.Hex())
+	if err != nil {
+		return err
+	}
+	if err := writeJSONToZip(zw, "account/attributes.json", attributes); err != nil {
+		return err
+	}
+
+	inboxMessages := []messagingTypes.InboxMessage{}
+	for page := int64(1); ; page++ {
+		messages, totalCount, err := h.messagingDBConn.GetInboxMessagesForUser(instanceID, user.ID.Hex(), false, page, dataExportFilePageSize)
+		if err != nil {
+			return err
+		}
+		inboxMessages = append(inboxMessages, messages...)
+		if len(messages) == 0 || int64(len(inboxMessages)) >= totalCount {
+			break
+		}
+	}
+	if err := writeJSONToZip(zw, "account/inbox.json", inboxMessages); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (h *HttpEndpoints) writeStudyDataToZip(zw *zip.Writer, instanceID string, target dataExportTarget) error {
+	studyKey := target.Study.Key
+	pID := target.ParticipantID
+
+	pState, err := h.studyDBConn.GetParticipantByID(instanceID, studyKey, pID)
+	if err != nil {
+		// profile never participated in this study
+		return nil
+	}
+	// linking codes are managed by the study team, participants can query them one by one
+	pState.LinkingCodes = nil
+
+	folder := fmt.Sprintf("studies/%s/%s/", studyKey, target.ProfileID)
+
+	if err := writeJSONToZip(zw, folder+"participant-state.json", pState); err != nil {
+		return err
+	}
+
+	flagRows := [][]string{}
+	for k, v := range pState.Flags {
+		flagRows = append(flagRows, []string{k, v})
+	}
+	sort.Slice(flagRows, func(i, j int) bool { return flagRows[i][0] < flagRows[j][0] })
+	if err := writeCSVToZip(zw, folder+"participant-flags.csv", []string{"key", "value"}, flagRows); err != nil {
+		return err
+	}
+
+	if err := h.writeResponsesToZip(zw, instanceID, studyKey, pID, folder); err != nil {
+		return err
+	}
+
+	confidentialResponses, err := h.studyDBConn.FindConfidentialResponses(instanceID, studyKey, target.ConfidentialID, "")
+	if err != nil {
+		return err
+	}
+	confidentialRows := [][]string{}
+	for _, r := range confidentialResponses {
+		for _, entry := range studyutils.PrepConfidentialResponseExport(r, pID, nil) {
+			confidentialRows = append(confidentialRows, []string{entry.EntryID, r.Key, entry.ResponseKey, entry.Value})
+		}
+	}
+	if len(confidentialRows) > 0 {
+		if err := writeCSVToZip(zw, folder+"confidential-responses.csv", []string{"entryID", "surveyKey", "responseKey", "value"}, confidentialRows); err != nil {
+			return err
+		}
+	}
+
+	if err := h.writeReportsToZip(zw, instanceID, studyKey, pID, folder); err != nil {
+		return err
+	}
+
+	consentRecords, err := h.studyDBConn.GetConsentRecordsForParticipant(instanceID, studyKey, pID)
+	if err != nil {
+		return err
+	}
+	if len(consentRecords) > 0 {
+		consentRows := [][]string{}
+		for _, r := range consentRecords {
+			withdrawnAt := ""
+			if r.WithdrawnAt != nil {
+				withdrawnAt = r.WithdrawnAt.UTC().Format(time.RFC3339)
+			}
+			consentRows = append(consentRows, []string{r.ConsentKey, strconv.Itoa(r.Version), r.Language, r.ContentHash, r.Status, r.AcceptedAt.UTC().Format(time.RFC3339), withdrawnAt})
+		}
+		if err := writeCSVToZip(zw, folder+"consents.csv", []string{"consentKey", "version", "language", "contentHash", "status", "acceptedAt", "withdrawnAt"}, consentRows); err != nil {
+			return err
+		}
+	}
+
+	scores, err := h.studyDBConn.GetSurveyScoreRecordsForParticipant(instanceID, studyKey, pID, "", 0)
+	if err != nil {
+		return err
+	}
+	if len(scores) > 0 {
+		if err := writeJSONToZip(zw, folder+"survey-scores.json", scores); err != nil {
+			return err
+		}
+	}
+
+	return h.writeFilesToZip(zw, instanceID, studyKey, pID, folder)
+}
+
+func (h *HttpEndpoints) writeResponsesToZip(zw *zip.Writer, instanceID string, studyKey string, participantID string, folder string) error {
+	responsesBySurvey := map[string][]studyTypes.SurveyResponse{}
+	allResponses := []studyTypes.SurveyResponse{}
+
+	err := h.studyDBConn.FindAndExecuteOnResponses(
+		context.Background(),
+		instanceID,
+		studyKey,
+		bson.M{"participantID": participantID},
+		bson.M{"arrivedAt": 1},
+		true,
+		func(dbService *studyDB.StudyDBService, r studyTypes.SurveyResponse, instanceID, studyKey string, args ...interface{}) error {
+			allResponses = append(allResponses, r)
+			responsesBySurvey[r.Key] = append(responsesBySurvey[r.Key], r)
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSONToZip(zw, folder+"responses.json", allResponses); err != nil {
+		return err
+	}
+
+	surveyKeys := make([]string, 0, len(responsesBySurvey))
+	for k := range responsesBySurvey {
+		surveyKeys = append(surveyKeys, k)
+	}
+	sort.Strings(surveyKeys)
+
+	for _, surveyKey := range surveyKeys {
+		surveyVersions, err := surveydefinition.PrepareSurveyInfosFromDB(
+			h.studyDBConn,
+			instanceID,
+			studyKey,
+			surveyKey,
+			&surveydefinition.ExtractOptions{},
+		)
+		if err != nil {
+			// responses are still part of responses.json
+			slog.Warn("cannot prepare survey infos for data export", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("error", err.Error()))
+			continue
+		}
+
+		respParser, err := surveyresponses.NewResponseParser(
+			surveyKey,
+			surveyVersions,
+			false,
+			&surveyresponses.IncludeMeta{},
+			"-",
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+
+		w, err := zw.Create(folder + "responses/" + surveyKey + ".csv")
+		if err != nil {
+			return err
+		}
+		exporter, err := surveyresponses.NewResponseExporter(respParser, w, "wide")
+		if err != nil {
+			return err
+		}
+		for _, r := range responsesBySurvey[surveyKey] {
+			if err := exporter.WriteResponse(&r); err != nil {
+				slog.Warn("cannot write response to data export", slog.String("responseID", r.ID.Hex()), slog.String("error", err.Error()))
+			}
+		}
+		if err := exporter.Finish(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HttpEndpoints) writeReportsToZip(zw *zip.Writer, instanceID string, studyKey string, participantID string, folder string) error {
+	reports := []studyTypes.Report{}
+	err := h.studyDBConn.FindAndExecuteOnReports(
+		context.Background(),
+		instanceID,
+		studyKey,
+		bson.M{"participantID": participantID},
+		true,
+		func(instanceID string, studyKey string, report studyTypes.Report, args ...interface{}) error {
+			reports = append(reports, report)
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := writeJSONToZip(zw, folder+"reports.json", reports); err != nil {
+		return err
+	}
+
+	rows := [][]string{}
+	for _, r := range reports {
+		if len(r.Data) == 0 {
+			rows = append(rows, []string{r.ID.Hex(), r.Key, formatUnixTime(r.Timestamp), "", "", ""})
+			continue
+		}
+		for _, d := range r.Data {
+			rows = append(rows, []string{r.ID.Hex(), r.Key, formatUnixTime(r.Timestamp), d.Key, d.Value, d.Dtype})
+		}
+	}
+	return writeCSVToZip(zw, folder+"reports.csv", []string{"reportID", "key", "timestamp", "dataKey", "value", "dtype"}, rows)
+}
+
+func (h *HttpEndpoints) writeFilesToZip(zw *zip.Writer, instanceID string, studyKey string, participantID string, folder string) error {
+	// files shared with the participant or uploaded by themselves
+	filter := bson.M{
+		"participantID": participantID,
+		"status":        studyTypes.FILE_STATUS_READY,
+		"$or": bson.A{
+			bson.M{"visibleToParticipant": true},
+			bson.M{"uploadedBy": bson.M{"$in": bson.A{"", nil}}},
+		},
+	}
+
+	fileInfos := []studyTypes.FileInfo{}
+	for page := int64(1); ; page++ {
+		infos, paginationInfo, err := h.studyDBConn.GetParticipantFileInfos(instanceID, studyKey, filter, page, dataExportFilePageSize)
+		if err != nil {
+			return err
+		}
+		fileInfos = append(fileInfos, infos...)
+		if paginationInfo == nil || page >= paginationInfo.TotalPages {
+			break
+		}
+	}
+	if len(fileInfos) == 0 {
+		return nil
+	}
+
+	rows := [][]string{}
+	for _, fileInfo := range fileInfos {
+		nameInExport := "files/" + fileInfo.ID.Hex() + "_" + filepath.Base(fileInfo.Path)
+		if err := copyFileToZip(zw, folder+nameInExport, filepath.Join(h.filestorePath, fileInfo.Path)); err != nil {
+			slog.Error("cannot add participant file to data export", slog.String("fileID", fileInfo.ID.Hex()), slog.String("error", err.Error()))
+			nameInExport = ""
+		}
+		rows = append(rows, []string{
+			fileInfo.ID.Hex(),
+			fileInfo.FileType,
+			strconv.FormatInt(fileInfo.Size, 10),
+			fileInfo.CreatedAt.UTC().Format(time.RFC3339),
+			nameInExport,
+		})
+	}
+	return writeCSVToZip(zw, folder+"files.csv", []string{"fileID", "fileType", "size", "createdAt", "file"}, rows)
+}
+
+func writeJSONToZip(zw *zip.Writer, name string, content any) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(content)
+}
+
+func writeCSVToZip(zw *zip.Writer, name string, header []string, rows [][]string) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(header); err != nil {
+		return err
+	}
+	if err := csvWriter.WriteAll(rows); err != nil {
+		return err
+	}
+	return csvWriter.Error()
+}
+
+func copyFileToZip(zw *zip.Writer, name string, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, src)
+	return err
+}
+
+func formatUnixTime(ts int64) string {
+	if ts <= 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).UTC().Format(time.RFC3339)
+}
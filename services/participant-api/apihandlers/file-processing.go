@@ -0,0 +1,100 @@
+package apihandlers
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	fileprocessing "github.com/case-framework/case-backend/pkg/file-processing"
+)
+
+const (
+	previewsFolderName = "previews"
+
+	maxConcurrentFileProcessing = 2
+)
+
+// fileProcessingSlots bounds how many files are read and decoded at the same time, further uploads wait for a free slot
+var fileProcessingSlots = make(chan struct{}, maxConcurrentFileProcessing)
+
+// processStoredParticipantFile runs in the background once a file is ready: it removes metadata from images
+// if the study is configured so and creates a downscaled preview
+func (h *HttpEndpoints) processStoredParticipantFile(instanceID string, studyKey string, fileInfoID string, relativePath string, fileType string) {
+	stripMetadata := false
+	study, err := h.studyDBConn.GetStudy(instanceID, studyKey)
+	if err != nil {
+		slog.Error("failed to get study", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+	} else {
+		stripMetadata = study.Configs.StripFileMetadata && fileprocessing.IsMetadataStrippingSupported(fileType)
+	}
+	createPreview := fileprocessing.IsPreviewSupported(fileType)
+	if !stripMetadata && !createPreview {
+		return
+	}
+
+	fileProcessingSlots <- struct{}{}
+	defer func() { <-fileProcessingSlots }()
+
+	filePath := filepath.Join(h.filestorePath, relativePath)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		slog.Error("failed to read file for processing", slog.String("error", err.Error()), slog.String("path", relativePath))
+		return
+	}
+
+	if stripMetadata {
+		cleaned, stripped, err := fileprocessing.StripMetadata(data, fileType)
+		if err != nil {
+			slog.Error("failed to strip file metadata", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfoID))
+		} else if stripped {
+			if err := replaceFileContent(filePath, cleaned); err != nil {
+				slog.Error("failed to store file without metadata", slog.String("error", err.Error()), slog.String("path", relativePath))
+			} else {
+				data = cleaned
+				slog.Info("removed metadata from file", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfoID))
+			}
+		}
+	}
+
+	previewPath := ""
+	if createPreview {
+		var preview bytes.Buffer
+		err := fileprocessing.GeneratePreview(data, fileType, fileprocessing.DefaultPreviewMaxSize, &preview)
+		switch {
+		case errors.Is(err, fileprocessing.ErrNoPreviewAvailable), errors.Is(err, fileprocessing.ErrImageTooLarge):
+			slog.Debug("no preview available for file", slog.String("reason", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfoID))
+		case err != nil:
+			slog.Error("failed to generate preview", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfoID))
+		default:
+			relativePreviewPath := filepath.Join(instanceID, studyKey, previewsFolderName, fileInfoID+fileprocessing.PreviewFileExtension)
+			previewFilePath := filepath.Join(h.filestorePath, relativePreviewPath)
+			if err := os.MkdirAll(filepath.Dir(previewFilePath), os.ModePerm); err != nil {
+				slog.Error("failed to create directory", slog.String("error", err.Error()), slog.String("path", filepath.Dir(previewFilePath)))
+			} else if err := os.WriteFile(previewFilePath, preview.Bytes(), 0o644); err != nil {
+				slog.Error("failed to save preview", slog.String("error", err.Error()), slog.String("path", relativePreviewPath))
+			} else {
+				previewPath = relativePreviewPath
+			}
+		}
+	}
+
+	err = h.studyDBConn.UpdateParticipantFileInfoPreviewAndSize(instanceID, studyKey, fileInfoID, previewPath, int64(len(data)))
+	if err != nil {
+		slog.Error("failed to update file info", slog.String("error", err.Error()), slog.String("fileID", fileInfoID))
+		if previewPath != "" {
+			os.Remove(filepath.Join(h.filestorePath, previewPath))
+		}
+	}
+}
+
+// replaceFileContent writes to a temporary file first, so the file is never visible half written
+func replaceFileContent(path string, content []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0o644); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,451 @@
+package apihandlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	filescanner "github.com/case-framework/case-backend/pkg/file-scanner"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
+	"github.com/case-framework/case-backend/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// MAX_PARTICIPANT_RESUMABLE_UPLOAD_SIZE is the maximum declared total size of a resumable upload (100 MB)
+	MAX_PARTICIPANT_RESUMABLE_UPLOAD_SIZE = 100 * 1024 * 1024
+	// MAX_PARTICIPANT_UPLOAD_CHUNK_SIZE is the maximum size of a single chunk of a resumable upload (5 MB)
+	MAX_PARTICIPANT_UPLOAD_CHUNK_SIZE = 5 * 1024 * 1024
+
+	uploadsFolderName    = "uploads"
+	quarantineFolderName = "quarantine"
+
+	HEADER_UPLOAD_OFFSET   = "Upload-Offset"
+	HEADER_UPLOAD_CHECKSUM = "Upload-Checksum" // "sha256 <base64 encoded digest of the chunk>"
+)
+
+var errFileScanFailed = errors.New("file scan failed")
+
+var allowedParticipantFileTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/webp",
+	"application/pdf",
+}
+
+// initFileUpload creates the file info for a resumable upload after checking the declared size and type against the study rules
+func (h *HttpEndpoints) initFileUpload(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	studyKey := c.Param("studyKey")
+
+	var req struct {
+		ProfileID string `json:"profileID"`
+		Size      int64  `json:"size"`
+		FileType  string `json:"fileType"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.ProfileID == "" {
+		slog.Error("profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "profileID is required"})
+		return
+	}
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, req.ProfileID) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	if req.Size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "size must be greater than 0"})
+		return
+	}
+	if req.Size > MAX_PARTICIPANT_RESUMABLE_UPLOAD_SIZE {
+		slog.Warn("File size exceeds maximum allowed size", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("profileID", req.ProfileID), slog.Int64("fileSize", req.Size), slog.Int64("maxSize", MAX_PARTICIPANT_RESUMABLE_UPLOAD_SIZE))
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("File size exceeds maximum allowed size of %d MB", MAX_PARTICIPANT_RESUMABLE_UPLOAD_SIZE/(1024*1024))})
+		return
+	}
+
+	typeAllowed := false
+	for _, t := range allowedParticipantFileTypes {
+		if t == req.FileType {
+			typeAllowed = true
+			break
+		}
+	}
+	if !typeAllowed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid file type: %s", req.FileType)})
+		return
+	}
+
+	allowed, participantID := studyService.IsAllowedToUploadFile(token.InstanceID, studyKey, req.ProfileID, req.Size, req.FileType)
+	if !allowed {
+		slog.Warn("file upload not allowed", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("profileID", req.ProfileID))
+		c.JSON(http.StatusForbidden, gin.H{"error": "file upload not allowed"})
+		return
+	}
+
+	now := time.Now()
+	savedFileInfo, err := h.studyDBConn.CreateParticipantFileInfo(token.InstanceID, studyKey, studyTypes.FileInfo{
+		ParticipantID:        participantID,
+		Status:               studyTypes.FILE_STATUS_UPLOADING,
+		CreatedAt:            now,
+		UpdatedAt:            now,
+		FileType:             req.FileType,
+		VisibleToParticipant: true,
+		Size:                 req.Size,
+	})
+	if err != nil {
+		slog.Error("failed to create file info", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create file info"})
+		return
+	}
+
+	// chunks are collected in a partial file until the upload is completed
+	uploadDir := filepath.Join(h.filestorePath, token.InstanceID, studyKey, uploadsFolderName)
+	relativePath := filepath.Join(token.InstanceID, studyKey, uploadsFolderName, savedFileInfo.ID.Hex()+".part")
+	err = os.MkdirAll(uploadDir, os.ModePerm)
+	if err == nil {
+		var f *os.File
+		f, err = os.Create(filepath.Join(h.filestorePath, relativePath))
+		if err == nil {
+			err = f.Close()
+		}
+	}
+	if err == nil {
+		err = h.studyDBConn.UpdateParticipantFileInfoPathAndStatus(token.InstanceID, studyKey, savedFileInfo.ID.Hex(), relativePath, studyTypes.FILE_STATUS_UPLOADING)
+	}
+	if err != nil {
+		slog.Error("failed to prepare upload", slog.String("error", err.Error()), slog.String("path", relativePath))
+		os.Remove(filepath.Join(h.filestorePath, relativePath))
+		if err := h.studyDBConn.DeleteParticipantFileInfoByID(token.InstanceID, studyKey, savedFileInfo.ID.Hex()); err != nil {
+			slog.Error("failed to delete file info", slog.String("error", err.Error()))
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare upload"})
+		return
+	}
+
+	slog.Info("file upload initialised", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("profileID", req.ProfileID), slog.String("fileID", savedFileInfo.ID.Hex()), slog.Int64("size", req.Size))
+
+	c.Header(HEADER_UPLOAD_OFFSET, "0")
+	c.JSON(http.StatusCreated, gin.H{
+		"id":           savedFileInfo.ID.Hex(),
+		"size":         req.Size,
+		"uploadOffset": 0,
+		"maxChunkSize": MAX_PARTICIPANT_UPLOAD_CHUNK_SIZE,
+	})
+}
+
+// getFileUploadStatus returns the current offset so clients can resume an interrupted upload
+func (h *HttpEndpoints) getFileUploadStatus(c *gin.Context) {
+	fileInfo, ok := h.getOwnUploadingFileInfo(c)
+	if !ok {
+		return
+	}
+
+	c.Header(HEADER_UPLOAD_OFFSET, strconv.FormatInt(fileInfo.UploadOffset, 10))
+	c.JSON(http.StatusOK, gin.H{
+		"id":           fileInfo.ID.Hex(),
+		"status":       fileInfo.Status,
+		"size":         fileInfo.Size,
+		"uploadOffset": fileInfo.UploadOffset,
+		"maxChunkSize": MAX_PARTICIPANT_UPLOAD_CHUNK_SIZE,
+	})
+}
+
+// uploadFileChunk appends the request body at the given offset after verifying the chunk checksum
+func (h *HttpEndpoints) uploadFileChunk(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	studyKey := c.Param("studyKey")
+
+	fileInfo, ok := h.getOwnUploadingFileInfo(c)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader(HEADER_UPLOAD_OFFSET), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or missing " + HEADER_UPLOAD_OFFSET + " header"})
+		return
+	}
+	if offset != fileInfo.UploadOffset {
+		slog.Warn("upload offset mismatch", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfo.ID.Hex()), slog.Int64("expected", fileInfo.UploadOffset), slog.Int64("received", offset))
+		c.Header(HEADER_UPLOAD_OFFSET, strconv.FormatInt(fileInfo.UploadOffset, 10))
+		c.JSON(http.StatusConflict, gin.H{"error": "offset mismatch", "uploadOffset": fileInfo.UploadOffset})
+		return
+	}
+
+	expectedChecksum, err := parseUploadChecksumHeader(c.GetHeader(HEADER_UPLOAD_CHECKSUM))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	chunk, err := io.ReadAll(io.LimitReader(c.Request.Body, MAX_PARTICIPANT_UPLOAD_CHUNK_SIZE+1))
+	if err != nil {
+		slog.Error("failed to read chunk", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read chunk"})
+		return
+	}
+	if len(chunk) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chunk is empty"})
+		return
+	}
+	if len(chunk) > MAX_PARTICIPANT_UPLOAD_CHUNK_SIZE {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("chunk exceeds maximum allowed size of %d MB", MAX_PARTICIPANT_UPLOAD_CHUNK_SIZE/(1024*1024))})
+		return
+	}
+	newOffset := offset + int64(len(chunk))
+	if newOffset > fileInfo.Size {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "chunk exceeds declared file size"})
+		return
+	}
+
+	sum := sha256.Sum256(chunk)
+	if !bytes.Equal(sum[:], expectedChecksum) {
+		slog.Warn("chunk checksum mismatch", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfo.ID.Hex()), slog.Int64("offset", offset))
+		c.Header(HEADER_UPLOAD_OFFSET, strconv.FormatInt(offset, 10))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "checksum mismatch", "uploadOffset": offset})
+		return
+	}
+
+	// the offset is advanced before writing, so only one of several requests for the same offset writes its chunk
+	err = h.studyDBConn.UpdateParticipantFileUploadOffset(token.InstanceID, studyKey, fileInfo.ID.Hex(), offset, newOffset)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// another request updated the upload in the meantime, client has to check the current offset
+			c.JSON(http.StatusConflict, gin.H{"error": "offset mismatch"})
+			return
+		}
+		slog.Error("failed to update upload offset", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update upload offset"})
+		return
+	}
+
+	if err := writeChunkAt(filepath.Join(h.filestorePath, fileInfo.Path), chunk, offset); err != nil {
+		slog.Error("failed to write chunk", slog.String("error", err.Error()), slog.String("path", fileInfo.Path))
+		// give the range back, so the client can send the chunk again
+		if rbErr := h.studyDBConn.UpdateParticipantFileUploadOffset(token.InstanceID, studyKey, fileInfo.ID.Hex(), newOffset, offset); rbErr != nil {
+			slog.Error("failed to reset upload offset", slog.String("error", rbErr.Error()), slog.String("fileID", fileInfo.ID.Hex()))
+		}
+		c.Header(HEADER_UPLOAD_OFFSET, strconv.FormatInt(offset, 10))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write chunk", "uploadOffset": offset})
+		return
+	}
+
+	c.Header(HEADER_UPLOAD_OFFSET, strconv.FormatInt(newOffset, 10))
+	c.JSON(http.StatusOK, gin.H{"uploadOffset": newOffset, "size": fileInfo.Size})
+}
+
+// completeFileUpload validates the received content and moves the file to its final location
+func (h *HttpEndpoints) completeFileUpload(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	studyKey := c.Param("studyKey")
+
+	fileInfo, ok := h.getOwnUploadingFileInfo(c)
+	if !ok {
+		return
+	}
+
+	if fileInfo.UploadOffset != fileInfo.Size {
+		c.Header(HEADER_UPLOAD_OFFSET, strconv.FormatInt(fileInfo.UploadOffset, 10))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "upload is incomplete", "uploadOffset": fileInfo.UploadOffset, "size": fileInfo.Size})
+		return
+	}
+
+	partPath := filepath.Join(h.filestorePath, fileInfo.Path)
+	f, err := os.Open(partPath)
+	if err != nil {
+		slog.Error("failed to open uploaded file", slog.String("error", err.Error()), slog.String("path", fileInfo.Path))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to open uploaded file"})
+		return
+	}
+	fileType, err := utils.ValidateFileTypeFromReader(f, allowedParticipantFileTypes)
+	f.Close()
+	if err == nil && fileType != fileInfo.FileType {
+		err = fmt.Errorf("file content (%s) does not match declared file type (%s)", fileType, fileInfo.FileType)
+	}
+	if err != nil {
+		slog.Warn("failed to validate file type", slog.String("error", err.Error()), slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfo.ID.Hex()))
+		h.removeFileUpload(token.InstanceID, studyKey, fileInfo)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fileInfo.ID.Hex() + utils.GetFileExtensionFromContentType(fileType)
+	status, relativePath, err := h.storeScannedParticipantFile(c.Request.Context(), token.InstanceID, studyKey, fileInfo.ID.Hex(), fileInfo.Path, filename, fileType)
+	if err != nil {
+		if errors.Is(err, errFileScanFailed) {
+			// upload stays in progress, completing can be retried
+			slog.Error("failed to scan uploaded file", slog.String("error", err.Error()), slog.String("fileID", fileInfo.ID.Hex()))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "file could not be scanned"})
+			return
+		}
+		slog.Error("failed to store uploaded file", slog.String("error", err.Error()), slog.String("fileID", fileInfo.ID.Hex()))
+		fileInfo.Path = relativePath
+		h.removeFileUpload(token.InstanceID, studyKey, fileInfo)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save file"})
+		return
+	}
+	if status == studyTypes.FILE_STATUS_QUARANTINED {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "file rejected by malware scan"})
+		return
+	}
+
+	slog.Info("file uploaded successfully", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfo.ID.Hex()))
+	c.JSON(http.StatusOK, gin.H{
+		"id":     fileInfo.ID.Hex(),
+		"status": status,
+	})
+}
+
+// storeScannedParticipantFile scans the file at currentPath (relative to the filestore) if a scanner is configured and moves it to
+// the study folder, or to the quarantine folder if a threat was detected. Returns the new status and path of the file.
+// Ready files are post-processed in the background.
+func (h *HttpEndpoints) storeScannedParticipantFile(ctx context.Context, instanceID string, studyKey string, fileInfoID string, currentPath string, filename string, fileType string) (status string, relativePath string, err error) {
+	status = studyTypes.FILE_STATUS_READY
+	relativePath = filepath.Join(instanceID, studyKey, filename)
+
+	var scanResult *studyTypes.FileScanResult
+	if h.fileScanner != nil {
+		res, err := filescanner.ScanFile(ctx, h.fileScanner, filepath.Join(h.filestorePath, currentPath))
+		if err != nil {
+			return "", currentPath, fmt.Errorf("%w: %s", errFileScanFailed, err.Error())
+		}
+		scanResult = &studyTypes.FileScanResult{
+			Status:    studyTypes.FILE_SCAN_STATUS_CLEAN,
+			Scanner:   res.Scanner,
+			ScannedAt: time.Now(),
+		}
+		if res.Infected {
+			slog.Warn("malware detected in uploaded file", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileInfoID), slog.String("threat", res.Threat))
+			scanResult.Status = studyTypes.FILE_SCAN_STATUS_INFECTED
+			scanResult.Threat = res.Threat
+			status = studyTypes.FILE_STATUS_QUARANTINED
+			relativePath = filepath.Join(instanceID, studyKey, quarantineFolderName, filename)
+		}
+	}
+
+	if relativePath != currentPath {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(h.filestorePath, relativePath)), os.ModePerm); err != nil {
+			return "", currentPath, err
+		}
+		if err := os.Rename(filepath.Join(h.filestorePath, currentPath), filepath.Join(h.filestorePath, relativePath)); err != nil {
+			return "", currentPath, err
+		}
+	}
+
+	if scanResult == nil {
+		err = h.studyDBConn.UpdateParticipantFileInfoPathAndStatus(instanceID, studyKey, fileInfoID, relativePath, status)
+	} else {
+		err = h.studyDBConn.UpdateParticipantFileInfoScanResult(instanceID, studyKey, fileInfoID, relativePath, status, *scanResult)
+	}
+	if err == nil && status == studyTypes.FILE_STATUS_READY {
+		go h.processStoredParticipantFile(instanceID, studyKey, fileInfoID, relativePath, fileType)
+	}
+	return status, relativePath, err
+}
+
+// getOwnUploadingFileInfo loads the file info of the fileID param and makes sure it is an ongoing upload of the profile (?pid=)
+func (h *HttpEndpoints) getOwnUploadingFileInfo(c *gin.Context) (fileInfo studyTypes.FileInfo, ok bool) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	studyKey := c.Param("studyKey")
+	fileID := c.Param("fileID")
+	profileID := c.DefaultQuery("pid", "")
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, profileID) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", profileID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	study, err := h.studyDBConn.GetStudy(token.InstanceID, studyKey)
+	if err != nil {
+		slog.Error("failed to get study", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get study"})
+		return
+	}
+	if study.Status != studyTypes.STUDY_STATUS_ACTIVE {
+		slog.Warn("Study is not active", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "study is not active"})
+		return
+	}
+
+	participantID, _, err := studyService.ComputeParticipantIDs(study, profileID)
+	if err != nil {
+		slog.Error("failed to compute participant IDs", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute participant IDs"})
+		return
+	}
+
+	fileInfo, err = h.studyDBConn.GetParticipantFileInfoByID(token.InstanceID, studyKey, fileID)
+	if err != nil || fileInfo.ParticipantID != participantID {
+		slog.Warn("upload not found", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileID))
+		c.JSON(http.StatusNotFound, gin.H{"error": "upload not found"})
+		return
+	}
+	if fileInfo.Status != studyTypes.FILE_STATUS_UPLOADING || !strings.HasSuffix(fileInfo.Path, ".part") {
+		c.JSON(http.StatusConflict, gin.H{"error": "upload is not in progress", "status": fileInfo.Status})
+		return
+	}
+	return fileInfo, true
+}
+
+func (h *HttpEndpoints) removeFileUpload(instanceID string, studyKey string, fileInfo studyTypes.FileInfo) {
+	if fileInfo.Path != "" {
+		if err := os.Remove(filepath.Join(h.filestorePath, fileInfo.Path)); err != nil && !os.IsNotExist(err) {
+			slog.Error("failed to delete file", slog.String("error", err.Error()), slog.String("path", fileInfo.Path))
+		}
+	}
+	if err := h.studyDBConn.DeleteParticipantFileInfoByID(instanceID, studyKey, fileInfo.ID.Hex()); err != nil {
+		slog.Error("failed to delete file info", slog.String("error", err.Error()))
+	}
+}
+
+func parseUploadChecksumHeader(value string) ([]byte, error) {
+	algorithm, encoded, found := strings.Cut(strings.TrimSpace(value), " ")
+	if !found {
+		return nil, errors.New("invalid or missing " + HEADER_UPLOAD_CHECKSUM + " header")
+	}
+	if !strings.EqualFold(algorithm, "sha256") {
+		return nil, fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+	checksum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(checksum) != sha256.Size {
+		return nil, errors.New("invalid checksum value")
+	}
+	return checksum, nil
+}
+
+// writeChunkAt writes the chunk at the offset. The file is not truncated, since the range after the chunk
+// may already be claimed by the next request; bytes of a failed write are overwritten when the chunk is sent again.
+func writeChunkAt(path string, chunk []byte, offset int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(chunk, offset); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
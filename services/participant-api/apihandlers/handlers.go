@@ -8,6 +8,10 @@ import (
 	messagingDB "github.com/case-framework/case-backend/pkg/db/messaging"
 	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
 	studyDB "github.com/case-framework/case-backend/pkg/db/study"
+	filescanner "github.com/case-framework/case-backend/pkg/file-scanner"
+	participantevents "github.com/case-framework/case-backend/pkg/participant-events"
+	"github.com/case-framework/case-backend/pkg/user-management/oidc"
+	"github.com/case-framework/case-backend/pkg/user-management/webauthn"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,6 +22,13 @@ func HealthCheckHandle(c *gin.Context) {
 type TTLs struct {
 	AccessToken                   time.Duration
 	EmailContactVerificationToken time.Duration
+	CalendarFeedToken             time.Duration
+	LoginLinkToken                time.Duration
+}
+
+type WebPushSettings struct {
+	VAPIDPublicKey         string
+	AllowInsecureEndpoints bool
 }
 
 type HttpEndpoints struct {
@@ -29,6 +40,11 @@ type HttpEndpoints struct {
 	allowedInstanceIDs    []string
 	globalStudySecret     string
 	filestorePath         string
+	fileScanner           filescanner.Scanner
+	webPush               WebPushSettings
+	webAuthn              webauthn.Config
+	oidcProviders         map[string]*oidc.Provider
+	participantEvents     *participantevents.Hub
 	maxNewUsersPer5Minute int
 	ttls                  TTLs
 }
@@ -42,6 +58,11 @@ func NewHTTPHandler(
 	allowedInstanceIDs []string,
 	globalStudySecret string,
 	filestorePath string,
+	fileScanner filescanner.Scanner,
+	webPush WebPushSettings,
+	webAuthn webauthn.Config,
+	oidcProviders map[string]*oidc.Provider,
+	participantEvents *participantevents.Hub,
 	maxNewUsersPer5Minute int,
 	ttls TTLs,
 ) *HttpEndpoints {
@@ -54,6 +75,11 @@ func NewHTTPHandler(
 		allowedInstanceIDs:    allowedInstanceIDs,
 		globalStudySecret:     globalStudySecret,
 		filestorePath:         filestorePath,
+		fileScanner:           fileScanner,
+		webPush:               webPush,
+		webAuthn:              webAuthn,
+		oidcProviders:         oidcProviders,
+		participantEvents:     participantEvents,
 		maxNewUsersPer5Minute: maxNewUsersPer5Minute,
 		ttls:                  ttls,
 	}
@@ -0,0 +1,104 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/case-framework/case-backend/pkg/apihelpers"
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const MAX_INBOX_PAGE_SIZE = 100
+
+// getInboxMessages lists the inbox of the user, newest first. Query parameter unreadOnly=true skips read messages.
+func (h *HttpEndpoints) getInboxMessages(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	query, err := apihelpers.ParsePaginatedQueryFromCtx(c)
+	if err != nil {
+		slog.Error("failed to parse query", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query"})
+		return
+	}
+	if query.Limit > MAX_INBOX_PAGE_SIZE {
+		query.Limit = MAX_INBOX_PAGE_SIZE
+	}
+	unreadOnly := c.DefaultQuery("unreadOnly", "false") == "true"
+
+	messages, totalCount, err := h.messagingDBConn.GetInboxMessagesForUser(token.InstanceID, token.Subject, unreadOnly, query.Page, query.Limit)
+	if err != nil {
+		slog.Error("failed to get inbox messages", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get inbox messages"})
+		return
+	}
+
+	unreadCount, err := h.messagingDBConn.CountUnreadInboxMessagesForUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to count unread inbox messages", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get inbox messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages":    messages,
+		"unreadCount": unreadCount,
+		"pagination": gin.H{
+			"totalCount":  totalCount,
+			"currentPage": query.Page,
+			"pageSize":    query.Limit,
+			"totalPages":  (totalCount + query.Limit - 1) / query.Limit,
+		},
+	})
+}
+
+func (h *HttpEndpoints) markInboxMessageAsRead(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	messageID := c.Param("messageID")
+
+	err := h.messagingDBConn.MarkInboxMessageAsRead(token.InstanceID, token.Subject, messageID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		slog.Error("failed to mark inbox message as read", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageID", messageID), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to mark message as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "message marked as read"})
+}
+
+func (h *HttpEndpoints) markAllInboxMessagesAsRead(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	count, err := h.messagingDBConn.MarkAllInboxMessagesAsRead(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to mark inbox messages as read", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark messages as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+func (h *HttpEndpoints) deleteInboxMessage(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	messageID := c.Param("messageID")
+
+	err := h.messagingDBConn.DeleteInboxMessage(token.InstanceID, token.Subject, messageID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		slog.Error("failed to delete inbox message", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageID", messageID), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to delete message"})
+		return
+	}
+
+	slog.Info("inbox message deleted", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("messageID", messageID))
+	c.JSON(http.StatusOK, gin.H{"message": "message deleted"})
+}
@@ -0,0 +1,163 @@
+package apihandlers
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	participantevents "github.com/case-framework/case-backend/pkg/participant-events"
+	studyService "github.com/case-framework/case-backend/pkg/study"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	LIVE_UPDATES_KEEPALIVE_INTERVAL = 25 * time.Second
+	LIVE_UPDATES_BUFFER_SIZE        = 32
+)
+
+type liveUpdateProfileRef struct {
+	StudyKey  string
+	ProfileID string
+}
+
+// streamParticipantEvents keeps a server-sent events stream open and notifies the client about changed assigned surveys,
+// new reports and new inbox messages of the user's profiles. The events only reference what changed, the client reloads
+// the data with the regular endpoints. The stream ends when the access token expires (event "expired").
+func (h *HttpEndpoints) streamParticipantEvents(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if h.participantEvents == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "live updates not enabled"})
+		return
+	}
+
+	participantRefs, err := h.getParticipantIDsForUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to prepare live updates", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to prepare live updates"})
+		return
+	}
+
+	sub := h.participantEvents.Subscribe(func(e participantevents.Event) bool {
+		if e.InstanceID != token.InstanceID {
+			return false
+		}
+		switch e.Type {
+		case participantevents.EVENT_TYPE_INBOX:
+			return e.UserID == token.Subject
+		case participantevents.EVENT_TYPE_SURVEYS, participantevents.EVENT_TYPE_REPORT:
+			_, ok := participantRefs[e.StudyKey+"/"+e.ParticipantID]
+			return ok
+		}
+		return true
+	}, LIVE_UPDATES_BUFFER_SIZE)
+	defer sub.Close()
+
+	var expired <-chan time.Time
+	if token.ExpiresAt != nil {
+		timer := time.NewTimer(time.Until(token.ExpiresAt.Time))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	keepalive := time.NewTicker(LIVE_UPDATES_KEEPALIVE_INTERVAL)
+	defer keepalive.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // disable response buffering of nginx
+	c.Status(http.StatusOK)
+	c.SSEvent("connected", gin.H{})
+	c.Writer.Flush()
+
+	slog.Debug("live updates stream opened", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject))
+
+	// participant states change for many reasons, only notify if the assigned surveys are different
+	lastSurveys := map[string][32]byte{}
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-expired:
+			c.SSEvent("expired", gin.H{})
+			c.Writer.Flush()
+			return
+		case <-keepalive.C:
+			if sub.Overflowed() {
+				c.SSEvent(participantevents.EVENT_TYPE_RESYNC, gin.H{})
+			} else if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if sub.Overflowed() {
+				// the client has to reload everything anyway
+				c.SSEvent(participantevents.EVENT_TYPE_RESYNC, gin.H{})
+				c.Writer.Flush()
+				continue
+			}
+
+			switch e.Type {
+			case participantevents.EVENT_TYPE_SURVEYS:
+				ref := participantRefs[e.StudyKey+"/"+e.ParticipantID]
+				surveys, err := json.Marshal(e.AssignedSurveys)
+				if err != nil {
+					continue
+				}
+				hash := sha256.Sum256(surveys)
+				if last, ok := lastSurveys[e.StudyKey+"/"+e.ParticipantID]; ok && last == hash {
+					continue
+				}
+				lastSurveys[e.StudyKey+"/"+e.ParticipantID] = hash
+				c.SSEvent(e.Type, gin.H{"studyKey": ref.StudyKey, "profileId": ref.ProfileID})
+			case participantevents.EVENT_TYPE_REPORT:
+				ref := participantRefs[e.StudyKey+"/"+e.ParticipantID]
+				c.SSEvent(e.Type, gin.H{"studyKey": ref.StudyKey, "profileId": ref.ProfileID, "reportKey": e.ReportKey, "reportId": e.ReportID})
+			case participantevents.EVENT_TYPE_INBOX:
+				c.SSEvent(e.Type, gin.H{"messageId": e.InboxMessageID, "studyKey": e.StudyKey, "title": e.Title})
+			case participantevents.EVENT_TYPE_RESYNC:
+				lastSurveys = map[string][32]byte{}
+				c.SSEvent(e.Type, gin.H{})
+			default:
+				continue
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// getParticipantIDsForUser maps "studyKey/participantID" to the profile for all studies and profiles of the user.
+// Studies created or profiles added later are only considered once the client reconnects.
+func (h *HttpEndpoints) getParticipantIDsForUser(instanceID string, userID string) (map[string]liveUpdateProfileRef, error) {
+	user, err := h.userDBConn.GetUser(instanceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	studies, err := h.studyDBConn.GetStudies(instanceID, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := map[string]liveUpdateProfileRef{}
+	for _, study := range studies {
+		for _, profile := range user.Profiles {
+			participantID, _, err := studyService.ComputeParticipantIDs(study, profile.ID.Hex())
+			if err != nil {
+				slog.Error("Error computing participant IDs", slog.String("instanceID", instanceID), slog.String("studyKey", study.Key), slog.String("error", err.Error()))
+				continue
+			}
+			refs[study.Key+"/"+participantID] = liveUpdateProfileRef{
+				StudyKey:  study.Key,
+				ProfileID: profile.ID.Hex(),
+			}
+		}
+	}
+	return refs, nil
+}
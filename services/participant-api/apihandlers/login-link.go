@@ -0,0 +1,233 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	userDB "github.com/case-framework/case-backend/pkg/db/participant-user"
+	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	loginLinkRequestWindow  = 60 * 60 // seconds
+	LOGIN_LINK_MAX_REQUESTS = 5
+
+	DEFAULT_LOGIN_LINK_TOKEN_TTL = 15 * time.Minute
+)
+
+// requestLoginLink sends a single-use sign-in link to the email address if an account exists.
+// The response does not reveal whether the account exists.
+func (h *HttpEndpoints) requestLoginLink(c *gin.Context) {
+	var req struct {
+		Email      string `json:"email"`
+		InstanceID string `json:"instanceId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Email == "" || req.InstanceID == "" {
+		slog.Error("missing required fields")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	req.Email = umUtils.SanitizeEmail(req.Email)
+
+	auditRecord := userDB.LoginLinkAuditRecord{
+		AccountID: req.Email,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	// counted per email address, so that the limit applies to unknown accounts too
+	count, err := h.userDBConn.CountLoginLinkRequests(req.InstanceID, req.Email, loginLinkRequestWindow)
+	if err != nil {
+		slog.Error("failed to count login link requests", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if count >= LOGIN_LINK_MAX_REQUESTS {
+		slog.Warn("login link request rate limited", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+		auditRecord.Event = userDB.LOGIN_LINK_EVENT_RATE_LIMITED
+		h.saveLoginLinkAuditRecord(req.InstanceID, auditRecord)
+		randomWait(5, 10)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limited"})
+		return
+	}
+
+	auditRecord.Event = userDB.LOGIN_LINK_EVENT_REQUESTED
+
+	user, err := h.userDBConn.GetUserByAccountID(req.InstanceID, req.Email)
+	if err == nil && user.Account.Type == userTypes.ACCOUNT_TYPE_PHONE {
+		// phone accounts sign in and reset the password with SMS codes
+		err = errors.New("phone account")
+	}
+	if err != nil {
+		slog.Warn("login link for non-existing user", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		auditRecord.Reason = "account not found"
+		h.saveLoginLinkAuditRecord(req.InstanceID, auditRecord)
+		randomWait(1, 4)
+		c.JSON(http.StatusOK, gin.H{"message": "login link sent"})
+		return
+	}
+	auditRecord.UserID = user.ID.Hex()
+	h.saveLoginLinkAuditRecord(req.InstanceID, auditRecord)
+
+	// only the latest link is valid
+	if err := h.globalInfosDBConn.DeleteAllTempTokenForUser(req.InstanceID, user.ID.Hex(), userTypes.TOKEN_PURPOSE_LOGIN_LINK); err != nil {
+		slog.Error("failed to delete previous login links", slog.String("error", err.Error()))
+	}
+
+	ttl := h.loginLinkTokenTTL()
+	go h.prepTokenAndSendEmail(
+		user.ID.Hex(),
+		req.InstanceID,
+		user.Account.AccountID,
+		user.Account.PreferredLanguage,
+		userTypes.TOKEN_PURPOSE_LOGIN_LINK,
+		ttl,
+		emailTypes.EMAIL_TYPE_LOGIN_LINK,
+		map[string]string{
+			"validUntil": strconv.Itoa(int(ttl.Minutes())),
+		},
+	)
+
+	slog.Info("login link requested", slog.String("userID", user.ID.Hex()), slog.String("instanceID", req.InstanceID))
+	randomWait(1, 4) // to discourage click-flooding
+	c.JSON(http.StatusOK, gin.H{"message": "login link sent"})
+}
+
+// loginWithLoginLink exchanges the token from the sign-in link for a new session, the token is removed on first use
+func (h *HttpEndpoints) loginWithLoginLink(c *gin.Context) {
+	var req struct {
+		Token      string `json:"token"`
+		InstanceID string `json:"instanceId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Token == "" || req.InstanceID == "" {
+		slog.Error("missing required fields")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	auditRecord := userDB.LoginLinkAuditRecord{
+		Event:     userDB.LOGIN_LINK_EVENT_REJECTED,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	tokenInfos, err := h.validateTempToken(req.Token, []string{userTypes.TOKEN_PURPOSE_LOGIN_LINK})
+	if err != nil {
+		slog.Warn("invalid login link", slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		auditRecord.Reason = err.Error()
+		h.saveLoginLinkAuditRecord(req.InstanceID, auditRecord)
+		randomWait(5, 10)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		return
+	}
+
+	auditRecord.UserID = tokenInfos.UserID
+	auditRecord.AccountID = tokenInfos.Info["email"]
+
+	if req.InstanceID != tokenInfos.InstanceID {
+		slog.Warn("instanceID does not match", slog.String("instanceID", req.InstanceID), slog.String("tokenInfos.InstanceID", tokenInfos.InstanceID))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		return
+	}
+
+	// removing the token fails if the link was used by a concurrent request
+	if _, err := h.globalInfosDBConn.ConsumeTempToken(req.Token); err != nil {
+		slog.Warn("login link already used", slog.String("instanceID", req.InstanceID), slog.String("userID", tokenInfos.UserID), slog.String("error", err.Error()))
+		auditRecord.Reason = "already used"
+		h.saveLoginLinkAuditRecord(req.InstanceID, auditRecord)
+		randomWait(5, 10)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(tokenInfos.InstanceID, tokenInfos.UserID)
+	if err != nil {
+		slog.Warn("user not found", slog.String("subject", tokenInfos.UserID), slog.String("instanceID", tokenInfos.InstanceID), slog.String("error", err.Error()))
+		auditRecord.Reason = "user not found"
+		h.saveLoginLinkAuditRecord(req.InstanceID, auditRecord)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	if user.Account.AccountID != tokenInfos.Info["email"] {
+		// account email changed since the link was sent
+		slog.Warn("login link for outdated email address", slog.String("subject", tokenInfos.UserID), slog.String("instanceID", tokenInfos.InstanceID))
+		auditRecord.Reason = "account email changed"
+		h.saveLoginLinkAuditRecord(req.InstanceID, auditRecord)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	// the link was received by email, so the address is confirmed
+	if user.Account.AccountConfirmedAt == 0 {
+		if err := user.ConfirmContactInfo(userTypes.CONTACT_INFO_TYPE_EMAIL, user.Account.AccountID); err != nil {
+			slog.Error("failed to confirm contact info", slog.String("error", err.Error()))
+		}
+		user.Account.AccountConfirmedAt = time.Now().Unix()
+	}
+
+	lastOTP := map[string]int64{
+		"email": time.Now().Unix(),
+	}
+
+	tokenResp, user, err := h.startSession(tokenInfos.InstanceID, user, lastOTP)
+	if err != nil {
+		slog.Error("failed to start session", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	auditRecord.Event = userDB.LOGIN_LINK_EVENT_USED
+	h.saveLoginLinkAuditRecord(req.InstanceID, auditRecord)
+
+	slog.Info("login with login link successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": tokenResp,
+		"user":  user,
+	})
+}
+
+func (h *HttpEndpoints) saveLoginLinkAuditRecord(instanceID string, record userDB.LoginLinkAuditRecord) {
+	if err := h.userDBConn.AddLoginLinkAuditRecord(instanceID, record); err != nil {
+		slog.Error("failed to save login link audit record", slog.String("instanceID", instanceID), slog.String("event", record.Event), slog.String("error", err.Error()))
+	}
+}
+
+func (h *HttpEndpoints) loginLinkTokenTTL() time.Duration {
+	if h.ttls.LoginLinkToken > 0 {
+		return h.ttls.LoginLinkToken
+	}
+	return DEFAULT_LOGIN_LINK_TOKEN_TTL
+}
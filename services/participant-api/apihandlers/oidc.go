@@ -0,0 +1,473 @@
+package apihandlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	"github.com/case-framework/case-backend/pkg/user-management/oidc"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// time the participant has to sign in at the provider
+const oidcFlowTimeout = 10 * time.Minute
+
+func (h *HttpEndpoints) getOIDCProviders(c *gin.Context) {
+	providers := []gin.H{}
+	for _, p := range h.oidcProviders {
+		providers = append(providers, gin.H{
+			"name":        p.Name(),
+			"displayName": p.DisplayName(),
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i]["name"].(string) < providers[j]["name"].(string)
+	})
+
+	c.JSON(http.StatusOK, gin.H{"providers": providers})
+}
+
+// beginOIDCLogin returns the URL of the provider's sign-in page, the state and PKCE verifier are kept in a temp token
+func (h *HttpEndpoints) beginOIDCLogin(c *gin.Context) {
+	var req struct {
+		InstanceID        string `json:"instanceId"`
+		PreferredLanguage string `json:"preferredLanguage"` // used if a new account is created
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	provider, ok := h.oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	if !umUtils.CheckLanguageCode(req.PreferredLanguage) {
+		slog.Error("invalid preferred language code", slog.String("preferredLanguage", req.PreferredLanguage))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid preferred language code"})
+		return
+	}
+
+	authURL, err := h.createOIDCAuthURL(c, provider, req.InstanceID, "", userTypes.TOKEN_PURPOSE_OIDC_LOGIN, req.PreferredLanguage)
+	if err != nil {
+		slog.Error("failed to start sign-in with provider", slog.String("instanceID", req.InstanceID), slog.String("provider", provider.Name()), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "provider not available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authUrl": authURL})
+}
+
+// loginWithOIDC redeems the authorization code, signs in the owner of the external identity or creates a new account
+func (h *HttpEndpoints) loginWithOIDC(c *gin.Context) {
+	var req struct {
+		InstanceID string `json:"instanceId"`
+		Code       string `json:"code"`
+		State      string `json:"state"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	provider, ok := h.oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	tokenInfos, err := h.consumeOIDCState(req.State, req.InstanceID, "", provider.Name(), userTypes.TOKEN_PURPOSE_OIDC_LOGIN)
+	if err != nil {
+		slog.Warn("invalid oidc state", slog.String("instanceID", req.InstanceID), slog.String("provider", provider.Name()), slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state"})
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), req.Code, tokenInfos.Info["verifier"], tokenInfos.Info["nonce"])
+	if err != nil {
+		slog.Warn("sign-in with provider failed", slog.String("instanceID", req.InstanceID), slog.String("provider", provider.Name()), slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in with provider failed"})
+		return
+	}
+	identity := newExternalIdentity(provider.Name(), claims)
+
+	lastOTP := map[string]int64{
+		string(userTypes.OIDC): time.Now().Unix(),
+	}
+
+	user, err := h.userDBConn.GetUserByExternalIdentity(req.InstanceID, identity.Provider, identity.Subject)
+	if err == nil {
+		updateExternalIdentity(&user, identity)
+
+		tokenResp, user, err := h.startSession(req.InstanceID, user, lastOTP)
+		if err != nil {
+			slog.Error("failed to start session", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+
+		slog.Info("login with provider successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", req.InstanceID), slog.String("provider", provider.Name()))
+		c.JSON(http.StatusOK, gin.H{
+			"token":      tokenResp,
+			"user":       user,
+			"newAccount": false,
+		})
+		return
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		slog.Error("failed to get user", slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	if identity.Email != "" && identity.EmailVerified {
+		// the provider confirmed that the participant owns the address, so the existing account can be revealed.
+		// Accounts are never linked automatically, the owner has to sign in and link the provider.
+		if _, err := h.userDBConn.GetUserByAccountID(req.InstanceID, identity.Email); err == nil {
+			slog.Info("account with email of external identity exists", slog.String("instanceID", req.InstanceID), slog.String("provider", provider.Name()))
+			c.JSON(http.StatusConflict, gin.H{"error": "account exists, sign in and link the provider in the account settings"})
+			return
+		}
+	} else if identity.Email != "" {
+		// not verified by the provider, so the address is dropped if another account uses it.
+		// The existing account is not revealed, the new account is created without email address.
+		_, err := h.userDBConn.GetUserByAccountID(req.InstanceID, identity.Email)
+		if err == nil {
+			slog.Info("unverified email of external identity is used by another account, dropping it", slog.String("instanceID", req.InstanceID), slog.String("provider", provider.Name()))
+			identity.Email = ""
+		} else if !errors.Is(err, mongo.ErrNoDocuments) {
+			slog.Error("failed to get user", slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+
+	// rate limit
+	newUserCount, err := h.userDBConn.CountRecentlyCreatedUsers(req.InstanceID, signupRateLimitWindow)
+	if err != nil {
+		slog.Error("failed to count new users", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if newUserCount >= int64(h.maxNewUsersPer5Minute) {
+		slog.Warn("rate limit for new users reached", slog.String("instanceID", req.InstanceID))
+		randomWait(5, 10)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "try again later"})
+		return
+	}
+
+	newUser := umUtils.InitNewOIDCUser(identity, tokenInfos.Info["lang"])
+	id, err := h.userDBConn.AddUser(req.InstanceID, newUser)
+	if err != nil {
+		slog.Error("failed to create new user", slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	newUser.ID, _ = primitive.ObjectIDFromHex(id)
+
+	if identity.Email != "" && !identity.EmailVerified {
+		// not verified by the provider, the participant has to confirm the address before emails are sent to it
+		go h.prepAndSendEmailVerification(
+			newUser.ID.Hex(),
+			req.InstanceID,
+			identity.Email,
+			newUser.Account.PreferredLanguage,
+			h.ttls.EmailContactVerificationToken,
+			emailTypes.EMAIL_TYPE_REGISTRATION,
+		)
+	}
+
+	tokenResp, newUser, err := h.startSession(req.InstanceID, newUser, lastOTP)
+	if err != nil {
+		slog.Error("failed to start session", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	slog.Info("signup with provider successful", slog.String("subject", newUser.ID.Hex()), slog.String("instanceID", req.InstanceID), slog.String("provider", provider.Name()))
+	c.JSON(http.StatusOK, gin.H{
+		"token":      tokenResp,
+		"user":       newUser,
+		"newAccount": true,
+	})
+}
+
+// beginOIDCLink returns the sign-in URL of the provider to link an external identity to the current account
+func (h *HttpEndpoints) beginOIDCLink(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	provider, ok := h.oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	if !confirmedWithPasswordOrRecentOTP(user, req.Password, token) {
+		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
+		return
+	}
+
+	if _, found := user.Account.FindExternalIdentity(provider.Name()); found {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider already linked"})
+		return
+	}
+
+	authURL, err := h.createOIDCAuthURL(c, provider, token.InstanceID, token.Subject, userTypes.TOKEN_PURPOSE_OIDC_LINK, "")
+	if err != nil {
+		slog.Error("failed to start sign-in with provider", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("provider", provider.Name()), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "provider not available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authUrl": authURL})
+}
+
+// finishOIDCLink redeems the authorization code and links the external identity to the current account
+func (h *HttpEndpoints) finishOIDCLink(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	var req struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	provider, ok := h.oidcProviders[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not found"})
+		return
+	}
+
+	tokenInfos, err := h.consumeOIDCState(req.State, token.InstanceID, token.Subject, provider.Name(), userTypes.TOKEN_PURPOSE_OIDC_LINK)
+	if err != nil {
+		slog.Warn("invalid oidc state", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("provider", provider.Name()), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid state"})
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), req.Code, tokenInfos.Info["verifier"], tokenInfos.Info["nonce"])
+	if err != nil {
+		slog.Warn("sign-in with provider failed", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("provider", provider.Name()), slog.String("error", err.Error()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "sign-in with provider failed"})
+		return
+	}
+	identity := newExternalIdentity(provider.Name(), claims)
+
+	owner, err := h.userDBConn.GetUserByExternalIdentity(token.InstanceID, identity.Provider, identity.Subject)
+	if err == nil {
+		if owner.ID.Hex() == token.Subject {
+			c.JSON(http.StatusOK, gin.H{"message": "provider linked"})
+			return
+		}
+		slog.Warn("external identity already linked to another account", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("provider", provider.Name()))
+		c.JSON(http.StatusConflict, gin.H{"error": "identity is linked to another account"})
+		return
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		slog.Error("failed to get user", slog.String("instanceId", token.InstanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to link provider"})
+		return
+	}
+
+	added, err := h.userDBConn.AddExternalIdentity(token.InstanceID, token.Subject, identity)
+	if err != nil {
+		slog.Error("failed to link external identity", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to link provider"})
+		return
+	}
+	if !added {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider already linked"})
+		return
+	}
+
+	slog.Info("external identity linked", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("provider", provider.Name()))
+	c.JSON(http.StatusOK, gin.H{"message": "provider linked", "identity": identity})
+}
+
+func (h *HttpEndpoints) unlinkOIDCIdentity(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	providerName := c.Param("provider")
+
+	// the password is optional in the body, passwordless accounts confirm with a recent OTP
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	if !confirmedWithPasswordOrRecentOTP(user, req.Password, token) {
+		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
+		return
+	}
+
+	if _, found := user.Account.FindExternalIdentity(providerName); !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not linked"})
+		return
+	}
+
+	// accounts created with a provider have no password, at least one identity must remain to sign in
+	if user.Account.Type == userTypes.ACCOUNT_TYPE_OIDC && len(user.Account.ExternalIdentities) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot remove the last sign-in method"})
+		return
+	}
+
+	removed, err := h.userDBConn.RemoveExternalIdentity(token.InstanceID, token.Subject, providerName)
+	if err != nil {
+		slog.Error("failed to unlink external identity", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlink provider"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "provider not linked"})
+		return
+	}
+
+	slog.Info("external identity unlinked", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("provider", providerName))
+	c.JSON(http.StatusOK, gin.H{"message": "provider unlinked"})
+}
+
+func (h *HttpEndpoints) createOIDCAuthURL(c *gin.Context, provider *oidc.Provider, instanceID string, userID string, purpose string, lang string) (string, error) {
+	nonce, err := umUtils.GenerateUniqueTokenString()
+	if err != nil {
+		return "", err
+	}
+	verifier := oidc.GenerateVerifier()
+
+	state, err := h.globalInfosDBConn.AddTempToken(userTypes.TempToken{
+		UserID:     userID,
+		InstanceID: instanceID,
+		Purpose:    purpose,
+		Info: map[string]string{
+			"provider": provider.Name(),
+			"nonce":    nonce,
+			"verifier": verifier,
+			"lang":     lang,
+		},
+		Expiration: time.Now().Add(oidcFlowTimeout),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return provider.AuthCodeURL(c.Request.Context(), state, nonce, verifier)
+}
+
+// consumeOIDCState removes the temp token of the sign-in flow, so that the authorization response can be used only once
+func (h *HttpEndpoints) consumeOIDCState(state string, instanceID string, userID string, providerName string, purpose string) (userTypes.TempToken, error) {
+	if state == "" {
+		return userTypes.TempToken{}, errors.New("missing state")
+	}
+
+	tokenInfos, err := h.globalInfosDBConn.ConsumeTempToken(state)
+	if err != nil {
+		return tokenInfos, err
+	}
+	if tokenInfos.Purpose != purpose {
+		return tokenInfos, errors.New("wrong token purpose: " + tokenInfos.Purpose)
+	}
+	if tokenInfos.Expiration.Before(time.Now()) {
+		return tokenInfos, errors.New("token expired")
+	}
+	if tokenInfos.InstanceID != instanceID || tokenInfos.UserID != userID || tokenInfos.Info["provider"] != providerName {
+		return tokenInfos, errors.New("state was issued for a different sign-in")
+	}
+	return tokenInfos, nil
+}
+
+// newExternalIdentity from the claims of the ID token, the email address is only kept in a valid format
+func newExternalIdentity(providerName string, claims oidc.Claims) userTypes.ExternalIdentity {
+	now := time.Now().Unix()
+	identity := userTypes.ExternalIdentity{
+		Provider:   providerName,
+		Subject:    claims.Subject,
+		LinkedAt:   now,
+		LastUsedAt: now,
+	}
+
+	email := umUtils.SanitizeEmail(claims.Email)
+	if email != "" && umUtils.CheckEmailFormat(email) {
+		identity.Email = email
+		identity.EmailVerified = claims.EmailVerified
+	}
+	return identity
+}
+
+// updateExternalIdentity stores the latest claims with the linked identity, an address of the account is confirmed if the provider verified it
+func updateExternalIdentity(user *userTypes.User, identity userTypes.ExternalIdentity) {
+	for i, linked := range user.Account.ExternalIdentities {
+		if linked.Provider != identity.Provider {
+			continue
+		}
+		user.Account.ExternalIdentities[i].Email = identity.Email
+		user.Account.ExternalIdentities[i].EmailVerified = identity.EmailVerified
+		user.Account.ExternalIdentities[i].LastUsedAt = identity.LastUsedAt
+	}
+
+	if user.Account.Type != userTypes.ACCOUNT_TYPE_OIDC || identity.Email == "" || !identity.EmailVerified {
+		return
+	}
+	if ci, found := user.FindContactInfoByTypeAndAddr(userTypes.CONTACT_INFO_TYPE_EMAIL, identity.Email); found && ci.ConfirmedAt < 1 {
+		if err := user.ConfirmContactInfo(userTypes.CONTACT_INFO_TYPE_EMAIL, identity.Email); err != nil {
+			slog.Warn("failed to confirm email of external identity", slog.String("userID", user.ID.Hex()), slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package apihandlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+	"github.com/case-framework/case-backend/pkg/user-management/webauthn"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxPasskeysPerUser   = 10
+	maxPasskeyNameLength = 64
+	DEFAULT_PASSKEY_NAME = "Passkey"
+)
+
+var errPasskeyNotValid = errors.New("passkey not valid")
+
+func (h *HttpEndpoints) getPasskeys(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	credentials, err := h.userDBConn.GetWebAuthnCredentialsForUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to get passkeys", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get passkeys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"available": h.webAuthn.Enabled(),
+		"passkeys":  credentials,
+	})
+}
+
+// beginPasskeyRegistration returns the options for navigator.credentials.create()
+func (h *HttpEndpoints) beginPasskeyRegistration(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if !h.webAuthn.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkeys not configured"})
+		return
+	}
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	if !confirmedWithPasswordOrRecentOTP(user, req.Password, token) {
+		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
+		return
+	}
+
+	credentials, err := h.userDBConn.GetWebAuthnCredentialsForUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to get passkeys", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get passkeys"})
+		return
+	}
+	if len(credentials) >= maxPasskeysPerUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many passkeys"})
+		return
+	}
+
+	challenge, err := h.createPasskeyChallenge(token.InstanceID, token.Subject, userTypes.TOKEN_PURPOSE_WEBAUTHN_REGISTRATION)
+	if err != nil {
+		slog.Error("failed to create challenge", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start registration"})
+		return
+	}
+
+	// the same authenticator cannot be registered twice
+	exclude := credentialDescriptors(credentials)
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": h.webAuthn.NewCreationOptions(challenge, user.ID[:], user.Account.AccountID, exclude),
+	})
+}
+
+// finishPasskeyRegistration verifies the response of navigator.credentials.create() and stores the passkey
+func (h *HttpEndpoints) finishPasskeyRegistration(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if !h.webAuthn.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkeys not configured"})
+		return
+	}
+
+	var req struct {
+		Credential webauthn.RegistrationResponse `json:"credential"`
+		Name       string                        `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	challenge, err := h.consumePasskeyChallenge(req.Credential.Response.ClientDataJSON, token.InstanceID, token.Subject, userTypes.TOKEN_PURPOSE_WEBAUTHN_REGISTRATION)
+	if err != nil {
+		slog.Warn("invalid passkey registration challenge", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid challenge"})
+		return
+	}
+
+	credential, err := h.webAuthn.VerifyRegistration(req.Credential, challenge)
+	if err != nil {
+		slog.Warn("passkey registration failed", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passkey registration failed"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = DEFAULT_PASSKEY_NAME
+	}
+	if len(name) > maxPasskeyNameLength {
+		name = name[:maxPasskeyNameLength]
+	}
+
+	passkey := userTypes.WebAuthnCredential{
+		UserID:         token.Subject,
+		CredentialID:   base64.RawURLEncoding.EncodeToString(credential.ID),
+		PublicKey:      credential.PublicKey,
+		SignCount:      credential.SignCount,
+		AAGUID:         formatAAGUID(credential.AAGUID),
+		Transports:     credential.Transports,
+		Name:           name,
+		BackupEligible: credential.BackupEligible,
+		BackedUp:       credential.BackedUp,
+		CreatedAt:      time.Now(),
+	}
+	id, err := h.userDBConn.AddWebAuthnCredential(token.InstanceID, passkey)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "passkey already registered"})
+			return
+		}
+		slog.Error("failed to save passkey", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save passkey"})
+		return
+	}
+
+	slog.Info("passkey registered", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("id", id))
+	c.JSON(http.StatusOK, gin.H{"passkey": passkey})
+}
+
+func (h *HttpEndpoints) deletePasskey(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	credentialID := c.Param("credentialID")
+	if credentialID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing credential id"})
+		return
+	}
+
+	// the password is optional in the body, passwordless accounts confirm with a recent OTP
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	if !confirmedWithPasswordOrRecentOTP(user, req.Password, token) {
+		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
+		return
+	}
+
+	if err := h.userDBConn.DeleteWebAuthnCredential(token.InstanceID, token.Subject, credentialID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "passkey not found"})
+			return
+		}
+		slog.Error("failed to delete passkey", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete passkey"})
+		return
+	}
+
+	slog.Info("passkey removed", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("credentialId", credentialID))
+	c.JSON(http.StatusOK, gin.H{"message": "passkey removed"})
+}
+
+// beginPasskeyLogin returns the options for navigator.credentials.get(), any passkey of the instance can be used
+func (h *HttpEndpoints) beginPasskeyLogin(c *gin.Context) {
+	var req struct {
+		InstanceID string `json:"instanceId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.webAuthn.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkeys not configured"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	challenge, err := h.createPasskeyChallenge(req.InstanceID, "", userTypes.TOKEN_PURPOSE_WEBAUTHN_LOGIN)
+	if err != nil {
+		slog.Error("failed to create challenge", slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": h.webAuthn.NewRequestOptions(challenge, nil),
+	})
+}
+
+// loginWithPasskey verifies the response of navigator.credentials.get() and starts a new session
+func (h *HttpEndpoints) loginWithPasskey(c *gin.Context) {
+	var req struct {
+		InstanceID string                     `json:"instanceId"`
+		Credential webauthn.AssertionResponse `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.webAuthn.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkeys not configured"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	challenge, err := h.consumePasskeyChallenge(req.Credential.Response.ClientDataJSON, req.InstanceID, "", userTypes.TOKEN_PURPOSE_WEBAUTHN_LOGIN)
+	if err != nil {
+		slog.Warn("invalid passkey login challenge", slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid challenge"})
+		return
+	}
+
+	passkey, err := h.verifyPasskeyAssertion(req.InstanceID, "", req.Credential, challenge)
+	if err != nil {
+		slog.Warn("passkey login failed", slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credential"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(req.InstanceID, passkey.UserID)
+	if err != nil {
+		slog.Warn("user not found", slog.String("subject", passkey.UserID), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credential"})
+		return
+	}
+
+	lastOTP := map[string]int64{
+		string(userTypes.Passkey): time.Now().Unix(),
+	}
+
+	tokenResp, user, err := h.startSession(req.InstanceID, user, lastOTP)
+	if err != nil {
+		slog.Error("failed to start session", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	slog.Info("login with passkey successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", req.InstanceID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": tokenResp,
+		"user":  user,
+	})
+}
+
+// beginPasskeyOTP returns the options to confirm the current session with one of the user's passkeys
+func (h *HttpEndpoints) beginPasskeyOTP(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if !h.webAuthn.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkeys not configured"})
+		return
+	}
+
+	credentials, err := h.userDBConn.GetWebAuthnCredentialsForUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to get passkeys", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get passkeys"})
+		return
+	}
+	if len(credentials) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no passkey registered"})
+		return
+	}
+
+	challenge, err := h.createPasskeyChallenge(token.InstanceID, token.Subject, userTypes.TOKEN_PURPOSE_WEBAUTHN_LOGIN)
+	if err != nil {
+		slog.Error("failed to create challenge", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"publicKey": h.webAuthn.NewRequestOptions(challenge, credentialDescriptors(credentials)),
+	})
+}
+
+// verifyPasskeyOTP accepts a passkey assertion as second factor, the renewed token contains the passkey in LastOTPProvided
+func (h *HttpEndpoints) verifyPasskeyOTP(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	var req struct {
+		Credential webauthn.AssertionResponse `json:"credential"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.webAuthn.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "passkeys not configured"})
+		return
+	}
+
+	if h.hasTooManyFailedOtpAttempts(token.InstanceID, token.Subject) {
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "too many failed otp attempts"})
+		return
+	}
+
+	challenge, err := h.consumePasskeyChallenge(req.Credential.Response.ClientDataJSON, token.InstanceID, token.Subject, userTypes.TOKEN_PURPOSE_WEBAUTHN_LOGIN)
+	if err == nil {
+		_, err = h.verifyPasskeyAssertion(token.InstanceID, token.Subject, req.Credential, challenge)
+	}
+	if err != nil {
+		slog.Warn("failed to verify passkey", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		h.saveFailedOtpAttempt(token.InstanceID, token.Subject)
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credential"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Warn("user not found", slog.String("subject", token.Subject), slog.String("instanceID", token.InstanceID), slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	if token.LastOTPProvided == nil {
+		token.LastOTPProvided = make(map[string]int64)
+	}
+	token.LastOTPProvided[string(userTypes.Passkey)] = time.Now().Unix()
+
+	tokenResp, err := h.renewSessionToken(token, user)
+	if err != nil {
+		slog.Error("failed to renew token", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	user.Account.Password = ""
+	c.JSON(http.StatusOK, gin.H{
+		"token": tokenResp,
+		"user":  user,
+	})
+}
+
+// createPasskeyChallenge stores a temp token for the ceremony, the token string itself is sent as challenge
+func (h *HttpEndpoints) createPasskeyChallenge(instanceID string, userID string, purpose string) ([]byte, error) {
+	tempToken, err := h.globalInfosDBConn.AddTempToken(userTypes.TempToken{
+		UserID:     userID,
+		InstanceID: instanceID,
+		Purpose:    purpose,
+		Expiration: time.Now().Add(h.webAuthn.CeremonyTimeout()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(tempToken), nil
+}
+
+// consumePasskeyChallenge looks up the ceremony by the challenge the browser signed and removes it, so that it can be used only once
+func (h *HttpEndpoints) consumePasskeyChallenge(clientDataJSON []byte, instanceID string, userID string, purpose string) ([]byte, error) {
+	challenge, err := webauthn.ChallengeFromClientData(clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenInfos, err := h.validateTempToken(string(challenge), []string{purpose})
+	if err != nil {
+		return nil, err
+	}
+	if tokenInfos.InstanceID != instanceID || tokenInfos.UserID != userID {
+		return nil, errors.New("challenge was issued for a different user")
+	}
+
+	if _, err := h.globalInfosDBConn.ConsumeTempToken(tokenInfos.Token); err != nil {
+		return nil, fmt.Errorf("challenge already used: %w", err)
+	}
+	return challenge, nil
+}
+
+// verifyPasskeyAssertion checks the assertion against the stored passkey and updates its sign counter.
+// If userID is not empty, the passkey must belong to that user.
+func (h *HttpEndpoints) verifyPasskeyAssertion(instanceID string, userID string, resp webauthn.AssertionResponse, challenge []byte) (userTypes.WebAuthnCredential, error) {
+	credentialID := base64.RawURLEncoding.EncodeToString(resp.RawID)
+	passkey, err := h.userDBConn.GetWebAuthnCredentialByCredentialID(instanceID, credentialID)
+	if err != nil {
+		return passkey, fmt.Errorf("%w: %s", errPasskeyNotValid, err.Error())
+	}
+	if userID != "" && passkey.UserID != userID {
+		return passkey, fmt.Errorf("%w: belongs to a different user", errPasskeyNotValid)
+	}
+
+	// discoverable credentials return the user handle, which is the user ID
+	if len(resp.Response.UserHandle) > 0 && fmt.Sprintf("%x", []byte(resp.Response.UserHandle)) != passkey.UserID {
+		return passkey, fmt.Errorf("%w: user handle does not match", errPasskeyNotValid)
+	}
+
+	authData, err := h.webAuthn.VerifyAssertion(resp, challenge, passkey.PublicKey, passkey.SignCount)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrSignCountNotValid) {
+			slog.Warn("passkey sign counter did not increase", slog.String("instanceID", instanceID), slog.String("userID", passkey.UserID), slog.String("credentialID", credentialID))
+		}
+		return passkey, err
+	}
+
+	if err := h.userDBConn.UpdateWebAuthnCredentialUsage(instanceID, credentialID, passkey.SignCount, authData.SignCount, authData.BackedUp()); err != nil {
+		return passkey, err
+	}
+	return passkey, nil
+}
+
+func credentialDescriptors(credentials []userTypes.WebAuthnCredential) []webauthn.CredentialDescriptor {
+	descriptors := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, cred := range credentials {
+		id, err := webauthn.DecodeBase64URL(cred.CredentialID)
+		if err != nil {
+			continue
+		}
+		descriptors = append(descriptors, webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         id,
+			Transports: cred.Transports,
+		})
+	}
+	return descriptors
+}
+
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", aaguid[0:4], aaguid[4:6], aaguid[6:8], aaguid[8:10], aaguid[10:])
+}
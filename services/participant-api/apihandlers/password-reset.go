@@ -1,11 +1,13 @@
 package apihandlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
 	mw "github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
+	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
 	"github.com/case-framework/case-backend/pkg/user-management/pwhash"
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
@@ -28,6 +30,10 @@ func (h *HttpEndpoints) AddPasswordResetAPI(rg *gin.RouterGroup) {
 		pwResetGroup.POST("/initiate", mw.RequirePayload(), h.initiatePasswordReset)
 		pwResetGroup.POST("/get-infos", mw.RequirePayload(), h.getPasswordResetInfos)
 		pwResetGroup.POST("/reset", mw.RequirePayload(), h.resetPassword)
+
+		// phone accounts receive an SMS code instead of a reset link
+		pwResetGroup.POST("/sms/initiate", mw.RequirePayload(), h.initiatePasswordResetBySMS)
+		pwResetGroup.POST("/sms/reset", mw.RequirePayload(), h.resetPasswordWithSMSCode)
 	}
 }
 
@@ -50,6 +56,10 @@ func (h *HttpEndpoints) initiatePasswordReset(c *gin.Context) {
 	req.Email = umUtils.SanitizeEmail(req.Email)
 
 	user, err := h.userDBConn.GetUserByAccountID(req.InstanceID, req.Email)
+	if err == nil && user.Account.Type == userTypes.ACCOUNT_TYPE_PHONE {
+		// phone accounts sign in and reset the password with SMS codes
+		err = errors.New("phone account")
+	}
 	if err != nil {
 		slog.Warn("password reset for non-existing user", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
 		randomWait(5, 10)
@@ -64,6 +74,14 @@ func (h *HttpEndpoints) initiatePasswordReset(c *gin.Context) {
 		return
 	}
 
+	if user.Account.Passwordless {
+		// same response as for other accounts, participant should use a login link instead
+		slog.Warn("password reset for passwordless account", slog.String("email", req.Email), slog.String("instanceID", req.InstanceID))
+		randomWait(1, 4)
+		c.JSON(http.StatusOK, gin.H{"message": "password reset initiated"})
+		return
+	}
+
 	go h.prepTokenAndSendEmail(
 		user.ID.Hex(),
 		req.InstanceID,
@@ -176,6 +194,12 @@ func (h *HttpEndpoints) resetPassword(c *gin.Context) {
 		return
 	}
 
+	if user.Account.Passwordless && tokenInfos.Purpose == userTypes.TOKEN_PURPOSE_PASSWORD_RESET {
+		slog.Warn("password reset for passwordless account", slog.String("userID", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password login disabled for this account"})
+		return
+	}
+
 	password, err := pwhash.HashPassword(req.NewPassword)
 	if err != nil {
 		slog.Error("failed to hash password", slog.String("error", err.Error()))
@@ -202,16 +226,7 @@ func (h *HttpEndpoints) resetPassword(c *gin.Context) {
 		}
 	}
 
-	go h.sendSimpleEmail(
-		tokenInfos.InstanceID,
-		[]string{user.Account.AccountID},
-		user.ID.Hex(),
-		emailTypes.EMAIL_TYPE_PASSWORD_CHANGED,
-		"",
-		user.Account.PreferredLanguage,
-		nil,
-		true,
-	)
+	go h.sendPasswordChangedNotice(tokenInfos.InstanceID, user)
 
 	slog.Info("password reset successful", slog.String("userID", user.ID.Hex()), slog.String("instanceID", tokenInfos.InstanceID))
 
@@ -221,3 +236,142 @@ func (h *HttpEndpoints) resetPassword(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "password reset successful"})
 }
+
+func (h *HttpEndpoints) initiatePasswordResetBySMS(c *gin.Context) {
+	var req struct {
+		PhoneNumber string `json:"phoneNumber"`
+		InstanceID  string `json:"instanceID"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("bad request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	req.PhoneNumber = umUtils.NormalizePhoneNumber(req.PhoneNumber)
+
+	user, err := h.getPhoneAccount(req.InstanceID, req.PhoneNumber)
+	if err != nil {
+		slog.Warn("password reset for non-existing phone account", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		randomWait(1, 4)
+		c.JSON(http.StatusOK, gin.H{"message": "password reset initiated"})
+		return
+	}
+
+	if umUtils.HasMoreAttemptsRecently(user.Account.PasswordResetTriggers, PASSWWORD_RESET_MAX_ATTEMPTS, passwordResetAttemptWindow) {
+		slog.Warn("password reset rate limited", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID))
+		randomWait(5, 10)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limited"})
+		return
+	}
+
+	if user.Account.Passwordless {
+		// same response as for other accounts, participant signs in with SMS codes only
+		slog.Warn("password reset for passwordless account", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID))
+		randomWait(1, 4)
+		c.JSON(http.StatusOK, gin.H{"message": "password reset initiated"})
+		return
+	}
+
+	go func(instanceID string, userID string) {
+		if err := usermanagement.SendPasswordResetCodeBySMS(instanceID, userID); err != nil {
+			slog.Error("failed to send password reset code by SMS", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("error", err.Error()))
+		}
+	}(req.InstanceID, user.ID.Hex())
+
+	if err := h.userDBConn.SavePasswordResetTrigger(
+		req.InstanceID,
+		user.ID.Hex(),
+	); err != nil {
+		slog.Error("failed to save password reset trigger", slog.String("error", err.Error()))
+	}
+
+	slog.Info("password reset by SMS initiated", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID))
+	randomWait(1, 4) // to discourage click-flooding
+	c.JSON(http.StatusOK, gin.H{"message": "password reset initiated"})
+}
+
+func (h *HttpEndpoints) resetPasswordWithSMSCode(c *gin.Context) {
+	var req struct {
+		PhoneNumber string `json:"phoneNumber"`
+		InstanceID  string `json:"instanceID"`
+		Code        string `json:"code"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("missing or invalid request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Code == "" {
+		randomWait(5, 10)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	if !umUtils.CheckPasswordFormat(req.NewPassword) {
+		slog.Error("invalid password format")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password format"})
+		return
+	}
+
+	if umUtils.IsPasswordOnBlocklist(req.NewPassword) {
+		slog.Error("password on blocklist")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password on blocklist"})
+		return
+	}
+
+	req.PhoneNumber = umUtils.NormalizePhoneNumber(req.PhoneNumber)
+
+	user, err := h.getPhoneAccount(req.InstanceID, req.PhoneNumber)
+	if err != nil {
+		slog.Warn("password reset for non-existing phone account", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	if user.Account.Passwordless {
+		slog.Warn("password reset for passwordless account", slog.String("userID", user.ID.Hex()), slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password login disabled for this account"})
+		return
+	}
+
+	if !h.verifySMSCode(req.InstanceID, user.ID.Hex(), req.Code) {
+		randomWait(5, 10)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid code"})
+		return
+	}
+
+	password, err := pwhash.HashPassword(req.NewPassword)
+	if err != nil {
+		slog.Error("failed to hash password", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"account.password": password, "timestamps.lastPasswordChange": time.Now().Unix()}}
+	err = h.userDBConn.UpdateUser(req.InstanceID, user.ID.Hex(), update)
+	if err != nil {
+		slog.Error("failed to update user", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	go h.sendPasswordChangedNotice(req.InstanceID, user)
+
+	slog.Info("password reset by SMS successful", slog.String("userID", user.ID.Hex()), slog.String("instanceID", req.InstanceID))
+	c.JSON(http.StatusOK, gin.H{"message": "password reset successful"})
+}
@@ -0,0 +1,315 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
+	"github.com/case-framework/case-backend/pkg/user-management/pwhash"
+	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
+)
+
+type SignupWithPhoneReq struct {
+	PhoneNumber       string `json:"phoneNumber"`
+	Password          string `json:"password"` // optional, without password sign-in is only possible with SMS codes
+	InstanceID        string `json:"instanceId"`
+	InfoCheck         string `json:"infoCheck"`
+	PreferredLanguage string `json:"preferredLanguage"`
+}
+
+// signupWithPhone creates a phone account and sends the first SMS code, the code confirms the number at the first login
+func (h *HttpEndpoints) signupWithPhone(c *gin.Context) {
+	var req SignupWithPhoneReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.PhoneNumber == "" || req.InstanceID == "" {
+		slog.Error("missing required fields")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
+		return
+	}
+
+	if req.InfoCheck != "" {
+		slog.Warn("honeypot field filled out", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID), slog.String("infoCheck", req.InfoCheck))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	req.PhoneNumber = umUtils.NormalizePhoneNumber(req.PhoneNumber)
+
+	if !umUtils.CheckPhoneNumberFormat(req.PhoneNumber) {
+		slog.Error("invalid phone number format", slog.String("phoneNumber", req.PhoneNumber))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid phone number format"})
+		return
+	}
+
+	if req.Password != "" {
+		if !umUtils.CheckPasswordFormat(req.Password) {
+			slog.Error("invalid password format")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password format"})
+			return
+		}
+
+		if umUtils.IsPasswordOnBlocklist(req.Password) {
+			slog.Error("password on blocklist")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "password on blocklist"})
+			return
+		}
+	}
+
+	if !umUtils.CheckLanguageCode(req.PreferredLanguage) {
+		slog.Error("invalid preferred language code", slog.String("preferredLanguage", req.PreferredLanguage))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid preferred language code"})
+		return
+	}
+
+	existingUser, err := h.userDBConn.GetUserByAccountID(req.InstanceID, req.PhoneNumber)
+	if err == nil {
+		// same response as for a new account, the existing one just receives a code
+		slog.Warn("signup with existing phone number", slog.String("instanceID", req.InstanceID), slog.String("userID", existingUser.ID.Hex()))
+		go h.sendPhoneLoginCode(req.InstanceID, existingUser.ID.Hex())
+		randomWait(1, 4)
+		c.JSON(http.StatusOK, gin.H{"message": "code sent"})
+		return
+	}
+
+	// rate limit
+	newUserCount, err := h.userDBConn.CountRecentlyCreatedUsers(req.InstanceID, signupRateLimitWindow)
+	if err != nil {
+		slog.Error("failed to count new users", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	if newUserCount >= int64(h.maxNewUsersPer5Minute) {
+		slog.Warn("rate limit for new users reached", slog.String("instanceID", req.InstanceID))
+		randomWait(5, 10)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "try again later"})
+		return
+	}
+
+	// hash password
+	password := ""
+	if req.Password != "" {
+		password, err = pwhash.HashPassword(req.Password)
+		if err != nil {
+			slog.Error("failed to hash password", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+	}
+
+	// create user
+	newUser := umUtils.InitNewPhoneUser(req.PhoneNumber, password, req.PreferredLanguage)
+	id, err := h.userDBConn.AddUser(req.InstanceID, newUser)
+	if err != nil {
+		slog.Error("failed to create new user", slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+	newUser.ID, _ = primitive.ObjectIDFromHex(id)
+
+	go h.sendPhoneLoginCode(req.InstanceID, newUser.ID.Hex())
+
+	slog.Info("signup with phone number successful", slog.String("userID", newUser.ID.Hex()), slog.String("instanceID", req.InstanceID))
+	c.JSON(http.StatusOK, gin.H{"message": "code sent"})
+}
+
+// requestPhoneLoginCode sends an SMS code to a phone account, the response does not reveal if the account exists
+func (h *HttpEndpoints) requestPhoneLoginCode(c *gin.Context) {
+	var req struct {
+		PhoneNumber string `json:"phoneNumber"`
+		InstanceID  string `json:"instanceId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.PhoneNumber == "" || req.InstanceID == "" {
+		slog.Error("missing required fields")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	req.PhoneNumber = umUtils.NormalizePhoneNumber(req.PhoneNumber)
+
+	user, err := h.getPhoneAccount(req.InstanceID, req.PhoneNumber)
+	if err != nil {
+		slog.Warn("login code for non-existing phone account", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		randomWait(1, 4)
+		c.JSON(http.StatusOK, gin.H{"message": "code sent"})
+		return
+	}
+
+	go h.sendPhoneLoginCode(req.InstanceID, user.ID.Hex())
+
+	randomWait(1, 4)
+	c.JSON(http.StatusOK, gin.H{"message": "code sent"})
+}
+
+type LoginWithPhoneReq struct {
+	PhoneNumber string `json:"phoneNumber"`
+	Code        string `json:"code"`
+	Password    string `json:"password"` // required if the account has a password
+	InstanceID  string `json:"instanceId"`
+}
+
+// loginWithPhone starts a session with the SMS code, and the password if the account has one
+func (h *HttpEndpoints) loginWithPhone(c *gin.Context) {
+	var req LoginWithPhoneReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.PhoneNumber == "" || req.Code == "" || req.InstanceID == "" {
+		slog.Error("missing required fields")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing required fields"})
+		return
+	}
+
+	if !h.isInstanceAllowed(req.InstanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", req.InstanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid instance id"})
+		return
+	}
+
+	req.PhoneNumber = umUtils.NormalizePhoneNumber(req.PhoneNumber)
+
+	user, err := h.getPhoneAccount(req.InstanceID, req.PhoneNumber)
+	if err != nil {
+		slog.Warn("login attempt with unknown phone number", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid phone number, code or password"})
+		return
+	}
+	userID := user.ID.Hex()
+
+	if umUtils.HasMoreAttemptsRecently(user.Account.FailedLoginAttempts, allowedPasswordAttempts, loginFailedAttemptWindow) {
+		slog.Warn("login attempt with too many failed attempts", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID))
+		if err := h.userDBConn.SaveFailedLoginAttempt(req.InstanceID, userID); err != nil {
+			slog.Error("failed to save failed login attempt", slog.String("error", err.Error()))
+		}
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid phone number, code or password"})
+		return
+	}
+
+	if !user.Account.Passwordless {
+		match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.Password)
+		if err != nil || !match {
+			if err == nil {
+				err = errors.New("passwords do not match")
+			}
+			slog.Warn("login attempt with wrong password", slog.String("phoneNumber", req.PhoneNumber), slog.String("instanceID", req.InstanceID), slog.String("error", err.Error()))
+			if err := h.userDBConn.SaveFailedLoginAttempt(req.InstanceID, userID); err != nil {
+				slog.Error("failed to save failed login attempt", slog.String("error", err.Error()))
+			}
+			randomWait(5, 10)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid phone number, code or password"})
+			return
+		}
+	}
+
+	if !h.verifySMSCode(req.InstanceID, userID, req.Code) {
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid phone number, code or password"})
+		return
+	}
+
+	// the code confirms the phone number and with it the account
+	if user.Account.AccountConfirmedAt <= 0 {
+		user.Account.AccountConfirmedAt = time.Now().Unix()
+	}
+	if err := user.ConfirmContactInfo(userTypes.CONTACT_INFO_TYPE_PHONE, user.Account.AccountID); err != nil {
+		slog.Error("failed to confirm phone number", slog.String("error", err.Error()))
+	}
+
+	lastOTP := map[string]int64{
+		string(userTypes.SMSOTP): time.Now().Unix(),
+	}
+
+	tokenResp, user, err := h.startSession(req.InstanceID, user, lastOTP)
+	if err != nil {
+		slog.Error("failed to start session", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	slog.Info("login with phone number successful", slog.String("subject", user.ID.Hex()), slog.String("instanceID", req.InstanceID))
+
+	c.JSON(http.StatusOK, gin.H{
+		"token": tokenResp,
+		"user":  user,
+	})
+}
+
+// getPhoneAccount finds the account with the phone number as account ID
+func (h *HttpEndpoints) getPhoneAccount(instanceID string, phoneNumber string) (userTypes.User, error) {
+	if !umUtils.CheckPhoneNumberFormat(phoneNumber) {
+		return userTypes.User{}, errors.New("invalid phone number format")
+	}
+	user, err := h.userDBConn.GetUserByAccountID(instanceID, phoneNumber)
+	if err != nil {
+		return user, err
+	}
+	if user.Account.Type != userTypes.ACCOUNT_TYPE_PHONE {
+		return user, errors.New("not a phone account")
+	}
+	return user, nil
+}
+
+func (h *HttpEndpoints) sendPhoneLoginCode(instanceID string, userID string) {
+	if err := usermanagement.SendOTPBySMS(instanceID, userID); err != nil {
+		slog.Error("failed to send login code by SMS", slog.String("instanceID", instanceID), slog.String("userID", userID), slog.String("error", err.Error()))
+	}
+}
+
+// verifySMSCode checks and consumes an SMS code, failed attempts count towards the OTP limit of the account
+func (h *HttpEndpoints) verifySMSCode(instanceID string, userID string, code string) bool {
+	if h.hasTooManyFailedOtpAttempts(instanceID, userID) {
+		if err := h.userDBConn.DeleteOTPs(instanceID, userID); err != nil {
+			slog.Error("failed to delete otps", slog.String("error", err.Error()))
+		}
+		return false
+	}
+
+	// codes are formatted as "123-456" in the SMS
+	code = strings.ReplaceAll(strings.TrimSpace(code), "-", "")
+	otp, err := usermanagement.VerifyOTP(instanceID, userID, code)
+	if err == nil && otp.Type != userTypes.SMSOTP {
+		err = errors.New("not an SMS code")
+	}
+	if err != nil {
+		slog.Warn("failed to verify SMS code", slog.String("error", err.Error()), slog.String("instanceID", instanceID), slog.String("userID", userID))
+		h.saveFailedOtpAttempt(instanceID, userID)
+		return false
+	}
+	return true
+}
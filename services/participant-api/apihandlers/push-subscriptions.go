@@ -0,0 +1,118 @@
+package apihandlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	webpush "github.com/case-framework/case-backend/pkg/messaging/web-push"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const MAX_PUSH_SUBSCRIPTIONS_PER_USER = 10
+
+// getPushPublicKey returns the application server key the browser needs for PushManager.subscribe()
+func (h *HttpEndpoints) getPushPublicKey(c *gin.Context) {
+	if h.webPush.VAPIDPublicKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "web push not configured"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publicKey": h.webPush.VAPIDPublicKey})
+}
+
+func (h *HttpEndpoints) getPushSubscriptions(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	subs, err := h.messagingDBConn.GetPushSubscriptionsForUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to get push subscriptions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get push subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subs})
+}
+
+// addPushSubscription registers the push subscription of the current device, expects the JSON of PushSubscription.toJSON()
+func (h *HttpEndpoints) addPushSubscription(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if h.webPush.VAPIDPublicKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "web push not configured"})
+		return
+	}
+
+	var req struct {
+		Endpoint string                              `json:"endpoint"`
+		Keys     messagingTypes.PushSubscriptionKeys `json:"keys"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		return
+	}
+
+	if err := webpush.ValidateSubscription(webpush.Subscription{
+		Endpoint: req.Endpoint,
+		P256dh:   req.Keys.P256dh,
+		Auth:     req.Keys.Auth,
+	}, h.webPush.AllowInsecureEndpoints); err != nil {
+		slog.Warn("invalid push subscription", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	existing, err := h.messagingDBConn.GetPushSubscriptionsForUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to get push subscriptions", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save push subscription"})
+		return
+	}
+	isUpdate := false
+	for _, s := range existing {
+		if s.Endpoint == req.Endpoint {
+			isUpdate = true
+			break
+		}
+	}
+	if !isUpdate && len(existing) >= MAX_PUSH_SUBSCRIPTIONS_PER_USER {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many push subscriptions"})
+		return
+	}
+
+	sub, err := h.messagingDBConn.SavePushSubscription(token.InstanceID, messagingTypes.PushSubscription{
+		UserID:    token.Subject,
+		Endpoint:  req.Endpoint,
+		Keys:      req.Keys,
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		slog.Error("failed to save push subscription", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save push subscription"})
+		return
+	}
+
+	slog.Info("push subscription saved", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("subscriptionID", sub.ID.Hex()))
+	c.JSON(http.StatusOK, gin.H{"subscription": sub})
+}
+
+func (h *HttpEndpoints) deletePushSubscription(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+	subscriptionID := c.Param("subscriptionID")
+
+	err := h.messagingDBConn.DeletePushSubscription(token.InstanceID, token.Subject, subscriptionID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+			return
+		}
+		slog.Error("failed to delete push subscription", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("subscriptionID", subscriptionID), slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to delete push subscription"})
+		return
+	}
+
+	slog.Info("push subscription deleted", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("subscriptionID", subscriptionID))
+	c.JSON(http.StatusOK, gin.H{"message": "subscription deleted"})
+}
@@ -1,6 +1,7 @@
 package apihandlers
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -20,6 +21,7 @@ import (
 	studyService "github.com/case-framework/case-backend/pkg/study"
 	surveydefinition "github.com/case-framework/case-backend/pkg/study/exporter/survey-definition"
 	surveyresponses "github.com/case-framework/case-backend/pkg/study/exporter/survey-responses"
+	"github.com/case-framework/case-backend/pkg/study/surveyengine"
 	studyTypes "github.com/case-framework/case-backend/pkg/study/types"
 	studyutils "github.com/case-framework/case-backend/pkg/study/utils"
 )
@@ -42,6 +44,8 @@ func (h *HttpEndpoints) AddStudyServiceAPI(rg *gin.RouterGroup) {
 
 		studiesGroup.GET("/:studyKey/variables", h.getStudyVariables)             // ?instanceID=test
 		studiesGroup.GET("/:studyKey/variables/:variableKey", h.getStudyVariable) // ?instanceID=test
+
+		studiesGroup.GET("/:studyKey/consents/:consentKey", h.getConsentDocument) // ?instanceID=test&version=2
 	}
 
 	// study events
@@ -52,6 +56,7 @@ func (h *HttpEndpoints) AddStudyServiceAPI(rg *gin.RouterGroup) {
 		eventsGroup.POST("/enter", h.enterStudy)
 		eventsGroup.POST("/custom", h.customStudyEvent)
 		eventsGroup.POST("/submit", h.submitSurveyEvent)
+		eventsGroup.POST("/submit-batch", h.submitSurveyBatchEvent)
 		eventsGroup.POST("/leave", h.leaveStudyEvent)
 		eventsGroup.POST("/merge-temporary-participant", h.mergeTempParticipant)
 		eventsGroup.POST("/merge-virtual-participant", h.mergeVirtualParticipant) // requires profile id, virtual participant id, linking code key, linking code value
@@ -61,13 +66,27 @@ func (h *HttpEndpoints) AddStudyServiceAPI(rg *gin.RouterGroup) {
 	participantInfoGroup.Use(mw.GetAndValidateParticipantUserJWT(h.tokenSignKey, h.globalInfosDBConn))
 	{
 		participantInfoGroup.GET("/surveys", h.getAssignedSurveys)             // ?pids=p1,p2,p3
-		participantInfoGroup.GET("/survey/:surveyKey", h.getSurveyWithContext) // ?pid=profileID
+		participantInfoGroup.GET("/survey/:surveyKey", h.getSurveyWithContext) // ?pid=profileID&includeDraft=true
+
+		// survey response drafts
+		participantInfoGroup.GET("/survey/:surveyKey/draft", h.getSurveyResponseDraft)                       // ?pid=profileID
+		participantInfoGroup.PUT("/survey/:surveyKey/draft", mw.RequirePayload(), h.saveSurveyResponseDraft) // ?pid=profileID
+		participantInfoGroup.DELETE("/survey/:surveyKey/draft", h.discardSurveyResponseDraft)                // ?pid=profileID
+
+		// consents
+		participantInfoGroup.GET("/consents", h.getConsentStatus)                                       // ?pid=profileID
+		participantInfoGroup.POST("/consents/:consentKey/accept", mw.RequirePayload(), h.acceptConsent) // ?pid=profileID
+		participantInfoGroup.POST("/consents/:consentKey/withdraw", h.withdrawConsent)                  // ?pid=profileID
 
 		// files
 		participantInfoGroup.POST("/files", h.uploadParticipantFile)
-		participantInfoGroup.GET("/files", h.getParticipantFiles)              // ?pid=profileID&page=1&limit=10
-		participantInfoGroup.GET("/files/:fileID", h.getParticipantFile)       // ?pid=profileID
-		participantInfoGroup.DELETE("/files/:fileID", h.deleteParticipantFile) // ?pid=profileID
+		participantInfoGroup.POST("/files/uploads", mw.RequirePayload(), h.initFileUpload)
+		participantInfoGroup.GET("/files/uploads/:fileID", h.getFileUploadStatus)          // ?pid=profileID
+		participantInfoGroup.PATCH("/files/uploads/:fileID", h.uploadFileChunk)            // ?pid=profileID
+		participantInfoGroup.POST("/files/uploads/:fileID/complete", h.completeFileUpload) // ?pid=profileID
+		participantInfoGroup.GET("/files", h.getParticipantFiles)                          // ?pid=profileID&page=1&limit=10
+		participantInfoGroup.GET("/files/:fileID", h.getParticipantFile)                   // ?pid=profileID
+		participantInfoGroup.DELETE("/files/:fileID", h.deleteParticipantFile)             // ?pid=profileID
 
 		participantInfoGroup.GET("/participant-state", h.getParticipantState) // ?pid=profileID
 		participantInfoGroup.GET("/linking-code", h.getLinkingCode)           // ?pid=profileID&key=key
@@ -386,6 +405,10 @@ func (h *HttpEndpoints) enterStudy(c *gin.Context) {
 	result, err := studyService.OnEnterStudy(token.InstanceID, studyKey, req.ProfileID, user.Account.AccountID, isMainProfile)
 	if err != nil {
 		slog.Error("error entering study", slog.String("error", err.Error()))
+		if errors.Is(err, studyTypes.ErrStudyQuotaFull) {
+			c.JSON(http.StatusConflict, gin.H{"error": "study quota is full"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error entering study"})
 		return
 	}
@@ -432,6 +455,10 @@ func (h *HttpEndpoints) customStudyEvent(c *gin.Context) {
 	result, err := studyService.OnCustomStudyEvent(token.InstanceID, studyKey, req.ProfileID, req.EventKey, req.Payload)
 	if err != nil {
 		slog.Error("error firing custom study event", slog.String("error", err.Error()))
+		if errors.Is(err, studyTypes.ErrStudyQuotaFull) {
+			c.JSON(http.StatusConflict, gin.H{"error": "study quota is full"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error firing custom study event"})
 		return
 	}
@@ -464,6 +491,15 @@ func (h *HttpEndpoints) submitSurveyEvent(c *gin.Context) {
 
 	result, err := studyService.OnSubmitResponse(token.InstanceID, studyKey, req.ProfileID, req.Response)
 	if err != nil {
+		if errors.Is(err, studyService.ErrInvalidSubmissionID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var vErrs surveyengine.ValidationErrors
+		if errors.As(err, &vErrs) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid response", "validationErrors": vErrs})
+			return
+		}
 		slog.Error("error submitting survey", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error submitting survey"})
 		return
@@ -472,6 +508,48 @@ func (h *HttpEndpoints) submitSurveyEvent(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"assignedSurveys": result})
 }
 
+func (h *HttpEndpoints) submitSurveyBatchEvent(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+
+	var req struct {
+		ProfileID string                      `json:"profileID"`
+		Responses []studyTypes.SurveyResponse `json:"responses"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Responses) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "responses are required"})
+		return
+	}
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, req.ProfileID) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", req.ProfileID))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "profile not found"})
+		return
+	}
+
+	slog.Info("submitting survey batch", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("profileID", req.ProfileID), slog.Int("count", len(req.Responses)))
+
+	result, err := studyService.OnSubmitResponseBatch(token.InstanceID, studyKey, req.ProfileID, req.Responses)
+	if err != nil {
+		if errors.Is(err, studyService.ErrSubmissionBatchTooLarge) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("error submitting survey batch", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error submitting survey batch"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 func (h *HttpEndpoints) leaveStudyEvent(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
 
@@ -636,7 +714,9 @@ func (h *HttpEndpoints) getSurveyWithContext(c *gin.Context) {
 
 	slog.Info("getting survey with context", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("profileID", pid))
 
-	result, err := studyService.GetAssignedSurveyWithContext(token.InstanceID, studyKey, surveyKey, pid)
+	includeDraft := c.DefaultQuery("includeDraft", "false") == "true"
+
+	result, err := studyService.GetAssignedSurveyWithContext(token.InstanceID, studyKey, surveyKey, pid, includeDraft)
 	if err != nil {
 		slog.Error("error getting survey with context", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting survey with context"})
@@ -645,6 +725,254 @@ func (h *HttpEndpoints) getSurveyWithContext(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"surveyWithContext": result})
 }
 
+func (h *HttpEndpoints) getSurveyResponseDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	surveyKey := c.Param("surveyKey")
+	pid := c.DefaultQuery("pid", "")
+
+	if pid == "" {
+		slog.Error("profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "profileID is required"})
+		return
+	}
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	draft, err := studyService.GetSurveyResponseDraft(token.InstanceID, studyKey, pid, surveyKey)
+	if err != nil {
+		slog.Error("error getting survey response draft", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting survey response draft"})
+		return
+	}
+	if draft == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "draft not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"draft": draft})
+}
+
+func (h *HttpEndpoints) saveSurveyResponseDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	surveyKey := c.Param("surveyKey")
+	pid := c.DefaultQuery("pid", "")
+
+	if pid == "" {
+		slog.Error("profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "profileID is required"})
+		return
+	}
+
+	var req struct {
+		Response studyTypes.SurveyResponse `json:"response"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Response.Key != "" && req.Response.Key != surveyKey {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "survey key in response does not match"})
+		return
+	}
+	req.Response.Key = surveyKey
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	slog.Debug("saving survey response draft", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("surveyKey", surveyKey), slog.String("profileID", pid))
+
+	draft, err := studyService.SaveSurveyResponseDraft(token.InstanceID, studyKey, pid, req.Response)
+	if err != nil {
+		if errors.Is(err, studyService.ErrSurveyResponseDraftOutdated) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("error saving survey response draft", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving survey response draft"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"draft": draft})
+}
+
+func (h *HttpEndpoints) discardSurveyResponseDraft(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	surveyKey := c.Param("surveyKey")
+	pid := c.DefaultQuery("pid", "")
+
+	if pid == "" {
+		slog.Error("profileID is required", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "profileID is required"})
+		return
+	}
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	err := studyService.DiscardSurveyResponseDraft(token.InstanceID, studyKey, pid, surveyKey)
+	if err != nil {
+		slog.Error("error discarding survey response draft", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error discarding survey response draft"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "draft discarded"})
+}
+
+func (h *HttpEndpoints) getConsentDocument(c *gin.Context) {
+	instanceID := c.DefaultQuery("instanceID", "")
+	studyKey := c.Param("studyKey")
+	consentKey := c.Param("consentKey")
+
+	if !h.isInstanceAllowed(instanceID) {
+		slog.Error("instance not allowed", slog.String("instanceID", instanceID))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "instance not allowed"})
+		return
+	}
+
+	var (
+		doc studyTypes.ConsentDocument
+		err error
+	)
+	if v := c.DefaultQuery("version", ""); v != "" {
+		version, convErr := strconv.Atoi(v)
+		if convErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+			return
+		}
+		doc, err = h.studyDBConn.GetConsentDocument(instanceID, studyKey, consentKey, version)
+	} else {
+		doc, err = h.studyDBConn.GetLatestConsentDocument(instanceID, studyKey, consentKey)
+	}
+	if err != nil {
+		slog.Warn("consent document not found", slog.String("instanceID", instanceID), slog.String("studyKey", studyKey), slog.String("consentKey", consentKey), slog.String("error", err.Error()))
+		c.JSON(http.StatusNotFound, gin.H{"error": "consent document not found"})
+		return
+	}
+	doc.PublishedBy = ""
+
+	c.JSON(http.StatusOK, gin.H{"consent": doc})
+}
+
+func (h *HttpEndpoints) getConsentStatus(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	pid := c.DefaultQuery("pid", "")
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	consents, err := studyService.GetConsentStatusForProfile(token.InstanceID, studyKey, pid)
+	if err != nil {
+		slog.Error("error getting consent status", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error getting consent status"})
+		return
+	}
+
+	for i := range consents {
+		consents[i].Document.PublishedBy = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"consents": consents})
+}
+
+func (h *HttpEndpoints) acceptConsent(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	consentKey := c.Param("consentKey")
+	pid := c.DefaultQuery("pid", "")
+
+	var req struct {
+		Version     int    `json:"version"`
+		Language    string `json:"language"`
+		ContentHash string `json:"contentHash"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		slog.Error("failed to bind request", slog.String("error", err.Error()))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Version < 1 || req.Language == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version and language are required"})
+		return
+	}
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	record, err := studyService.OnAcceptConsent(token.InstanceID, studyKey, pid, consentKey, req.Version, req.Language, req.ContentHash)
+	if err != nil {
+		switch {
+		case errors.Is(err, studyTypes.ErrConsentDocumentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, studyTypes.ErrConsentVersionOutdated), errors.Is(err, studyTypes.ErrConsentContentMismatch):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, studyTypes.ErrConsentLanguageNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			slog.Error("error accepting consent", slog.String("error", err.Error()))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error accepting consent"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"record": record})
+}
+
+func (h *HttpEndpoints) withdrawConsent(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	studyKey := c.Param("studyKey")
+	consentKey := c.Param("consentKey")
+	pid := c.DefaultQuery("pid", "")
+
+	if !h.checkProfileBelongsToUser(token.InstanceID, token.Subject, pid) {
+		slog.Warn("profile not found", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("profileID", pid))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "profile not found"})
+		return
+	}
+
+	slog.Info("withdrawing consent", slog.String("instanceID", token.InstanceID), slog.String("userID", token.Subject), slog.String("studyKey", studyKey), slog.String("consentKey", consentKey))
+
+	result, err := studyService.OnWithdrawConsent(token.InstanceID, studyKey, pid, consentKey)
+	if err != nil {
+		if errors.Is(err, studyTypes.ErrNoActiveConsentToWithdraw) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Error("error withdrawing consent", slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error withdrawing consent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"assignedSurveys": result})
+}
+
 func (h *HttpEndpoints) uploadParticipantFile(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
 	studyKey := c.Param("studyKey")
@@ -680,11 +1008,7 @@ func (h *HttpEndpoints) uploadParticipantFile(c *gin.Context) {
 	}
 
 	// Extract and validate file type based on content
-	allowedTypes := []string{
-		"image/jpeg",
-		"image/png",
-	}
-	fileType, err := utils.ValidateFileTypeFromContent(file, allowedTypes)
+	fileType, err := utils.ValidateFileTypeFromContent(file, allowedParticipantFileTypes)
 	if err != nil {
 		slog.Error("failed to validate file type", slog.String("error", err.Error()), slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("profileID", profileID))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -745,18 +1069,26 @@ func (h *HttpEndpoints) uploadParticipantFile(c *gin.Context) {
 		return
 	}
 
-	// update file info with the relative path and status
-	err = h.studyDBConn.UpdateParticipantFileInfoPathAndStatus(token.InstanceID, studyKey, savedFileInfo.ID.Hex(), relativePath, studyTypes.FILE_STATUS_READY)
+	// scan file and update file info with the relative path and status
+	status, relativePath, err := h.storeScannedParticipantFile(c.Request.Context(), token.InstanceID, studyKey, savedFileInfo.ID.Hex(), relativePath, filename, fileType)
 	if err != nil {
 		slog.Error("failed to update file info", slog.String("error", err.Error()))
-		os.Remove(filePath)
-		err = h.studyDBConn.DeleteParticipantFileInfoByID(token.InstanceID, studyKey, savedFileInfo.ID.Hex())
-		if err != nil {
-			slog.Error("failed to delete file info", slog.String("error", err.Error()))
+		os.Remove(filepath.Join(h.filestorePath, relativePath))
+		err2 := h.studyDBConn.DeleteParticipantFileInfoByID(token.InstanceID, studyKey, savedFileInfo.ID.Hex())
+		if err2 != nil {
+			slog.Error("failed to delete file info", slog.String("error", err2.Error()))
+		}
+		if errors.Is(err, errFileScanFailed) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "file could not be scanned"})
+			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update file info"})
 		return
 	}
+	if status == studyTypes.FILE_STATUS_QUARANTINED {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "file rejected by malware scan"})
+		return
+	}
 
 	slog.Info("file uploaded successfully", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("profileID", profileID), slog.String("fileID", savedFileInfo.ID.Hex()))
 	c.JSON(http.StatusOK, gin.H{"fileInfo": gin.H{
@@ -879,6 +1211,12 @@ func (h *HttpEndpoints) getParticipantFile(c *gin.Context) {
 		return
 	}
 
+	if fileInfo.Status != studyTypes.FILE_STATUS_READY {
+		slog.Warn("file not ready", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileID), slog.String("status", fileInfo.Status))
+		c.JSON(http.StatusNotFound, gin.H{"error": "file not found"})
+		return
+	}
+
 	filePath := filepath.Join(h.filestorePath, fileInfo.Path)
 
 	// Check if file exists
@@ -971,6 +1309,12 @@ func (h *HttpEndpoints) deleteParticipantFile(c *gin.Context) {
 	} else {
 		slog.Info("file deleted successfully", slog.String("instanceID", token.InstanceID), slog.String("studyKey", studyKey), slog.String("fileID", fileID))
 	}
+	if fileInfo.PreviewPath != "" {
+		err = os.Remove(filepath.Join(h.filestorePath, fileInfo.PreviewPath))
+		if err != nil {
+			slog.Error("failed to delete file preview", slog.String("error", err.Error()), slog.String("path", fileInfo.PreviewPath))
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "file deleted successfully"})
 }
@@ -1091,6 +1435,15 @@ func (h *HttpEndpoints) submitTempParticipantResponse(c *gin.Context) {
 
 	result, err := studyService.OnSubmitResponseForTempParticipant(req.InstanceID, req.StudyKey, req.Pid, req.Response)
 	if err != nil {
+		if errors.Is(err, studyService.ErrInvalidSubmissionID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var vErrs surveyengine.ValidationErrors
+		if errors.As(err, &vErrs) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid response", "validationErrors": vErrs})
+			return
+		}
 		slog.Error("error submitting response for temporary participant", slog.String("error", err.Error()))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error submitting response for temporary participant"})
 		return
@@ -0,0 +1,200 @@
+package apihandlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	jwthandling "github.com/case-framework/case-backend/pkg/jwt-handling"
+	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *HttpEndpoints) getTOTPStatus(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	resp := gin.H{
+		"available": usermanagement.TOTPEnabled(),
+		"enabled":   user.Account.HasTOTP(),
+	}
+	if user.Account.HasTOTP() {
+		resp["confirmedAt"] = user.Account.TOTP.ConfirmedAt
+		resp["recoveryCodesLeft"] = len(user.Account.TOTP.RecoveryCodes)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// startTOTPEnrolment returns the secret and provisioning URI (for the QR code) of a new authenticator app enrolment
+func (h *HttpEndpoints) startTOTPEnrolment(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	if !confirmedWithPasswordOrRecentOTP(user, req.Password, token) {
+		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
+		return
+	}
+
+	secret, uri, err := usermanagement.StartTOTPEnrolment(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to start totp enrolment", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, usermanagement.ErrTOTPNotConfigured):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, usermanagement.ErrTOTPAlreadyEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start enrolment"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
+
+// confirmTOTPEnrolment activates the authenticator app and returns the recovery codes, which are shown only once
+func (h *HttpEndpoints) confirmTOTPEnrolment(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	var req VerifyOTPReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	if h.hasTooManyFailedOtpAttempts(token.InstanceID, token.Subject) {
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "too many failed otp attempts"})
+		return
+	}
+
+	recoveryCodes, err := usermanagement.ConfirmTOTPEnrolment(token.InstanceID, token.Subject, req.Code)
+	if err != nil {
+		slog.Warn("failed to confirm totp enrolment", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, usermanagement.ErrInvalidTOTPCode):
+			h.saveFailedOtpAttempt(token.InstanceID, token.Subject)
+			randomWait(5, 10)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		case errors.Is(err, usermanagement.ErrTOTPNotConfigured):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, usermanagement.ErrTOTPNotEnrolled), errors.Is(err, usermanagement.ErrTOTPAlreadyEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to confirm enrolment"})
+		}
+		return
+	}
+
+	slog.Info("totp enrolled", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+func (h *HttpEndpoints) regenerateTOTPRecoveryCodes(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if !h.verifyTOTPFromRequest(c, token) {
+		return
+	}
+
+	recoveryCodes, err := usermanagement.RegenerateTOTPRecoveryCodes(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("failed to regenerate recovery codes", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to regenerate recovery codes"})
+		return
+	}
+
+	slog.Info("totp recovery codes regenerated", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": recoveryCodes})
+}
+
+func (h *HttpEndpoints) disableTOTP(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	if !h.verifyTOTPFromRequest(c, token) {
+		return
+	}
+
+	if err := usermanagement.DisableTOTP(token.InstanceID, token.Subject); err != nil {
+		slog.Error("failed to disable totp", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to disable authenticator app"})
+		return
+	}
+
+	slog.Info("totp disabled", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+	c.JSON(http.StatusOK, gin.H{"message": "authenticator app removed"})
+}
+
+// verifyTOTPFromRequest checks the code (or recovery code) in the request body and writes the error response if it is not valid
+func (h *HttpEndpoints) verifyTOTPFromRequest(c *gin.Context, token *jwthandling.ParticipantUserClaims) bool {
+	var req VerifyOTPReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return false
+	}
+
+	if h.hasTooManyFailedOtpAttempts(token.InstanceID, token.Subject) {
+		randomWait(5, 10)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "too many failed otp attempts"})
+		return false
+	}
+
+	if _, err := usermanagement.VerifyTOTP(token.InstanceID, token.Subject, req.Code); err != nil {
+		slog.Warn("failed to verify totp", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, usermanagement.ErrTOTPNotEnrolled):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, usermanagement.ErrTOTPNotConfigured):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			h.saveFailedOtpAttempt(token.InstanceID, token.Subject)
+			randomWait(5, 10)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+		}
+		return false
+	}
+	return true
+}
+
+func (h *HttpEndpoints) hasTooManyFailedOtpAttempts(instanceID string, userID string) bool {
+	count, err := h.userDBConn.CountFailedOtpAttempts(instanceID, userID)
+	if err != nil {
+		slog.Error("failed to count failed otp attempts", slog.String("error", err.Error()))
+	}
+	if count >= maxFailedOtpAttempts {
+		slog.Warn("too many failed otp attempts", slog.String("instanceID", instanceID), slog.String("userID", userID))
+		return true
+	}
+	return false
+}
+
+func (h *HttpEndpoints) saveFailedOtpAttempt(instanceID string, userID string) {
+	if err := h.userDBConn.AddFailedOtpAttempt(instanceID, userID); err != nil {
+		slog.Error("failed to add failed otp attempt", slog.String("error", err.Error()))
+	}
+}
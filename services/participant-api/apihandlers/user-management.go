@@ -24,6 +24,8 @@ import (
 const (
 	MAX_PROFILES_ALLOWED                          = 6
 	MAX_PHONE_NUMBER_VERIFICATION_REQUEST_PER_24H = 10
+
+	passwordlessConfirmationMaxAge = 15 * time.Minute
 )
 
 func (h *HttpEndpoints) AddUserManagementAPI(rg *gin.RouterGroup) {
@@ -39,6 +41,23 @@ func (h *HttpEndpoints) AddUserManagementAPI(rg *gin.RouterGroup) {
 		userGroup.POST("/profiles/remove", mw.RequirePayload(), h.removeProfileHandl)
 
 		userGroup.POST("/password", mw.RequirePayload(), h.changePasswordHandl)
+		userGroup.POST("/passwordless", mw.RequirePayload(), h.enablePasswordlessHandl)
+		userGroup.POST("/passwordless/disable", mw.RequirePayload(), h.disablePasswordlessHandl)
+
+		userGroup.GET("/totp", h.getTOTPStatus)
+		userGroup.POST("/totp", mw.RequirePayload(), h.startTOTPEnrolment)
+		userGroup.POST("/totp/verify", mw.RequirePayload(), h.confirmTOTPEnrolment)
+		userGroup.POST("/totp/recovery-codes", mw.RequirePayload(), h.regenerateTOTPRecoveryCodes)
+		userGroup.POST("/totp/disable", mw.RequirePayload(), h.disableTOTP)
+
+		userGroup.GET("/passkeys", h.getPasskeys)
+		userGroup.POST("/passkeys/registration/begin", mw.RequirePayload(), h.beginPasskeyRegistration)
+		userGroup.POST("/passkeys/registration/finish", mw.RequirePayload(), h.finishPasskeyRegistration)
+		userGroup.DELETE("/passkeys/:credentialID", h.deletePasskey)
+
+		userGroup.POST("/oidc/:provider/link/begin", mw.RequirePayload(), h.beginOIDCLink)
+		userGroup.POST("/oidc/:provider/link/finish", mw.RequirePayload(), h.finishOIDCLink)
+		userGroup.DELETE("/oidc/:provider", h.unlinkOIDCIdentity)
 
 		userGroup.POST("/change-account-email", mw.RequirePayload(), h.changeAccountEmailHandl)
 		userGroup.POST("/change-phone-number", mw.RequirePayload(), h.updatePhoneNumberHandler)
@@ -50,10 +69,31 @@ func (h *HttpEndpoints) AddUserManagementAPI(rg *gin.RouterGroup) {
 		userGroup.DELETE("/attributes/:attributeID", h.deleteUserAttributeHandl)
 		userGroup.GET("/attributes", h.getUserAttributesHandl)
 
+		userGroup.POST("/data-export", h.startDataExport)
+		userGroup.GET("/data-export/:taskID", h.getDataExportStatus)
+		userGroup.GET("/data-export/:taskID/download", h.downloadDataExport)
+
+		userGroup.GET("/inbox", h.getInboxMessages)
+		userGroup.PUT("/inbox/read-all", h.markAllInboxMessagesAsRead)
+		userGroup.PUT("/inbox/:messageID/read", h.markInboxMessageAsRead)
+		userGroup.DELETE("/inbox/:messageID", h.deleteInboxMessage)
+
+		userGroup.GET("/push-subscriptions/public-key", h.getPushPublicKey)
+		userGroup.GET("/push-subscriptions", h.getPushSubscriptions)
+		userGroup.POST("/push-subscriptions", mw.RequirePayload(), h.addPushSubscription)
+		userGroup.DELETE("/push-subscriptions/:subscriptionID", h.deletePushSubscription)
+
+		userGroup.GET("/events", h.streamParticipantEvents) // server-sent events
+
+		userGroup.GET("/calendar", h.getCalendar) // ?lang=en
+		userGroup.POST("/calendar/feed-token", h.createCalendarFeedToken)
+		userGroup.DELETE("/calendar/feed-token", h.revokeCalendarFeedToken)
+
 		userGroup.DELETE("/", h.deleteUser)
 	}
 
 	rg.POST("/unsubscribe-newsletter", mw.RequirePayload(), h.unsubscribeNewsletter)
+	rg.GET("/calendar/feed/:token", h.getCalendarFeed) // token with optional .ics suffix, ?lang=en
 }
 
 func (h *HttpEndpoints) getUser(c *gin.Context) {
@@ -260,16 +300,7 @@ func (h *HttpEndpoints) changePasswordHandl(c *gin.Context) {
 		return
 	}
 
-	go h.sendSimpleEmail(
-		token.InstanceID,
-		[]string{user.Account.AccountID},
-		user.ID.Hex(),
-		emailTypes.EMAIL_TYPE_PASSWORD_CHANGED,
-		"",
-		user.Account.PreferredLanguage,
-		nil,
-		true,
-	)
+	go h.sendPasswordChangedNotice(token.InstanceID, user)
 
 	slog.Info("password change successful", slog.String("userID", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
 
@@ -280,6 +311,146 @@ func (h *HttpEndpoints) changePasswordHandl(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
 }
 
+// confirmedWithPasswordOrRecentOTP checks the password, or for passwordless accounts whether the session
+// was confirmed recently through the email address (login link or email OTP), the phone number, an authenticator app,
+// a passkey or an identity provider
+func confirmedWithPasswordOrRecentOTP(user userTypes.User, password string, token *jwthandling.ParticipantUserClaims) bool {
+	if user.Account.Passwordless {
+		for _, otpType := range []userTypes.OTPType{userTypes.EmailOTP, userTypes.SMSOTP, userTypes.TOTP, userTypes.Passkey, userTypes.OIDC} {
+			lastOTP, ok := token.LastOTPProvided[string(otpType)]
+			if ok && lastOTP >= time.Now().Add(-passwordlessConfirmationMaxAge).Unix() {
+				return true
+			}
+		}
+		return false
+	}
+	match, err := pwhash.ComparePasswordWithHash(user.Account.Password, password)
+	return err == nil && match
+}
+
+// enablePasswordlessHandl removes the password of the account after confirming it, afterwards sign-in is only possible with login links or SMS codes
+func (h *HttpEndpoints) enablePasswordlessHandl(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	var req struct {
+		Password string `json:"password"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	if user.Account.Type != userTypes.ACCOUNT_TYPE_EMAIL && user.Account.Type != userTypes.ACCOUNT_TYPE_PHONE {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passwordless sign-in is only available for email and phone accounts"})
+		return
+	}
+
+	if user.Account.Passwordless {
+		c.JSON(http.StatusOK, gin.H{"message": "account is passwordless"})
+		return
+	}
+
+	match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.Password)
+	if err != nil || !match {
+		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"account.password": "", "account.passwordless": true, "timestamps.lastPasswordChange": time.Now().Unix()}}
+	if err := h.userDBConn.UpdateUser(token.InstanceID, user.ID.Hex(), update); err != nil {
+		slog.Error("cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
+		return
+	}
+
+	if err := h.globalInfosDBConn.DeleteAllTempTokenForUser(token.InstanceID, user.ID.Hex(), userTypes.TOKEN_PURPOSE_PASSWORD_RESET); err != nil {
+		slog.Error("failed to delete temp tokens", slog.String("error", err.Error()))
+	}
+
+	go h.sendPasswordChangedNotice(token.InstanceID, user)
+
+	slog.Info("passwordless sign-in enabled", slog.String("userID", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
+	c.JSON(http.StatusOK, gin.H{"message": "account is passwordless"})
+}
+
+// disablePasswordlessHandl sets a new password for a passwordless account
+func (h *HttpEndpoints) disablePasswordlessHandl(c *gin.Context) {
+	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
+
+	var req struct {
+		NewPassword string `json:"newPassword"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot bind request"})
+		return
+	}
+
+	if !umUtils.CheckPasswordFormat(req.NewPassword) {
+		slog.Error("invalid password format", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "invalid password format"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid password format"})
+		return
+	}
+
+	if umUtils.IsPasswordOnBlocklist(req.NewPassword) {
+		slog.Error("password on blocklist", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", "password on blocklist"))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password on blocklist"})
+		return
+	}
+
+	user, err := h.userDBConn.GetUser(token.InstanceID, token.Subject)
+	if err != nil {
+		slog.Error("user not found", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "user not found"})
+		return
+	}
+
+	if user.Account.Type != userTypes.ACCOUNT_TYPE_EMAIL && user.Account.Type != userTypes.ACCOUNT_TYPE_PHONE {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "passwords are only available for email and phone accounts"})
+		return
+	}
+
+	if !user.Account.Passwordless {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account has a password"})
+		return
+	}
+
+	if !confirmedWithPasswordOrRecentOTP(user, "", token) {
+		slog.Error("no recent verification to set password", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "recent verification required"})
+		return
+	}
+
+	hashedPassword, err := pwhash.HashPassword(req.NewPassword)
+	if err != nil {
+		slog.Error("cannot hash password", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot hash password"})
+		return
+	}
+
+	update := bson.M{
+		"$set":   bson.M{"account.password": hashedPassword, "timestamps.lastPasswordChange": time.Now().Unix()},
+		"$unset": bson.M{"account.passwordless": ""},
+	}
+	if err := h.userDBConn.UpdateUser(token.InstanceID, user.ID.Hex(), update); err != nil {
+		slog.Error("cannot update user", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject), slog.String("error", err.Error()))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot update user"})
+		return
+	}
+
+	go h.sendPasswordChangedNotice(token.InstanceID, user)
+
+	slog.Info("passwordless sign-in disabled", slog.String("userID", user.ID.Hex()), slog.String("instanceID", token.InstanceID))
+	c.JSON(http.StatusOK, gin.H{"message": "password set"})
+}
+
 func (h *HttpEndpoints) changeAccountEmailHandl(c *gin.Context) {
 	token := c.MustGet("validatedToken").(*jwthandling.ParticipantUserClaims)
 
@@ -313,8 +484,7 @@ func (h *HttpEndpoints) changeAccountEmailHandl(c *gin.Context) {
 		return
 	}
 
-	match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.Password)
-	if err != nil || !match {
+	if !confirmedWithPasswordOrRecentOTP(user, req.Password, token) {
 		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
 		return
@@ -444,8 +614,13 @@ func (h *HttpEndpoints) updatePhoneNumberHandler(c *gin.Context) {
 		return
 	}
 
-	match, err := pwhash.ComparePasswordWithHash(user.Account.Password, req.Password)
-	if err != nil || !match {
+	if user.Account.Type == userTypes.ACCOUNT_TYPE_PHONE {
+		// the number is the account ID, a new number needs a new account
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone number of phone accounts cannot be changed"})
+		return
+	}
+
+	if !confirmedWithPasswordOrRecentOTP(user, req.Password, token) {
 		slog.Error("password does not match", slog.String("instanceId", token.InstanceID), slog.String("userId", token.Subject))
 		randomWait(5, 10)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong password"})
@@ -477,12 +652,12 @@ func (h *HttpEndpoints) updatePhoneNumberHandler(c *gin.Context) {
 	user.SetPhoneNumber(req.NewPhoneNumber)
 
 	// send email to user about phone number change
-	if user.Account.AccountConfirmedAt > 0 {
+	if email, err := user.GetMessagingEmail(); err == nil && user.Account.AccountConfirmedAt > 0 {
 		// old account is confirmed already
 		go h.prepTokenAndSendEmail(
 			user.ID.Hex(),
 			token.InstanceID,
-			user.Account.AccountID,
+			email,
 			user.Account.PreferredLanguage,
 			userTypes.TOKEN_PURPOSE_RESTORE_ACCOUNT_ID,
 			h.ttls.EmailContactVerificationToken,
@@ -787,16 +962,36 @@ func (h *HttpEndpoints) deleteUser(c *gin.Context) {
 		slog.Error("failed to delete temp tokens", slog.String("error", err.Error()))
 	}
 
-	h.sendSimpleEmail(
-		token.InstanceID,
-		[]string{user.Account.AccountID},
-		user.ID.Hex(),
-		emailTypes.EMAIL_TYPE_ACCOUNT_DELETED,
-		"",
-		user.Account.PreferredLanguage,
-		nil,
-		true,
-	)
+	if _, err := h.messagingDBConn.DeleteInboxMessagesForUser(token.InstanceID, user.ID.Hex()); err != nil {
+		slog.Error("failed to delete inbox messages", slog.String("error", err.Error()))
+	}
+
+	studyService.DeleteDataExportsForUser(token.InstanceID, user.ID.Hex(), h.filestorePath)
+
+	if _, err := h.messagingDBConn.DeletePushSubscriptionsForUser(token.InstanceID, user.ID.Hex()); err != nil {
+		slog.Error("failed to delete push subscriptions", slog.String("error", err.Error()))
+	}
+
+	if err := h.userDBConn.DeleteWebAuthnCredentialsForUser(token.InstanceID, user.ID.Hex()); err != nil {
+		slog.Error("failed to delete passkeys", slog.String("error", err.Error()))
+	}
+
+	if email, err := user.GetMessagingEmail(); err == nil {
+		h.sendSimpleEmail(
+			token.InstanceID,
+			[]string{email},
+			user.ID.Hex(),
+			emailTypes.EMAIL_TYPE_ACCOUNT_DELETED,
+			"",
+			user.Account.PreferredLanguage,
+			nil,
+			true,
+		)
+	} else if phone, err := user.GetMessagingPhone(); err == nil {
+		if err := sms.SendSMS(token.InstanceID, phone, user.ID.Hex(), sms.SMS_MESSAGE_TYPE_ACCOUNT_DELETED, user.Account.PreferredLanguage, nil); err != nil {
+			slog.Error("failed to send account deleted SMS", slog.String("error", err.Error()))
+		}
+	}
 
 	err = h.userDBConn.DeleteUser(token.InstanceID, user.ID.Hex())
 	if err != nil {
@@ -8,6 +8,8 @@ import (
 	"time"
 
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
+	"github.com/case-framework/case-backend/pkg/messaging/sms"
+	emailTypes "github.com/case-framework/case-backend/pkg/messaging/types"
 	userTypes "github.com/case-framework/case-backend/pkg/user-management/types"
 	umUtils "github.com/case-framework/case-backend/pkg/user-management/utils"
 )
@@ -113,6 +115,27 @@ func (h *HttpEndpoints) sendSimpleEmail(
 	}
 }
 
+// sendPasswordChangedNotice informs the participant by email, or by SMS for phone accounts
+func (h *HttpEndpoints) sendPasswordChangedNotice(instanceID string, user userTypes.User) {
+	if user.Account.Type == userTypes.ACCOUNT_TYPE_PHONE {
+		err := sms.SendSMS(instanceID, user.Account.AccountID, user.ID.Hex(), sms.SMS_MESSAGE_TYPE_PASSWORD_CHANGED, user.Account.PreferredLanguage, nil)
+		if err != nil {
+			slog.Error("failed to send SMS", slog.String("error", err.Error()))
+		}
+		return
+	}
+	h.sendSimpleEmail(
+		instanceID,
+		[]string{user.Account.AccountID},
+		user.ID.Hex(),
+		emailTypes.EMAIL_TYPE_PASSWORD_CHANGED,
+		"",
+		user.Account.PreferredLanguage,
+		nil,
+		true,
+	)
+}
+
 func randomWait(minTimeSec int, maxTimeSec int) {
 	time.Sleep(time.Duration(rand.Intn(maxTimeSec-minTimeSec)+minTimeSec) * time.Second)
 }
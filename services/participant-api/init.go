@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"os"
 	"time"
@@ -8,15 +9,19 @@ import (
 	"github.com/case-framework/case-backend/pkg/apihelpers"
 	"github.com/case-framework/case-backend/pkg/apihelpers/middlewares"
 	"github.com/case-framework/case-backend/pkg/db"
+	filescanner "github.com/case-framework/case-backend/pkg/file-scanner"
 	httpclient "github.com/case-framework/case-backend/pkg/http-client"
 	emailsending "github.com/case-framework/case-backend/pkg/messaging/email-sending"
 	"github.com/case-framework/case-backend/pkg/messaging/sms"
 	messagingTypes "github.com/case-framework/case-backend/pkg/messaging/types"
+	participantevents "github.com/case-framework/case-backend/pkg/participant-events"
 	"github.com/case-framework/case-backend/pkg/study"
 	"github.com/case-framework/case-backend/pkg/study/studyengine"
 	studySender "github.com/case-framework/case-backend/pkg/study/studyengine/sender"
 	usermanagement "github.com/case-framework/case-backend/pkg/user-management"
+	"github.com/case-framework/case-backend/pkg/user-management/oidc"
 	"github.com/case-framework/case-backend/pkg/user-management/pwhash"
+	"github.com/case-framework/case-backend/pkg/user-management/webauthn"
 	"github.com/case-framework/case-backend/pkg/utils"
 	"github.com/gin-gonic/gin"
 	"gopkg.in/yaml.v2"
@@ -43,11 +48,13 @@ const (
 	ENV_MESSAGING_DB_USERNAME        = "MESSAGING_DB_USERNAME"
 	ENV_MESSAGING_DB_PASSWORD        = "MESSAGING_DB_PASSWORD"
 
-	ENV_SMTP_BRIDGE_API_KEY = "SMTP_BRIDGE_API_KEY"
-	ENV_SMS_GATEWAY_API_KEY = "SMS_GATEWAY_API_KEY"
+	ENV_SMTP_BRIDGE_API_KEY  = "SMTP_BRIDGE_API_KEY"
+	ENV_SMS_GATEWAY_API_KEY  = "SMS_GATEWAY_API_KEY"
+	ENV_FILE_SCANNER_API_KEY = "FILE_SCANNER_API_KEY"
 
 	ENV_STUDY_GLOBAL_SECRET           = "STUDY_GLOBAL_SECRET"
 	ENV_PARTICIPANT_USER_JWT_SIGN_KEY = "PARTICIPANT_USER_JWT_SIGN_KEY"
+	ENV_TOTP_ENCRYPTION_KEY           = "TOTP_ENCRYPTION_KEY"
 )
 
 type ParticipantApiConfig struct {
@@ -79,10 +86,15 @@ type ParticipantApiConfig struct {
 			SignKey   string        `json:"sign_key" yaml:"sign_key"`
 			ExpiresIn time.Duration `json:"expires_in" yaml:"expires_in"`
 		} `json:"participant_user_jwt_config" yaml:"participant_user_jwt_config"`
-		MaxNewUsersPer5Minutes           int            `json:"max_new_users_per_5_minutes" yaml:"max_new_users_per_5_minutes"`
-		EmailContactVerificationTokenTTL time.Duration  `json:"email_contact_verification_token_ttl" yaml:"email_contact_verification_token_ttl"`
-		WeekdayAssignationWeights        map[string]int `json:"weekday_assignation_weights" yaml:"weekday_assignation_weights"`
-		BlockedPasswordsFilePath         string         `json:"blocked_passwords_file_path" yaml:"blocked_passwords_file_path"`
+		MaxNewUsersPer5Minutes           int                       `json:"max_new_users_per_5_minutes" yaml:"max_new_users_per_5_minutes"`
+		EmailContactVerificationTokenTTL time.Duration             `json:"email_contact_verification_token_ttl" yaml:"email_contact_verification_token_ttl"`
+		CalendarFeedTokenTTL             time.Duration             `json:"calendar_feed_token_ttl" yaml:"calendar_feed_token_ttl"` // extended on each use, default one year
+		LoginLinkTokenTTL                time.Duration             `json:"login_link_token_ttl" yaml:"login_link_token_ttl"`       // default 15 minutes
+		WeekdayAssignationWeights        map[string]int            `json:"weekday_assignation_weights" yaml:"weekday_assignation_weights"`
+		BlockedPasswordsFilePath         string                    `json:"blocked_passwords_file_path" yaml:"blocked_passwords_file_path"`
+		TOTP                             usermanagement.TOTPConfig `json:"totp" yaml:"totp"`
+		WebAuthn                         webauthn.Config           `json:"webauthn" yaml:"webauthn"` // passkeys are disabled if rp_id is empty
+		OIDCProviders                    []oidc.ProviderConfig     `json:"oidc_providers" yaml:"oidc_providers"`
 	} `json:"user_management_config" yaml:"user_management_config"`
 
 	AllowedInstanceIDs []string `json:"allowed_instance_ids" yaml:"allowed_instance_ids"`
@@ -104,7 +116,21 @@ type ParticipantApiConfig struct {
 
 	FilestorePath string `json:"filestore_path" yaml:"filestore_path"`
 
+	// Malware scanning of uploaded participant files, no scanning if not configured
+	FileScannerConfig *filescanner.Config `json:"file_scanner" yaml:"file_scanner"`
+
 	MessagingConfigs messagingTypes.MessagingConfigs `json:"messaging_configs" yaml:"messaging_configs"`
+
+	// Web Push subscriptions, the public key is handed to browsers - push registration is disabled if empty
+	WebPushConfig struct {
+		VAPIDPublicKey         string `json:"vapid_public_key" yaml:"vapid_public_key"`
+		AllowInsecureEndpoints bool   `json:"allow_insecure_endpoints" yaml:"allow_insecure_endpoints"` // accept http endpoints, only for local testing
+	} `json:"web_push" yaml:"web_push"`
+
+	// Server-sent events about changes for participants, needs MongoDB change streams (replica set)
+	LiveUpdatesConfig struct {
+		Enabled bool `json:"enabled" yaml:"enabled"`
+	} `json:"live_updates" yaml:"live_updates"`
 }
 
 var (
@@ -112,6 +138,9 @@ var (
 	globalInfosDBService     *globalinfosDB.GlobalInfosDBService
 	messagingDBService       *messagingDB.MessagingDBService
 	studyDBService           *studyDB.StudyDBService
+	fileScanner              filescanner.Scanner
+	participantEventsHub     *participantevents.Hub
+	oidcProviders            map[string]*oidc.Provider
 )
 
 func init() {
@@ -173,6 +202,10 @@ func init() {
 	initStudyService()
 
 	checkParticipantFilestorePath()
+
+	initFileScanner()
+
+	initLiveUpdates()
 }
 
 func secretsOverride() {
@@ -220,6 +253,10 @@ func secretsOverride() {
 		conf.MessagingConfigs.SMSConfig.APIKey = smsGatewayAPIKey
 	}
 
+	if fileScannerAPIKey := os.Getenv(ENV_FILE_SCANNER_API_KEY); fileScannerAPIKey != "" && conf.FileScannerConfig != nil {
+		conf.FileScannerConfig.APIKey = fileScannerAPIKey
+	}
+
 	if studyGlobalSecret := os.Getenv(ENV_STUDY_GLOBAL_SECRET); studyGlobalSecret != "" {
 		conf.StudyConfigs.GlobalSecret = studyGlobalSecret
 	}
@@ -228,6 +265,21 @@ func secretsOverride() {
 		conf.UserManagementConfig.ParticipantUserJWTConfig.SignKey = participantUserJwtSignKey
 	}
 
+	if totpEncryptionKey := os.Getenv(ENV_TOTP_ENCRYPTION_KEY); totpEncryptionKey != "" {
+		conf.UserManagementConfig.TOTP.EncryptionKey = totpEncryptionKey
+	}
+
+	// Override client secrets of OpenID Connect providers
+	for i := range conf.UserManagementConfig.OIDCProviders {
+		provider := &conf.UserManagementConfig.OIDCProviders[i]
+		if provider.Name == "" {
+			continue
+		}
+		if clientSecret := os.Getenv(utils.GenerateOIDCClientSecretEnvVarName(provider.Name)); clientSecret != "" {
+			provider.ClientSecret = clientSecret
+		}
+	}
+
 	// Override API keys for external services
 	for i := range conf.StudyConfigs.ExternalServices {
 		service := &conf.StudyConfigs.ExternalServices[i]
@@ -262,7 +314,27 @@ func checkParticipantFilestorePath() {
 }
 
 func initUserManagement() {
-	usermanagement.Init(participantUserDBService, globalInfosDBService)
+	usermanagement.Init(participantUserDBService, globalInfosDBService, messagingDBService)
+
+	if conf.UserManagementConfig.TOTP.EncryptionKey != "" {
+		if err := usermanagement.InitTOTP(conf.UserManagementConfig.TOTP); err != nil {
+			slog.Error("Error initializing TOTP", slog.String("error", err.Error()))
+			panic(err)
+		}
+	}
+
+	oidcProviders = map[string]*oidc.Provider{}
+	for _, providerConfig := range conf.UserManagementConfig.OIDCProviders {
+		provider, err := oidc.NewProvider(providerConfig)
+		if err != nil {
+			slog.Error("Error initializing OpenID Connect provider", slog.String("name", providerConfig.Name), slog.String("error", err.Error()))
+			panic(err)
+		}
+		if _, ok := oidcProviders[provider.Name()]; ok {
+			panic("duplicate OpenID Connect provider name: " + provider.Name())
+		}
+		oidcProviders[provider.Name()] = provider
+	}
 }
 
 func initStudyService() {
@@ -340,3 +412,30 @@ func initDBs() {
 		panic(err)
 	}
 }
+
+func initFileScanner() {
+	var err error
+	fileScanner, err = filescanner.NewScanner(conf.FileScannerConfig)
+	if err != nil {
+		slog.Error("Error initializing file scanner", slog.String("error", err.Error()))
+		panic(err)
+	}
+	if fileScanner == nil {
+		slog.Warn("No file scanner configured - uploaded participant files are not scanned for malware")
+	}
+}
+
+func initLiveUpdates() {
+	if !conf.LiveUpdatesConfig.Enabled {
+		return
+	}
+	participantEventsHub = participantevents.NewHub()
+	participantevents.RunChangeStreamWatchers(
+		context.Background(),
+		participantEventsHub,
+		conf.AllowedInstanceIDs,
+		studyDBService,
+		messagingDBService,
+	)
+	slog.Info("Live updates enabled", slog.Int("instances", len(conf.AllowedInstanceIDs)))
+}
@@ -21,9 +21,9 @@ func main() {
 	router.Use(cors.New(cors.Config{
 		// AllowAllOrigins: true,
 		AllowOrigins:     conf.GinConfig.AllowOrigins,
-		AllowMethods:     []string{"POST", "GET", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Content-Length"},
-		ExposeHeaders:    []string{"Authorization", "Content-Type", "Content-Length"},
+		AllowMethods:     []string{"POST", "GET", "PUT", "PATCH", "DELETE"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "Content-Length", "Upload-Offset", "Upload-Checksum"},
+		ExposeHeaders:    []string{"Authorization", "Content-Type", "Content-Length", "Upload-Offset"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -42,16 +42,27 @@ func main() {
 		conf.AllowedInstanceIDs,
 		conf.StudyConfigs.GlobalSecret,
 		conf.FilestorePath,
+		fileScanner,
+		apihandlers.WebPushSettings{
+			VAPIDPublicKey:         conf.WebPushConfig.VAPIDPublicKey,
+			AllowInsecureEndpoints: conf.WebPushConfig.AllowInsecureEndpoints,
+		},
+		conf.UserManagementConfig.WebAuthn,
+		oidcProviders,
+		participantEventsHub,
 		conf.UserManagementConfig.MaxNewUsersPer5Minutes,
 		apihandlers.TTLs{
 			AccessToken:                   conf.UserManagementConfig.ParticipantUserJWTConfig.ExpiresIn,
 			EmailContactVerificationToken: conf.UserManagementConfig.EmailContactVerificationTokenTTL,
+			CalendarFeedToken:             conf.UserManagementConfig.CalendarFeedTokenTTL,
+			LoginLinkToken:                conf.UserManagementConfig.LoginLinkTokenTTL,
 		},
 	)
 	v1APIHandlers.AddParticipantAuthAPI(v1Root)
 	v1APIHandlers.AddPasswordResetAPI(v1Root)
 	v1APIHandlers.AddUserManagementAPI(v1Root)
 	v1APIHandlers.AddStudyServiceAPI(v1Root)
+	v1APIHandlers.StartDataExportCleanup()
 
 	if conf.GinConfig.DebugMode {
 		apihelpers.WriteRoutesToFile(router, "participant-api-routes.txt")
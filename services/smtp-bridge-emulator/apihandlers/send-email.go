@@ -22,11 +22,12 @@ const (
 )
 
 type SendEmailReq struct {
-	To              []string                        `json:"to"`
-	Subject         string                          `json:"subject"`
-	Content         string                          `json:"content"`
-	HighPrio        bool                            `json:"highPrio"`
-	HeaderOverrides *messagingTypes.HeaderOverrides `json:"headerOverrides"`
+	To              []string                         `json:"to"`
+	Subject         string                           `json:"subject"`
+	Content         string                           `json:"content"`
+	HighPrio        bool                             `json:"highPrio"`
+	HeaderOverrides *messagingTypes.HeaderOverrides  `json:"headerOverrides"`
+	Attachments     []messagingTypes.EmailAttachment `json:"attachments,omitempty"`
 }
 
 func (h *HttpEndpoints) AddRoutes(rg *gin.RouterGroup) {
@@ -83,6 +84,15 @@ func saveEmailAsEML(email SendEmailReq, emailsDir string) error {
 			slog.Error("Failed to write email content", slog.String("error", err.Error()))
 			return err
 		}
+
+		// attachments are stored next to the EML file: <timestamp>_<filename>
+		for _, attachment := range email.Attachments {
+			attachmentPath := strings.TrimSuffix(emlFilePath, EML_FILE_EXTENSION) + "_" + filepath.Base(attachment.Filename)
+			if err := os.WriteFile(attachmentPath, attachment.Content, 0644); err != nil {
+				slog.Error("Failed to write attachment", slog.String("path", attachmentPath), slog.String("error", err.Error()))
+				return err
+			}
+		}
 	}
 
 	slog.Info("Email has been saved as EML file successfully")
@@ -26,11 +26,12 @@ func (h *HttpEndpoints) AddRoutes(rg *gin.RouterGroup) {
 }
 
 type SendEmailReq struct {
-	To              []string                        `json:"to"`
-	Subject         string                          `json:"subject"`
-	Content         string                          `json:"content"`
-	HighPrio        bool                            `json:"highPrio"`
-	HeaderOverrides *messagingTypes.HeaderOverrides `json:"headerOverrides"`
+	To              []string                         `json:"to"`
+	Subject         string                           `json:"subject"`
+	Content         string                           `json:"content"`
+	HighPrio        bool                             `json:"highPrio"`
+	HeaderOverrides *messagingTypes.HeaderOverrides  `json:"headerOverrides"`
+	Attachments     []messagingTypes.EmailAttachment `json:"attachments,omitempty"`
 }
 
 func (h *HttpEndpoints) sendEmail(c *gin.Context) {
@@ -56,6 +57,7 @@ func (h *HttpEndpoints) sendEmail(c *gin.Context) {
 				req.Subject,
 				req.Content,
 				req.HeaderOverrides,
+				req.Attachments,
 			)
 		} else {
 			err = h.lowPrioSmtpClients.SendMail(
@@ -63,6 +65,7 @@ func (h *HttpEndpoints) sendEmail(c *gin.Context) {
 				req.Subject,
 				req.Content,
 				req.HeaderOverrides,
+				req.Attachments,
 			)
 		}
 		if err != nil {